@@ -26,8 +26,8 @@ var (
 	EndpointWriteAPI api.WriteAPI
 
 	Queue           amqp.Queue
-	ConsumeChannel  amqp.Channel
-	AmqpChannels    []amqp.Channel
+	ConsumeChannel  *amqp.Channel
+	AmqpChannels    []*amqp.Channel
 	amqlChannelLock sync.Mutex
 
 	UsableAPIKeys datastructures.APIKeysInUse
@@ -39,9 +39,13 @@ func InitConfig() error {
 		return err
 	}
 	var waitG sync.WaitGroup
-	commonUtil.EnsureAllEnvVarsAreSet("RABBITMQ_PASSWORD", "RABBITMQ_USER",
-		"RABBITMQ_URL", "DATASTORE_INSTANCE", "WORKER_AMOUNT", "STEAM_API_KEYS",
+	commonUtil.EnsureAllEnvVarsAreSet("DATASTORE_INSTANCE", "WORKER_AMOUNT", "STEAM_API_KEYS",
 		"KEY_SLEEP_TIME")
+	usingRabbitMQ := getJobQueueBackend() == "rabbitmq"
+	if usingRabbitMQ {
+		commonUtil.EnsureAllEnvVarsAreSet("RABBITMQ_PASSWORD", "RABBITMQ_USER",
+			"RABBITMQ_URL")
+	}
 	logConfig, err := commonUtil.LoadLoggingConfig()
 	if err != nil {
 		return commonUtil.MakeErr(err)
@@ -49,11 +53,14 @@ func InitConfig() error {
 	logger := commonUtil.InitLogger(logConfig)
 	Logger = logger
 
-	waitG.Add(4)
+	waitG.Add(2)
 	go InitAndSetWorkerConfig(&waitG)
-	go setupMainAMQPConnection(&waitG)
-	go InitExtraAMQPChannels(&waitG)
 	go InitAndSetInfluxClient(&waitG)
+	if usingRabbitMQ {
+		waitG.Add(2)
+		go setupMainAMQPConnection(&waitG)
+		go InitExtraAMQPChannels(&waitG)
+	}
 
 	waitG.Wait()
 	return nil
@@ -65,11 +72,34 @@ func InitAndSetWorkerConfig(waitG *sync.WaitGroup) {
 
 	workerAmountFromEnv, _ := strconv.Atoi(os.Getenv("WORKER_AMOUNT"))
 	workerConfig.WorkerAmount = workerAmountFromEnv
+	workerConfig.JobQueueBackend = getJobQueueBackend()
+	workerConfig.FreshnessWindowHours = getFreshnessWindowHours()
 
 	WorkerConfig = workerConfig
 }
 
-func InitRabbitMQConnection() (amqp.Queue, amqp.Channel) {
+// getJobQueueBackend returns the job queue backend to use,
+// defaulting to rabbitMQ when none is given
+func getJobQueueBackend() string {
+	backend := os.Getenv("JOB_QUEUE_BACKEND")
+	if backend == "" {
+		return "rabbitmq"
+	}
+	return backend
+}
+
+// getFreshnessWindowHours returns how many hours a stored user is
+// trusted for before being refetched, defaulting to one week when
+// none is given
+func getFreshnessWindowHours() int {
+	freshnessWindowHours, err := strconv.Atoi(os.Getenv("FRESHNESS_WINDOW_HOURS"))
+	if err != nil || freshnessWindowHours <= 0 {
+		return 168
+	}
+	return freshnessWindowHours
+}
+
+func InitRabbitMQConnection() (amqp.Queue, *amqp.Channel) {
 	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s", os.Getenv("RABBITMQ_USER"), os.Getenv("RABBITMQ_PASSWORD"), os.Getenv("RABBITMQ_URL")))
 	if err != nil {
 		log.Fatal(commonUtil.MakeErr(err))
@@ -103,7 +133,7 @@ func InitRabbitMQConnection() (amqp.Queue, amqp.Channel) {
 	}
 
 	Logger.Info("started rabbitMQ connection")
-	return queue, *channel
+	return queue, channel
 }
 
 func setupMainAMQPConnection(waitG *sync.WaitGroup) {
@@ -16,8 +16,44 @@ func TestMain(m *testing.M) {
 
 func TestInitAndSetWorkerConfig(t *testing.T) {
 	os.Setenv("WORKER_AMOUNT", "8")
+	os.Unsetenv("JOB_QUEUE_BACKEND")
 	expectedWorkerConfig := datastructures.WorkerConfig{
-		WorkerAmount: 8,
+		WorkerAmount:         8,
+		JobQueueBackend:      "rabbitmq",
+		FreshnessWindowHours: 168,
+	}
+	var waitG sync.WaitGroup
+	waitG.Add(1)
+	InitAndSetWorkerConfig(&waitG)
+	waitG.Wait()
+	assert.Equal(t, expectedWorkerConfig, WorkerConfig)
+}
+
+func TestInitAndSetWorkerConfigUsesJobQueueBackendFromEnv(t *testing.T) {
+	os.Setenv("WORKER_AMOUNT", "8")
+	os.Setenv("JOB_QUEUE_BACKEND", "inmemory")
+	defer os.Unsetenv("JOB_QUEUE_BACKEND")
+	expectedWorkerConfig := datastructures.WorkerConfig{
+		WorkerAmount:         8,
+		JobQueueBackend:      "inmemory",
+		FreshnessWindowHours: 168,
+	}
+	var waitG sync.WaitGroup
+	waitG.Add(1)
+	InitAndSetWorkerConfig(&waitG)
+	waitG.Wait()
+	assert.Equal(t, expectedWorkerConfig, WorkerConfig)
+}
+
+func TestInitAndSetWorkerConfigUsesFreshnessWindowFromEnv(t *testing.T) {
+	os.Setenv("WORKER_AMOUNT", "8")
+	os.Unsetenv("JOB_QUEUE_BACKEND")
+	os.Setenv("FRESHNESS_WINDOW_HOURS", "24")
+	defer os.Unsetenv("FRESHNESS_WINDOW_HOURS")
+	expectedWorkerConfig := datastructures.WorkerConfig{
+		WorkerAmount:         8,
+		JobQueueBackend:      "rabbitmq",
+		FreshnessWindowHours: 24,
 	}
 	var waitG sync.WaitGroup
 	waitG.Add(1)
@@ -14,33 +14,43 @@ import (
 
 	"github.com/iamcathal/neo/services/crawler/apikeymanager"
 	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/iamcathal/neo/services/crawler/jobqueue"
 	"github.com/neosteamfriendgraphing/common"
 	"github.com/neosteamfriendgraphing/common/dtos"
 	"github.com/neosteamfriendgraphing/common/util"
 	commonUtil "github.com/neosteamfriendgraphing/common/util"
-	"github.com/streadway/amqp"
 	"go.uber.org/zap"
 )
 
-type Cntr struct{}
+type Cntr struct {
+	JobQueue jobqueue.JobQueue
+}
 
 type CntrInterface interface {
 	// Steam web API related functions
 	CallGetFriends(steamID string) ([]string, error)
 	CallGetPlayerSummaries(steamIDList string) ([]common.Player, error)
 	CallGetOwnedGames(steamID string) (common.GamesOwnedResponse, error)
-	// RabbitMQ related functions
-	PublishToJobsQueue(channel amqp.Channel, jobJSON []byte) error
-	ConsumeFromJobsQueue() (<-chan amqp.Delivery, error)
+	// Job queue related functions
+	PublishToJobsQueue(job datastructures.Job) error
+	ConsumeFromJobsQueue() (<-chan jobqueue.Delivery, error)
 	// Datastore related functions
-	SaveUserToDataStore(dtos.SaveUserDTO) (bool, error)
+	SaveUserToDataStore(datastructures.SaveUserDTO) (bool, error)
 	GetUserFromDataStore(steamID string) (common.UserDocument, error)
-	SaveCrawlingStatsToDataStore(currentLevel int, crawlingStatus common.CrawlingStatus) (bool, error)
-	GetCrawlingStatsFromDataStore(crawlID string) (common.CrawlingStatus, error)
+	GetUsersFromDataStore(steamIDs []string) ([]common.UserDocument, error)
+	SaveCrawlingStatsToDataStore(currentLevel int, crawlingStatus datastructures.CrawlingStatus) (bool, error)
+	GetCrawlingStatsFromDataStore(crawlID string) (datastructures.CrawlingStatus, error)
+	UpdateCrawlBudgetInDataStore(crawlID string, budgetUsage datastructures.CrawlBudgetUsage) (int, error)
+	UpdateCrawlStateInDataStore(crawlID, state, leaseID string) (bool, error)
+	RenewGraphingLeaseInDataStore(crawlID, leaseID string) (bool, error)
 	GetGraphableDataFromDataStore(steamID string) (dtos.GetGraphableDataForUserDTO, error)
 	GetUsernamesForSteamIDs(steamIDs []string) (map[string]string, error)
-	SaveProcessedGraphDataToDataStore(crawlID string, graphData common.UsersGraphData) (bool, error)
+	SaveProcessedGraphDataToDataStore(crawlID string, graphData datastructures.UsersGraphData) (bool, error)
 	GetGameDetailsFromIDs(gameIDs []int) ([]common.BareGameInfo, error)
+	SaveWatchedUserToDataStore(watchedUser datastructures.WatchedUser) (bool, error)
+	GetWatchedUsersFromDataStore() ([]datastructures.WatchedUser, error)
+	ClaimWatchedUserCrawlInDataStore(steamID string, claim datastructures.WatchedUserCrawlClaim) (bool, error)
 
 	Sleep(duration time.Duration)
 }
@@ -284,35 +294,21 @@ func (control Cntr) CallGetOwnedGames(steamID string) (common.GamesOwnedResponse
 	return apiResponse.Response, nil
 }
 
-// PublishToJobsQueue publishes a job to the rabbitMQ queue
+// PublishToJobsQueue publishes a job to the jobs queue
 //		err := PublishToJobsQueue(job)
-func (control Cntr) PublishToJobsQueue(channel amqp.Channel, jobJSON []byte) error {
-	return channel.Publish(
-		"",                       // exchange
-		configuration.Queue.Name, // routing key
-		false,                    // mandatory
-		false,                    // immediate
-		amqp.Publishing{
-			ContentType: "text/json",
-			Body:        jobJSON,
-		})
+func (control Cntr) PublishToJobsQueue(job datastructures.Job) error {
+	return control.JobQueue.Publish(job)
 }
 
-func (control Cntr) ConsumeFromJobsQueue() (<-chan amqp.Delivery, error) {
-	return configuration.ConsumeChannel.Consume(
-		configuration.Queue.Name, // queue
-		"",                       // consumer
-		false,                    // auto-ack
-		false,                    // exclusive
-		false,                    // no-local
-		false,                    // no-wait
-		nil,                      // args
-	)
+// ConsumeFromJobsQueue starts consuming jobs from the jobs queue
+//		deliveries, err := ConsumeFromJobsQueue()
+func (control Cntr) ConsumeFromJobsQueue() (<-chan jobqueue.Delivery, error) {
+	return control.JobQueue.Consume()
 }
 
 // SaveUserToDataStore sends a user to the datastore service to be saved
 // 		userWasSaved, err := SaveUserToDataStore(user)
-func (control Cntr) SaveUserToDataStore(saveUser dtos.SaveUserDTO) (bool, error) {
+func (control Cntr) SaveUserToDataStore(saveUser datastructures.SaveUserDTO) (bool, error) {
 	targetURL := fmt.Sprintf("http://%s/api/saveuser", os.Getenv("DATASTORE_INSTANCE"))
 	jsonObj, err := json.Marshal(saveUser)
 	if err != nil {
@@ -440,9 +436,9 @@ func (control Cntr) GetUserFromDataStore(steamID string) (common.UserDocument, e
 	return userDoc.User, nil
 }
 
-func (control Cntr) SaveCrawlingStatsToDataStore(currentLevel int, crawlingStatus common.CrawlingStatus) (bool, error) {
+func (control Cntr) SaveCrawlingStatsToDataStore(currentLevel int, crawlingStatus datastructures.CrawlingStatus) (bool, error) {
 	targetURL := fmt.Sprintf("http://%s/api/savecrawlingstats", os.Getenv("DATASTORE_INSTANCE"))
-	crawlingStatsDTO := dtos.SaveCrawlingStatsDTO{
+	crawlingStatsDTO := datastructures.SaveCrawlingStatsDTO{
 		CurrentLevel:   currentLevel,
 		CrawlingStatus: crawlingStatus,
 	}
@@ -512,11 +508,67 @@ func (control Cntr) SaveCrawlingStatsToDataStore(currentLevel int, crawlingStatu
 	return true, nil
 }
 
-func (control Cntr) GetCrawlingStatsFromDataStore(crawlID string) (common.CrawlingStatus, error) {
+// GetUsersFromDataStore gets many users from the datastore service in one
+// request. Users that are not in the datastore are left out
+// 		usersFromDataStore, err := GetUsersFromDataStore(steamIDs)
+func (control Cntr) GetUsersFromDataStore(steamIDs []string) ([]common.UserDocument, error) {
+	targetURL := fmt.Sprintf("http://%s/api/getusers", os.Getenv("DATASTORE_INSTANCE"))
+	usersInput := datastructures.GetUsersInputDTO{
+		SteamIDs: steamIDs,
+	}
+	jsonObj, err := json.Marshal(usersInput)
+	if err != nil {
+		return []common.UserDocument{}, commonUtil.MakeErr(err)
+	}
+
+	client := &http.Client{}
+	maxRetryCount := 3
+	var lastErr error
+	for i := 0; i <= maxRetryCount; i++ {
+		if i > 0 {
+			exponentialBackOffSleepTime := math.Pow(2, float64(i-1)) * 16
+			configuration.Logger.Sugar().Infof("failed to get %d users from %s %d times (%+v). Sleeping for %d ms", len(steamIDs), targetURL, i, lastErr, int(exponentialBackOffSleepTime))
+			time.Sleep(time.Duration(exponentialBackOffSleepTime) * time.Millisecond)
+		}
+
+		req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+		if err != nil {
+			return []common.UserDocument{}, err
+		}
+		req.Close = true
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+		res, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		// The users are streamed back so a failure part way through
+		// is only noticed when decoding the response
+		usersRes := datastructures.GetUsersDTO{}
+		err = json.NewDecoder(res.Body).Decode(&usersRes)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("getusers returned status %d", res.StatusCode)
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return usersRes.Users, nil
+	}
+
+	failedAllRetriesErr := fmt.Errorf("failed all retries to %s for %d steamIDs: %+v", targetURL, len(steamIDs), lastErr)
+	return []common.UserDocument{}, commonUtil.MakeErr(failedAllRetriesErr)
+}
+
+func (control Cntr) GetCrawlingStatsFromDataStore(crawlID string) (datastructures.CrawlingStatus, error) {
 	targetURL := fmt.Sprintf("http://%s/api/getcrawlingstatus/%s", os.Getenv("DATASTORE_INSTANCE"), crawlID)
 	req, err := http.NewRequest("GET", targetURL, nil)
 	if err != nil {
-		return common.CrawlingStatus{}, err
+		return datastructures.CrawlingStatus{}, err
 	}
 	req.Close = true
 	req.Header.Set("Content-Type", "application/json")
@@ -552,22 +604,94 @@ func (control Cntr) GetCrawlingStatsFromDataStore(crawlID string) (common.Crawli
 	// Failed after all retries
 	if !successfulRequest {
 		failedAllRetriesErr := fmt.Errorf("failed all retries to %s for crawlID: %s", targetURL, crawlID)
-		return common.CrawlingStatus{}, commonUtil.MakeErr(failedAllRetriesErr)
+		return datastructures.CrawlingStatus{}, commonUtil.MakeErr(failedAllRetriesErr)
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return common.CrawlingStatus{}, commonUtil.MakeErr(err, "failed to readAll for getcrawlingstatus body")
+		return datastructures.CrawlingStatus{}, commonUtil.MakeErr(err, "failed to readAll for getcrawlingstatus body")
 	}
-	APIRes := dtos.GetCrawlingStatusDTO{}
+	APIRes := datastructures.GetCrawlingStatusDTO{}
 	err = json.Unmarshal(body, &APIRes)
 	if err != nil {
-		return common.CrawlingStatus{}, commonUtil.MakeErr(err, fmt.Sprintf("failed to unmarshal getcrawlingstatus object: %+v", string(body)))
+		return datastructures.CrawlingStatus{}, commonUtil.MakeErr(err, fmt.Sprintf("failed to unmarshal getcrawlingstatus object: %+v", string(body)))
 	}
 
 	return APIRes.CrawlingStatus, nil
 }
 
+// UpdateCrawlBudgetInDataStore records the budget usage of a crawl and
+// reserves users from its budget. The amount of the requested users that
+// may be placed in the queue is returned
+//		usersGranted, err := UpdateCrawlBudgetInDataStore(crawlID, budgetUsage)
+func (control Cntr) UpdateCrawlBudgetInDataStore(crawlID string, budgetUsage datastructures.CrawlBudgetUsage) (int, error) {
+	targetURL := fmt.Sprintf("http://%s/api/updatecrawlbudget/%s", os.Getenv("DATASTORE_INSTANCE"), crawlID)
+	jsonObj, err := json.Marshal(budgetUsage)
+	if err != nil {
+		return 0, commonUtil.MakeErr(err)
+	}
+	req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+	if err != nil {
+		return 0, err
+	}
+	req.Close = true
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	client := &http.Client{}
+	maxRetryCount := 3
+	successfulRequest := false
+
+	res, err := client.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		logMsg := fmt.Sprintf("error from first call to updatecrawlbudget (%s), retrying now", targetURL)
+		configuration.Logger.Info(logMsg,
+			zap.String("errorMsg", fmt.Sprint(err)),
+			zap.String("request", fmt.Sprintf("%+v", res)),
+			zap.String("response", fmt.Sprintf("%+v", req)))
+
+		for i := 0; i < maxRetryCount; i++ {
+			req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+			if err != nil {
+				return 0, err
+			}
+			req.Close = true
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+			res, err = client.Do(req)
+			if err == nil && res.StatusCode == http.StatusOK {
+				successfulRequest = true
+				defer res.Body.Close()
+				break
+			}
+
+			exponentialBackOffSleepTime := math.Pow(2, float64(i)) * 16
+			configuration.Logger.Sugar().Infof("failed to call %s for %+v %d times. Sleeping for %d ms", targetURL, budgetUsage, i, int(exponentialBackOffSleepTime))
+			time.Sleep(time.Duration(exponentialBackOffSleepTime) * time.Millisecond)
+		}
+	} else {
+		successfulRequest = true
+	}
+	// Failed after all retries
+	if !successfulRequest {
+		failedAllRetriesErr := fmt.Errorf("failed all retries to %s for crawlID: %s", targetURL, crawlID)
+		return 0, commonUtil.MakeErr(failedAllRetriesErr)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, commonUtil.MakeErr(err, "failed to readAll for updatecrawlbudget body")
+	}
+	APIRes := datastructures.UpdateCrawlBudgetDTO{}
+	err = json.Unmarshal(body, &APIRes)
+	if err != nil {
+		return 0, commonUtil.MakeErr(err, fmt.Sprintf("failed to unmarshal updatecrawlbudget object: %+v", string(body)))
+	}
+
+	return APIRes.UsersGranted, nil
+}
+
 func (control Cntr) GetGraphableDataFromDataStore(steamID string) (dtos.GetGraphableDataForUserDTO, error) {
 	targetURL := fmt.Sprintf("http://%s/api/getgraphabledata/%s", os.Getenv("DATASTORE_INSTANCE"), steamID)
 	req, err := http.NewRequest("GET", targetURL, nil)
@@ -703,7 +827,7 @@ func (control Cntr) GetUsernamesForSteamIDs(steamIDs []string) (map[string]strin
 	return steamIDToUserMap, nil
 }
 
-func (control Cntr) SaveProcessedGraphDataToDataStore(crawlID string, graphData common.UsersGraphData) (bool, error) {
+func (control Cntr) SaveProcessedGraphDataToDataStore(crawlID string, graphData datastructures.UsersGraphData) (bool, error) {
 	targetURL := fmt.Sprintf("http://%s/api/saveprocessedgraphdata/%s", os.Getenv("DATASTORE_INSTANCE"), crawlID)
 
 	jsonObj, err := json.Marshal(graphData)
@@ -878,3 +1002,300 @@ func IsInvalidKeyResponse(response string) bool {
 func IsErrorResponse(response string) bool {
 	return strings.HasPrefix(response, "<html>")
 }
+
+func (control Cntr) SaveWatchedUserToDataStore(watchedUser datastructures.WatchedUser) (bool, error) {
+	targetURL := fmt.Sprintf("http://%s/api/savewatcheduser", os.Getenv("DATASTORE_INSTANCE"))
+	jsonObj, err := json.Marshal(watchedUser)
+	if err != nil {
+		return false, commonUtil.MakeErr(err)
+	}
+	req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+	if err != nil {
+		return false, err
+	}
+	req.Close = true
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	client := &http.Client{}
+	maxRetryCount := 3
+	successfulRequest := false
+
+	res, err := client.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		logMsg := fmt.Sprintf("error from first call to savewatcheduser (%s), retrying now", targetURL)
+		configuration.Logger.Info(logMsg,
+			zap.String("errorMsg", fmt.Sprint(err)),
+			zap.String("request", fmt.Sprintf("%+v", res)),
+			zap.String("response", fmt.Sprintf("%+v", req)))
+
+		for i := 0; i < maxRetryCount; i++ {
+			req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+			if err != nil {
+				return false, err
+			}
+			req.Close = true
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+			res, err = client.Do(req)
+			if err == nil && res.StatusCode == http.StatusOK {
+				successfulRequest = true
+				defer res.Body.Close()
+				break
+			}
+
+			exponentialBackOffSleepTime := math.Pow(2, float64(i)) * 16
+			configuration.Logger.Sugar().Infof("failed to call %s for %+v %d times. Sleeping for %d ms", targetURL, watchedUser, i, int(exponentialBackOffSleepTime))
+			time.Sleep(time.Duration(exponentialBackOffSleepTime) * time.Millisecond)
+		}
+	} else {
+		successfulRequest = true
+	}
+	// Failed after all retries
+	if !successfulRequest {
+		failedAllRetriesErr := fmt.Errorf("failed all retries to %s for steamID: %s", targetURL, watchedUser.SteamID)
+		return false, commonUtil.MakeErr(failedAllRetriesErr)
+	}
+	return true, nil
+}
+
+func (control Cntr) GetWatchedUsersFromDataStore() ([]datastructures.WatchedUser, error) {
+	targetURL := fmt.Sprintf("http://%s/api/getwatchedusers", os.Getenv("DATASTORE_INSTANCE"))
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return []datastructures.WatchedUser{}, err
+	}
+	req.Close = true
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	client := &http.Client{}
+	maxRetryCount := 3
+	successfulRequest := false
+
+	res, err := client.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		logMsg := fmt.Sprintf("error from first call to getwatchedusers (%s), retrying now", targetURL)
+		configuration.Logger.Info(logMsg,
+			zap.String("errorMsg", fmt.Sprint(err)),
+			zap.String("request", fmt.Sprintf("%+v", res)),
+			zap.String("response", fmt.Sprintf("%+v", req)))
+
+		for i := 0; i < maxRetryCount; i++ {
+			res, err = client.Do(req)
+			if err == nil && res.StatusCode == http.StatusOK {
+				successfulRequest = true
+				defer res.Body.Close()
+				break
+			}
+
+			exponentialBackOffSleepTime := math.Pow(2, float64(i)) * 16
+			configuration.Logger.Sugar().Infof("failed to call %s %d times. Sleeping for %d ms", targetURL, i, int(exponentialBackOffSleepTime))
+			time.Sleep(time.Duration(exponentialBackOffSleepTime) * time.Millisecond)
+		}
+	} else {
+		successfulRequest = true
+	}
+	// Failed after all retries
+	if !successfulRequest {
+		failedAllRetriesErr := fmt.Errorf("failed all retries to %s", targetURL)
+		return []datastructures.WatchedUser{}, commonUtil.MakeErr(failedAllRetriesErr)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return []datastructures.WatchedUser{}, commonUtil.MakeErr(err, "failed to readAll for getwatchedusers body")
+	}
+	APIRes := datastructures.GetWatchedUsersDTO{}
+	err = json.Unmarshal(body, &APIRes)
+	if err != nil {
+		return []datastructures.WatchedUser{}, commonUtil.MakeErr(err, fmt.Sprintf("failed to unmarshal getwatchedusers object: %+v", string(body)))
+	}
+
+	return APIRes.WatchedUsers, nil
+}
+
+// ClaimWatchedUserCrawlInDataStore records a scheduled crawl for a watched
+// user. False is returned if another crawler has already claimed the crawl
+//		claimed, err := ClaimWatchedUserCrawlInDataStore(steamID, claim)
+func (control Cntr) ClaimWatchedUserCrawlInDataStore(steamID string, claim datastructures.WatchedUserCrawlClaim) (bool, error) {
+	targetURL := fmt.Sprintf("http://%s/api/claimwatchedusercrawl/%s", os.Getenv("DATASTORE_INSTANCE"), steamID)
+	jsonObj, err := json.Marshal(claim)
+	if err != nil {
+		return false, commonUtil.MakeErr(err)
+	}
+	req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+	if err != nil {
+		return false, err
+	}
+	req.Close = true
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	client := &http.Client{}
+	maxRetryCount := 3
+	successfulRequest := false
+
+	res, err := client.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		logMsg := fmt.Sprintf("error from first call to claimwatchedusercrawl (%s), retrying now", targetURL)
+		configuration.Logger.Info(logMsg,
+			zap.String("errorMsg", fmt.Sprint(err)),
+			zap.String("request", fmt.Sprintf("%+v", res)),
+			zap.String("response", fmt.Sprintf("%+v", req)))
+
+		for i := 0; i < maxRetryCount; i++ {
+			req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+			if err != nil {
+				return false, err
+			}
+			req.Close = true
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+			res, err = client.Do(req)
+			if err == nil && res.StatusCode == http.StatusOK {
+				successfulRequest = true
+				defer res.Body.Close()
+				break
+			}
+
+			exponentialBackOffSleepTime := math.Pow(2, float64(i)) * 16
+			configuration.Logger.Sugar().Infof("failed to call %s for %+v %d times. Sleeping for %d ms", targetURL, claim, i, int(exponentialBackOffSleepTime))
+			time.Sleep(time.Duration(exponentialBackOffSleepTime) * time.Millisecond)
+		}
+	} else {
+		successfulRequest = true
+	}
+	// Failed after all retries
+	if !successfulRequest {
+		failedAllRetriesErr := fmt.Errorf("failed all retries to %s for crawlID: %s", targetURL, claim.CrawlID)
+		return false, commonUtil.MakeErr(failedAllRetriesErr)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return false, commonUtil.MakeErr(err, "failed to readAll for claimwatchedusercrawl body")
+	}
+	APIRes := datastructures.ClaimWatchedUserCrawlDTO{}
+	err = json.Unmarshal(body, &APIRes)
+	if err != nil {
+		return false, commonUtil.MakeErr(err, fmt.Sprintf("failed to unmarshal claimwatchedusercrawl object: %+v", string(body)))
+	}
+
+	return APIRes.Claimed, nil
+}
+
+// UpdateCrawlStateInDataStore moves a crawl to a new state. False is
+// returned if the crawl was not in a state that can move to the new state.
+// Graph builds give the lease they hold on the crawl while it is graphing
+//		updated, err := UpdateCrawlStateInDataStore(crawlID, state, leaseID)
+func (control Cntr) UpdateCrawlStateInDataStore(crawlID, state, leaseID string) (bool, error) {
+	targetURL := fmt.Sprintf("http://%s/api/updatecrawlstate/%s", os.Getenv("DATASTORE_INSTANCE"), crawlID)
+	jsonObj, err := json.Marshal(datastructures.UpdateCrawlStateInputDTO{State: state, LeaseID: leaseID})
+	if err != nil {
+		return false, commonUtil.MakeErr(err)
+	}
+	req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+	if err != nil {
+		return false, err
+	}
+	req.Close = true
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	client := &http.Client{}
+	maxRetryCount := 3
+	successfulRequest := false
+
+	res, err := client.Do(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		logMsg := fmt.Sprintf("error from first call to updatecrawlstate (%s), retrying now", targetURL)
+		configuration.Logger.Info(logMsg,
+			zap.String("errorMsg", fmt.Sprint(err)),
+			zap.String("request", fmt.Sprintf("%+v", res)),
+			zap.String("response", fmt.Sprintf("%+v", req)))
+
+		for i := 0; i < maxRetryCount; i++ {
+			req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+			if err != nil {
+				return false, err
+			}
+			req.Close = true
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+			res, err = client.Do(req)
+			if err == nil && res.StatusCode == http.StatusOK {
+				successfulRequest = true
+				defer res.Body.Close()
+				break
+			}
+
+			exponentialBackOffSleepTime := math.Pow(2, float64(i)) * 16
+			configuration.Logger.Sugar().Infof("failed to call %s for state %s %d times. Sleeping for %d ms", targetURL, state, i, int(exponentialBackOffSleepTime))
+			time.Sleep(time.Duration(exponentialBackOffSleepTime) * time.Millisecond)
+		}
+	} else {
+		successfulRequest = true
+	}
+	// Failed after all retries
+	if !successfulRequest {
+		failedAllRetriesErr := fmt.Errorf("failed all retries to %s for crawlID: %s", targetURL, crawlID)
+		return false, commonUtil.MakeErr(failedAllRetriesErr)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return false, commonUtil.MakeErr(err, "failed to readAll for updatecrawlstate body")
+	}
+	APIRes := datastructures.UpdateCrawlStateDTO{}
+	err = json.Unmarshal(body, &APIRes)
+	if err != nil {
+		return false, commonUtil.MakeErr(err, fmt.Sprintf("failed to unmarshal updatecrawlstate object: %+v", string(body)))
+	}
+
+	return APIRes.Updated, nil
+}
+
+// RenewGraphingLeaseInDataStore records that the graph build holding the
+// lease of a graphing crawl is still running. It is not retried as the
+// lease is renewed again shortly after. False is returned if the crawl is
+// no longer graphing under the lease
+//		renewed, err := RenewGraphingLeaseInDataStore(crawlID, leaseID)
+func (control Cntr) RenewGraphingLeaseInDataStore(crawlID, leaseID string) (bool, error) {
+	targetURL := fmt.Sprintf("http://%s/api/renewgraphinglease/%s", os.Getenv("DATASTORE_INSTANCE"), crawlID)
+	jsonObj, err := json.Marshal(datastructures.RenewGraphingLeaseInputDTO{LeaseID: leaseID})
+	if err != nil {
+		return false, commonUtil.MakeErr(err)
+	}
+	req, err := http.NewRequest("POST", targetURL, bytes.NewBuffer(jsonObj))
+	if err != nil {
+		return false, err
+	}
+	req.Close = true
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return false, commonUtil.MakeErr(err, "failed to call renewgraphinglease")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return false, commonUtil.MakeErr(fmt.Errorf("renewgraphinglease returned status %d for crawlID: %s", res.StatusCode, crawlID))
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return false, commonUtil.MakeErr(err, "failed to readAll for renewgraphinglease body")
+	}
+	APIRes := datastructures.RenewGraphingLeaseDTO{}
+	err = json.Unmarshal(body, &APIRes)
+	if err != nil {
+		return false, commonUtil.MakeErr(err, fmt.Sprintf("failed to unmarshal renewgraphinglease object: %+v", string(body)))
+	}
+
+	return APIRes.Renewed, nil
+}
@@ -4,10 +4,13 @@ package controller
 
 import (
 	common "github.com/neosteamfriendgraphing/common"
-	amqp "github.com/streadway/amqp"
+
+	datastructures "github.com/iamcathal/neo/services/crawler/datastructures"
 
 	dtos "github.com/neosteamfriendgraphing/common/dtos"
 
+	jobqueue "github.com/iamcathal/neo/services/crawler/jobqueue"
+
 	mock "github.com/stretchr/testify/mock"
 
 	time "time"
@@ -85,16 +88,37 @@ func (_m *MockCntrInterface) CallGetPlayerSummaries(steamIDList string) ([]commo
 	return r0, r1
 }
 
+// ClaimWatchedUserCrawlInDataStore provides a mock function with given fields: steamID, claim
+func (_m *MockCntrInterface) ClaimWatchedUserCrawlInDataStore(steamID string, claim datastructures.WatchedUserCrawlClaim) (bool, error) {
+	ret := _m.Called(steamID, claim)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, datastructures.WatchedUserCrawlClaim) bool); ok {
+		r0 = rf(steamID, claim)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, datastructures.WatchedUserCrawlClaim) error); ok {
+		r1 = rf(steamID, claim)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ConsumeFromJobsQueue provides a mock function with given fields:
-func (_m *MockCntrInterface) ConsumeFromJobsQueue() (<-chan amqp.Delivery, error) {
+func (_m *MockCntrInterface) ConsumeFromJobsQueue() (<-chan jobqueue.Delivery, error) {
 	ret := _m.Called()
 
-	var r0 <-chan amqp.Delivery
-	if rf, ok := ret.Get(0).(func() <-chan amqp.Delivery); ok {
+	var r0 <-chan jobqueue.Delivery
+	if rf, ok := ret.Get(0).(func() <-chan jobqueue.Delivery); ok {
 		r0 = rf()
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(<-chan amqp.Delivery)
+			r0 = ret.Get(0).(<-chan jobqueue.Delivery)
 		}
 	}
 
@@ -109,14 +133,14 @@ func (_m *MockCntrInterface) ConsumeFromJobsQueue() (<-chan amqp.Delivery, error
 }
 
 // GetCrawlingStatsFromDataStore provides a mock function with given fields: crawlID
-func (_m *MockCntrInterface) GetCrawlingStatsFromDataStore(crawlID string) (common.CrawlingStatus, error) {
+func (_m *MockCntrInterface) GetCrawlingStatsFromDataStore(crawlID string) (datastructures.CrawlingStatus, error) {
 	ret := _m.Called(crawlID)
 
-	var r0 common.CrawlingStatus
-	if rf, ok := ret.Get(0).(func(string) common.CrawlingStatus); ok {
+	var r0 datastructures.CrawlingStatus
+	if rf, ok := ret.Get(0).(func(string) datastructures.CrawlingStatus); ok {
 		r0 = rf(crawlID)
 	} else {
-		r0 = ret.Get(0).(common.CrawlingStatus)
+		r0 = ret.Get(0).(datastructures.CrawlingStatus)
 	}
 
 	var r1 error
@@ -217,13 +241,59 @@ func (_m *MockCntrInterface) GetUsernamesForSteamIDs(steamIDs []string) (map[str
 	return r0, r1
 }
 
-// PublishToJobsQueue provides a mock function with given fields: channel, jobJSON
-func (_m *MockCntrInterface) PublishToJobsQueue(channel amqp.Channel, jobJSON []byte) error {
-	ret := _m.Called(channel, jobJSON)
+// GetUsersFromDataStore provides a mock function with given fields: steamIDs
+func (_m *MockCntrInterface) GetUsersFromDataStore(steamIDs []string) ([]common.UserDocument, error) {
+	ret := _m.Called(steamIDs)
+
+	var r0 []common.UserDocument
+	if rf, ok := ret.Get(0).(func([]string) []common.UserDocument); ok {
+		r0 = rf(steamIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]common.UserDocument)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(steamIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWatchedUsersFromDataStore provides a mock function with given fields: 
+func (_m *MockCntrInterface) GetWatchedUsersFromDataStore() ([]datastructures.WatchedUser, error) {
+	ret := _m.Called()
+
+	var r0 []datastructures.WatchedUser
+	if rf, ok := ret.Get(0).(func() []datastructures.WatchedUser); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]datastructures.WatchedUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PublishToJobsQueue provides a mock function with given fields: job
+func (_m *MockCntrInterface) PublishToJobsQueue(job datastructures.Job) error {
+	ret := _m.Called(job)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(amqp.Channel, []byte) error); ok {
-		r0 = rf(channel, jobJSON)
+	if rf, ok := ret.Get(0).(func(datastructures.Job) error); ok {
+		r0 = rf(job)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -231,19 +301,40 @@ func (_m *MockCntrInterface) PublishToJobsQueue(channel amqp.Channel, jobJSON []
 	return r0
 }
 
+// RenewGraphingLeaseInDataStore provides a mock function with given fields: crawlID, leaseID
+func (_m *MockCntrInterface) RenewGraphingLeaseInDataStore(crawlID string, leaseID string) (bool, error) {
+	ret := _m.Called(crawlID, leaseID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
+		r0 = rf(crawlID, leaseID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(crawlID, leaseID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveCrawlingStatsToDataStore provides a mock function with given fields: currentLevel, crawlingStatus
-func (_m *MockCntrInterface) SaveCrawlingStatsToDataStore(currentLevel int, crawlingStatus common.CrawlingStatus) (bool, error) {
+func (_m *MockCntrInterface) SaveCrawlingStatsToDataStore(currentLevel int, crawlingStatus datastructures.CrawlingStatus) (bool, error) {
 	ret := _m.Called(currentLevel, crawlingStatus)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(int, common.CrawlingStatus) bool); ok {
+	if rf, ok := ret.Get(0).(func(int, datastructures.CrawlingStatus) bool); ok {
 		r0 = rf(currentLevel, crawlingStatus)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(int, common.CrawlingStatus) error); ok {
+	if rf, ok := ret.Get(1).(func(int, datastructures.CrawlingStatus) error); ok {
 		r1 = rf(currentLevel, crawlingStatus)
 	} else {
 		r1 = ret.Error(1)
@@ -253,18 +344,18 @@ func (_m *MockCntrInterface) SaveCrawlingStatsToDataStore(currentLevel int, craw
 }
 
 // SaveProcessedGraphDataToDataStore provides a mock function with given fields: crawlID, graphData
-func (_m *MockCntrInterface) SaveProcessedGraphDataToDataStore(crawlID string, graphData common.UsersGraphData) (bool, error) {
+func (_m *MockCntrInterface) SaveProcessedGraphDataToDataStore(crawlID string, graphData datastructures.UsersGraphData) (bool, error) {
 	ret := _m.Called(crawlID, graphData)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(string, common.UsersGraphData) bool); ok {
+	if rf, ok := ret.Get(0).(func(string, datastructures.UsersGraphData) bool); ok {
 		r0 = rf(crawlID, graphData)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, common.UsersGraphData) error); ok {
+	if rf, ok := ret.Get(1).(func(string, datastructures.UsersGraphData) error); ok {
 		r1 = rf(crawlID, graphData)
 	} else {
 		r1 = ret.Error(1)
@@ -274,18 +365,18 @@ func (_m *MockCntrInterface) SaveProcessedGraphDataToDataStore(crawlID string, g
 }
 
 // SaveUserToDataStore provides a mock function with given fields: _a0
-func (_m *MockCntrInterface) SaveUserToDataStore(_a0 dtos.SaveUserDTO) (bool, error) {
+func (_m *MockCntrInterface) SaveUserToDataStore(_a0 datastructures.SaveUserDTO) (bool, error) {
 	ret := _m.Called(_a0)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(dtos.SaveUserDTO) bool); ok {
+	if rf, ok := ret.Get(0).(func(datastructures.SaveUserDTO) bool); ok {
 		r0 = rf(_a0)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(dtos.SaveUserDTO) error); ok {
+	if rf, ok := ret.Get(1).(func(datastructures.SaveUserDTO) error); ok {
 		r1 = rf(_a0)
 	} else {
 		r1 = ret.Error(1)
@@ -294,7 +385,70 @@ func (_m *MockCntrInterface) SaveUserToDataStore(_a0 dtos.SaveUserDTO) (bool, er
 	return r0, r1
 }
 
+// SaveWatchedUserToDataStore provides a mock function with given fields: watchedUser
+func (_m *MockCntrInterface) SaveWatchedUserToDataStore(watchedUser datastructures.WatchedUser) (bool, error) {
+	ret := _m.Called(watchedUser)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(datastructures.WatchedUser) bool); ok {
+		r0 = rf(watchedUser)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(datastructures.WatchedUser) error); ok {
+		r1 = rf(watchedUser)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Sleep provides a mock function with given fields: duration
 func (_m *MockCntrInterface) Sleep(duration time.Duration) {
 	_m.Called(duration)
 }
+
+// UpdateCrawlBudgetInDataStore provides a mock function with given fields: crawlID, budgetUsage
+func (_m *MockCntrInterface) UpdateCrawlBudgetInDataStore(crawlID string, budgetUsage datastructures.CrawlBudgetUsage) (int, error) {
+	ret := _m.Called(crawlID, budgetUsage)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string, datastructures.CrawlBudgetUsage) int); ok {
+		r0 = rf(crawlID, budgetUsage)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, datastructures.CrawlBudgetUsage) error); ok {
+		r1 = rf(crawlID, budgetUsage)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateCrawlStateInDataStore provides a mock function with given fields: crawlID, state, leaseID
+func (_m *MockCntrInterface) UpdateCrawlStateInDataStore(crawlID string, state string, leaseID string) (bool, error) {
+	ret := _m.Called(crawlID, state, leaseID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string, string) bool); ok {
+		r0 = rf(crawlID, state, leaseID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(crawlID, state, leaseID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -1,14 +1,19 @@
 package datastructures
 
 import (
-	"sync"
+	"strings"
 	"time"
 
-	"github.com/streadway/amqp"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/neosteamfriendgraphing/common/dtos"
 )
 
 type WorkerConfig struct {
-	WorkerAmount int
+	WorkerAmount    int
+	JobQueueBackend string
+	// FreshnessWindowHours is how long a stored user is reused for
+	// when a crawl does not give its own freshness window
+	FreshnessWindowHours int
 }
 
 type Job struct {
@@ -19,6 +24,95 @@ type Job struct {
 
 	MaxLevel     int `json:"maxLevel"`
 	CurrentLevel int `json:"currentLevel"`
+
+	Budget       CrawlBudget  `json:"budget"`
+	HubThreshold int          `json:"hubThreshold"`
+	Filters      CrawlFilters `json:"filters"`
+	// FreshnessWindowHours of zero means the worker's default is used
+	FreshnessWindowHours int `json:"freshnessWindowHours"`
+}
+
+// CrawlOptions are the optional settings given when starting a crawl
+type CrawlOptions struct {
+	Budget CrawlBudget
+	// HubThreshold is the friend count above which a user is saved
+	// but their friends are not crawled. Zero means no threshold
+	HubThreshold int
+	Filters      CrawlFilters
+	// FreshnessWindowHours is how old a stored user can be before
+	// it is refetched from steam. Zero means the default is used
+	FreshnessWindowHours int
+}
+
+// CrawlBudget bounds how much work a crawl may do. A value of
+// zero means that there is no limit
+type CrawlBudget struct {
+	MaxUsers    int `json:"maxusers"`
+	MaxAPICalls int `json:"maxapicalls"`
+}
+
+func (budget CrawlBudget) IsSet() bool {
+	return budget.MaxUsers > 0 || budget.MaxAPICalls > 0
+}
+
+// CrawlFilters limit which friends are expanded during a crawl. The
+// original crawl target is always crawled. Empty values mean that no
+// filtering is done
+type CrawlFilters struct {
+	// Countries are the Loccountrycodes that friends must be in
+	Countries         []string `json:"countries"`
+	MinAccountAgeDays int      `json:"minaccountagedays"`
+	MinGames          int      `json:"mingames"`
+}
+
+// FiltersAccounts checks if any filter that works off of
+// account details is set
+func (filters CrawlFilters) FiltersAccounts() bool {
+	return len(filters.Countries) > 0 || filters.MinAccountAgeDays > 0
+}
+
+// AllowsAccount checks if an account with the given country code and
+// creation time passes the country and account age filters
+func (filters CrawlFilters) AllowsAccount(countryCode string, timeCreated int) bool {
+	if len(filters.Countries) > 0 {
+		isInAllowedCountry := false
+		for _, country := range filters.Countries {
+			if strings.EqualFold(country, countryCode) {
+				isInAllowedCountry = true
+				break
+			}
+		}
+		if !isInAllowedCountry {
+			return false
+		}
+	}
+	if filters.MinAccountAgeDays > 0 {
+		// Accounts that hide when they were created cannot be shown to be old enough
+		if timeCreated == 0 {
+			return false
+		}
+		minAccountAge := time.Duration(filters.MinAccountAgeDays) * 24 * time.Hour
+		if time.Since(time.Unix(int64(timeCreated), 0)) < minAccountAge {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsGameCount checks if a user owning the given amount of
+// games passes the minimum games filter
+func (filters CrawlFilters) AllowsGameCount(gameCount int) bool {
+	return gameCount >= filters.MinGames
+}
+
+// AllowsStoredGameCount is AllowsGameCount for users retrieved from the
+// datastore. Only the top fifty games of a user are stored so a user with
+// fifty stored games may own many more
+func (filters CrawlFilters) AllowsStoredGameCount(storedGameCount int) bool {
+	if storedGameCount >= 50 {
+		return true
+	}
+	return filters.AllowsGameCount(storedGameCount)
 }
 
 type APIKeysInUse struct {
@@ -30,11 +124,6 @@ type APIKey struct {
 	LastUsed time.Time
 }
 
-type AmqpChannel struct {
-	Channel amqp.Channel
-	Lock    *sync.Mutex
-}
-
 type CrawlJob struct {
 	CrawlID string
 	SteamID string
@@ -45,11 +134,241 @@ type CrawlJob struct {
 }
 
 type CrawlUserTempDTO struct {
-	Level    int      `json:"level"`
-	SteamIDs []string `json:"steamids"`
+	Level                int          `json:"level"`
+	SteamIDs             []string     `json:"steamids"`
+	MaxUsers             int          `json:"maxusers"`
+	MaxAPICalls          int          `json:"maxapicalls"`
+	HubThreshold         int          `json:"hubthreshold"`
+	Filters              CrawlFilters `json:"filters"`
+	FreshnessWindowHours int          `json:"freshnesswindowhours"`
 }
 
 type CrawlResponseDTO struct {
 	Status   string   `json:"status"`
 	CrawlIDs []string `json:"crawlids"`
 }
+
+// CrawlingStatus extends common.CrawlingStatus with the crawl budget
+// and how much of it has been consumed so far
+type CrawlingStatus struct {
+	common.CrawlingStatus
+
+	Budget        CrawlBudget  `json:"budget"`
+	APICallsMade  int          `json:"apicallsmade"`
+	UsersReserved int          `json:"usersreserved"`
+	Truncated     bool         `json:"truncated"`
+	HubThreshold  int          `json:"hubthreshold"`
+	CappedHubs    []string     `json:"cappedhubs"`
+	Filters       CrawlFilters `json:"filters"`
+	// PrivateProfiles and FailedJobs are the amount of jobs that
+	// were counted as crawled without the user being saved
+	PrivateProfiles int `json:"privateprofiles"`
+	FailedJobs      int `json:"failedjobs"`
+	// State is where the crawl is in its lifecycle and StateTimestamps
+	// holds the unix time at which each state was entered
+	State           string           `json:"state"`
+	StateTimestamps map[string]int64 `json:"statetimestamps"`
+	// EstimatedProgress is the percentage of the crawl that is done,
+	// it is worked out by the datastore
+	EstimatedProgress int `json:"estimatedprogress"`
+}
+
+// The states a crawl moves through, these are validated by the datastore
+const (
+	CrawlStateQueued        = "queued"
+	CrawlStateCrawling      = "crawling"
+	CrawlStateCrawlComplete = "crawl-complete"
+	CrawlStateGraphing      = "graphing"
+	CrawlStateGraphed       = "graphed"
+	CrawlStateFailed        = "failed"
+	CrawlStateCancelled     = "cancelled"
+)
+
+// UpdateCrawlStateInputDTO moves a crawl to a new state. LeaseID is given
+// by the graph build that moves a crawl to the graphing state and is
+// needed to move the crawl out of it again
+type UpdateCrawlStateInputDTO struct {
+	State   string `json:"state"`
+	LeaseID string `json:"leaseid"`
+}
+
+type UpdateCrawlStateDTO struct {
+	Status  string `json:"status"`
+	Updated bool   `json:"updated"`
+}
+
+type RenewGraphingLeaseInputDTO struct {
+	LeaseID string `json:"leaseid"`
+}
+
+type RenewGraphingLeaseDTO struct {
+	Status  string `json:"status"`
+	Renewed bool   `json:"renewed"`
+}
+
+// CrawlBudgetUsage records steam API calls made and reserves users
+// from the crawl budget before they are placed in the queue
+type CrawlBudgetUsage struct {
+	APICallsMade   int  `json:"apicallsmade"`
+	UsersRequested int  `json:"usersrequested"`
+	Truncated      bool `json:"truncated"`
+}
+
+type UpdateCrawlBudgetDTO struct {
+	Status       string `json:"status"`
+	UsersGranted int    `json:"usersgranted"`
+}
+
+type SaveCrawlingStatsDTO struct {
+	CurrentLevel   int            `json:"currentlevel"`
+	CrawlingStatus CrawlingStatus `json:"crawlingstatus"`
+}
+
+type GetCrawlingStatusDTO struct {
+	Status         string         `json:"status"`
+	CrawlingStatus CrawlingStatus `json:"crawlingstatus"`
+}
+
+type GetUsersInputDTO struct {
+	SteamIDs []string `json:"steamids"`
+}
+
+type GetUsersDTO struct {
+	Status string                `json:"status"`
+	Users  []common.UserDocument `json:"users"`
+}
+
+// SaveUserDTO extends dtos.SaveUserDTO with the amount of the
+// user's friends that were placed in the jobs queue
+type SaveUserDTO struct {
+	dtos.SaveUserDTO
+
+	FriendsQueued int  `json:"friendsqueued"`
+	HubCapped     bool `json:"hubcapped"`
+}
+
+// UsersGraphData extends common.UsersGraphData with the steamIDs
+// of hubs whose friends were not crawled
+type UsersGraphData struct {
+	common.UsersGraphData
+
+	CappedHubs []string `json:"cappedhubs"`
+	// OldestDataTimestamp is the unix time at which the oldest
+	// user in the graph was fetched from steam
+	OldestDataTimestamp int64 `json:"oldestdatatimestamp"`
+	// Partial graphs are made from the users crawled so far while the
+	// crawl is still going. Progress is how much of the crawl was done
+	Partial  bool `json:"partial"`
+	Progress int  `json:"progress"`
+	// UserCommunities is the community of each user by steamID
+	UserCommunities map[string]int     `json:"usercommunities"`
+	Communities     []CommunitySummary `json:"communities"`
+	// UserCentrality is the centrality of each user by steamID.
+	// MostConnectedUsers have the most friends in the crawl and
+	// BridgeUsers are on the most shortest paths between others
+	UserCentrality     map[string]Centrality `json:"usercentrality"`
+	MostConnectedUsers []string              `json:"mostconnectedusers"`
+	BridgeUsers        []string              `json:"bridgeusers"`
+	NetworkStats       NetworkStats          `json:"networkstats"`
+	Layout             GraphLayout           `json:"layout"`
+}
+
+// CommunitySummary describes a group of users that are more connected
+// to each other than to the rest of the crawl
+type CommunitySummary struct {
+	ID              int    `json:"id"`
+	Size            int    `json:"size"`
+	DominantCountry string `json:"dominantcountry"`
+	// TopGames are the appIDs of the games with the most playtime
+	TopGames []int `json:"topgames"`
+}
+
+// Centrality measures how important a user is to the shape of a graph.
+// Betweenness and Closeness are between 0 and 1
+type Centrality struct {
+	Degree      int     `json:"degree"`
+	Betweenness float64 `json:"betweenness"`
+	Closeness   float64 `json:"closeness"`
+	PageRank    float64 `json:"pagerank"`
+}
+
+// NetworkStats describes the overall shape of a graph. DegreeDistribution
+// is how many users have each amount of friends in the crawl. The diameter
+// and average path length only count users that can reach each other
+type NetworkStats struct {
+	NodeCount             int     `json:"nodecount"`
+	EdgeCount             int     `json:"edgecount"`
+	Density               float64 `json:"density"`
+	AverageDegree         float64 `json:"averagedegree"`
+	DegreeDistribution    []int   `json:"degreedistribution"`
+	ClusteringCoefficient float64 `json:"clusteringcoefficient"`
+	ConnectedComponents   int     `json:"connectedcomponents"`
+	LargestComponentSize  int     `json:"largestcomponentsize"`
+	EstimatedDiameter     int     `json:"estimateddiameter"`
+	AveragePathLength     float64 `json:"averagepathlength"`
+}
+
+// GraphLayout is the position of every user by steamID when the graph
+// is laid out in two and three dimensions. The same seed always gives
+// the same layout
+type GraphLayout struct {
+	Seed        int64                 `json:"seed"`
+	Positions2D map[string][2]float64 `json:"positions2d"`
+	Positions3D map[string][3]float64 `json:"positions3d"`
+}
+
+// WatchedUser is a user that is crawled on a schedule. Either a five
+// field cron expression or an interval in minutes is given
+type WatchedUser struct {
+	SteamID         string `json:"steamid"`
+	Level           int    `json:"level"`
+	CronExpression  string `json:"cronexpression"`
+	IntervalMinutes int    `json:"intervalminutes"`
+	TimeAdded       int64  `json:"timeadded"`
+	LastCrawlTime   int64  `json:"lastcrawltime"`
+	// CrawlIDs are the crawls started for this user, oldest first
+	CrawlIDs []string `json:"crawlids"`
+}
+
+type GetWatchedUsersDTO struct {
+	Status       string        `json:"status"`
+	WatchedUsers []WatchedUser `json:"watchedusers"`
+}
+
+// WatchedUserCrawlClaim is sent to the datastore before a scheduled
+// crawl is started so that only one crawler starts each crawl
+type WatchedUserCrawlClaim struct {
+	PreviousCrawlTime int64  `json:"previouscrawltime"`
+	CrawlTime         int64  `json:"crawltime"`
+	CrawlID           string `json:"crawlid"`
+}
+
+type ClaimWatchedUserCrawlDTO struct {
+	Status  string `json:"status"`
+	Claimed bool   `json:"claimed"`
+}
+
+// The statuses of a graph build
+const (
+	GraphBuildPending   = "pending"
+	GraphBuildRunning   = "running"
+	GraphBuildSucceeded = "succeeded"
+	GraphBuildFailed    = "failed"
+)
+
+// GraphBuild tracks the creation of the graph for a crawl
+type GraphBuild struct {
+	CrawlID  string `json:"crawlid"`
+	Partial  bool   `json:"partial"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// Error is the reason the last attempt failed
+	Error        string `json:"error"`
+	TimeQueued   int64  `json:"timequeued"`
+	TimeFinished int64  `json:"timefinished"`
+}
+
+type GraphBuildStatusDTO struct {
+	Status     string     `json:"status"`
+	GraphBuild GraphBuild `json:"graphbuild"`
+}
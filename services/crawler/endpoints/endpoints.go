@@ -13,6 +13,7 @@ import (
 	"github.com/iamcathal/neo/services/crawler/controller"
 	"github.com/iamcathal/neo/services/crawler/datastructures"
 	"github.com/iamcathal/neo/services/crawler/graphing"
+	"github.com/iamcathal/neo/services/crawler/scheduler"
 	"github.com/iamcathal/neo/services/crawler/worker"
 	"github.com/neosteamfriendgraphing/common"
 	commonUtil "github.com/neosteamfriendgraphing/common/util"
@@ -31,6 +32,8 @@ func (endpoints *Endpoints) SetupRouter() *mux.Router {
 	r.HandleFunc("/crawl", endpoints.CrawlUsers).Methods("POST", "OPTIONS")
 	r.HandleFunc("/isprivateprofile/{steamid}", endpoints.IsPrivateProfile).Methods("GET", "OPTIONS")
 	r.HandleFunc("/creategraph/{crawlid}", endpoints.CreateGraph).Methods("POST", "OPTIONS")
+	r.HandleFunc("/graphstatus/{crawlid}", endpoints.GetGraphStatus).Methods("GET", "OPTIONS")
+	r.HandleFunc("/watchuser", endpoints.WatchUser).Methods("POST", "OPTIONS")
 
 	r.Use(endpoints.LoggingMiddleware)
 	return r
@@ -111,10 +114,32 @@ func (endpoints *Endpoints) CrawlUsers(w http.ResponseWriter, r *http.Request) {
 		commonUtil.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
 		return
 	}
-	if userInput.Level < 2 || userInput.Level > 3 {
+	if userInput.Level < 2 {
 		commonUtil.SendBasicInvalidResponse(w, r, "Invalid level given", vars, http.StatusBadRequest)
 		return
 	}
+	if userInput.MaxUsers < 0 || userInput.MaxAPICalls < 0 {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid budget given", vars, http.StatusBadRequest)
+		return
+	}
+	if userInput.HubThreshold < 0 {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid hub threshold given", vars, http.StatusBadRequest)
+		return
+	}
+	if userInput.FreshnessWindowHours < 0 {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid freshness window given", vars, http.StatusBadRequest)
+		return
+	}
+	if !filtersAreValid(userInput.Filters) {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid filters given", vars, http.StatusBadRequest)
+		return
+	}
+	// Crawls deeper than level three grow too quickly to
+	// be allowed without a limit on the amount of users
+	if userInput.Level > 3 && userInput.MaxUsers == 0 {
+		commonUtil.SendBasicInvalidResponse(w, r, "A user budget is required for crawls deeper than level 3", vars, http.StatusBadRequest)
+		return
+	}
 	if len(userInput.SteamIDs) == 0 {
 		commonUtil.SendBasicInvalidResponse(w, r, "No steamIDs given", vars, http.StatusBadRequest)
 		return
@@ -126,11 +151,20 @@ func (endpoints *Endpoints) CrawlUsers(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	crawlOptions := datastructures.CrawlOptions{
+		Budget: datastructures.CrawlBudget{
+			MaxUsers:    userInput.MaxUsers,
+			MaxAPICalls: userInput.MaxAPICalls,
+		},
+		HubThreshold:         userInput.HubThreshold,
+		Filters:              userInput.Filters,
+		FreshnessWindowHours: userInput.FreshnessWindowHours,
+	}
 	firstCrawlID := vars["requestID"]
 	secondCrawlID := ""
 	crawlIDsGenerated := []string{firstCrawlID}
 
-	err = worker.CrawlUser(endpoints.Cntr, userInput.SteamIDs[0], firstCrawlID, userInput.Level)
+	err = worker.CrawlUser(endpoints.Cntr, userInput.SteamIDs[0], firstCrawlID, userInput.Level, crawlOptions)
 	if err != nil {
 		commonUtil.SendBasicInvalidResponse(w, r, "couldn't start crawl", vars, http.StatusBadRequest)
 		configuration.Logger.Sugar().Errorf("failed to start crawl for first user with crawlID: %s steamID: %s level: %d", firstCrawlID, userInput.SteamIDs[0], userInput.Level)
@@ -142,7 +176,7 @@ func (endpoints *Endpoints) CrawlUsers(w http.ResponseWriter, r *http.Request) {
 		configuration.Logger.Sugar().Infof("creating new crawlID %s from request %s for user %s", secondCrawlID, firstCrawlID, userInput.SteamIDs[1])
 		crawlIDsGenerated = append(crawlIDsGenerated, secondCrawlID)
 
-		err = worker.CrawlUser(endpoints.Cntr, userInput.SteamIDs[1], secondCrawlID, userInput.Level)
+		err = worker.CrawlUser(endpoints.Cntr, userInput.SteamIDs[1], secondCrawlID, userInput.Level, crawlOptions)
 		if err != nil {
 			commonUtil.SendBasicInvalidResponse(w, r, "couldn't start crawl", vars, http.StatusBadRequest)
 			configuration.Logger.Sugar().Errorf("failed to start crawl for second user with crawlID: %s steamID: %s level: %d", secondCrawlID, userInput.SteamIDs[1], userInput.Level)
@@ -165,6 +199,54 @@ func (endpoints *Endpoints) CrawlUsers(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, string(jsonObj))
 }
 
+// WatchUser registers a user to be crawled on a schedule given as
+// either a cron expression or an interval in minutes
+func (endpoints *Endpoints) WatchUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	watchedUser := datastructures.WatchedUser{}
+	err := json.NewDecoder(r.Body).Decode(&watchedUser)
+	if err != nil {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	if isValid := commonUtil.IsValidFormatSteamID(watchedUser.SteamID); !isValid {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	// Scheduled crawls have no budget so they are limited
+	// to the depths that can be crawled without one
+	if watchedUser.Level < 2 || watchedUser.Level > 3 {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid level given", vars, http.StatusBadRequest)
+		return
+	}
+	if _, err := scheduler.ParseSchedule(watchedUser.CronExpression, watchedUser.IntervalMinutes); err != nil {
+		commonUtil.SendBasicInvalidResponse(w, r, "Invalid schedule given", vars, http.StatusBadRequest)
+		return
+	}
+
+	success, err := endpoints.Cntr.SaveWatchedUserToDataStore(watchedUser)
+	if err != nil || !success {
+		commonUtil.SendBasicInvalidResponse(w, r, "couldn't watch user", vars, http.StatusBadRequest)
+		configuration.Logger.Sugar().Errorf("failed to save watched user %s: %+v", watchedUser.SteamID, err)
+		return
+	}
+
+	response := common.BasicAPIResponse{
+		Status:  "success",
+		Message: "user is now being watched",
+	}
+	jsonObj, err := json.Marshal(response)
+	if err != nil {
+		commonUtil.SendBasicInvalidResponse(w, r, "couldn't return response", vars, http.StatusBadRequest)
+		configuration.Logger.Sugar().Errorf("failed to marshal watch user response: %+v", commonUtil.MakeErr(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(jsonObj))
+}
+
 func (endpoints *Endpoints) IsPrivateProfile(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -210,6 +292,15 @@ func (endpoints *Endpoints) CreateGraph(w http.ResponseWriter, r *http.Request)
 		commonUtil.SendBasicInvalidResponse(w, r, "invalid crawlid", vars, http.StatusBadRequest)
 		return
 	}
+	// The same seed always lays the graph out the same way
+	layoutSeed := int64(0)
+	if seedParam := r.URL.Query().Get("layoutseed"); seedParam != "" {
+		layoutSeed, err = strconv.ParseInt(seedParam, 10, 64)
+		if err != nil {
+			commonUtil.SendBasicInvalidResponse(w, r, "invalid layoutseed", vars, http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Check if this crawl session is actually finished
 	crawlingStats, err := endpoints.Cntr.GetCrawlingStatsFromDataStore(vars["crawlid"])
@@ -218,17 +309,155 @@ func (endpoints *Endpoints) CreateGraph(w http.ResponseWriter, r *http.Request)
 		configuration.Logger.Sugar().Errorf("failed to retrieve crawling status: %+v", err)
 		return
 	}
+	if r.URL.Query().Get("partial") == "true" {
+		endpoints.createPartialGraph(w, r, vars, crawlingStats, layoutSeed)
+		return
+	}
+	// The build holds a lease on crawls it moved to the graphing state
+	leaseID, claimed := "", false
+	switch crawlingStats.State {
+	case datastructures.CrawlStateQueued, datastructures.CrawlStateCrawling:
+		commonUtil.SendBasicInvalidResponse(w, r, "crawl has not finished", vars, http.StatusBadRequest)
+		return
+	case datastructures.CrawlStateFailed, datastructures.CrawlStateCancelled:
+		// Crawls that failed after they finished crawling failed to be
+		// graphed and can be graphed again when asked to
+		graphCreationFailed := crawlingStats.State == datastructures.CrawlStateFailed &&
+			crawlingStats.StateTimestamps[datastructures.CrawlStateCrawlComplete] != 0
+		if !graphCreationFailed || r.URL.Query().Get("retry") != "true" {
+			commonUtil.SendBasicInvalidResponse(w, r, fmt.Sprintf("crawl is %s", crawlingStats.State), vars, http.StatusBadRequest)
+			return
+		}
+		if leaseID, claimed = endpoints.claimGraphCreation(w, r, vars); !claimed {
+			return
+		}
+	case datastructures.CrawlStateGraphing, datastructures.CrawlStateGraphed:
+		sendGraphCreationResponse(w, r, vars, "graph creation has already been initiated")
+		return
+	case datastructures.CrawlStateCrawlComplete:
+		if leaseID, claimed = endpoints.claimGraphCreation(w, r, vars); !claimed {
+			return
+		}
+	default:
+		// Crawls started before states were recorded
+		if crawlingStats.UsersCrawled < crawlingStats.TotalUsersToCrawl {
+			commonUtil.SendBasicInvalidResponse(w, r, "crawl has not finished", vars, http.StatusBadRequest)
+			return
+		}
+	}
 	graphWorkerConfig := graphing.GraphWorkerConfig{
 		TotalUsersToCrawl: crawlingStats.TotalUsersToCrawl,
 		UsersCrawled:      0,
 		MaxLevel:          crawlingStats.MaxLevel,
+		CappedHubs:        crawlingStats.CappedHubs,
+		Filters:           crawlingStats.Filters,
+		LayoutSeed:        layoutSeed,
+		LeaseID:           leaseID,
+	}
+
+	if started := graphing.StartGraphBuild(endpoints.Cntr, crawlingStats.OriginalCrawlTarget, vars["crawlid"], graphWorkerConfig); !started {
+		sendGraphCreationResponse(w, r, vars, "graph creation has already been initiated")
+		return
+	}
+
+	sendGraphCreationResponse(w, r, vars, "graph creation has been initiated")
+}
+
+// claimGraphCreation moves a crawl into the graphing state under a new
+// lease. Only the request that moves it creates the graph, a response is
+// sent to any other request
+func (endpoints *Endpoints) claimGraphCreation(w http.ResponseWriter, r *http.Request, vars map[string]string) (string, bool) {
+	leaseID := ksuid.New().String()
+	claimed, err := endpoints.Cntr.UpdateCrawlStateInDataStore(vars["crawlid"], datastructures.CrawlStateGraphing, leaseID)
+	if err != nil {
+		commonUtil.SendBasicInvalidResponse(w, r, "could not start graph creation", vars, http.StatusBadRequest)
+		configuration.Logger.Sugar().Errorf("failed to move crawl %s to the graphing state: %+v", vars["crawlid"], err)
+		return "", false
+	}
+	if !claimed {
+		sendGraphCreationResponse(w, r, vars, "graph creation has already been initiated")
+		return "", false
+	}
+	return leaseID, true
+}
+
+// createPartialGraph creates a graph from the users crawled so far.
+// It is replaced by the full graph once the crawl has finished
+func (endpoints *Endpoints) createPartialGraph(w http.ResponseWriter, r *http.Request, vars map[string]string, crawlingStats datastructures.CrawlingStatus, layoutSeed int64) {
+	switch crawlingStats.State {
+	case datastructures.CrawlStateQueued, datastructures.CrawlStateCrawling:
+	case datastructures.CrawlStateFailed, datastructures.CrawlStateCancelled:
+		commonUtil.SendBasicInvalidResponse(w, r, fmt.Sprintf("crawl is %s", crawlingStats.State), vars, http.StatusBadRequest)
+		return
+	case "":
+		// Crawls started before states were recorded
+		if crawlingStats.UsersCrawled >= crawlingStats.TotalUsersToCrawl {
+			commonUtil.SendBasicInvalidResponse(w, r, "crawl has finished", vars, http.StatusBadRequest)
+			return
+		}
+	default:
+		commonUtil.SendBasicInvalidResponse(w, r, "crawl has finished", vars, http.StatusBadRequest)
+		return
+	}
+	graphWorkerConfig := graphing.GraphWorkerConfig{
+		TotalUsersToCrawl: crawlingStats.TotalUsersToCrawl,
+		UsersCrawled:      0,
+		MaxLevel:          crawlingStats.MaxLevel,
+		CappedHubs:        crawlingStats.CappedHubs,
+		Filters:           crawlingStats.Filters,
+		Partial:           true,
+		Progress:          crawlingStats.EstimatedProgress,
+		LayoutSeed:        layoutSeed,
+	}
+
+	if started := graphing.StartGraphBuild(endpoints.Cntr, crawlingStats.OriginalCrawlTarget, vars["crawlid"], graphWorkerConfig); !started {
+		sendGraphCreationResponse(w, r, vars, "partial graph creation has already been initiated")
+		return
+	}
+
+	sendGraphCreationResponse(w, r, vars, "partial graph creation has been initiated")
+}
+
+func (endpoints *Endpoints) GetGraphStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := ksuid.Parse(vars["crawlid"])
+	if err != nil {
+		commonUtil.SendBasicInvalidResponse(w, r, "invalid crawlid", vars, http.StatusBadRequest)
+		return
 	}
 
-	go graphing.CollectGraphData(endpoints.Cntr, crawlingStats.OriginalCrawlTarget, vars["crawlid"], graphWorkerConfig)
+	partial := r.URL.Query().Get("partial") == "true"
+	exists, graphBuild, err := graphing.GetGraphBuild(endpoints.Cntr, vars["crawlid"], partial)
+	if err != nil {
+		commonUtil.SendBasicInvalidResponse(w, r, "could not get graph status", vars, http.StatusBadRequest)
+		configuration.Logger.Sugar().Errorf("failed to get graph build for crawlID %s: %+v", vars["crawlid"], err)
+		return
+	}
+	if !exists {
+		commonUtil.SendBasicInvalidResponse(w, r, "graph creation has not been started", vars, http.StatusNotFound)
+		return
+	}
+
+	response := datastructures.GraphBuildStatusDTO{
+		Status:     "success",
+		GraphBuild: graphBuild,
+	}
+	jsonObj, err := json.Marshal(response)
+	if err != nil {
+		commonUtil.SendBasicInvalidResponse(w, r, "couldn't return response", vars, http.StatusBadRequest)
+		configuration.Logger.Sugar().Errorf("failed to marshal GraphBuildStatusDTO: %+v", commonUtil.MakeErr(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(jsonObj))
+}
 
+func sendGraphCreationResponse(w http.ResponseWriter, r *http.Request, vars map[string]string, message string) {
 	response := common.BasicAPIResponse{
 		Status:  "success",
-		Message: "graph creation has been initiated",
+		Message: message,
 	}
 	jsonObj, err := json.Marshal(response)
 	if err != nil {
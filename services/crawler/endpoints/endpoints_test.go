@@ -20,7 +20,6 @@ import (
 	"github.com/neosteamfriendgraphing/common"
 	commonUtil "github.com/neosteamfriendgraphing/common/util"
 	"github.com/segmentio/ksuid"
-	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -39,7 +38,6 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 	configuration.Logger = logger
-	configuration.AmqpChannels = append(configuration.AmqpChannels, amqp.Channel{})
 
 	code := m.Run()
 
@@ -176,7 +174,7 @@ func TestCrawlOneValidUser(t *testing.T) {
 		log.Fatal(err)
 	}
 
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(nil)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(nil)
 	mockController.On("SaveCrawlingStatsToDataStore", 1, mock.Anything).Return(true, nil)
 
 	res, err := http.Post(fmt.Sprintf("http://localhost:%d/crawl", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
@@ -231,6 +229,148 @@ func TestCrawlUserReturnsInvalidLevelGivenWhenItGetsInvalidInput(t *testing.T) {
 	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
 }
 
+func TestCrawlUserReturnsAnErrorForDeepCrawlsWithoutAUserBudget(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	userCrawlInput := datastructures.CrawlUserTempDTO{
+		Level:    5,
+		SteamIDs: []string{"76561197969081524"},
+	}
+	requestBodyJSON, err := json.Marshal(userCrawlInput)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/crawl", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expectedResponse := struct {
+		Error string `json:"error"`
+	}{
+		"A user budget is required for crawls deeper than level 3",
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "SaveCrawlingStatsToDataStore", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
+func TestCrawlUserReturnsAnErrorForANegativeBudget(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	userCrawlInput := datastructures.CrawlUserTempDTO{
+		Level:       2,
+		SteamIDs:    []string{"76561197969081524"},
+		MaxAPICalls: -5,
+	}
+	requestBodyJSON, err := json.Marshal(userCrawlInput)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/crawl", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "SaveCrawlingStatsToDataStore", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestCrawlUserReturnsAnErrorForInvalidFilters(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	userCrawlInput := datastructures.CrawlUserTempDTO{
+		Level:    2,
+		SteamIDs: []string{"76561197969081524"},
+		Filters: datastructures.CrawlFilters{
+			Countries: []string{"Ireland"},
+		},
+	}
+	requestBodyJSON, err := json.Marshal(userCrawlInput)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/crawl", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "SaveCrawlingStatsToDataStore", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestCrawlUserReturnsAnErrorForANegativeFreshnessWindow(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	userCrawlInput := datastructures.CrawlUserTempDTO{
+		Level:                2,
+		SteamIDs:             []string{"76561197969081524"},
+		FreshnessWindowHours: -1,
+	}
+	requestBodyJSON, err := json.Marshal(userCrawlInput)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/crawl", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "SaveCrawlingStatsToDataStore", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestWatchUserSavesTheWatchedUser(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	watchedUser := datastructures.WatchedUser{
+		SteamID:        "76561197969081524",
+		Level:          2,
+		CronExpression: "0 6 * * 1",
+	}
+	mockController.On("SaveWatchedUserToDataStore", watchedUser).Return(true, nil)
+	requestBodyJSON, err := json.Marshal(watchedUser)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/watchuser", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNumberOfCalls(t, "SaveWatchedUserToDataStore", 1)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestWatchUserReturnsAnErrorForAnInvalidCronExpression(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	watchedUser := datastructures.WatchedUser{
+		SteamID:        "76561197969081524",
+		Level:          2,
+		CronExpression: "every monday",
+	}
+	requestBodyJSON, err := json.Marshal(watchedUser)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/watchuser", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "SaveWatchedUserToDataStore", mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
 func TestCrawlUserReturnsInvalidFormatSteamIDsForInvalidSteamIDs(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
@@ -278,7 +418,7 @@ func TestCrawlUsersWithTwoUsers(t *testing.T) {
 	}
 
 	mockController.On("SaveCrawlingStatsToDataStore", 1, mock.Anything).Return(true, nil)
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(nil)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(nil)
 
 	res, err := http.Post(fmt.Sprintf("http://localhost:%d/crawl", serverPort), "application/json", bytes.NewBuffer(requestBodyJSON))
 	if err != nil {
@@ -292,11 +432,18 @@ func TestCrawlUsersWithTwoUsers(t *testing.T) {
 func TestCreateGraph(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	returnedCrawlingStatus := common.CrawlingStatus{
-		TimeStarted: time.Now().Unix(),
-		CrawlID:     ksuid.New().String(),
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     ksuid.New().String(),
+		},
+		State: datastructures.CrawlStateCrawlComplete,
 	}
 	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+	mockController.On("UpdateCrawlStateInDataStore", returnedCrawlingStatus.CrawlID, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(true, nil)
+	// The graph is created in the background after the response is sent
+	mockController.On("GetUsersFromDataStore", mock.Anything).Return([]common.UserDocument{}, nil)
+	mockController.On("Sleep", mock.AnythingOfType("time.Duration")).Return()
 
 	expectedResponse := common.BasicAPIResponse{
 		Status:  "success",
@@ -318,3 +465,257 @@ func TestCreateGraph(t *testing.T) {
 
 	assert.Equal(t, string(expectedJSONResponse), string(body))
 }
+
+func TestCreateGraphReturnsInvalidResponseForCrawlsThatHaveNotFinished(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted:       time.Now().Unix(),
+			CrawlID:           ksuid.New().String(),
+			TotalUsersToCrawl: 10,
+			UsersCrawled:      10,
+		},
+		State: datastructures.CrawlStateCrawling,
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/creategraph/%s", serverPort, returnedCrawlingStatus.CrawlID), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Contains(t, string(body), "crawl has not finished")
+	mockController.AssertNotCalled(t, "UpdateCrawlStateInDataStore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateGraphReturnsInvalidResponseForAnInvalidLayoutSeed(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/creategraph/%s?layoutseed=notanumber", serverPort, ksuid.New().String()), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Contains(t, string(body), "invalid layoutseed")
+	mockController.AssertNotCalled(t, "GetCrawlingStatsFromDataStore", mock.Anything)
+}
+
+func TestCreateGraphDoesNotCreateTheGraphAgainWhenAnotherRequestHasClaimedIt(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     ksuid.New().String(),
+		},
+		State: datastructures.CrawlStateCrawlComplete,
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+	mockController.On("UpdateCrawlStateInDataStore", returnedCrawlingStatus.CrawlID, datastructures.CrawlStateGraphing, mock.AnythingOfType("string")).Return(false, nil)
+
+	expectedResponse := common.BasicAPIResponse{
+		Status:  "success",
+		Message: "graph creation has already been initiated",
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(commonUtil.MakeErr(err))
+	}
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/creategraph/%s", serverPort, returnedCrawlingStatus.CrawlID), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse), string(body))
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlStateInDataStore", 1)
+}
+
+func TestCreateGraphCreatesTheGraphAgainForACrawlWhoseGraphCreationFailed(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     ksuid.New().String(),
+		},
+		State: datastructures.CrawlStateFailed,
+		StateTimestamps: map[string]int64{
+			datastructures.CrawlStateCrawlComplete: time.Now().Unix(),
+			datastructures.CrawlStateFailed:        time.Now().Unix(),
+		},
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+	mockController.On("UpdateCrawlStateInDataStore", returnedCrawlingStatus.CrawlID, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(true, nil)
+	mockController.On("GetUsersFromDataStore", mock.Anything).Return([]common.UserDocument{}, nil)
+	mockController.On("Sleep", mock.AnythingOfType("time.Duration")).Return()
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/creategraph/%s?retry=true", serverPort, returnedCrawlingStatus.CrawlID), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), "graph creation has been initiated")
+	mockController.AssertCalled(t, "UpdateCrawlStateInDataStore", returnedCrawlingStatus.CrawlID, datastructures.CrawlStateGraphing, mock.AnythingOfType("string"))
+}
+
+func TestCreateGraphDoesNotRetryACrawlThatFailedWhileCrawling(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     ksuid.New().String(),
+		},
+		State: datastructures.CrawlStateFailed,
+		StateTimestamps: map[string]int64{
+			datastructures.CrawlStateCrawling: time.Now().Unix(),
+			datastructures.CrawlStateFailed:   time.Now().Unix(),
+		},
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/creategraph/%s?retry=true", serverPort, returnedCrawlingStatus.CrawlID), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Contains(t, string(body), "crawl is failed")
+	mockController.AssertNotCalled(t, "UpdateCrawlStateInDataStore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateGraphCreatesAPartialGraphOfACrawlThatIsStillGoing(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted:       time.Now().Unix(),
+			CrawlID:           ksuid.New().String(),
+			TotalUsersToCrawl: 10,
+			UsersCrawled:      4,
+		},
+		State:             datastructures.CrawlStateCrawling,
+		EstimatedProgress: 40,
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+	// The graph is created in the background after the response is sent
+	mockController.On("GetUsersFromDataStore", mock.Anything).Return([]common.UserDocument{}, nil)
+	mockController.On("Sleep", mock.AnythingOfType("time.Duration")).Return()
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/creategraph/%s?partial=true", serverPort, returnedCrawlingStatus.CrawlID), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), "partial graph creation has been initiated")
+	mockController.AssertNotCalled(t, "UpdateCrawlStateInDataStore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateGraphDoesNotCreateAPartialGraphOfAFinishedCrawl(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     ksuid.New().String(),
+		},
+		State: datastructures.CrawlStateCrawlComplete,
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/creategraph/%s?partial=true", serverPort, returnedCrawlingStatus.CrawlID), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Contains(t, string(body), "crawl has finished")
+	mockController.AssertNotCalled(t, "UpdateCrawlStateInDataStore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetGraphStatusReturnsNotFoundForCrawlsThatHaveNotBeenGraphed(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     ksuid.New().String(),
+		},
+		State: datastructures.CrawlStateCrawling,
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/graphstatus/%s", serverPort, returnedCrawlingStatus.CrawlID))
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Contains(t, string(body), "graph creation has not been started")
+}
+
+func TestGetGraphStatusReturnsTheStatusOfAFinishedGraphBuild(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	returnedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     ksuid.New().String(),
+		},
+		State: datastructures.CrawlStateGraphed,
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", returnedCrawlingStatus.CrawlID).Return(returnedCrawlingStatus, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/graphstatus/%s", serverPort, returnedCrawlingStatus.CrawlID))
+	if err != nil {
+		log.Fatal(err)
+	}
+	graphStatus := datastructures.GraphBuildStatusDTO{}
+	err = json.NewDecoder(res.Body).Decode(&graphStatus)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, returnedCrawlingStatus.CrawlID, graphStatus.GraphBuild.CrawlID)
+	assert.Equal(t, datastructures.GraphBuildSucceeded, graphStatus.GraphBuild.Status)
+}
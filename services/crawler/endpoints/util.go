@@ -1,6 +1,10 @@
 package endpoints
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
 
 // responseWriter is a minimal wrapper for http.ResponseWriter that allows the
 // written HTTP status code to be captured for logging.
@@ -27,3 +31,17 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 	rw.wroteHeader = true
 }
+
+// filtersAreValid checks that crawl filters have no negative
+// values and only two letter country codes
+func filtersAreValid(filters datastructures.CrawlFilters) bool {
+	if filters.MinAccountAgeDays < 0 || filters.MinGames < 0 {
+		return false
+	}
+	for _, country := range filters.Countries {
+		if len(country) != 2 {
+			return false
+		}
+	}
+	return true
+}
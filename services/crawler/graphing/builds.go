@@ -0,0 +1,208 @@
+package graphing
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/controller"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
+
+const (
+	maxGraphBuildAttempts = 3
+	// graphBuildRetryDelay is doubled after every failed attempt
+	graphBuildRetryDelay = 5 * time.Second
+	// graphingLeaseRenewalInterval is how often a full build renews its
+	// lease on the crawl. The datastore gives the crawl to another build
+	// once the lease has not been renewed for five minutes
+	graphingLeaseRenewalInterval = time.Minute
+	// finishedGraphBuildRetention is how long finished builds are kept
+	// for their status to be looked up. Full builds are worked out from
+	// the state of their crawl after that
+	finishedGraphBuildRetention = time.Hour
+)
+
+var (
+	graphBuildsLock sync.Mutex
+	graphBuilds     = make(map[string]*datastructures.GraphBuild)
+)
+
+// StartGraphBuild queues the graph creation for a crawl and runs it in the
+// background. False is returned if a build for the crawl is already pending
+// or running on this crawler. Partial and full builds of a crawl are
+// tracked separately
+func StartGraphBuild(cntr controller.CntrInterface, steamID, crawlID string, workerConfig GraphWorkerConfig) bool {
+	buildKey := getGraphBuildKey(crawlID, workerConfig.Partial)
+
+	graphBuildsLock.Lock()
+	evictFinishedGraphBuilds(time.Now())
+	if existingBuild, exists := graphBuilds[buildKey]; exists && !graphBuildHasFinished(*existingBuild) {
+		graphBuildsLock.Unlock()
+		return false
+	}
+	graphBuilds[buildKey] = &datastructures.GraphBuild{
+		CrawlID:    crawlID,
+		Partial:    workerConfig.Partial,
+		Status:     datastructures.GraphBuildPending,
+		TimeQueued: time.Now().Unix(),
+	}
+	graphBuildsLock.Unlock()
+
+	go runGraphBuild(cntr, steamID, crawlID, workerConfig)
+	return true
+}
+
+// GetGraphBuild returns the graph build for a crawl. Full builds that were
+// run by another crawler are worked out from the state of the crawl
+func GetGraphBuild(cntr controller.CntrInterface, crawlID string, partial bool) (bool, datastructures.GraphBuild, error) {
+	graphBuildsLock.Lock()
+	build, exists := graphBuilds[getGraphBuildKey(crawlID, partial)]
+	if exists {
+		graphBuildsLock.Unlock()
+		return true, *build, nil
+	}
+	graphBuildsLock.Unlock()
+	// Partial builds do not change the state of the crawl
+	if partial {
+		return false, datastructures.GraphBuild{}, nil
+	}
+
+	crawlingStatus, err := cntr.GetCrawlingStatsFromDataStore(crawlID)
+	if err != nil {
+		return false, datastructures.GraphBuild{}, err
+	}
+	graphBuildStatuses := map[string]string{
+		datastructures.CrawlStateCrawlComplete: datastructures.GraphBuildPending,
+		datastructures.CrawlStateGraphing:      datastructures.GraphBuildRunning,
+		datastructures.CrawlStateGraphed:       datastructures.GraphBuildSucceeded,
+		datastructures.CrawlStateFailed:        datastructures.GraphBuildFailed,
+	}
+	status, hasGraphBuild := graphBuildStatuses[crawlingStatus.State]
+	if !hasGraphBuild {
+		return false, datastructures.GraphBuild{}, nil
+	}
+	return true, datastructures.GraphBuild{
+		CrawlID:      crawlID,
+		Status:       status,
+		TimeQueued:   crawlingStatus.StateTimestamps[datastructures.CrawlStateCrawlComplete],
+		TimeFinished: crawlingStatus.StateTimestamps[crawlingStatus.State],
+	}, nil
+}
+
+func getGraphBuildKey(crawlID string, partial bool) string {
+	if partial {
+		return crawlID + "/partial"
+	}
+	return crawlID
+}
+
+func graphBuildHasFinished(build datastructures.GraphBuild) bool {
+	return build.Status == datastructures.GraphBuildSucceeded || build.Status == datastructures.GraphBuildFailed
+}
+
+// evictFinishedGraphBuilds removes builds that finished longer than the
+// retention period ago. graphBuildsLock must be held
+func evictFinishedGraphBuilds(now time.Time) {
+	finishedBefore := now.Add(-finishedGraphBuildRetention).Unix()
+	for buildKey, build := range graphBuilds {
+		if graphBuildHasFinished(*build) && build.TimeFinished < finishedBefore {
+			delete(graphBuilds, buildKey)
+		}
+	}
+}
+
+func updateGraphBuild(buildKey string, update func(build *datastructures.GraphBuild)) {
+	graphBuildsLock.Lock()
+	defer graphBuildsLock.Unlock()
+	update(graphBuilds[buildKey])
+}
+
+// runGraphBuild creates the graph for a crawl, retrying failed attempts
+// with an increasing delay. The crawl is marked as graphed or failed
+// once a full build has finished, which the datastore only allows while
+// the build still holds its lease on the crawl
+func runGraphBuild(cntr controller.CntrInterface, steamID, crawlID string, workerConfig GraphWorkerConfig) {
+	buildKey := getGraphBuildKey(crawlID, workerConfig.Partial)
+	if workerConfig.LeaseID != "" {
+		buildFinished := make(chan struct{})
+		defer close(buildFinished)
+		go renewGraphingLease(cntr, crawlID, workerConfig.LeaseID, graphingLeaseRenewalInterval, buildFinished)
+	}
+	var err error
+	retryDelay := graphBuildRetryDelay
+	for attempt := 1; attempt <= maxGraphBuildAttempts; attempt++ {
+		updateGraphBuild(buildKey, func(build *datastructures.GraphBuild) {
+			build.Status = datastructures.GraphBuildRunning
+			build.Attempts = attempt
+		})
+
+		err = collectGraphDataSafely(cntr, steamID, crawlID, workerConfig)
+		if err == nil {
+			break
+		}
+		configuration.Logger.Sugar().Errorf("attempt %d of %d to create the graph for crawlID %s failed: %+v", attempt, maxGraphBuildAttempts, crawlID, err)
+		updateGraphBuild(buildKey, func(build *datastructures.GraphBuild) {
+			build.Error = err.Error()
+		})
+		if attempt < maxGraphBuildAttempts {
+			cntr.Sleep(retryDelay)
+			retryDelay *= 2
+		}
+	}
+
+	if err != nil {
+		updateGraphBuild(buildKey, func(build *datastructures.GraphBuild) {
+			build.Status = datastructures.GraphBuildFailed
+			build.TimeFinished = time.Now().Unix()
+		})
+		if !workerConfig.Partial {
+			updateCrawlState(cntr, crawlID, datastructures.CrawlStateFailed, workerConfig.LeaseID)
+		}
+		return
+	}
+	updateGraphBuild(buildKey, func(build *datastructures.GraphBuild) {
+		build.Status = datastructures.GraphBuildSucceeded
+		build.Error = ""
+		build.TimeFinished = time.Now().Unix()
+	})
+	if !workerConfig.Partial {
+		updateCrawlState(cntr, crawlID, datastructures.CrawlStateGraphed, workerConfig.LeaseID)
+	}
+}
+
+// renewGraphingLease renews the lease of a build on a graphing crawl every
+// interval until the build has finished. Failed renewals are tried again
+// at the next interval and renewing stops once the lease has been lost
+func renewGraphingLease(cntr controller.CntrInterface, crawlID, leaseID string, interval time.Duration, buildFinished <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-buildFinished:
+			return
+		case <-ticker.C:
+			renewed, err := cntr.RenewGraphingLeaseInDataStore(crawlID, leaseID)
+			if err != nil {
+				configuration.Logger.Sugar().Errorf("failed to renew the graphing lease for crawlID %s: %+v", crawlID, err)
+				continue
+			}
+			if !renewed {
+				configuration.Logger.Sugar().Warnf("the graph build for crawlID %s has lost its lease", crawlID)
+				return
+			}
+		}
+	}
+}
+
+// collectGraphDataSafely is CollectGraphData with any panic returned
+// as an error so that it does not take down the crawler
+func collectGraphDataSafely(cntr controller.CntrInterface, steamID, crawlID string, workerConfig GraphWorkerConfig) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("graph creation panicked: %v", r)
+		}
+	}()
+	return CollectGraphData(cntr, steamID, crawlID, workerConfig)
+}
@@ -0,0 +1,185 @@
+package graphing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iamcathal/neo/services/crawler/controller"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunGraphBuildRetriesWithBackoffAndThenMarksTheCrawlAsFailed(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+	graphBuilds[crawlID] = &datastructures.GraphBuild{
+		CrawlID: crawlID,
+		Status:  datastructures.GraphBuildPending,
+	}
+
+	mockController.On("GetUsersFromDataStore", mock.Anything).Return([]common.UserDocument{}, errors.New("failed all retries"))
+	mockController.On("Sleep", mock.AnythingOfType("time.Duration")).Return()
+	mockController.On("UpdateCrawlStateInDataStore", crawlID, datastructures.CrawlStateFailed, "").Return(true, nil)
+
+	runGraphBuild(mockController, "12345", crawlID, GraphWorkerConfig{MaxLevel: 2})
+
+	build := graphBuilds[crawlID]
+	assert.Equal(t, datastructures.GraphBuildFailed, build.Status)
+	assert.Equal(t, maxGraphBuildAttempts, build.Attempts)
+	assert.NotEmpty(t, build.Error)
+	mockController.AssertCalled(t, "Sleep", graphBuildRetryDelay)
+	mockController.AssertCalled(t, "Sleep", 2*graphBuildRetryDelay)
+	mockController.AssertNumberOfCalls(t, "Sleep", maxGraphBuildAttempts-1)
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlStateInDataStore", 1)
+}
+
+func TestRunGraphBuildMarksTheCrawlUsingTheLeaseOfTheBuild(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+	graphBuilds[crawlID] = &datastructures.GraphBuild{
+		CrawlID: crawlID,
+		Status:  datastructures.GraphBuildPending,
+	}
+
+	mockController.On("GetUsersFromDataStore", mock.Anything).Return([]common.UserDocument{}, errors.New("failed all retries"))
+	mockController.On("Sleep", mock.AnythingOfType("time.Duration")).Return()
+	mockController.On("UpdateCrawlStateInDataStore", crawlID, datastructures.CrawlStateFailed, "leaseID").Return(false, nil)
+
+	runGraphBuild(mockController, "12345", crawlID, GraphWorkerConfig{MaxLevel: 2, LeaseID: "leaseID"})
+
+	mockController.AssertCalled(t, "UpdateCrawlStateInDataStore", crawlID, datastructures.CrawlStateFailed, "leaseID")
+}
+
+func TestRenewGraphingLeaseRetriesFailedRenewalsAndStopsOnceTheLeaseIsLost(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+
+	mockController.On("RenewGraphingLeaseInDataStore", crawlID, "leaseID").Return(false, errors.New("datastore is down")).Once()
+	mockController.On("RenewGraphingLeaseInDataStore", crawlID, "leaseID").Return(true, nil).Once()
+	mockController.On("RenewGraphingLeaseInDataStore", crawlID, "leaseID").Return(false, nil).Once()
+
+	renewGraphingLease(mockController, crawlID, "leaseID", time.Millisecond, make(chan struct{}))
+
+	mockController.AssertNumberOfCalls(t, "RenewGraphingLeaseInDataStore", 3)
+}
+
+func TestRenewGraphingLeaseStopsOnceTheBuildHasFinished(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	buildFinished := make(chan struct{})
+	close(buildFinished)
+
+	renewGraphingLease(mockController, ksuid.New().String(), "leaseID", time.Hour, buildFinished)
+
+	mockController.AssertNotCalled(t, "RenewGraphingLeaseInDataStore", mock.Anything, mock.Anything)
+}
+
+func TestStartGraphBuildDoesNotStartASecondBuildForTheSameCrawl(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+	graphBuilds[crawlID] = &datastructures.GraphBuild{
+		CrawlID: crawlID,
+		Status:  datastructures.GraphBuildRunning,
+	}
+
+	started := StartGraphBuild(mockController, "12345", crawlID, GraphWorkerConfig{MaxLevel: 2})
+
+	assert.False(t, started)
+	assert.Equal(t, datastructures.GraphBuildRunning, graphBuilds[crawlID].Status)
+}
+
+func TestEvictFinishedGraphBuildsOnlyRemovesBuildsThatFinishedLongAgo(t *testing.T) {
+	now := time.Now()
+	oldBuildKey := ksuid.New().String()
+	recentBuildKey := ksuid.New().String()
+	runningBuildKey := ksuid.New().String()
+	graphBuilds[oldBuildKey] = &datastructures.GraphBuild{
+		Status:       datastructures.GraphBuildSucceeded,
+		TimeFinished: now.Add(-finishedGraphBuildRetention - time.Minute).Unix(),
+	}
+	graphBuilds[recentBuildKey] = &datastructures.GraphBuild{
+		Status:       datastructures.GraphBuildFailed,
+		TimeFinished: now.Add(-time.Minute).Unix(),
+	}
+	graphBuilds[runningBuildKey] = &datastructures.GraphBuild{
+		Status:     datastructures.GraphBuildRunning,
+		TimeQueued: now.Add(-finishedGraphBuildRetention - time.Minute).Unix(),
+	}
+
+	graphBuildsLock.Lock()
+	evictFinishedGraphBuilds(now)
+	graphBuildsLock.Unlock()
+
+	assert.NotContains(t, graphBuilds, oldBuildKey)
+	assert.Contains(t, graphBuilds, recentBuildKey)
+	assert.Contains(t, graphBuilds, runningBuildKey)
+}
+
+func TestGetGraphBuildUsesTheCrawlStateForBuildsOnOtherCrawlers(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+	graphingStarted := time.Now().Unix()
+
+	mockController.On("GetCrawlingStatsFromDataStore", crawlID).Return(datastructures.CrawlingStatus{
+		State: datastructures.CrawlStateGraphing,
+		StateTimestamps: map[string]int64{
+			datastructures.CrawlStateCrawlComplete: graphingStarted - 1,
+			datastructures.CrawlStateGraphing:      graphingStarted,
+		},
+	}, nil)
+
+	exists, build, err := GetGraphBuild(mockController, crawlID, false)
+
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, datastructures.GraphBuildRunning, build.Status)
+	assert.Equal(t, graphingStarted-1, build.TimeQueued)
+}
+
+func TestGetGraphBuildReturnsNothingForCrawlsThatAreStillCrawling(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+
+	mockController.On("GetCrawlingStatsFromDataStore", crawlID).Return(datastructures.CrawlingStatus{
+		State: datastructures.CrawlStateCrawling,
+	}, nil)
+
+	exists, _, err := GetGraphBuild(mockController, crawlID, false)
+
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+func TestRunGraphBuildDoesNotChangeTheCrawlStateForPartialBuilds(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+	graphBuilds[getGraphBuildKey(crawlID, true)] = &datastructures.GraphBuild{
+		CrawlID: crawlID,
+		Partial: true,
+		Status:  datastructures.GraphBuildPending,
+	}
+
+	mockController.On("GetUsersFromDataStore", mock.Anything).Return([]common.UserDocument{}, errors.New("failed all retries"))
+	mockController.On("Sleep", mock.AnythingOfType("time.Duration")).Return()
+
+	runGraphBuild(mockController, "12345", crawlID, GraphWorkerConfig{MaxLevel: 2, Partial: true})
+
+	assert.Equal(t, datastructures.GraphBuildFailed, graphBuilds[getGraphBuildKey(crawlID, true)].Status)
+	_, fullBuildExists := graphBuilds[crawlID]
+	assert.False(t, fullBuildExists)
+	mockController.AssertNotCalled(t, "UpdateCrawlStateInDataStore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetGraphBuildOnlyLooksForPartialBuildsOnThisCrawler(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+
+	exists, _, err := GetGraphBuild(mockController, crawlID, true)
+
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	mockController.AssertNotCalled(t, "GetCrawlingStatsFromDataStore", mock.Anything)
+}
@@ -0,0 +1,231 @@
+package graphing
+
+import (
+	"math"
+	"sort"
+
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
+
+const (
+	// maxCentralitySources is how many users shortest paths are found
+	// from when working out betweenness and closeness. Larger graphs
+	// use an evenly spaced sample of users so that graphs of level 3
+	// crawls do not take hours
+	maxCentralitySources = 256
+	pageRankDamping      = 0.85
+	maxPageRankRounds    = 100
+	pageRankTolerance    = 1e-9
+	// maxCalledOutUsers is how many of the most connected and bridge
+	// users are listed
+	maxCalledOutUsers = 10
+)
+
+// getCentrality works out the centrality of every user in the graph
+func getCentrality(graph friendGraph) []datastructures.Centrality {
+	centrality := make([]datastructures.Centrality, graph.nodeCount())
+	betweenness, closeness := getBetweennessAndCloseness(graph)
+	pageRank := getPageRank(graph)
+	for i := range centrality {
+		centrality[i] = datastructures.Centrality{
+			Degree:      len(graph.neighbours[i]),
+			Betweenness: betweenness[i],
+			Closeness:   closeness[i],
+			PageRank:    pageRank[i],
+		}
+	}
+	return centrality
+}
+
+// getCentralitySources returns the users that shortest paths are found
+// from, every user if the graph is small enough
+func getCentralitySources(nodeCount int) []int {
+	sourceCount := nodeCount
+	if sourceCount > maxCentralitySources {
+		sourceCount = maxCentralitySources
+	}
+	sources := make([]int, sourceCount)
+	for i := range sources {
+		sources[i] = i * nodeCount / sourceCount
+	}
+	return sources
+}
+
+// getBetweennessAndCloseness uses Brandes' algorithm to find how often
+// each user is on the shortest path between two others. Closeness is
+// worked out from the same shortest paths and is scaled by how much of
+// the graph a user can reach so that small disconnected groups do not
+// look central. Both are between 0 and 1
+func getBetweennessAndCloseness(graph friendGraph) ([]float64, []float64) {
+	nodeCount := graph.nodeCount()
+	betweenness := make([]float64, nodeCount)
+	closeness := make([]float64, nodeCount)
+	if nodeCount < 2 {
+		return betweenness, closeness
+	}
+	sources := getCentralitySources(nodeCount)
+
+	// Graphs are undirected so the distance from a source to a user is
+	// the same as the distance from that user to the source
+	totalDistanceToSources := make([]int, nodeCount)
+	sourcesReached := make([]int, nodeCount)
+
+	distances := make([]int, nodeCount)
+	pathCounts := make([]float64, nodeCount)
+	dependencies := make([]float64, nodeCount)
+	predecessors := make([][]int, nodeCount)
+	for _, source := range sources {
+		for i := range distances {
+			distances[i] = -1
+			pathCounts[i] = 0
+			dependencies[i] = 0
+			predecessors[i] = predecessors[i][:0]
+		}
+		distances[source] = 0
+		pathCounts[source] = 1
+
+		visitOrder := []int{source}
+		for next := 0; next < len(visitOrder); next++ {
+			current := visitOrder[next]
+			for _, neighbour := range graph.neighbours[current] {
+				if distances[neighbour] == -1 {
+					distances[neighbour] = distances[current] + 1
+					visitOrder = append(visitOrder, neighbour)
+				}
+				if distances[neighbour] == distances[current]+1 {
+					pathCounts[neighbour] += pathCounts[current]
+					predecessors[neighbour] = append(predecessors[neighbour], current)
+				}
+			}
+		}
+
+		for i := len(visitOrder) - 1; i > 0; i-- {
+			current := visitOrder[i]
+			for _, predecessor := range predecessors[current] {
+				dependencies[predecessor] += pathCounts[predecessor] / pathCounts[current] * (1 + dependencies[current])
+			}
+			betweenness[current] += dependencies[current]
+			totalDistanceToSources[current] += distances[current]
+			sourcesReached[current]++
+		}
+	}
+
+	// Each pair of users is counted from both ends when every user is a
+	// source. Sampled sources are scaled up to the whole graph
+	scale := float64(nodeCount) / float64(len(sources)) / 2
+	if nodeCount > 2 {
+		scale /= float64((nodeCount - 1) * (nodeCount - 2) / 2)
+	}
+	for i := range betweenness {
+		betweenness[i] *= scale
+	}
+
+	isSource := make(map[int]bool)
+	for _, source := range sources {
+		isSource[source] = true
+	}
+	for i := range closeness {
+		if totalDistanceToSources[i] == 0 {
+			continue
+		}
+		otherSources := len(sources)
+		if isSource[i] {
+			otherSources--
+		}
+		reached := float64(sourcesReached[i])
+		closeness[i] = reached / float64(totalDistanceToSources[i]) * reached / float64(otherSources)
+	}
+	return betweenness, closeness
+}
+
+// getPageRank ranks users by how well connected their friends are.
+// Users with no friends share their rank with everyone
+func getPageRank(graph friendGraph) []float64 {
+	nodeCount := graph.nodeCount()
+	pageRank := make([]float64, nodeCount)
+	if nodeCount == 0 {
+		return pageRank
+	}
+	for i := range pageRank {
+		pageRank[i] = 1 / float64(nodeCount)
+	}
+
+	nextPageRank := make([]float64, nodeCount)
+	for round := 0; round < maxPageRankRounds; round++ {
+		unsharedRank := 0.0
+		for i := range pageRank {
+			if len(graph.neighbours[i]) == 0 {
+				unsharedRank += pageRank[i]
+			}
+		}
+		baseRank := (1-pageRankDamping)/float64(nodeCount) + pageRankDamping*unsharedRank/float64(nodeCount)
+		for i := range nextPageRank {
+			nextPageRank[i] = baseRank
+		}
+		for i, neighbours := range graph.neighbours {
+			if len(neighbours) == 0 {
+				continue
+			}
+			sharedRank := pageRankDamping * pageRank[i] / float64(len(neighbours))
+			for _, neighbour := range neighbours {
+				nextPageRank[neighbour] += sharedRank
+			}
+		}
+
+		change := 0.0
+		for i := range pageRank {
+			change += math.Abs(nextPageRank[i] - pageRank[i])
+		}
+		pageRank, nextPageRank = nextPageRank, pageRank
+		if change < pageRankTolerance {
+			break
+		}
+	}
+	return pageRank
+}
+
+// getUserCentrality works out the centrality of every user by steamID
+// along with the most connected users and the users that bridge the
+// most of the graph together
+func getUserCentrality(graph friendGraph, crawlTarget string) (map[string]datastructures.Centrality, []string, []string) {
+	centrality := getCentrality(graph)
+	userCentrality := make(map[string]datastructures.Centrality)
+	for i, steamID := range graph.steamIDs {
+		userCentrality[steamID] = centrality[i]
+	}
+
+	mostConnectedUsers := getTopUsersBy(graph, crawlTarget, func(i int) float64 {
+		return float64(centrality[i].Degree)
+	})
+	bridgeUsers := getTopUsersBy(graph, crawlTarget, func(i int) float64 {
+		return centrality[i].Betweenness
+	})
+	return userCentrality, mostConnectedUsers, bridgeUsers
+}
+
+// getTopUsersBy returns the steamIDs of the users with the highest
+// score, leaving out the original crawl target that every level 2
+// user is connected to. Ties are broken by steamID
+func getTopUsersBy(graph friendGraph, crawlTarget string, score func(i int) float64) []string {
+	users := []int{}
+	for i, steamID := range graph.steamIDs {
+		if steamID != crawlTarget && score(i) > 0 {
+			users = append(users, i)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if score(users[i]) != score(users[j]) {
+			return score(users[i]) > score(users[j])
+		}
+		return graph.steamIDs[users[i]] < graph.steamIDs[users[j]]
+	})
+	if len(users) > maxCalledOutUsers {
+		users = users[:maxCalledOutUsers]
+	}
+
+	topUsers := make([]string, len(users))
+	for i, user := range users {
+		topUsers[i] = graph.steamIDs[user]
+	}
+	return topUsers
+}
@@ -0,0 +1,78 @@
+package graphing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCentralityOfAPath(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2"},
+		"2": {"3"},
+	}, "1", "2", "3")
+
+	centrality := getCentrality(newFriendGraph(users))
+
+	assert.Equal(t, []int{1, 2, 1}, []int{centrality[0].Degree, centrality[1].Degree, centrality[2].Degree})
+	assert.InDelta(t, 0, centrality[0].Betweenness, 1e-9)
+	assert.InDelta(t, 1, centrality[1].Betweenness, 1e-9)
+	assert.InDelta(t, 2.0/3.0, centrality[0].Closeness, 1e-9)
+	assert.InDelta(t, 1, centrality[1].Closeness, 1e-9)
+	assert.Greater(t, centrality[1].PageRank, centrality[0].PageRank)
+	assert.InDelta(t, 1, centrality[0].PageRank+centrality[1].PageRank+centrality[2].PageRank, 1e-6)
+}
+
+func TestGetCentralityScalesClosenessByHowMuchOfTheGraphIsReachable(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2"},
+	}, "1", "2", "3", "4")
+
+	centrality := getCentrality(newFriendGraph(users))
+
+	assert.InDelta(t, 1.0/3.0, centrality[0].Closeness, 1e-9)
+	assert.Equal(t, 0.0, centrality[2].Closeness)
+	assert.InDelta(t, 1, centrality[0].PageRank+centrality[1].PageRank+centrality[2].PageRank+centrality[3].PageRank, 1e-6)
+}
+
+func TestGetCentralitySourcesSamplesLargeGraphsEvenly(t *testing.T) {
+	sources := getCentralitySources(1000)
+
+	assert.Len(t, sources, maxCentralitySources)
+	assert.Equal(t, 0, sources[0])
+	for i := 1; i < len(sources); i++ {
+		assert.Greater(t, sources[i], sources[i-1])
+	}
+	assert.Len(t, getCentralitySources(10), 10)
+}
+
+func TestGetCentralityEstimatesBetweennessOfLargeGraphs(t *testing.T) {
+	friendLists := map[string][]string{"0": {}}
+	steamIDs := []string{"0"}
+	for i := 1; i <= 600; i++ {
+		friendLists["0"] = append(friendLists["0"], fmt.Sprint(i))
+		steamIDs = append(steamIDs, fmt.Sprint(i))
+	}
+	users := makeGraphUsers(friendLists, steamIDs...)
+
+	centrality := getCentrality(newFriendGraph(users))
+
+	assert.InDelta(t, 1, centrality[0].Betweenness, 0.01)
+	assert.InDelta(t, 0, centrality[1].Betweenness, 1e-9)
+}
+
+func TestGetUserCentralityCallsOutUsersOtherThanTheCrawlTarget(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2", "3", "4"},
+		"2": {"5"},
+		"5": {"6"},
+	}, "1", "2", "3", "4", "5", "6")
+
+	userCentrality, mostConnectedUsers, bridgeUsers := getUserCentrality(newFriendGraph(users), "1")
+
+	assert.Len(t, userCentrality, 6)
+	assert.Equal(t, 3, userCentrality["1"].Degree)
+	assert.Equal(t, []string{"2", "5", "3", "4", "6"}, mostConnectedUsers)
+	assert.Equal(t, []string{"2", "5"}, bridgeUsers)
+}
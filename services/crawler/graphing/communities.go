@@ -0,0 +1,280 @@
+package graphing
+
+import (
+	"sort"
+
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+const (
+	// maxCommunityTopGames is how many games are listed for each community
+	maxCommunityTopGames = 3
+	// maxLouvainPasses stops the local moving phase from running forever
+	// if moves keep undoing each other
+	maxLouvainPasses = 100
+)
+
+// weightedGraph is a friendGraph whose nodes may be whole communities.
+// loops holds the weight of the edges inside each node
+type weightedGraph struct {
+	edges []map[int]float64
+	loops []float64
+}
+
+// detectCommunities groups users into communities with the Louvain method
+// and returns the community of every user in the graph. Communities are
+// numbered from largest to smallest
+func detectCommunities(graph friendGraph) []int {
+	membership := make([]int, graph.nodeCount())
+	for i := range membership {
+		membership[i] = i
+	}
+
+	currentGraph := weightedGraph{
+		edges: make([]map[int]float64, graph.nodeCount()),
+		loops: make([]float64, graph.nodeCount()),
+	}
+	for i, neighbours := range graph.neighbours {
+		currentGraph.edges[i] = make(map[int]float64)
+		for _, neighbour := range neighbours {
+			currentGraph.edges[i][neighbour] = 1
+		}
+	}
+
+	for {
+		communities, moved := moveNodesToBestCommunities(currentGraph)
+		if !moved {
+			break
+		}
+		for i := range membership {
+			membership[i] = communities[membership[i]]
+		}
+		currentGraph = aggregateCommunities(currentGraph, communities)
+	}
+	return numberCommunitiesBySize(membership)
+}
+
+// moveNodesToBestCommunities moves each node into the neighbouring
+// community that gives the largest gain in modularity until no node
+// can be moved. The returned communities are numbered from zero and
+// false is returned if no nodes were merged
+func moveNodesToBestCommunities(graph weightedGraph) ([]int, bool) {
+	nodeCount := len(graph.edges)
+	degrees := make([]float64, nodeCount)
+	totalDegree := 0.0
+	for i := range graph.edges {
+		for _, weight := range graph.edges[i] {
+			degrees[i] += weight
+		}
+		degrees[i] += 2 * graph.loops[i]
+		totalDegree += degrees[i]
+	}
+
+	communities := make([]int, nodeCount)
+	communityDegrees := make([]float64, nodeCount)
+	for i := range communities {
+		communities[i] = i
+		communityDegrees[i] = degrees[i]
+	}
+	if totalDegree == 0 {
+		return communities, false
+	}
+
+	for pass := 0; pass < maxLouvainPasses; pass++ {
+		movedInPass := false
+		for i := 0; i < nodeCount; i++ {
+			currentCommunity := communities[i]
+			communityDegrees[currentCommunity] -= degrees[i]
+
+			weightToCommunities := make(map[int]float64)
+			for neighbour, weight := range graph.edges[i] {
+				weightToCommunities[communities[neighbour]] += weight
+			}
+
+			bestCommunity := currentCommunity
+			bestGain := weightToCommunities[currentCommunity] - communityDegrees[currentCommunity]*degrees[i]/totalDegree
+			// Neighbouring communities are checked in order so that the
+			// same graph always gives the same communities
+			neighbouringCommunities := make([]int, 0, len(weightToCommunities))
+			for community := range weightToCommunities {
+				neighbouringCommunities = append(neighbouringCommunities, community)
+			}
+			sort.Ints(neighbouringCommunities)
+			for _, community := range neighbouringCommunities {
+				gain := weightToCommunities[community] - communityDegrees[community]*degrees[i]/totalDegree
+				if gain > bestGain {
+					bestCommunity = community
+					bestGain = gain
+				}
+			}
+
+			communities[i] = bestCommunity
+			communityDegrees[bestCommunity] += degrees[i]
+			if bestCommunity != currentCommunity {
+				movedInPass = true
+			}
+		}
+		if !movedInPass {
+			break
+		}
+	}
+	communities = renumberCommunities(communities)
+	return communities, countCommunities(communities) < nodeCount
+}
+
+// aggregateCommunities merges each community into a single node
+func aggregateCommunities(graph weightedGraph, communities []int) weightedGraph {
+	communityCount := countCommunities(communities)
+	aggregated := weightedGraph{
+		edges: make([]map[int]float64, communityCount),
+		loops: make([]float64, communityCount),
+	}
+	for i := range aggregated.edges {
+		aggregated.edges[i] = make(map[int]float64)
+	}
+
+	for i := range graph.edges {
+		community := communities[i]
+		aggregated.loops[community] += graph.loops[i]
+		for neighbour, weight := range graph.edges[i] {
+			neighbourCommunity := communities[neighbour]
+			if neighbourCommunity == community {
+				// Edges inside a community are seen from both ends
+				aggregated.loops[community] += weight / 2
+				continue
+			}
+			aggregated.edges[community][neighbourCommunity] += weight
+		}
+	}
+	return aggregated
+}
+
+// renumberCommunities numbers communities from zero in the order
+// they first appear
+func renumberCommunities(communities []int) []int {
+	newIDs := make(map[int]int)
+	renumbered := make([]int, len(communities))
+	for i, community := range communities {
+		if _, exists := newIDs[community]; !exists {
+			newIDs[community] = len(newIDs)
+		}
+		renumbered[i] = newIDs[community]
+	}
+	return renumbered
+}
+
+// countCommunities returns how many communities there are when they
+// are numbered from zero
+func countCommunities(communities []int) int {
+	communityCount := 0
+	for _, community := range communities {
+		if community+1 > communityCount {
+			communityCount = community + 1
+		}
+	}
+	return communityCount
+}
+
+// numberCommunitiesBySize numbers communities from largest to smallest.
+// Communities of the same size are ordered by their first user
+func numberCommunitiesBySize(communities []int) []int {
+	communities = renumberCommunities(communities)
+	sizes := make(map[int]int)
+	for _, community := range communities {
+		sizes[community]++
+	}
+	order := make([]int, 0, len(sizes))
+	for community := range sizes {
+		order = append(order, community)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if sizes[order[i]] != sizes[order[j]] {
+			return sizes[order[i]] > sizes[order[j]]
+		}
+		return order[i] < order[j]
+	})
+	newIDs := make(map[int]int)
+	for newID, community := range order {
+		newIDs[community] = newID
+	}
+
+	numbered := make([]int, len(communities))
+	for i, community := range communities {
+		numbered[i] = newIDs[community]
+	}
+	return numbered
+}
+
+// getCommunities detects the communities in a crawl and summarises each
+// of them. The community of each user is returned by steamID
+func getCommunities(graph friendGraph, users []common.UsersGraphInformation) (map[string]int, []datastructures.CommunitySummary) {
+	communities := detectCommunities(graph)
+
+	userCommunities := make(map[string]int)
+	for i, steamID := range graph.steamIDs {
+		userCommunities[steamID] = communities[i]
+	}
+
+	communityCount := countCommunities(communities)
+	countryCounts := make([]map[string]int, communityCount)
+	gamePlaytimes := make([]map[int]int, communityCount)
+	summaries := make([]datastructures.CommunitySummary, communityCount)
+	for i := range summaries {
+		summaries[i].ID = i
+		countryCounts[i] = make(map[string]int)
+		gamePlaytimes[i] = make(map[int]int)
+	}
+
+	seenUsers := make(map[string]bool)
+	for _, user := range users {
+		steamID := user.User.AccDetails.SteamID
+		if seenUsers[steamID] {
+			continue
+		}
+		seenUsers[steamID] = true
+		community := userCommunities[steamID]
+
+		summaries[community].Size++
+		if countryCode := user.User.AccDetails.Loccountrycode; countryCode != "" {
+			countryCounts[community][countryCode]++
+		}
+		for _, game := range user.User.GamesOwned {
+			gamePlaytimes[community][game.AppID] += game.Playtime_Forever
+		}
+	}
+
+	for i := range summaries {
+		summaries[i].DominantCountry = getMostFrequentCountry(countryCounts[i])
+		summaries[i].TopGames = getMostPlayedGames(gamePlaytimes[i], maxCommunityTopGames)
+	}
+	return userCommunities, summaries
+}
+
+func getMostFrequentCountry(countryCounts map[string]int) string {
+	dominantCountry := ""
+	for countryCode, count := range countryCounts {
+		if dominantCountry == "" || count > countryCounts[dominantCountry] ||
+			(count == countryCounts[dominantCountry] && countryCode < dominantCountry) {
+			dominantCountry = countryCode
+		}
+	}
+	return dominantCountry
+}
+
+func getMostPlayedGames(gamePlaytimes map[int]int, amount int) []int {
+	appIDs := make([]int, 0, len(gamePlaytimes))
+	for appID := range gamePlaytimes {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Slice(appIDs, func(i, j int) bool {
+		if gamePlaytimes[appIDs[i]] != gamePlaytimes[appIDs[j]] {
+			return gamePlaytimes[appIDs[i]] > gamePlaytimes[appIDs[j]]
+		}
+		return appIDs[i] < appIDs[j]
+	})
+	if len(appIDs) > amount {
+		appIDs = appIDs[:amount]
+	}
+	return appIDs
+}
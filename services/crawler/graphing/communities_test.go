@@ -0,0 +1,77 @@
+package graphing
+
+import (
+	"testing"
+
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCommunitiesSplitsTwoFriendGroupsJoinedByOneFriendship(t *testing.T) {
+	// Two groups of four friends where only 4 and 5 know each other
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2", "3", "4"},
+		"2": {"1", "3", "4"},
+		"3": {"1", "2", "4"},
+		"4": {"1", "2", "3", "5"},
+		"5": {"4", "6", "7", "8"},
+		"6": {"5", "7", "8"},
+		"7": {"5", "6", "8"},
+		"8": {"5", "6", "7"},
+	}, "1", "2", "3", "4", "5", "6", "7", "8")
+
+	communities := detectCommunities(newFriendGraph(users))
+
+	assert.Equal(t, []int{0, 0, 0, 0, 1, 1, 1, 1}, communities)
+}
+
+func TestDetectCommunitiesNumbersCommunitiesFromLargestToSmallest(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2"},
+		"3": {"4", "5"},
+		"4": {"5"},
+	}, "1", "2", "3", "4", "5", "6")
+
+	communities := detectCommunities(newFriendGraph(users))
+
+	assert.Equal(t, []int{1, 1, 0, 0, 0, 2}, communities)
+}
+
+func TestDetectCommunitiesGivesUsersWithNoFriendsTheirOwnCommunity(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{}, "1", "2")
+
+	communities := detectCommunities(newFriendGraph(users))
+
+	assert.Equal(t, []int{0, 1}, communities)
+}
+
+func TestGetCommunitiesSummarisesEachCommunity(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2", "3"},
+		"2": {"3"},
+		"4": {"5"},
+	}, "1", "2", "3", "4", "5")
+	countries := []string{"IE", "DE", "IE", "FR", ""}
+	games := [][]common.GameOwnedDocument{
+		{{AppID: 730, Playtime_Forever: 100}, {AppID: 570, Playtime_Forever: 10}},
+		{{AppID: 570, Playtime_Forever: 50}, {AppID: 440, Playtime_Forever: 5}, {AppID: 10, Playtime_Forever: 1}},
+		{{AppID: 730, Playtime_Forever: 1}},
+		{},
+		{{AppID: 220, Playtime_Forever: 30}},
+	}
+	for i := range users {
+		users[i].User.AccDetails.Loccountrycode = countries[i]
+		users[i].User.GamesOwned = games[i]
+	}
+
+	userCommunities, summaries := getCommunities(newFriendGraph(users), users)
+
+	assert.Equal(t, map[string]int{"1": 0, "2": 0, "3": 0, "4": 1, "5": 1}, userCommunities)
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, 3, summaries[0].Size)
+	assert.Equal(t, "IE", summaries[0].DominantCountry)
+	assert.Equal(t, []int{730, 570, 440}, summaries[0].TopGames)
+	assert.Equal(t, 2, summaries[1].Size)
+	assert.Equal(t, "FR", summaries[1].DominantCountry)
+	assert.Equal(t, []int{220}, summaries[1].TopGames)
+}
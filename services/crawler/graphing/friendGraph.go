@@ -0,0 +1,67 @@
+package graphing
+
+import (
+	"sort"
+
+	"github.com/neosteamfriendgraphing/common"
+)
+
+// friendGraph is the undirected friendship graph between the users of a
+// crawl. Users are referred to by their index in steamIDs
+type friendGraph struct {
+	steamIDs   []string
+	neighbours [][]int
+	edgeCount  int
+}
+
+// newFriendGraph links users that are friends with each other. Friend
+// lists can be out of date with each other so two users are friends if
+// either of them lists the other. Friends that are not part of the
+// crawl are left out
+func newFriendGraph(users []common.UsersGraphInformation) friendGraph {
+	graph := friendGraph{
+		steamIDs:   []string{},
+		neighbours: [][]int{},
+	}
+	steamIDToIndex := make(map[string]int)
+	for _, user := range users {
+		steamID := user.User.AccDetails.SteamID
+		if _, exists := steamIDToIndex[steamID]; exists {
+			continue
+		}
+		steamIDToIndex[steamID] = len(graph.steamIDs)
+		graph.steamIDs = append(graph.steamIDs, steamID)
+	}
+
+	neighbourSets := make([]map[int]bool, len(graph.steamIDs))
+	for i := range neighbourSets {
+		neighbourSets[i] = make(map[int]bool)
+	}
+	for _, user := range users {
+		userIndex := steamIDToIndex[user.User.AccDetails.SteamID]
+		for _, friendID := range user.User.FriendIDs {
+			friendIndex, exists := steamIDToIndex[friendID]
+			if !exists || friendIndex == userIndex {
+				continue
+			}
+			neighbourSets[userIndex][friendIndex] = true
+			neighbourSets[friendIndex][userIndex] = true
+		}
+	}
+
+	for _, neighbourSet := range neighbourSets {
+		neighbours := make([]int, 0, len(neighbourSet))
+		for neighbour := range neighbourSet {
+			neighbours = append(neighbours, neighbour)
+		}
+		sort.Ints(neighbours)
+		graph.neighbours = append(graph.neighbours, neighbours)
+		graph.edgeCount += len(neighbours)
+	}
+	graph.edgeCount /= 2
+	return graph
+}
+
+func (graph friendGraph) nodeCount() int {
+	return len(graph.steamIDs)
+}
@@ -0,0 +1,50 @@
+package graphing
+
+import (
+	"testing"
+
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeGraphUsers creates users with the given friend lists, the
+// users are created in steamID order
+func makeGraphUsers(friendLists map[string][]string, steamIDs ...string) []common.UsersGraphInformation {
+	users := []common.UsersGraphInformation{}
+	for _, steamID := range steamIDs {
+		users = append(users, common.UsersGraphInformation{
+			User: common.UserDocument{
+				AccDetails: common.AccDetailsDocument{SteamID: steamID},
+				FriendIDs:  friendLists[steamID],
+			},
+		})
+	}
+	return users
+}
+
+func TestNewFriendGraphLinksUsersThatOnlyOneOfListsAsAFriend(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2", "3"},
+		"2": {},
+		"3": {"1"},
+	}, "1", "2", "3")
+
+	graph := newFriendGraph(users)
+
+	assert.Equal(t, []string{"1", "2", "3"}, graph.steamIDs)
+	assert.Equal(t, [][]int{{1, 2}, {0}, {0}}, graph.neighbours)
+	assert.Equal(t, 2, graph.edgeCount)
+}
+
+func TestNewFriendGraphLeavesOutFriendsThatAreNotInTheCrawl(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2", "99", "1"},
+		"2": {"1", "98"},
+	}, "1", "2", "2")
+
+	graph := newFriendGraph(users)
+
+	assert.Equal(t, 2, graph.nodeCount())
+	assert.Equal(t, [][]int{{1}, {0}}, graph.neighbours)
+	assert.Equal(t, 1, graph.edgeCount)
+}
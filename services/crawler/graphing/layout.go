@@ -0,0 +1,192 @@
+package graphing
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
+
+const (
+	// layoutEdgeLength is the ideal distance between two friends. It is
+	// large enough that the frontend can draw positions as they are
+	layoutEdgeLength = 40.0
+	layoutIterations = 100
+	// layoutPrecision is how many decimal places positions are kept to
+	layoutPrecision = 100.0
+)
+
+// layoutCell is a cell of the grid used to find users near each other.
+// Two dimensional layouts leave the last coordinate as zero
+type layoutCell [3]int
+
+// getGraphLayout lays out the graph in two and three dimensions. The same
+// graph and seed always give the same layout
+func getGraphLayout(graph friendGraph, seed int64) datastructures.GraphLayout {
+	layout := datastructures.GraphLayout{
+		Seed:        seed,
+		Positions2D: make(map[string][2]float64),
+		Positions3D: make(map[string][3]float64),
+	}
+	positions2D := layOutGraph(graph, 2, seed)
+	positions3D := layOutGraph(graph, 3, seed)
+	for i, steamID := range graph.steamIDs {
+		layout.Positions2D[steamID] = [2]float64{positions2D[i][0], positions2D[i][1]}
+		layout.Positions3D[steamID] = [3]float64{positions3D[i][0], positions3D[i][1], positions3D[i][2]}
+	}
+	return layout
+}
+
+// layOutGraph places users with the Fruchterman-Reingold algorithm. Friends
+// pull each other together while users push away others near them. As in
+// the original paper only users in neighbouring cells of a grid push each
+// other so that large graphs are laid out quickly. Layouts are centred on
+// the origin
+func layOutGraph(graph friendGraph, dimensions int, seed int64) [][]float64 {
+	nodeCount := graph.nodeCount()
+	random := rand.New(rand.NewSource(seed))
+	side := layoutEdgeLength * math.Pow(float64(nodeCount), 1/float64(dimensions))
+
+	positions := make([][]float64, nodeCount)
+	displacements := make([][]float64, nodeCount)
+	for i := range positions {
+		positions[i] = make([]float64, dimensions)
+		displacements[i] = make([]float64, dimensions)
+		for axis := range positions[i] {
+			positions[i][axis] = (random.Float64() - 0.5) * side
+		}
+	}
+
+	delta := make([]float64, dimensions)
+	cellOffsets := getNeighbouringCellOffsets(dimensions)
+	for iteration := 0; iteration < layoutIterations; iteration++ {
+		// The furthest a user can move cools down so the layout settles
+		temperature := side / 10 * (1 - float64(iteration)/layoutIterations)
+		grid := getLayoutGrid(positions)
+
+		for i := range positions {
+			for axis := range displacements[i] {
+				displacements[i][axis] = 0
+			}
+			cell := getLayoutCell(positions[i])
+			for _, offset := range cellOffsets {
+				neighbouringCell := layoutCell{cell[0] + offset[0], cell[1] + offset[1], cell[2] + offset[2]}
+				for _, other := range grid[neighbouringCell] {
+					if other == i {
+						continue
+					}
+					distance := getLayoutDelta(delta, positions[i], positions[other], i < other)
+					if distance > 2*layoutEdgeLength {
+						continue
+					}
+					push := layoutEdgeLength * layoutEdgeLength / distance
+					for axis := range delta {
+						displacements[i][axis] += delta[axis] / distance * push
+					}
+				}
+			}
+			for _, neighbour := range graph.neighbours[i] {
+				distance := getLayoutDelta(delta, positions[i], positions[neighbour], i < neighbour)
+				pull := distance * distance / layoutEdgeLength
+				for axis := range delta {
+					displacements[i][axis] -= delta[axis] / distance * pull
+				}
+			}
+		}
+
+		for i := range positions {
+			length := 0.0
+			for _, displacement := range displacements[i] {
+				length += displacement * displacement
+			}
+			length = math.Sqrt(length)
+			if length == 0 {
+				continue
+			}
+			step := math.Min(length, temperature)
+			for axis := range positions[i] {
+				positions[i][axis] += displacements[i][axis] / length * step
+			}
+		}
+	}
+
+	centreLayout(positions, dimensions)
+	return positions
+}
+
+// getLayoutDelta writes the vector from other to position into delta and
+// returns its length. Users in the same place are pushed apart along the
+// first axis in the order they are in the graph
+func getLayoutDelta(delta, position, other []float64, before bool) float64 {
+	length := 0.0
+	for axis := range delta {
+		delta[axis] = position[axis] - other[axis]
+		length += delta[axis] * delta[axis]
+	}
+	if length > 0 {
+		return math.Sqrt(length)
+	}
+	for axis := range delta {
+		delta[axis] = 0
+	}
+	delta[0] = 0.01
+	if before {
+		delta[0] = -0.01
+	}
+	return 0.01
+}
+
+// getLayoutGrid groups users by the grid cell they are in. Cells are as
+// wide as the furthest distance users push each other from
+func getLayoutGrid(positions [][]float64) map[layoutCell][]int {
+	grid := make(map[layoutCell][]int)
+	for i, position := range positions {
+		cell := getLayoutCell(position)
+		grid[cell] = append(grid[cell], i)
+	}
+	return grid
+}
+
+func getLayoutCell(position []float64) layoutCell {
+	cell := layoutCell{}
+	for axis, coordinate := range position {
+		cell[axis] = int(math.Floor(coordinate / (2 * layoutEdgeLength)))
+	}
+	return cell
+}
+
+// getNeighbouringCellOffsets returns how far away a cell and every cell
+// touching it are
+func getNeighbouringCellOffsets(dimensions int) []layoutCell {
+	cells := []layoutCell{{}}
+	for axis := 0; axis < dimensions; axis++ {
+		cellsSoFar := len(cells)
+		for i := 0; i < cellsSoFar; i++ {
+			for _, offset := range []int{-1, 1} {
+				neighbouringCell := cells[i]
+				neighbouringCell[axis] += offset
+				cells = append(cells, neighbouringCell)
+			}
+		}
+	}
+	return cells
+}
+
+// centreLayout moves the layout so that its centre is at the origin and
+// rounds positions so that they take up less space when stored
+func centreLayout(positions [][]float64, dimensions int) {
+	if len(positions) == 0 {
+		return
+	}
+	centre := make([]float64, dimensions)
+	for _, position := range positions {
+		for axis, coordinate := range position {
+			centre[axis] += coordinate / float64(len(positions))
+		}
+	}
+	for _, position := range positions {
+		for axis := range position {
+			position[axis] = math.Round((position[axis]-centre[axis])*layoutPrecision) / layoutPrecision
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package graphing
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestRingGraph(userCount int) friendGraph {
+	friendLists := make(map[string][]string)
+	steamIDs := []string{}
+	for i := 0; i < userCount; i++ {
+		steamIDs = append(steamIDs, fmt.Sprint(i))
+		friendLists[fmt.Sprint(i)] = []string{fmt.Sprint((i + 1) % userCount)}
+	}
+	return newFriendGraph(makeGraphUsers(friendLists, steamIDs...))
+}
+
+func TestGetGraphLayoutIsTheSameForTheSameSeed(t *testing.T) {
+	graph := getTestRingGraph(30)
+
+	assert.Equal(t, getGraphLayout(graph, 7), getGraphLayout(graph, 7))
+	assert.NotEqual(t, getGraphLayout(graph, 7).Positions3D, getGraphLayout(graph, 8).Positions3D)
+}
+
+func TestGetGraphLayoutPositionsEveryUserAroundTheOrigin(t *testing.T) {
+	graph := getTestRingGraph(30)
+
+	layout := getGraphLayout(graph, 1)
+
+	assert.Equal(t, int64(1), layout.Seed)
+	assert.Len(t, layout.Positions2D, 30)
+	assert.Len(t, layout.Positions3D, 30)
+	centre := [3]float64{}
+	for _, position := range layout.Positions3D {
+		for axis, coordinate := range position {
+			centre[axis] += coordinate / 30
+		}
+	}
+	for _, coordinate := range centre {
+		assert.InDelta(t, 0, coordinate, 0.01)
+	}
+}
+
+func TestLayOutGraphPlacesFriendsCloserThanOtherUsers(t *testing.T) {
+	graph := getTestRingGraph(30)
+
+	for _, dimensions := range []int{2, 3} {
+		positions := layOutGraph(graph, dimensions, 1)
+
+		friendDistance, otherDistance := 0.0, 0.0
+		friendPairs, otherPairs := 0, 0
+		for i := range positions {
+			for j := i + 1; j < len(positions); j++ {
+				distance := 0.0
+				for axis := range positions[i] {
+					distance += math.Pow(positions[i][axis]-positions[j][axis], 2)
+				}
+				if j == i+1 || (i == 0 && j == len(positions)-1) {
+					friendDistance += math.Sqrt(distance)
+					friendPairs++
+				} else {
+					otherDistance += math.Sqrt(distance)
+					otherPairs++
+				}
+			}
+		}
+		assert.Less(t, friendDistance/float64(friendPairs), otherDistance/float64(otherPairs)/2)
+	}
+}
+
+func TestLayOutGraphSeparatesUsersInTheSamePlace(t *testing.T) {
+	delta := make([]float64, 2)
+
+	distance := getLayoutDelta(delta, []float64{1, 1}, []float64{1, 1}, true)
+
+	assert.Greater(t, distance, 0.0)
+	assert.Equal(t, []float64{-0.01, 0}, delta)
+}
+
+func TestGetGraphLayoutOfAnEmptyGraph(t *testing.T) {
+	layout := getGraphLayout(newFriendGraph(nil), 1)
+
+	assert.Empty(t, layout.Positions2D)
+	assert.Empty(t, layout.Positions3D)
+}
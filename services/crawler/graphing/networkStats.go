@@ -0,0 +1,153 @@
+package graphing
+
+import (
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
+
+// getNetworkStats describes the overall shape of the graph. Distances
+// are found from the same sample of users as betweenness so the diameter
+// and average path length of large graphs are estimates
+func getNetworkStats(graph friendGraph) datastructures.NetworkStats {
+	nodeCount := graph.nodeCount()
+	stats := datastructures.NetworkStats{
+		NodeCount:          nodeCount,
+		EdgeCount:          graph.edgeCount,
+		DegreeDistribution: getDegreeDistribution(graph),
+	}
+	if nodeCount == 0 {
+		return stats
+	}
+	stats.AverageDegree = float64(2*graph.edgeCount) / float64(nodeCount)
+	if nodeCount > 1 {
+		stats.Density = float64(2*graph.edgeCount) / float64(nodeCount*(nodeCount-1))
+	}
+	stats.ClusteringCoefficient = getAverageClusteringCoefficient(graph)
+	stats.ConnectedComponents, stats.LargestComponentSize = getConnectedComponents(graph)
+	stats.EstimatedDiameter, stats.AveragePathLength = getDiameterAndAveragePathLength(graph)
+	return stats
+}
+
+// getDegreeDistribution returns how many users have each amount of
+// friends in the crawl, indexed by the amount of friends
+func getDegreeDistribution(graph friendGraph) []int {
+	maxDegree := 0
+	for _, neighbours := range graph.neighbours {
+		if len(neighbours) > maxDegree {
+			maxDegree = len(neighbours)
+		}
+	}
+	distribution := make([]int, maxDegree+1)
+	for _, neighbours := range graph.neighbours {
+		distribution[len(neighbours)]++
+	}
+	return distribution
+}
+
+// getAverageClusteringCoefficient returns the average over every user of
+// how many of their friends are also friends with each other. Users with
+// fewer than two friends count as zero
+func getAverageClusteringCoefficient(graph friendGraph) float64 {
+	nodeCount := graph.nodeCount()
+	if nodeCount == 0 {
+		return 0
+	}
+	total := 0.0
+	for i, neighbours := range graph.neighbours {
+		degree := len(neighbours)
+		if degree < 2 {
+			continue
+		}
+		// Each link between two friends is found from both of them
+		linksBetweenFriends := 0
+		for _, neighbour := range neighbours {
+			linksBetweenFriends += countCommonNeighbours(graph.neighbours[i], graph.neighbours[neighbour])
+		}
+		total += float64(linksBetweenFriends) / float64(degree*(degree-1))
+	}
+	return total / float64(nodeCount)
+}
+
+// countCommonNeighbours counts the users in both sorted neighbour lists
+func countCommonNeighbours(first, second []int) int {
+	commonCount := 0
+	i, j := 0, 0
+	for i < len(first) && j < len(second) {
+		switch {
+		case first[i] < second[j]:
+			i++
+		case first[i] > second[j]:
+			j++
+		default:
+			commonCount++
+			i++
+			j++
+		}
+	}
+	return commonCount
+}
+
+// getConnectedComponents returns how many separate groups of users there
+// are and the size of the largest one
+func getConnectedComponents(graph friendGraph) (int, int) {
+	visited := make([]bool, graph.nodeCount())
+	componentCount := 0
+	largestComponentSize := 0
+	for start := range visited {
+		if visited[start] {
+			continue
+		}
+		componentCount++
+		visited[start] = true
+		component := []int{start}
+		for next := 0; next < len(component); next++ {
+			for _, neighbour := range graph.neighbours[component[next]] {
+				if !visited[neighbour] {
+					visited[neighbour] = true
+					component = append(component, neighbour)
+				}
+			}
+		}
+		if len(component) > largestComponentSize {
+			largestComponentSize = len(component)
+		}
+	}
+	return componentCount, largestComponentSize
+}
+
+// getDiameterAndAveragePathLength finds the longest and the average
+// shortest path between users that can reach each other
+func getDiameterAndAveragePathLength(graph friendGraph) (int, float64) {
+	diameter := 0
+	totalPathLength := 0
+	pathCount := 0
+
+	distances := make([]int, graph.nodeCount())
+	for _, source := range getCentralitySources(graph.nodeCount()) {
+		for i := range distances {
+			distances[i] = -1
+		}
+		distances[source] = 0
+		visitOrder := []int{source}
+		for next := 0; next < len(visitOrder); next++ {
+			current := visitOrder[next]
+			for _, neighbour := range graph.neighbours[current] {
+				if distances[neighbour] == -1 {
+					distances[neighbour] = distances[current] + 1
+					visitOrder = append(visitOrder, neighbour)
+				}
+			}
+		}
+		for _, user := range visitOrder[1:] {
+			totalPathLength += distances[user]
+			pathCount++
+		}
+		if furthest := distances[visitOrder[len(visitOrder)-1]]; furthest > diameter {
+			diameter = furthest
+		}
+	}
+
+	if pathCount == 0 {
+		return diameter, 0
+	}
+	return diameter, float64(totalPathLength) / float64(pathCount)
+}
@@ -0,0 +1,50 @@
+package graphing
+
+import (
+	"testing"
+
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNetworkStatsOfATriangleWithATail(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2", "3"},
+		"2": {"3"},
+		"3": {"4"},
+	}, "1", "2", "3", "4")
+
+	stats := getNetworkStats(newFriendGraph(users))
+
+	assert.Equal(t, 4, stats.NodeCount)
+	assert.Equal(t, 4, stats.EdgeCount)
+	assert.InDelta(t, 4.0/6.0, stats.Density, 1e-9)
+	assert.InDelta(t, 2, stats.AverageDegree, 1e-9)
+	assert.Equal(t, []int{0, 1, 2, 1}, stats.DegreeDistribution)
+	assert.InDelta(t, (1+1+1.0/3.0+0)/4, stats.ClusteringCoefficient, 1e-9)
+	assert.Equal(t, 1, stats.ConnectedComponents)
+	assert.Equal(t, 4, stats.LargestComponentSize)
+	assert.Equal(t, 2, stats.EstimatedDiameter)
+	assert.InDelta(t, 16.0/12.0, stats.AveragePathLength, 1e-9)
+}
+
+func TestGetNetworkStatsOnlyMeasuresPathsBetweenReachableUsers(t *testing.T) {
+	users := makeGraphUsers(map[string][]string{
+		"1": {"2"},
+		"3": {"4"},
+	}, "1", "2", "3", "4", "5")
+
+	stats := getNetworkStats(newFriendGraph(users))
+
+	assert.Equal(t, 3, stats.ConnectedComponents)
+	assert.Equal(t, 2, stats.LargestComponentSize)
+	assert.Equal(t, 1, stats.EstimatedDiameter)
+	assert.InDelta(t, 1, stats.AveragePathLength, 1e-9)
+	assert.Equal(t, 0.0, stats.ClusteringCoefficient)
+}
+
+func TestGetNetworkStatsOfAnEmptyGraph(t *testing.T) {
+	stats := getNetworkStats(newFriendGraph(makeGraphUsers(map[string][]string{})))
+
+	assert.Equal(t, datastructures.NetworkStats{DegreeDistribution: []int{0}}, stats)
+}
@@ -73,3 +73,19 @@ func getTopTenOverallGameNames(cntr controller.CntrInterface, users []common.Use
 	}
 	return topTenGamesInfo, nil
 }
+
+// getOldestDataTimestamp returns the earliest time at which any of
+// the given users were saved, ignoring users with no insertion time
+func getOldestDataTimestamp(usersData []common.UsersGraphInformation) int64 {
+	oldestDataTimestamp := int64(0)
+	for _, userData := range usersData {
+		insertionTime := userData.User.InsertionTime
+		if insertionTime == 0 {
+			continue
+		}
+		if oldestDataTimestamp == 0 || insertionTime < oldestDataTimestamp {
+			oldestDataTimestamp = insertionTime
+		}
+	}
+	return oldestDataTimestamp
+}
@@ -196,3 +196,13 @@ func TestGetTopTenOverallGamesRrturnsanErrorWhenGameDetailsCannotBeRetrieved(t *
 	assert.Equal(t, []common.BareGameInfo{}, topTenOverallGames)
 	assert.Equal(t, randomErr, err)
 }
+
+func TestGetOldestDataTimestampIgnoresUsersWithNoInsertionTime(t *testing.T) {
+	usersData := []common.UsersGraphInformation{
+		{User: common.UserDocument{InsertionTime: 1645000000}},
+		{User: common.UserDocument{InsertionTime: 0}},
+		{User: common.UserDocument{InsertionTime: 1644000000}},
+	}
+
+	assert.Equal(t, int64(1644000000), getOldestDataTimestamp(usersData))
+}
@@ -11,181 +11,185 @@ import (
 	"go.uber.org/zap"
 )
 
-type jobStruct struct {
-	SteamID      string
-	Username     string
-	FromID       string
-	FromUsername string
-	MaxLevel     int
-	CurrentLevel int
-}
+const (
+	// maxUsersPerFetch is the most users requested from the datastore
+	// at once
+	maxUsersPerFetch = 500
+	// frontierFetchWorkers is how many requests for the users of a
+	// level are made at once
+	frontierFetchWorkers = 4
+)
 
 type GraphWorkerConfig struct {
-	jobMutex *sync.Mutex
-	resMutex *sync.Mutex
-
 	// CrawlingStatus related variables
-	usersCrawledMutex *sync.Mutex
 	TotalUsersToCrawl int
 	UsersCrawled      int
 	MaxLevel          int
+	// CappedHubs are users whose friends were not crawled
+	CappedHubs []string
+	Filters    datastructures.CrawlFilters
+	// Partial graphs are created from the users crawled so far while
+	// the crawl is still going. Progress is how much of it was done
+	Partial  bool
+	Progress int
+	// LayoutSeed is the seed the graph is laid out with
+	LayoutSeed int64
+	// LeaseID is the lease a full build holds on the graphing crawl
+	LeaseID string
 }
 
-func graphWorker(id int, stopSignal <-chan bool, cntr controller.CntrInterface, wg *sync.WaitGroup, workerConfig *GraphWorkerConfig, jobs <-chan datastructures.CrawlJob, res chan<- common.UsersGraphInformation) {
-	configuration.Logger.Sugar().Infof("%d graphWorker starting...\n", id)
-	for {
-		select {
-		case <-stopSignal:
-			configuration.Logger.Sugar().Infof("%d graphWorker exiting...\n", id)
-			wg.Done()
-			return
-		case currentJob := <-jobs:
-			emptyJob := datastructures.CrawlJob{}
-			if currentJob == emptyJob {
-				panic("EMPTY JOB, most likely means channel was closed and read from")
-			}
-
-			logMsg := fmt.Sprintf("[Worker ID:%d][jobs length: %d][res length: %d] worker received job: %+v",
-				id, len(jobs), len(res), currentJob)
-			configuration.Logger.Info(logMsg,
-				zap.String("requestID", currentJob.CrawlID))
-
-			userGraphData, err := cntr.GetUserFromDataStore(currentJob.SteamID)
-			if err != nil {
-				configuration.Logger.Sugar().Errorf("failed to get user data for %s: %+v", currentJob.SteamID, err)
-				return
-			}
+// frontierUser is a user at the current level of the graph along with
+// the user whose friend list they were found in
+type frontierUser struct {
+	SteamID string
+	FromID  string
+}
 
-			if currentJob.CurrentLevel <= workerConfig.MaxLevel {
-				newJob := common.UsersGraphInformation{
-					User:         userGraphData,
-					FromID:       currentJob.FromID,
-					CurrentLevel: currentJob.CurrentLevel,
-					MaxLevel:     currentJob.MaxLevel,
-				}
-				workerConfig.resMutex.Lock()
-				res <- newJob
-				workerConfig.resMutex.Unlock()
-			}
-		}
+// userPassesFilters checks if a user retrieved for the graph passes the
+// filters of the crawl. The original crawl target is always included
+func userPassesFilters(filters datastructures.CrawlFilters, user common.UsersGraphInformation) bool {
+	if user.CurrentLevel == 1 {
+		return true
 	}
+	return filters.AllowsAccount(user.User.AccDetails.Loccountrycode, user.User.AccDetails.Timecreated) &&
+		filters.AllowsStoredGameCount(len(user.User.GamesOwned))
 }
 
-func Control2Func(cntr controller.CntrInterface, crawlID, steamID string, workerConfig GraphWorkerConfig) ([]common.UsersGraphInformation, error) {
-	jobsChan := make(chan datastructures.CrawlJob, 70000)
-	resChan := make(chan common.UsersGraphInformation, 70000)
-
-	var jobMutex sync.Mutex
-	var resMutex sync.Mutex
-	var wg sync.WaitGroup
-	var usersCrawledMutex sync.Mutex
-	workerConfig.jobMutex = &jobMutex
-	workerConfig.resMutex = &resMutex
-	workerConfig.usersCrawledMutex = &usersCrawledMutex
-
-	allUsersGraphData := []common.UsersGraphInformation{}
-
-	firstJob := datastructures.CrawlJob{
-		CrawlID:      crawlID,
-		SteamID:      steamID,
-		FromID:       steamID,
-		CurrentLevel: 1,
-		MaxLevel:     workerConfig.MaxLevel,
+// GetUsersForGraph gathers every user in a crawl level by level. Each
+// user is only fetched once and the original crawl target is always the
+// first user returned
+func GetUsersForGraph(cntr controller.CntrInterface, crawlID, steamID string, workerConfig *GraphWorkerConfig) ([]common.UsersGraphInformation, error) {
+	cappedHubs := make(map[string]bool)
+	for _, hubSteamID := range workerConfig.CappedHubs {
+		cappedHubs[hubSteamID] = true
 	}
-	jobsChan <- firstJob
-
-	workerAmount := 6
-	var stopSignal chan bool = make(chan bool, 0)
-	workersAreDone := false
-	oneOrMoreUsersHasNoUsername := false
 
-	for i := 0; i < workerAmount; i++ {
-		wg.Add(1)
-		go graphWorker(i, stopSignal, cntr, &wg, &workerConfig, jobsChan, resChan)
-	}
+	allUsersGraphData := []common.UsersGraphInformation{}
+	seenUsers := map[string]bool{steamID: true}
+	frontier := []frontierUser{{SteamID: steamID, FromID: steamID}}
 
-	for {
-		if workersAreDone {
-			break
-		}
-		if workerConfig.UsersCrawled >= workerConfig.TotalUsersToCrawl &&
-			workerConfig.TotalUsersToCrawl != 0 {
-			workersAreDone = true
-			for i := 0; i < workerAmount; i++ {
-				stopSignal <- true
-			}
-			workersAreDone = true
+	for currentLevel := 1; currentLevel <= workerConfig.MaxLevel && len(frontier) > 0; currentLevel++ {
+		steamIDs := make([]string, len(frontier))
+		for i, user := range frontier {
+			steamIDs[i] = user.SteamID
 		}
-		if workersAreDone {
-			break
+		usersInLevel, err := getUsersInFrontier(cntr, steamIDs)
+		if err != nil {
+			return []common.UsersGraphInformation{}, fmt.Errorf("failed to get the %d users at level %d: %+v", len(steamIDs), currentLevel, err)
 		}
-
-		select {
-		case res := <-resChan:
-			if res.User.AccDetails.Personaname == "" && !oneOrMoreUsersHasNoUsername {
-				oneOrMoreUsersHasNoUsername = true
+		configuration.Logger.Info(fmt.Sprintf("found %d of %d users at level %d for crawlID: %s", len(usersInLevel), len(steamIDs), currentLevel, crawlID),
+			zap.String("requestID", crawlID))
+
+		nextFrontier := []frontierUser{}
+		for _, currentUser := range frontier {
+			// Users that were skipped or not found by the crawler
+			// were never saved
+			user, exists := usersInLevel[currentUser.SteamID]
+			if !exists {
+				continue
 			}
-
-			allUsersGraphData = append(allUsersGraphData, res)
-			workerConfig.usersCrawledMutex.Lock()
+			userGraphData := common.UsersGraphInformation{
+				User:         user,
+				FromID:       currentUser.FromID,
+				CurrentLevel: currentLevel,
+				MaxLevel:     workerConfig.MaxLevel,
+			}
+			// Users that do not pass the filters may have been stored by
+			// another crawl but they are not part of this one
+			if !userPassesFilters(workerConfig.Filters, userGraphData) {
+				continue
+			}
+			allUsersGraphData = append(allUsersGraphData, userGraphData)
 			workerConfig.UsersCrawled++
-			workerConfig.usersCrawledMutex.Unlock()
-
-			if res.CurrentLevel < res.MaxLevel {
-				for _, friendID := range res.User.FriendIDs {
-					newCrawlJob := datastructures.CrawlJob{
-						CrawlID:      crawlID,
-						SteamID:      friendID,
-						FromID:       res.User.AccDetails.SteamID,
-						CurrentLevel: res.CurrentLevel + 1,
-						MaxLevel:     res.MaxLevel,
-					}
-					workerConfig.jobMutex.Lock()
-					jobsChan <- newCrawlJob
-					workerConfig.jobMutex.Unlock()
-				}
+
+			// The friends of capped hubs were never crawled
+			if currentLevel == workerConfig.MaxLevel || cappedHubs[currentUser.SteamID] {
+				continue
 			}
-		default:
-			temp := false
-			if temp {
-				temp = false
+			for _, friendID := range user.FriendIDs {
+				if seenUsers[friendID] {
+					continue
+				}
+				seenUsers[friendID] = true
+				nextFrontier = append(nextFrontier, frontierUser{SteamID: friendID, FromID: currentUser.SteamID})
 			}
 		}
+		frontier = nextFrontier
 	}
 
-	close(jobsChan)
-	close(resChan)
-
-	logMsg := fmt.Sprintf("waiting for all jobs to be done for crawlID: %s", crawlID)
-	configuration.Logger.Info(logMsg,
-		zap.String("requestID", crawlID))
-	wg.Wait()
-	logMsg = fmt.Sprintf("all %d users have been found for crawlID: %s", len(allUsersGraphData), crawlID)
+	logMsg := fmt.Sprintf("all %d users have been found for crawlID: %s", len(allUsersGraphData), crawlID)
 	configuration.Logger.Info(logMsg,
 		zap.String("requestID", crawlID))
+	if len(allUsersGraphData) == 0 {
+		return []common.UsersGraphInformation{}, fmt.Errorf("original crawl target %s was not found for crawlID: %s", steamID, crawlID)
+	}
 
-	if oneOrMoreUsersHasNoUsername {
+	steamIDsWithoutAssociatedUsernames := getAllSteamIDsFromJobsWithNoAssociatedUsernames(allUsersGraphData)
+	if len(steamIDsWithoutAssociatedUsernames) > 0 {
 		configuration.Logger.Info("one or more users had no username, retrieving and correlating all usernames now")
-		steamIDsWithoutAssociatedUsernames := getAllSteamIDsFromJobsWithNoAssociatedUsernames(allUsersGraphData)
 		steamIDsToUsernames, err := cntr.GetUsernamesForSteamIDs(steamIDsWithoutAssociatedUsernames)
 		if err != nil {
 			return []common.UsersGraphInformation{}, err
 		}
-
-		for _, job := range allUsersGraphData {
-			if job.User.AccDetails.Personaname == "" {
-				job.User.AccDetails.Personaname = steamIDsToUsernames[job.User.AccDetails.SteamID]
+		for i, userGraphData := range allUsersGraphData {
+			if userGraphData.User.AccDetails.Personaname == "" {
+				allUsersGraphData[i].User.AccDetails.Personaname = steamIDsToUsernames[userGraphData.User.AccDetails.SteamID]
 			}
 		}
 	}
 	return allUsersGraphData, nil
 }
 
-func CollectGraphData(cntr controller.CntrInterface, steamID, crawlID string, workerConfig GraphWorkerConfig) {
-	usersDataForGraph, err := Control2Func(cntr, crawlID, steamID, workerConfig)
+// getUsersInFrontier fetches the given users from the datastore in
+// batches, a few batches at a time
+func getUsersInFrontier(cntr controller.CntrInterface, steamIDs []string) (map[string]common.UserDocument, error) {
+	batches := make(chan []string, len(steamIDs)/maxUsersPerFetch+1)
+	for start := 0; start < len(steamIDs); start += maxUsersPerFetch {
+		end := start + maxUsersPerFetch
+		if end > len(steamIDs) {
+			end = len(steamIDs)
+		}
+		batches <- steamIDs[start:end]
+	}
+	close(batches)
+
+	var usersLock sync.Mutex
+	var wg sync.WaitGroup
+	users := make(map[string]common.UserDocument)
+	var fetchErr error
+
+	for i := 0; i < frontierFetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				batchUsers, err := cntr.GetUsersFromDataStore(batch)
+
+				usersLock.Lock()
+				if err != nil && fetchErr == nil {
+					fetchErr = err
+				}
+				for _, user := range batchUsers {
+					users[user.AccDetails.SteamID] = user
+				}
+				usersLock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetchErr != nil {
+		return make(map[string]common.UserDocument), fetchErr
+	}
+	return users, nil
+}
+
+// CollectGraphData gathers the data of every user in a crawl and
+// saves it to the datastore as the crawl's processed graph
+func CollectGraphData(cntr controller.CntrInterface, steamID, crawlID string, workerConfig GraphWorkerConfig) error {
+	usersDataForGraph, err := GetUsersForGraph(cntr, crawlID, steamID, &workerConfig)
 	if err != nil {
-		configuration.Logger.Sugar().Errorf("failed to gather data for crawlID %s: %+v", crawlID, err)
-		panic(err)
+		return fmt.Errorf("failed to gather data for crawlID %s: %+v", crawlID, err)
 	}
 
 	usersDataForGraphWithOnlyTop40Games := []common.UsersGraphInformation{}
@@ -198,20 +202,53 @@ func CollectGraphData(cntr controller.CntrInterface, steamID, crawlID string, wo
 
 	topOverallGameDetails, err := getTopTenOverallGameNames(cntr, usersDataForGraphWithOnlyTop40Games)
 	if err != nil {
-		configuration.Logger.Sugar().Errorf("failed to get top 10 game detail: %+v", err)
-		panic(err)
+		return fmt.Errorf("failed to get top 10 game detail: %+v", err)
 	}
 
-	usersDataForGraphWithFriends := common.UsersGraphData{
-		UserDetails:    usersDataForGraphWithOnlyTop40Games[0],
-		FriendDetails:  usersDataForGraphWithOnlyTop40Games[1:],
-		TopGameDetails: topOverallGameDetails,
+	graph := newFriendGraph(usersDataForGraphWithOnlyTop40Games)
+	userCommunities, communities := getCommunities(graph, usersDataForGraphWithOnlyTop40Games)
+	userCentrality, mostConnectedUsers, bridgeUsers := getUserCentrality(graph, steamID)
+
+	usersDataForGraphWithFriends := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails:    usersDataForGraphWithOnlyTop40Games[0],
+			FriendDetails:  usersDataForGraphWithOnlyTop40Games[1:],
+			TopGameDetails: topOverallGameDetails,
+		},
+		CappedHubs:          workerConfig.CappedHubs,
+		OldestDataTimestamp: getOldestDataTimestamp(usersDataForGraph),
+		Partial:             workerConfig.Partial,
+		Progress:            workerConfig.Progress,
+		UserCommunities:     userCommunities,
+		Communities:         communities,
+		UserCentrality:      userCentrality,
+		MostConnectedUsers:  mostConnectedUsers,
+		BridgeUsers:         bridgeUsers,
+		NetworkStats:        getNetworkStats(graph),
+		Layout:              getGraphLayout(graph, workerConfig.LayoutSeed),
 	}
 
 	success, err := cntr.SaveProcessedGraphDataToDataStore(crawlID, usersDataForGraphWithFriends)
-	if err != nil || !success {
-		configuration.Logger.Sugar().Errorf("failed to save processed graph data for crawlID: %s to datastore: %+v", crawlID, err)
-		panic(err)
+	if err != nil {
+		return fmt.Errorf("failed to save processed graph data for crawlID %s to datastore: %+v", crawlID, err)
+	}
+	if !success {
+		return fmt.Errorf("datastore did not save processed graph data for crawlID %s", crawlID)
 	}
 	configuration.Logger.Sugar().Infof("successfully collected graph data for crawlID: %s", crawlID)
+	return nil
+}
+
+// updateCrawlState moves a crawl whose graph is being created to a new
+// state. Crawls started before states were recorded are never in the
+// graphing state and so are left as they are
+func updateCrawlState(cntr controller.CntrInterface, crawlID, state, leaseID string) {
+	updated, err := cntr.UpdateCrawlStateInDataStore(crawlID, state, leaseID)
+	if err != nil {
+		configuration.Logger.Sugar().Errorf("failed to move crawlID %s to the %s state: %+v", crawlID, state, err)
+		return
+	}
+	if !updated {
+		configuration.Logger.Sugar().Warnf("crawlID %s could not be moved to the %s state", crawlID, state)
+	}
 }
@@ -1,12 +1,15 @@
 package graphing
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"testing"
 
 	"github.com/iamcathal/neo/services/crawler/configuration"
 	"github.com/iamcathal/neo/services/crawler/controller"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
 	"github.com/neosteamfriendgraphing/common"
 	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/assert"
@@ -67,9 +70,8 @@ func TestCrawlerDoesFinish(t *testing.T) {
 	returnedSteamIDToUsernameMap[thirdUser.AccDetails.SteamID] = thirdUser.AccDetails.Personaname
 
 	mockController.On("Sleep", mock.Anything).Return()
-	mockController.On("GetUserFromDataStore", firstUser.AccDetails.SteamID).Return(firstUser, nil)
-	mockController.On("GetUserFromDataStore", secondUser.AccDetails.SteamID).Return(secondUser, nil)
-	mockController.On("GetUserFromDataStore", thirdUser.AccDetails.SteamID).Return(thirdUser, nil)
+	mockController.On("GetUsersFromDataStore", []string{firstUser.AccDetails.SteamID}).Return([]common.UserDocument{firstUser}, nil)
+	mockController.On("GetUsersFromDataStore", firstUser.FriendIDs).Return([]common.UserDocument{secondUser, thirdUser}, nil)
 	mockController.On("GetUsernamesForSteamIDs", mock.Anything).Return(returnedSteamIDToUsernameMap, nil)
 	graphWorkerConfig := GraphWorkerConfig{
 		TotalUsersToCrawl: 3,
@@ -77,8 +79,215 @@ func TestCrawlerDoesFinish(t *testing.T) {
 		MaxLevel:          2,
 	}
 
-	allUsersGraphableData, err := Control2Func(mockController, ksuid.New().String(), firstUser.AccDetails.SteamID, graphWorkerConfig)
+	allUsersGraphableData, err := GetUsersForGraph(mockController, ksuid.New().String(), firstUser.AccDetails.SteamID, &graphWorkerConfig)
 
 	assert.Nil(t, err)
 	assert.Len(t, allUsersGraphableData, 3)
 }
+
+func TestCrawlerDoesNotExpandCappedHubs(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	hubUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{
+			Personaname: "cathal",
+			SteamID:     "12345",
+		},
+		FriendIDs: []string{
+			"123456",
+			"1234567",
+			"12345678",
+		},
+	}
+
+	mockController.On("Sleep", mock.Anything).Return()
+	mockController.On("GetUsersFromDataStore", []string{hubUser.AccDetails.SteamID}).Return([]common.UserDocument{hubUser}, nil)
+	graphWorkerConfig := GraphWorkerConfig{
+		TotalUsersToCrawl: 1,
+		UsersCrawled:      0,
+		MaxLevel:          2,
+		CappedHubs:        []string{hubUser.AccDetails.SteamID},
+	}
+
+	allUsersGraphableData, err := GetUsersForGraph(mockController, ksuid.New().String(), hubUser.AccDetails.SteamID, &graphWorkerConfig)
+
+	assert.Nil(t, err)
+	assert.Len(t, allUsersGraphableData, 1)
+	mockController.AssertNumberOfCalls(t, "GetUsersFromDataStore", 1)
+}
+
+func TestCrawlerFinishesWhenUsersAreMissingOrFilteredOut(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	firstUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{
+			Personaname:    "cathal",
+			SteamID:        "12345",
+			Loccountrycode: "IE",
+		},
+		FriendIDs: []string{
+			"123456",
+			"1234567",
+			"12345678",
+		},
+	}
+	irishFriend := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{
+			Personaname:    "joe",
+			SteamID:        "123456",
+			Loccountrycode: "IE",
+		},
+	}
+	germanFriend := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{
+			Personaname:    "hans",
+			SteamID:        "1234567",
+			Loccountrycode: "DE",
+		},
+	}
+
+	mockController.On("Sleep", mock.Anything).Return()
+	mockController.On("GetUsersFromDataStore", []string{firstUser.AccDetails.SteamID}).Return([]common.UserDocument{firstUser}, nil)
+	// 12345678 was never saved by the crawler
+	mockController.On("GetUsersFromDataStore", firstUser.FriendIDs).Return([]common.UserDocument{irishFriend, germanFriend}, nil)
+	graphWorkerConfig := GraphWorkerConfig{
+		TotalUsersToCrawl: 2,
+		UsersCrawled:      0,
+		MaxLevel:          2,
+		Filters: datastructures.CrawlFilters{
+			Countries: []string{"IE"},
+		},
+	}
+
+	allUsersGraphableData, err := GetUsersForGraph(mockController, ksuid.New().String(), firstUser.AccDetails.SteamID, &graphWorkerConfig)
+
+	assert.Nil(t, err)
+	assert.Len(t, allUsersGraphableData, 2)
+	mockController.AssertNumberOfCalls(t, "GetUsersFromDataStore", 2)
+}
+
+func TestGetUsersForGraphOnlyFetchesEachUserOnce(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	firstUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "cathal", SteamID: "12345"},
+		FriendIDs:  []string{"123456", "1234567"},
+	}
+	secondUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "joe", SteamID: "123456"},
+		FriendIDs:  []string{"12345", "1234567", "12345678"},
+	}
+	thirdUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "padraic", SteamID: "1234567"},
+		FriendIDs:  []string{"12345", "123456", "12345678"},
+	}
+	sharedFriend := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "niamh", SteamID: "12345678"},
+		FriendIDs:  []string{"123456", "1234567"},
+	}
+
+	mockController.On("GetUsersFromDataStore", []string{firstUser.AccDetails.SteamID}).Return([]common.UserDocument{firstUser}, nil)
+	mockController.On("GetUsersFromDataStore", firstUser.FriendIDs).Return([]common.UserDocument{thirdUser, secondUser}, nil)
+	mockController.On("GetUsersFromDataStore", []string{sharedFriend.AccDetails.SteamID}).Return([]common.UserDocument{sharedFriend}, nil)
+	graphWorkerConfig := GraphWorkerConfig{
+		MaxLevel: 3,
+	}
+
+	allUsersGraphableData, err := GetUsersForGraph(mockController, ksuid.New().String(), firstUser.AccDetails.SteamID, &graphWorkerConfig)
+
+	assert.Nil(t, err)
+	assert.Len(t, allUsersGraphableData, 4)
+	assert.Equal(t, firstUser.AccDetails.SteamID, allUsersGraphableData[0].User.AccDetails.SteamID)
+	assert.Equal(t, secondUser.AccDetails.SteamID, allUsersGraphableData[3].FromID)
+	assert.Equal(t, 3, allUsersGraphableData[3].CurrentLevel)
+	assert.Equal(t, 4, graphWorkerConfig.UsersCrawled)
+	mockController.AssertNumberOfCalls(t, "GetUsersFromDataStore", 3)
+}
+
+func TestGetUsersForGraphFetchesLargeLevelsInBatches(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	hubUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "cathal", SteamID: "12345"},
+	}
+	for i := 0; i < maxUsersPerFetch*2+1; i++ {
+		hubUser.FriendIDs = append(hubUser.FriendIDs, fmt.Sprint(76561197960265728+i))
+	}
+
+	mockController.On("GetUsersFromDataStore", []string{hubUser.AccDetails.SteamID}).Return([]common.UserDocument{hubUser}, nil)
+	mockController.On("GetUsersFromDataStore", mock.MatchedBy(func(steamIDs []string) bool {
+		return len(steamIDs) <= maxUsersPerFetch && steamIDs[0] != hubUser.AccDetails.SteamID
+	})).Return([]common.UserDocument{}, nil)
+	graphWorkerConfig := GraphWorkerConfig{
+		MaxLevel: 2,
+	}
+
+	allUsersGraphableData, err := GetUsersForGraph(mockController, ksuid.New().String(), hubUser.AccDetails.SteamID, &graphWorkerConfig)
+
+	assert.Nil(t, err)
+	assert.Len(t, allUsersGraphableData, 1)
+	mockController.AssertNumberOfCalls(t, "GetUsersFromDataStore", 4)
+}
+
+func TestGetUsersForGraphReturnsAnErrorWhenALevelCannotBeFetched(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	firstUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "cathal", SteamID: "12345"},
+		FriendIDs:  []string{"123456", "1234567"},
+	}
+
+	mockController.On("GetUsersFromDataStore", []string{firstUser.AccDetails.SteamID}).Return([]common.UserDocument{firstUser}, nil)
+	mockController.On("GetUsersFromDataStore", firstUser.FriendIDs).Return([]common.UserDocument{}, errors.New("failed all retries"))
+	graphWorkerConfig := GraphWorkerConfig{
+		MaxLevel: 2,
+	}
+
+	allUsersGraphableData, err := GetUsersForGraph(mockController, ksuid.New().String(), firstUser.AccDetails.SteamID, &graphWorkerConfig)
+
+	assert.NotNil(t, err)
+	assert.Empty(t, allUsersGraphableData)
+}
+
+func TestCollectGraphDataReturnsAnErrorWhenTheCrawlTargetIsNotFound(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+
+	mockController.On("GetUsersFromDataStore", mock.Anything).Return([]common.UserDocument{}, nil)
+
+	err := CollectGraphData(mockController, "12345", crawlID, GraphWorkerConfig{MaxLevel: 2})
+
+	assert.NotNil(t, err)
+	mockController.AssertNotCalled(t, "SaveProcessedGraphDataToDataStore", mock.Anything, mock.Anything)
+}
+
+func TestCollectGraphDataLabelsPartialGraphsWithTheirProgress(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlID := ksuid.New().String()
+
+	firstUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "cathal", SteamID: "12345"},
+		FriendIDs:  []string{"123456", "1234567"},
+	}
+	// Only one of the friends has been crawled so far
+	secondUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{Personaname: "joe", SteamID: "123456"},
+	}
+
+	mockController.On("GetUsersFromDataStore", []string{firstUser.AccDetails.SteamID}).Return([]common.UserDocument{firstUser}, nil)
+	mockController.On("GetUsersFromDataStore", firstUser.FriendIDs).Return([]common.UserDocument{secondUser}, nil)
+	mockController.On("GetGameDetailsFromIDs", mock.Anything).Return([]common.BareGameInfo{}, nil)
+	mockController.On("SaveProcessedGraphDataToDataStore", crawlID, mock.MatchedBy(func(graphData datastructures.UsersGraphData) bool {
+		return graphData.Partial && graphData.Progress == 40 && len(graphData.FriendDetails) == 1
+	})).Return(true, nil)
+	graphWorkerConfig := GraphWorkerConfig{
+		MaxLevel: 2,
+		Partial:  true,
+		Progress: 40,
+	}
+
+	err := CollectGraphData(mockController, firstUser.AccDetails.SteamID, crawlID, graphWorkerConfig)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "SaveProcessedGraphDataToDataStore", 1)
+}
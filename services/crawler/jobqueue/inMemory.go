@@ -0,0 +1,73 @@
+package jobqueue
+
+import (
+	"sync"
+
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
+
+// InMemoryJobQueue is an unbounded in-process JobQueue. It lets the crawler
+// run as a single binary without RabbitMQ for local development and tests.
+// Jobs do not survive a restart
+type InMemoryJobQueue struct {
+	lock *sync.Mutex
+	cond *sync.Cond
+	jobs []datastructures.Job
+}
+
+func NewInMemoryJobQueue() *InMemoryJobQueue {
+	lock := &sync.Mutex{}
+	return &InMemoryJobQueue{
+		lock: lock,
+		cond: sync.NewCond(lock),
+	}
+}
+
+// Publish appends a job to the queue. It never blocks as
+// workers publish jobs while consuming from the same queue
+func (q *InMemoryJobQueue) Publish(job datastructures.Job) error {
+	q.lock.Lock()
+	q.jobs = append(q.jobs, job)
+	q.lock.Unlock()
+	q.cond.Signal()
+	return nil
+}
+
+// Consume returns a channel of deliveries. Each call creates a new consumer
+// and each job is only ever given to one consumer. Nacking a delivery with
+// requeue set places the job back at the end of the queue
+func (q *InMemoryJobQueue) Consume() (<-chan Delivery, error) {
+	deliveries := make(chan Delivery)
+	go func() {
+		for {
+			job := q.pop()
+			deliveries <- NewDelivery(job,
+				nil,
+				func(requeue bool) error {
+					if requeue {
+						return q.Publish(job)
+					}
+					return nil
+				})
+		}
+	}()
+	return deliveries, nil
+}
+
+// Len returns the amount of jobs waiting in the queue
+func (q *InMemoryJobQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.jobs)
+}
+
+func (q *InMemoryJobQueue) pop() datastructures.Job {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for len(q.jobs) == 0 {
+		q.cond.Wait()
+	}
+	job := q.jobs[0]
+	q.jobs = q.jobs[1:]
+	return job
+}
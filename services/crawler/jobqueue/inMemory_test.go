@@ -0,0 +1,129 @@
+package jobqueue
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	c := zap.NewProductionConfig()
+	c.OutputPaths = []string{"/dev/null"}
+	logger, err := c.Build()
+	if err != nil {
+		panic(err)
+	}
+	configuration.Logger = logger
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func receiveDelivery(t *testing.T, deliveries <-chan Delivery) Delivery {
+	select {
+	case delivery := <-deliveries:
+		return delivery
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+	return Delivery{}
+}
+
+func TestInMemoryJobQueueDeliversJobsInTheOrderTheyWerePublished(t *testing.T) {
+	jobQueue := NewInMemoryJobQueue()
+	firstJob := datastructures.Job{CurrentTargetSteamID: "first", CurrentLevel: 1}
+	secondJob := datastructures.Job{CurrentTargetSteamID: "second", CurrentLevel: 2}
+
+	assert.Nil(t, jobQueue.Publish(firstJob))
+	assert.Nil(t, jobQueue.Publish(secondJob))
+
+	deliveries, err := jobQueue.Consume()
+	assert.Nil(t, err)
+
+	assert.Equal(t, firstJob, receiveDelivery(t, deliveries).Job)
+	assert.Equal(t, secondJob, receiveDelivery(t, deliveries).Job)
+}
+
+func TestInMemoryJobQueueDeliversJobsPublishedAfterConsumingStarted(t *testing.T) {
+	jobQueue := NewInMemoryJobQueue()
+	job := datastructures.Job{CurrentTargetSteamID: "late"}
+
+	deliveries, err := jobQueue.Consume()
+	assert.Nil(t, err)
+	assert.Nil(t, jobQueue.Publish(job))
+
+	delivery := receiveDelivery(t, deliveries)
+	assert.Equal(t, job, delivery.Job)
+	assert.Nil(t, delivery.Ack())
+}
+
+func TestInMemoryJobQueueGivesEachJobToOnlyOneConsumer(t *testing.T) {
+	jobQueue := NewInMemoryJobQueue()
+	firstConsumer, _ := jobQueue.Consume()
+	secondConsumer, _ := jobQueue.Consume()
+
+	for i := 0; i < 10; i++ {
+		jobQueue.Publish(datastructures.Job{CurrentLevel: i})
+	}
+
+	seenLevels := make(map[int]bool)
+	for i := 0; i < 10; i++ {
+		var delivery Delivery
+		select {
+		case delivery = <-firstConsumer:
+		case delivery = <-secondConsumer:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for delivery")
+		}
+		assert.False(t, seenLevels[delivery.Job.CurrentLevel])
+		seenLevels[delivery.Job.CurrentLevel] = true
+	}
+	assert.Len(t, seenLevels, 10)
+}
+
+func TestInMemoryJobQueueNackWithRequeuePlacesJobBackOnQueue(t *testing.T) {
+	jobQueue := NewInMemoryJobQueue()
+	job := datastructures.Job{CurrentTargetSteamID: "requeued"}
+	jobQueue.Publish(job)
+
+	deliveries, _ := jobQueue.Consume()
+	delivery := receiveDelivery(t, deliveries)
+	assert.Nil(t, delivery.Nack(true))
+
+	assert.Equal(t, job, receiveDelivery(t, deliveries).Job)
+}
+
+func TestInMemoryJobQueueNackWithoutRequeueDropsJob(t *testing.T) {
+	jobQueue := NewInMemoryJobQueue()
+	jobQueue.Publish(datastructures.Job{})
+
+	deliveries, _ := jobQueue.Consume()
+	delivery := receiveDelivery(t, deliveries)
+	assert.Nil(t, delivery.Nack(false))
+
+	assert.Equal(t, 0, jobQueue.Len())
+}
+
+func TestInitJobQueueReturnsInMemoryJobQueueForInMemoryBackend(t *testing.T) {
+	configuration.WorkerConfig.JobQueueBackend = InMemoryBackend
+
+	jobQueue, err := InitJobQueue()
+
+	assert.Nil(t, err)
+	assert.IsType(t, &InMemoryJobQueue{}, jobQueue)
+}
+
+func TestInitJobQueueReturnsAnErrorForAnUnknownBackend(t *testing.T) {
+	configuration.WorkerConfig.JobQueueBackend = "carrierpigeon"
+
+	jobQueue, err := InitJobQueue()
+
+	assert.Nil(t, jobQueue)
+	assert.NotNil(t, err)
+}
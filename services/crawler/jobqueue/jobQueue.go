@@ -0,0 +1,69 @@
+package jobqueue
+
+import (
+	"fmt"
+
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
+
+const (
+	RabbitMQBackend = "rabbitmq"
+	InMemoryBackend = "inmemory"
+)
+
+// JobQueue is the queue that crawl jobs are published to and consumed
+// from. Workers only ever see datastructures.Job values and the ack/nack
+// handle that comes along with each delivery
+type JobQueue interface {
+	Publish(job datastructures.Job) error
+	Consume() (<-chan Delivery, error)
+}
+
+// Delivery is a single job received from a JobQueue. Every delivery must
+// be either acked or nacked once the job has been dealt with
+type Delivery struct {
+	Job datastructures.Job
+
+	ack  func() error
+	nack func(requeue bool) error
+}
+
+// NewDelivery creates a delivery for the given job with the given ack and
+// nack handlers. Nil handlers are treated as no-ops
+func NewDelivery(job datastructures.Job, ack func() error, nack func(requeue bool) error) Delivery {
+	return Delivery{
+		Job:  job,
+		ack:  ack,
+		nack: nack,
+	}
+}
+
+// Ack acknowledges that the job has been processed
+func (d Delivery) Ack() error {
+	if d.ack == nil {
+		return nil
+	}
+	return d.ack()
+}
+
+// Nack rejects the job, placing it back on the queue if requeue is set
+func (d Delivery) Nack(requeue bool) error {
+	if d.nack == nil {
+		return nil
+	}
+	return d.nack(requeue)
+}
+
+// InitJobQueue creates the job queue for the backend set in the
+// worker config
+//		jobQueue, err := InitJobQueue()
+func InitJobQueue() (JobQueue, error) {
+	switch configuration.WorkerConfig.JobQueueBackend {
+	case RabbitMQBackend:
+		return NewRabbitMQJobQueue(configuration.Queue.Name, configuration.ConsumeChannel, configuration.AmqpChannels), nil
+	case InMemoryBackend:
+		return NewInMemoryJobQueue(), nil
+	}
+	return nil, fmt.Errorf("unknown job queue backend '%s'", configuration.WorkerConfig.JobQueueBackend)
+}
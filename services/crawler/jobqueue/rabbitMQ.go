@@ -0,0 +1,92 @@
+package jobqueue
+
+import (
+	"encoding/json"
+
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	commonUtil "github.com/neosteamfriendgraphing/common/util"
+	"github.com/streadway/amqp"
+)
+
+// RabbitMQJobQueue is a JobQueue backed by a RabbitMQ queue. Publishing is
+// spread across a pool of channels as amqp channels are not safe to
+// publish on concurrently
+type RabbitMQJobQueue struct {
+	queueName       string
+	consumeChannel  *amqp.Channel
+	publishChannels chan *amqp.Channel
+}
+
+func NewRabbitMQJobQueue(queueName string, consumeChannel *amqp.Channel, publishChannels []*amqp.Channel) *RabbitMQJobQueue {
+	jobQueue := &RabbitMQJobQueue{
+		queueName:       queueName,
+		consumeChannel:  consumeChannel,
+		publishChannels: make(chan *amqp.Channel, len(publishChannels)),
+	}
+	for _, channel := range publishChannels {
+		jobQueue.publishChannels <- channel
+	}
+	return jobQueue
+}
+
+// Publish publishes a job to the rabbitMQ queue, blocking until
+// one of the publishing channels is free
+func (q *RabbitMQJobQueue) Publish(job datastructures.Job) error {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return commonUtil.MakeErr(err)
+	}
+
+	channel := <-q.publishChannels
+	defer func() { q.publishChannels <- channel }()
+
+	err = channel.Publish(
+		"",          // exchange
+		q.queueName, // routing key
+		false,       // mandatory
+		false,       // immediate
+		amqp.Publishing{
+			ContentType: "text/json",
+			Body:        jobJSON,
+		})
+	if err != nil {
+		return commonUtil.MakeErr(err)
+	}
+	return nil
+}
+
+// Consume starts consuming from the rabbitMQ queue. Messages that
+// cannot be unmarshalled into a job are rejected without being requeued
+func (q *RabbitMQJobQueue) Consume() (<-chan Delivery, error) {
+	msgs, err := q.consumeChannel.Consume(
+		q.queueName, // queue
+		"",          // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+	if err != nil {
+		return nil, commonUtil.MakeErr(err)
+	}
+
+	deliveries := make(chan Delivery)
+	go func() {
+		defer close(deliveries)
+		for msg := range msgs {
+			msg := msg
+			job := datastructures.Job{}
+			if err := json.Unmarshal(msg.Body, &job); err != nil {
+				configuration.Logger.Sugar().Errorf("failed to unmarshal job from queue: %s: %v", string(msg.Body), err)
+				msg.Nack(false, false)
+				continue
+			}
+			deliveries <- NewDelivery(job,
+				func() error { return msg.Ack(false) },
+				func(requeue bool) error { return msg.Nack(false, requeue) })
+		}
+	}()
+	return deliveries, nil
+}
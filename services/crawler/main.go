@@ -12,6 +12,8 @@ import (
 	"github.com/iamcathal/neo/services/crawler/configuration"
 	"github.com/iamcathal/neo/services/crawler/controller"
 	"github.com/iamcathal/neo/services/crawler/endpoints"
+	"github.com/iamcathal/neo/services/crawler/jobqueue"
+	"github.com/iamcathal/neo/services/crawler/scheduler"
 	"github.com/iamcathal/neo/services/crawler/statsmonitoring"
 	"github.com/iamcathal/neo/services/crawler/worker"
 	commonUtil "github.com/neosteamfriendgraphing/common/util"
@@ -23,7 +25,15 @@ func main() {
 		panic(fmt.Sprintf("failure initialising config: %v", err))
 	}
 
-	controller := controller.Cntr{}
+	jobQueue, err := jobqueue.InitJobQueue()
+	if err != nil {
+		panic(fmt.Sprintf("failure initialising job queue: %v", err))
+	}
+	configuration.Logger.Info(fmt.Sprintf("using %s job queue", configuration.WorkerConfig.JobQueueBackend))
+
+	controller := controller.Cntr{
+		JobQueue: jobQueue,
+	}
 
 	endpoints := &endpoints.Endpoints{
 		Cntr: controller,
@@ -36,6 +46,7 @@ func main() {
 	waitG.Wait()
 
 	go statsmonitoring.CollectAndShipStats()
+	go scheduler.StartScheduler(controller)
 	router := endpoints.SetupRouter()
 
 	srv := &http.Server{
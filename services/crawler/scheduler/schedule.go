@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule gives the next time at which a watched user should be crawled
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (schedule intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(schedule.interval)
+}
+
+// cronSchedule is a parsed five field cron expression. Each
+// field holds the values that the field matches
+type cronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	// When both days of the month and days of the week are restricted
+	// a day matching either is used, as is done by cron
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+// maxScheduleSearch is how far ahead a cron schedule is searched before
+// it is deemed to never run e.g. for the 31st of February
+const maxScheduleSearch = 5 * 366 * 24 * time.Hour
+
+// Next returns the first minute after the given time that matches the
+// schedule in UTC. The zero time is returned if no such minute exists
+func (schedule cronSchedule) Next(after time.Time) time.Time {
+	current := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	searchLimit := current.Add(maxScheduleSearch)
+
+	for current.Before(searchLimit) {
+		if !schedule.months[int(current.Month())] {
+			current = time.Date(current.Year(), current.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !schedule.dayMatches(current) {
+			current = time.Date(current.Year(), current.Month(), current.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !schedule.hours[current.Hour()] {
+			current = current.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !schedule.minutes[current.Minute()] {
+			current = current.Add(time.Minute)
+			continue
+		}
+		return current
+	}
+	return time.Time{}
+}
+
+func (schedule cronSchedule) dayMatches(day time.Time) bool {
+	dayOfMonthMatches := schedule.daysOfMonth[day.Day()]
+	dayOfWeekMatches := schedule.daysOfWeek[int(day.Weekday())]
+	if schedule.daysOfMonthRestricted && schedule.daysOfWeekRestricted {
+		return dayOfMonthMatches || dayOfWeekMatches
+	}
+	return dayOfMonthMatches && dayOfWeekMatches
+}
+
+// ParseSchedule returns the schedule for a watched user from either
+// its cron expression or its interval in minutes
+//		schedule, err := ParseSchedule("0 6 * * 1", 0)
+func ParseSchedule(cronExpression string, intervalMinutes int) (Schedule, error) {
+	if cronExpression != "" && intervalMinutes != 0 {
+		return nil, errors.New("only one of a cron expression or interval can be given")
+	}
+	if cronExpression == "" {
+		if intervalMinutes <= 0 {
+			return nil, errors.New("a cron expression or positive interval must be given")
+		}
+		return intervalSchedule{interval: time.Duration(intervalMinutes) * time.Minute}, nil
+	}
+
+	schedule, err := parseCronExpression(cronExpression)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression '%s' never runs", cronExpression)
+	}
+	return schedule, nil
+}
+
+func parseCronExpression(cronExpression string) (cronSchedule, error) {
+	fields := strings.Fields(cronExpression)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("cron expression '%s' must have five fields", cronExpression)
+	}
+
+	minutes, err := parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return cronSchedule{}, err
+	}
+	hours, err := parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return cronSchedule{}, err
+	}
+	daysOfMonth, err := parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return cronSchedule{}, err
+	}
+	months, err := parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return cronSchedule{}, err
+	}
+	// Both 0 and 7 are Sunday
+	daysOfWeek, err := parseCronField(fields[4], 0, 7)
+	if err != nil {
+		return cronSchedule{}, err
+	}
+	if daysOfWeek[7] {
+		daysOfWeek[0] = true
+	}
+
+	return cronSchedule{
+		minutes:               minutes,
+		hours:                 hours,
+		daysOfMonth:           daysOfMonth,
+		months:                months,
+		daysOfWeek:            daysOfWeek,
+		daysOfMonthRestricted: !strings.HasPrefix(fields[2], "*"),
+		daysOfWeekRestricted:  !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField parses a comma seperated list of values, ranges (a-b),
+// wildcards (*) and steps of ranges or wildcards (*/n, a-b/n)
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if stepIndex := strings.Index(part, "/"); stepIndex != -1 {
+			parsedStep, err := strconv.Atoi(part[stepIndex+1:])
+			if err != nil || parsedStep <= 0 {
+				return nil, fmt.Errorf("invalid step in cron field '%s'", field)
+			}
+			step = parsedStep
+			part = part[:stepIndex]
+		}
+
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			parsedStart, startErr := strconv.Atoi(bounds[0])
+			parsedEnd, endErr := strconv.Atoi(bounds[1])
+			if startErr != nil || endErr != nil {
+				return nil, fmt.Errorf("invalid range in cron field '%s'", field)
+			}
+			start, end = parsedStart, parsedEnd
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in cron field '%s'", field)
+			}
+			start, end = value, value
+			// A step after a single value runs from the value onwards
+			if step != 1 {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("cron field '%s' is out of range %d-%d", field, min, max)
+		}
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	c := zap.NewProductionConfig()
+	c.OutputPaths = []string{"/dev/null"}
+	log, err := c.Build()
+	if err != nil {
+		panic(err)
+	}
+	configuration.Logger = log
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func TestParseScheduleReturnsAnIntervalSchedule(t *testing.T) {
+	schedule, err := ParseSchedule("", 60)
+	after := time.Date(2022, time.March, 1, 12, 30, 0, 0, time.UTC)
+
+	assert.Nil(t, err)
+	assert.Equal(t, after.Add(time.Hour), schedule.Next(after))
+}
+
+func TestParseScheduleReturnsAnErrorWhenBothACronExpressionAndIntervalAreGiven(t *testing.T) {
+	_, err := ParseSchedule("0 6 * * 1", 60)
+
+	assert.NotNil(t, err)
+}
+
+func TestParseScheduleReturnsAnErrorWhenNoScheduleIsGiven(t *testing.T) {
+	_, err := ParseSchedule("", 0)
+
+	assert.NotNil(t, err)
+}
+
+func TestParseScheduleReturnsAnErrorForInvalidCronExpressions(t *testing.T) {
+	invalidCronExpressions := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"0 0 31 2 *",
+	}
+
+	for _, cronExpression := range invalidCronExpressions {
+		_, err := ParseSchedule(cronExpression, 0)
+		assert.NotNil(t, err, cronExpression)
+	}
+}
+
+func TestCronScheduleNextReturnsTheNextMatchingMinute(t *testing.T) {
+	after := time.Date(2022, time.March, 1, 12, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		cronExpression string
+		expectedNext   time.Time
+	}{
+		{"* * * * *", time.Date(2022, time.March, 1, 12, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2022, time.March, 1, 12, 45, 0, 0, time.UTC)},
+		{"0 6 * * *", time.Date(2022, time.March, 2, 6, 0, 0, 0, time.UTC)},
+		// The 1st of March 2022 is a Tuesday
+		{"0 6 * * 1", time.Date(2022, time.March, 7, 6, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2022, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9 1,15 6-8 *", time.Date(2022, time.June, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2022, time.March, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2022, time.March, 6, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		schedule, err := ParseSchedule(testCase.cronExpression, 0)
+		assert.Nil(t, err, testCase.cronExpression)
+		assert.Equal(t, testCase.expectedNext, schedule.Next(after), testCase.cronExpression)
+	}
+}
+
+func TestCronScheduleMatchesEitherDayWhenDaysOfMonthAndWeekAreRestricted(t *testing.T) {
+	// The 15th of every month or any Friday
+	schedule, err := ParseSchedule("0 0 15 * 5", 0)
+	after := time.Date(2022, time.March, 1, 12, 30, 0, 0, time.UTC)
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, time.March, 4, 0, 0, 0, 0, time.UTC), schedule.Next(after))
+}
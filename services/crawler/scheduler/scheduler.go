@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/controller"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/iamcathal/neo/services/crawler/worker"
+	"github.com/segmentio/ksuid"
+)
+
+// checkInterval is how often watched users are checked for due
+// crawls. Cron schedules have a resolution of one minute
+const checkInterval = time.Minute
+
+// StartScheduler periodically starts crawls for watched users
+// whose schedules are due
+func StartScheduler(cntr controller.CntrInterface) {
+	for {
+		RunDueCrawls(cntr, time.Now())
+		cntr.Sleep(checkInterval)
+	}
+}
+
+// RunDueCrawls starts a crawl for every watched user whose schedule is
+// due at the given time. Each crawl is claimed in the datastore before
+// being started so that crawlers running together do not repeat crawls
+func RunDueCrawls(cntr controller.CntrInterface, now time.Time) {
+	watchedUsers, err := cntr.GetWatchedUsersFromDataStore()
+	if err != nil {
+		configuration.Logger.Sugar().Errorf("failed to get watched users: %+v", err)
+		return
+	}
+
+	for _, watchedUser := range watchedUsers {
+		schedule, err := ParseSchedule(watchedUser.CronExpression, watchedUser.IntervalMinutes)
+		if err != nil {
+			configuration.Logger.Sugar().Errorf("invalid schedule for watched user %s: %+v", watchedUser.SteamID, err)
+			continue
+		}
+		if !crawlIsDue(watchedUser, schedule, now) {
+			continue
+		}
+
+		claim := datastructures.WatchedUserCrawlClaim{
+			PreviousCrawlTime: watchedUser.LastCrawlTime,
+			CrawlTime:         now.Unix(),
+			CrawlID:           ksuid.New().String(),
+		}
+		claimed, err := cntr.ClaimWatchedUserCrawlInDataStore(watchedUser.SteamID, claim)
+		if err != nil {
+			configuration.Logger.Sugar().Errorf("failed to claim crawl for watched user %s: %+v", watchedUser.SteamID, err)
+			continue
+		}
+		if !claimed {
+			configuration.Logger.Sugar().Infof("crawl for watched user %s was already claimed", watchedUser.SteamID)
+			continue
+		}
+
+		configuration.Logger.Sugar().Infof("starting scheduled crawl %s for watched user %s", claim.CrawlID, watchedUser.SteamID)
+		err = worker.CrawlUser(cntr, watchedUser.SteamID, claim.CrawlID, watchedUser.Level, datastructures.CrawlOptions{})
+		if err != nil {
+			configuration.Logger.Sugar().Errorf("failed to start scheduled crawl %s for watched user %s: %+v", claim.CrawlID, watchedUser.SteamID, err)
+		}
+	}
+}
+
+// crawlIsDue checks if a watched user should be crawled at the given
+// time. Users that have never been crawled are due straight away
+func crawlIsDue(watchedUser datastructures.WatchedUser, schedule Schedule, now time.Time) bool {
+	if watchedUser.LastCrawlTime == 0 {
+		return true
+	}
+	nextCrawlTime := schedule.Next(time.Unix(watchedUser.LastCrawlTime, 0))
+	if nextCrawlTime.IsZero() {
+		return false
+	}
+	return !now.Before(nextCrawlTime)
+}
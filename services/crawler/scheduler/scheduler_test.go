@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iamcathal/neo/services/crawler/controller"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunDueCrawlsOnlyStartsCrawlsForDueWatchedUsers(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	now := time.Date(2022, time.March, 8, 12, 0, 0, 0, time.UTC)
+	dueUser := datastructures.WatchedUser{
+		SteamID:         "76561197969081524",
+		Level:           2,
+		IntervalMinutes: 7 * 24 * 60,
+		LastCrawlTime:   now.Add(-8 * 24 * time.Hour).Unix(),
+	}
+	notDueUser := datastructures.WatchedUser{
+		SteamID:         "76561198000000001",
+		Level:           2,
+		IntervalMinutes: 7 * 24 * 60,
+		LastCrawlTime:   now.Add(-24 * time.Hour).Unix(),
+	}
+	mockController.On("GetWatchedUsersFromDataStore").Return([]datastructures.WatchedUser{dueUser, notDueUser}, nil)
+	mockController.On("ClaimWatchedUserCrawlInDataStore", dueUser.SteamID, mock.MatchedBy(func(claim datastructures.WatchedUserCrawlClaim) bool {
+		return claim.PreviousCrawlTime == dueUser.LastCrawlTime && claim.CrawlTime == now.Unix()
+	})).Return(true, nil)
+	mockController.On("SaveCrawlingStatsToDataStore", 1, mock.Anything).Return(true, nil)
+	mockController.On("PublishToJobsQueue", mock.MatchedBy(func(job datastructures.Job) bool {
+		return job.OriginalTargetSteamID == dueUser.SteamID && job.MaxLevel == dueUser.Level
+	})).Return(nil)
+
+	RunDueCrawls(mockController, now)
+
+	mockController.AssertNumberOfCalls(t, "ClaimWatchedUserCrawlInDataStore", 1)
+	mockController.AssertNumberOfCalls(t, "PublishToJobsQueue", 1)
+}
+
+func TestRunDueCrawlsDoesNotStartACrawlClaimedByAnotherCrawler(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	watchedUser := datastructures.WatchedUser{
+		SteamID:         "76561197969081524",
+		Level:           2,
+		IntervalMinutes: 60,
+	}
+	mockController.On("GetWatchedUsersFromDataStore").Return([]datastructures.WatchedUser{watchedUser}, nil)
+	mockController.On("ClaimWatchedUserCrawlInDataStore", watchedUser.SteamID, mock.Anything).Return(false, nil)
+
+	RunDueCrawls(mockController, time.Now())
+
+	mockController.AssertNotCalled(t, "PublishToJobsQueue", mock.Anything)
+}
+
+func TestRunDueCrawlsSkipsWatchedUsersWithInvalidSchedules(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	watchedUser := datastructures.WatchedUser{
+		SteamID:        "76561197969081524",
+		Level:          2,
+		CronExpression: "not a cron expression",
+	}
+	mockController.On("GetWatchedUsersFromDataStore").Return([]datastructures.WatchedUser{watchedUser}, nil)
+
+	RunDueCrawls(mockController, time.Now())
+
+	mockController.AssertNotCalled(t, "ClaimWatchedUserCrawlInDataStore", mock.Anything, mock.Anything)
+}
+
+func TestRunDueCrawlsDoesNothingWhenWatchedUsersCannotBeRetrieved(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	mockController.On("GetWatchedUsersFromDataStore").Return([]datastructures.WatchedUser{}, errors.New("test error"))
+
+	RunDueCrawls(mockController, time.Now())
+
+	mockController.AssertNotCalled(t, "ClaimWatchedUserCrawlInDataStore", mock.Anything, mock.Anything)
+}
+
+func TestCrawlIsDueForAWatchedUserThatHasNeverBeenCrawled(t *testing.T) {
+	schedule, err := ParseSchedule("0 6 * * 1", 0)
+
+	assert.Nil(t, err)
+	assert.True(t, crawlIsDue(datastructures.WatchedUser{}, schedule, time.Now()))
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/controller"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+)
+
+// getFriendsToQueue returns the friends of the current user that should be
+// placed in the queue. For crawls with a budget the API calls made for the
+// current user are recorded and the friends are reserved from the user budget,
+// any friends that do not fit in the budget are not queued
+func getFriendsToQueue(cntr controller.CntrInterface, job datastructures.Job, friendIDs []string, apiCallsMade int) ([]string, error) {
+	friendsToQueue := friendIDs
+	if job.CurrentLevel >= job.MaxLevel {
+		friendsToQueue = []string{}
+	}
+	if !job.Budget.IsSet() {
+		return friendsToQueue, nil
+	}
+
+	budgetUsage := datastructures.CrawlBudgetUsage{
+		APICallsMade:   apiCallsMade,
+		UsersRequested: len(friendsToQueue),
+	}
+	usersGranted, err := cntr.UpdateCrawlBudgetInDataStore(job.CrawlID, budgetUsage)
+	if err != nil {
+		return []string{}, err
+	}
+	if usersGranted < len(friendsToQueue) {
+		configuration.Logger.Sugar().Infof("crawl %s has reached its user budget, queueing %d of %d friends for %s",
+			job.CrawlID, usersGranted, len(friendsToQueue), job.CurrentTargetSteamID)
+		friendsToQueue = friendsToQueue[:usersGranted]
+	}
+	return friendsToQueue, nil
+}
+
+// getAPICallsMadeForUser returns the amount of steam web API calls made
+// to crawl a user that was not found in the datastore. This is one call
+// each for their friends list, player summary and owned games plus one
+// call per batch of player summaries for their friends
+func getAPICallsMadeForUser(friendIDs []string) int {
+	apiCallsMade := 3
+	if len(friendIDs) > 0 {
+		apiCallsMade += len(breakIntoStacksOf100OrLessSteamIDs(friendIDs))
+	}
+	return apiCallsMade
+}
+
+// apiCallBudgetIsExhausted checks if a crawl has used up its API call budget.
+// As this is checked before a user is crawled a crawl can make slightly more
+// calls than its budget allows by the users being crawled at the same time
+func apiCallBudgetIsExhausted(cntr controller.CntrInterface, job datastructures.Job) (bool, error) {
+	crawlingStatus, err := cntr.GetCrawlingStatsFromDataStore(job.CrawlID)
+	if err != nil {
+		return false, err
+	}
+	return crawlingStatus.APICallsMade >= job.Budget.MaxAPICalls, nil
+}
+
+// skipJobOverBudget counts the user of a job as crawled without crawling
+// them and marks the crawl as truncated
+func skipJobOverBudget(cntr controller.CntrInterface, job datastructures.Job) {
+	configuration.Logger.Sugar().Infof("crawl %s has used up its API call budget, not crawling %s",
+		job.CrawlID, job.CurrentTargetSteamID)
+
+	_, err := cntr.UpdateCrawlBudgetInDataStore(job.CrawlID, datastructures.CrawlBudgetUsage{Truncated: true})
+	if err != nil {
+		configuration.Logger.Sugar().Panicf("error marking crawl %s as truncated: %+v", job.CrawlID, err)
+	}
+
+	countJobAsCrawled(cntr, job)
+}
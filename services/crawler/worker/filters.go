@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"github.com/iamcathal/neo/services/crawler/configuration"
+	"github.com/iamcathal/neo/services/crawler/controller"
+	"github.com/iamcathal/neo/services/crawler/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+// getFriendsToExpand returns the friends of the current user that pass the
+// country and account age filters of the crawl. Hubs that are capped have
+// none of their friends expanded
+func getFriendsToExpand(job datastructures.Job, friendIDs []string, friendSummaries []common.Player) []string {
+	if hubShouldBeCapped(job, friendIDs) {
+		return []string{}
+	}
+	if !job.Filters.FiltersAccounts() {
+		return friendIDs
+	}
+
+	friendsToExpand := []string{}
+	for _, friend := range friendSummaries {
+		if job.Filters.AllowsAccount(friend.Loccountrycode, friend.Timecreated) {
+			friendsToExpand = append(friendsToExpand, friend.Steamid)
+		}
+	}
+	return friendsToExpand
+}
+
+// friendSummariesAreNeededForFilters checks if the player summaries of a
+// user's friends must be retrieved so that the crawl filters can be applied
+// to them. This is only the case when the friends could be expanded
+func friendSummariesAreNeededForFilters(job datastructures.Job, friendIDs []string) bool {
+	return job.Filters.FiltersAccounts() &&
+		job.CurrentLevel < job.MaxLevel &&
+		len(friendIDs) > 0 &&
+		!hubShouldBeCapped(job, friendIDs)
+}
+
+// userPassesGamesFilter checks if the target user of a job owns enough games
+// to be crawled. Owned games are not known until a user's job is processed so
+// unlike the other filters this is not checked before enqueueing them
+func userPassesGamesFilter(job datastructures.Job, gameCount int, isStoredUser bool) bool {
+	if job.CurrentLevel == 1 {
+		return true
+	}
+	if isStoredUser {
+		return job.Filters.AllowsStoredGameCount(gameCount)
+	}
+	return job.Filters.AllowsGameCount(gameCount)
+}
+
+// skipJobFilteredOut counts the user of a job as crawled without saving
+// them or expanding their friends as they do not pass the crawl filters
+func skipJobFilteredOut(cntr controller.CntrInterface, job datastructures.Job, apiCallsMade int) {
+	configuration.Logger.Sugar().Infof("%s does not pass the filters for crawl %s, not crawling them",
+		job.CurrentTargetSteamID, job.CrawlID)
+
+	if job.Budget.IsSet() && apiCallsMade > 0 {
+		_, err := cntr.UpdateCrawlBudgetInDataStore(job.CrawlID, datastructures.CrawlBudgetUsage{APICallsMade: apiCallsMade})
+		if err != nil {
+			configuration.Logger.Sugar().Panicf("error recording API calls for crawl %s: %+v", job.CrawlID, err)
+		}
+	}
+	countJobAsCrawled(cntr, job)
+}
@@ -1,12 +1,10 @@
 package worker
 
 import (
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
-	"github.com/iamcathal/neo/services/crawler/amqpchannelmanager"
 	"github.com/iamcathal/neo/services/crawler/configuration"
 	"github.com/iamcathal/neo/services/crawler/controller"
 	"github.com/iamcathal/neo/services/crawler/datastructures"
@@ -40,21 +38,16 @@ func publishJobWithThrottling(cntr controller.CntrInterface, job datastructures.
 func publishJob(cntr controller.CntrInterface, job datastructures.Job) error {
 	startTime := time.Now()
 
-	jobJSON, err := json.Marshal(job)
+	err := cntr.PublishToJobsQueue(job)
 	if err != nil {
-		return err
-	}
-
-	err = amqpchannelmanager.PublishToJobsQueue(cntr, jobJSON)
-	if err != nil {
-		configuration.Logger.Sugar().Infof("failed to publish job: %+v retrying now", string(jobJSON))
+		configuration.Logger.Sugar().Infof("failed to publish job: %+v retrying now", job)
 		maxRetries := 3
 		successfulRequest := false
 		sleepTimers := []int{80, 500, 8500}
 
 		for i := 0; i < maxRetries; i++ {
 			cntr.Sleep(time.Duration(sleepTimers[i]) * time.Millisecond)
-			err = amqpchannelmanager.PublishToJobsQueue(cntr, jobJSON)
+			err = cntr.PublishToJobsQueue(job)
 			if err == nil {
 				configuration.Logger.Sugar().Infof("successfully placed job in queue after %d retries", i)
 				successfulRequest = true
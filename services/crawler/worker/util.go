@@ -33,6 +33,11 @@ func putFriendsIntoQueue(cntr controller.CntrInterface, currentJob datastructure
 		// configuration.Logger.Info("not putting on friends")
 		return nil
 	}
+	if hubShouldBeCapped(currentJob, friendIDs) {
+		configuration.Logger.Sugar().Infof("not putting on the %d friends of %s as they exceed the hub threshold of %d",
+			len(friendIDs), currentJob.CurrentTargetSteamID, currentJob.HubThreshold)
+		return nil
+	}
 
 	for _, ID := range friendIDs {
 		newJob := datastructures.Job{
@@ -40,9 +45,13 @@ func putFriendsIntoQueue(cntr controller.CntrInterface, currentJob datastructure
 			OriginalTargetSteamID: currentJob.OriginalTargetSteamID,
 			CurrentTargetSteamID:  ID,
 
-			CrawlID:      currentJob.CrawlID,
-			MaxLevel:     currentJob.MaxLevel,
-			CurrentLevel: nextLevel,
+			CrawlID:              currentJob.CrawlID,
+			MaxLevel:             currentJob.MaxLevel,
+			CurrentLevel:         nextLevel,
+			Budget:               currentJob.Budget,
+			HubThreshold:         currentJob.HubThreshold,
+			Filters:              currentJob.Filters,
+			FreshnessWindowHours: currentJob.FreshnessWindowHours,
 		}
 
 		configuration.Logger.Sugar().Infof("pushing job: %+v", newJob)
@@ -57,6 +66,17 @@ func putFriendsIntoQueue(cntr controller.CntrInterface, currentJob datastructure
 	return nil
 }
 
+// hubShouldBeCapped checks if a user has more friends than the hub threshold
+// of their crawl. Hubs are saved as normal but their friends are not crawled
+// as they would otherwise dominate the crawl. Users at the max level are
+// never expanded so they are not capped
+func hubShouldBeCapped(job datastructures.Job, friendIDs []string) bool {
+	if job.HubThreshold <= 0 || job.CurrentLevel >= job.MaxLevel {
+		return false
+	}
+	return len(friendIDs) > job.HubThreshold
+}
+
 func getGamesOwned(cntr controller.CntrInterface, steamID string) ([]common.Game, error) {
 	gamesInfo := []common.Game{}
 	ownedGamesResponse, err := cntr.CallGetOwnedGames(steamID)
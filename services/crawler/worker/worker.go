@@ -1,14 +1,12 @@
 package worker
 
 import (
-	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
 	"sync"
 	"time"
 
-	"github.com/iamcathal/neo/services/crawler/amqpchannelmanager"
 	"github.com/iamcathal/neo/services/crawler/configuration"
 	"github.com/iamcathal/neo/services/crawler/controller"
 	"github.com/iamcathal/neo/services/crawler/datastructures"
@@ -20,37 +18,76 @@ import (
 )
 
 // Worker crawls the steam API to get data from steam for a given user
-// e.g account details and details of a user's friend
-func Worker(cntr controller.CntrInterface, job datastructures.Job) {
+// e.g account details and details of a user's friend. True is returned
+// once the job has been counted in its crawl's stats so that a job that
+// fails afterwards is not counted twice
+func Worker(cntr controller.CntrInterface, job datastructures.Job) (bool, error) {
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 
-	userWasFoundInDB, friendsList, err := GetFriends(cntr, job.CurrentTargetSteamID)
+	if job.Budget.MaxAPICalls > 0 {
+		budgetExhausted, err := apiCallBudgetIsExhausted(cntr, job)
+		if err != nil {
+			return false, fmt.Errorf("error checking API call budget in worker for %s: %+v", job.CurrentTargetSteamID, err)
+		}
+		if budgetExhausted {
+			skipJobOverBudget(cntr, job)
+			return true, nil
+		}
+	}
+
+	userWasFoundInDB, user, err := GetFriends(cntr, job.CurrentTargetSteamID, getFreshnessWindow(job))
 	if err != nil {
-		configuration.Logger.Sugar().Panicf("error getting friends initially in worker for %s: %+v", job.CurrentTargetSteamID, err)
+		return false, fmt.Errorf("error getting friends initially in worker for %s: %+v", job.CurrentTargetSteamID, err)
 	}
+	friendsList := user.FriendIDs
 	if userWasFoundInDB {
-		crawlingStatus := common.CrawlingStatus{
-			OriginalCrawlTarget: job.OriginalTargetSteamID,
-			MaxLevel:            job.MaxLevel,
-			CrawlID:             job.CrawlID,
-			TotalUsersToCrawl:   len(friendsList),
+		if !userPassesGamesFilter(job, len(user.GamesOwned), true) {
+			skipJobFilteredOut(cntr, job, 0)
+			return true, nil
+		}
+		// Stored users only have the steamIDs of their friends so
+		// their summaries are needed to apply the crawl filters
+		friendSummaries := []common.Player{}
+		apiCallsMade := 0
+		if friendSummariesAreNeededForFilters(job, friendsList) {
+			friendSummaries, err = getPlayerSummaries(cntr, friendsList)
+			if err != nil {
+				return false, fmt.Errorf("error getting friend summaries in worker for %s: %+v", job.CurrentTargetSteamID, err)
+			}
+			apiCallsMade = len(breakIntoStacksOf100OrLessSteamIDs(friendsList))
+		}
+		friendsToExpand := getFriendsToExpand(job, friendsList, friendSummaries)
+		friendsToQueue, err := getFriendsToQueue(cntr, job, friendsToExpand, apiCallsMade)
+		if err != nil {
+			return false, fmt.Errorf("error getting friends to queue in worker for %s: %+v", job.CurrentTargetSteamID, err)
+		}
+		crawlingStatus := datastructures.CrawlingStatus{
+			CrawlingStatus: common.CrawlingStatus{
+				OriginalCrawlTarget: job.OriginalTargetSteamID,
+				MaxLevel:            job.MaxLevel,
+				CrawlID:             job.CrawlID,
+				TotalUsersToCrawl:   len(friendsToQueue),
+			},
+		}
+		if hubShouldBeCapped(job, friendsList) {
+			crawlingStatus.CappedHubs = []string{job.CurrentTargetSteamID}
 		}
 
 		success, err := cntr.SaveCrawlingStatsToDataStore(job.CurrentLevel, crawlingStatus)
 		if err != nil {
-			configuration.Logger.Sugar().Panicf("error saving crawling stats in worker : %+v", err)
+			return false, fmt.Errorf("error saving crawling stats in worker : %+v", err)
 		}
 		if !success {
-			configuration.Logger.Sugar().Panicf("failed to save crawling stats in worker: %+v", err)
+			return false, fmt.Errorf("failed to save crawling stats in worker for %s", job.CurrentTargetSteamID)
 		}
 
 		friendsShoudlBeCrawled := util.JobIsNotLevelOneAndNotMax(job)
 		// If the job is not at max level or has a max level of one, add
 		// friends to the queue for crawling
 		if friendsShoudlBeCrawled {
-			err = putFriendsIntoQueue(cntr, job, friendsList)
+			err = putFriendsIntoQueue(cntr, job, friendsToQueue)
 			if err != nil {
-				configuration.Logger.Sugar().Panicf("error publishing friends from steamID: %s to queue: %+v", job.CurrentTargetSteamID, err)
+				return true, fmt.Errorf("error publishing friends from steamID: %s to queue: %+v", job.CurrentTargetSteamID, err)
 			}
 		}
 
@@ -63,22 +100,25 @@ func Worker(cntr controller.CntrInterface, job datastructures.Job) {
 			SetTime(time.Now())
 		writeAPI.WritePoint(point)
 		defer writeAPI.Close()
-		return
+		return true, nil
 	}
 	playerSummaryForCurrentUser := common.Player{}
 	fiftyOrFewerGamesOwnedForCurrentUser := []common.GameOwnedDocument{}
+	gamesOwnedCount := 0
 	friendPlayerSummaries := []common.Player{}
 	var waitG sync.WaitGroup
 
 	durationForGetSummaryForMainUser := int64(0)
 	durationForGetFiftyOrFewerGamesOwned := int64(0)
 	durationForGetSummariesForFriends := int64(0)
+	var getSummaryForMainUserErr, getFiftyOrFewerGamesOwnedErr, getSummariesForFriendsErr error
 	waitG.Add(1)
 	go getSummaryForMainUserFunc(
 		cntr,
 		job.CurrentTargetSteamID,
 		&playerSummaryForCurrentUser,
 		&durationForGetSummaryForMainUser,
+		&getSummaryForMainUserErr,
 		&waitG)
 
 	waitG.Add(1)
@@ -86,7 +126,9 @@ func Worker(cntr controller.CntrInterface, job datastructures.Job) {
 		cntr,
 		job.CurrentTargetSteamID,
 		&fiftyOrFewerGamesOwnedForCurrentUser,
+		&gamesOwnedCount,
 		&durationForGetFiftyOrFewerGamesOwned,
+		&getFiftyOrFewerGamesOwnedErr,
 		&waitG)
 
 	waitG.Add(1)
@@ -95,13 +137,25 @@ func Worker(cntr controller.CntrInterface, job datastructures.Job) {
 		friendsList,
 		&friendPlayerSummaries,
 		&durationForGetSummariesForFriends,
+		&getSummariesForFriendsErr,
 		&waitG)
 
 	waitG.Wait()
+	for _, err := range []error{getSummaryForMainUserErr, getFiftyOrFewerGamesOwnedErr, getSummariesForFriendsErr} {
+		if err != nil {
+			return false, err
+		}
+	}
 	emptyPlayer := common.Player{}
 	if playerSummaryForCurrentUser == emptyPlayer {
 		configuration.Logger.Sugar().Infof("caught ultra secure private user, ignoring this job: %+v", job)
-		return
+		countJobAsPrivate(cntr, job)
+		return true, nil
+	}
+	apiCallsMade := getAPICallsMadeForUser(friendsList)
+	if !userPassesGamesFilter(job, gamesOwnedCount, false) {
+		skipJobFilteredOut(cntr, job, apiCallsMade)
+		return true, nil
 	}
 
 	// ASYNC BLOCK TWO
@@ -116,38 +170,56 @@ func Worker(cntr controller.CntrInterface, job datastructures.Job) {
 
 	// PUT FRIENDS INTO QUEUE
 	friendPlayerSummarySteamIDs := getSteamIDsFromPlayers(friendPlayerSummaries)
+	friendsToExpand := getFriendsToExpand(job, friendPlayerSummarySteamIDs, friendPlayerSummaries)
+	friendsToQueue, err := getFriendsToQueue(cntr, job, friendsToExpand, apiCallsMade)
+	if err != nil {
+		return false, fmt.Errorf("error getting friends to queue in worker for %s: %+v", job.CurrentTargetSteamID, err)
+	}
 	friendsShoudlBeCrawled := util.JobIsNotLevelOneAndNotMax(job)
 	publishFriendsToQueueDuration := int64(0)
+	var publishFriendsToQueueErr error
 	if friendsShoudlBeCrawled {
 		waitG.Add(1)
-		go publishFriendsToQueueFunc(cntr, job, friendPlayerSummarySteamIDs, &publishFriendsToQueueDuration, &waitG)
+		go publishFriendsToQueueFunc(cntr, job, friendsToQueue, &publishFriendsToQueueDuration, &publishFriendsToQueueErr, &waitG)
 	}
 
 	// // Save user to DB
-	saveUser := dtos.SaveUserDTO{
-		OriginalCrawlTarget: job.OriginalTargetSteamID,
-		CurrentLevel:        job.CurrentLevel,
-		MaxLevel:            job.MaxLevel,
-		CrawlID:             job.CrawlID,
-		User: common.UserDocument{
-			AccDetails: common.AccDetailsDocument{
-				SteamID:        playerSummaryForCurrentUser.Steamid,
-				Personaname:    playerSummaryForCurrentUser.Personaname,
-				Profileurl:     playerSummaryForCurrentUser.Profileurl,
-				Avatar:         playerSummaryForCurrentUser.Avatar,
-				Timecreated:    playerSummaryForCurrentUser.Timecreated,
-				Loccountrycode: playerSummaryForCurrentUser.Loccountrycode,
+	saveUser := datastructures.SaveUserDTO{
+		SaveUserDTO: dtos.SaveUserDTO{
+			OriginalCrawlTarget: job.OriginalTargetSteamID,
+			CurrentLevel:        job.CurrentLevel,
+			MaxLevel:            job.MaxLevel,
+			CrawlID:             job.CrawlID,
+			User: common.UserDocument{
+				AccDetails: common.AccDetailsDocument{
+					SteamID:        playerSummaryForCurrentUser.Steamid,
+					Personaname:    playerSummaryForCurrentUser.Personaname,
+					Profileurl:     playerSummaryForCurrentUser.Profileurl,
+					Avatar:         playerSummaryForCurrentUser.Avatar,
+					Timecreated:    playerSummaryForCurrentUser.Timecreated,
+					Loccountrycode: playerSummaryForCurrentUser.Loccountrycode,
+				},
+				FriendIDs:  friendPlayerSummarySteamIDs,
+				GamesOwned: fiftyOrFewerGamesOwnedForCurrentUser,
 			},
-			FriendIDs:  friendPlayerSummarySteamIDs,
-			GamesOwned: fiftyOrFewerGamesOwnedForCurrentUser,
 		},
+		FriendsQueued: len(friendsToQueue),
+		HubCapped:     hubShouldBeCapped(job, friendPlayerSummarySteamIDs),
 	}
 
 	waitG.Add(1)
 	saveUserDuration := int64(0)
-	go saveUserFunc(cntr, saveUser, &saveUserDuration, &waitG)
+	var saveUserErr error
+	go saveUserFunc(cntr, saveUser, &saveUserDuration, &saveUserErr, &waitG)
 
 	waitG.Wait()
+	// The job is counted in its crawl's stats when the user is saved
+	if saveUserErr != nil {
+		return false, saveUserErr
+	}
+	if publishFriendsToQueueErr != nil {
+		return true, publishFriendsToQueueErr
+	}
 
 	writeAPI := configuration.InfluxDBClient.WriteAPI(os.Getenv("ORG"), "crawlerMetrics")
 	point := influxdb2.NewPointWithMeasurement("crawlerMetrics").
@@ -160,31 +232,131 @@ func Worker(cntr controller.CntrInterface, job datastructures.Job) {
 		AddField("publishfriendstoqueueduration", publishFriendsToQueueDuration).
 		AddField("saveuserduration", saveUserDuration).
 		AddField("gamesowned", len(fiftyOrFewerGamesOwnedForCurrentUser)).
+		AddField("apicallsmade", apiCallsMade).
 		SetTime(time.Now())
 	writeAPI.WritePoint(point)
 	defer writeAPI.Close()
+	return true, nil
 }
 
 // GetFriends gets the friendslist for a given user through either datastore
-// or the steam web API.
-// 		userWasFoundInDB, friendIDs, err := GetFriends(cntr, steamID)
-func GetFriends(cntr controller.CntrInterface, steamID string) (bool, []string, error) {
+// or the steam web API. The full stored user is returned if they were found
+// in the datastore and are within the freshness window, otherwise only their
+// FriendIDs are set
+// 		userWasFoundInDB, user, err := GetFriends(cntr, steamID, freshnessWindow)
+func GetFriends(cntr controller.CntrInterface, steamID string, freshnessWindow time.Duration) (bool, common.UserDocument, error) {
 	userFromDB, err := cntr.GetUserFromDataStore(steamID)
 	if err != nil {
 		configuration.Logger.Sugar().Infof("error getting user in DB: %+v", err)
 	}
 	if userFromDB.AccDetails.SteamID != "" {
-		configuration.Logger.Sugar().Infof("returning user retrieved from DB: %+v", userFromDB.AccDetails.SteamID)
-		return true, userFromDB.FriendIDs, nil
+		if !storedUserIsStale(userFromDB, freshnessWindow) {
+			configuration.Logger.Sugar().Infof("returning user retrieved from DB: %+v", userFromDB.AccDetails.SteamID)
+			return true, userFromDB, nil
+		}
+		configuration.Logger.Sugar().Infof("user %s in DB is older than the freshness window, refetching", steamID)
 	}
 
 	configuration.Logger.Sugar().Infof("user %s was not found in DB", steamID)
 	// User was not found in DB, call the API
 	friendsList, err := cntr.CallGetFriends(steamID)
 	if err != nil {
-		return false, []string{}, err
+		return false, common.UserDocument{FriendIDs: []string{}}, err
+	}
+	return false, common.UserDocument{FriendIDs: friendsList}, nil
+}
+
+// getFreshnessWindow returns how old a stored user can be before
+// they are refetched for the given job
+func getFreshnessWindow(job datastructures.Job) time.Duration {
+	freshnessWindowHours := job.FreshnessWindowHours
+	if freshnessWindowHours == 0 {
+		freshnessWindowHours = configuration.WorkerConfig.FreshnessWindowHours
+	}
+	return time.Duration(freshnessWindowHours) * time.Hour
+}
+
+// storedUserIsStale checks if a stored user was saved longer ago than
+// the freshness window. Users without an insertion time are stale
+func storedUserIsStale(user common.UserDocument, freshnessWindow time.Duration) bool {
+	if user.InsertionTime == 0 {
+		return true
+	}
+	return time.Since(time.Unix(user.InsertionTime, 0)) > freshnessWindow
+}
+
+// countJobAsCrawled increments the users crawled for a job's crawl
+// without adding any more users to crawl
+func countJobAsCrawled(cntr controller.CntrInterface, job datastructures.Job) {
+	crawlingStatus := getCrawlingStatusForSkippedJob(job)
+	success, err := cntr.SaveCrawlingStatsToDataStore(job.CurrentLevel, crawlingStatus)
+	if err != nil {
+		configuration.Logger.Sugar().Panicf("error saving crawling stats for skipped job: %+v", err)
+	}
+	if !success {
+		configuration.Logger.Sugar().Panicf("failed to save crawling stats for skipped job: %+v", err)
+	}
+}
+
+// countJobAsPrivate counts a job whose user's profile could not be
+// retrieved as crawled and records it as a private profile
+func countJobAsPrivate(cntr controller.CntrInterface, job datastructures.Job) {
+	crawlingStatus := getCrawlingStatusForSkippedJob(job)
+	crawlingStatus.PrivateProfiles = 1
+	success, err := cntr.SaveCrawlingStatsToDataStore(job.CurrentLevel, crawlingStatus)
+	if err != nil {
+		configuration.Logger.Sugar().Panicf("error saving crawling stats for private user: %+v", err)
+	}
+	if !success {
+		configuration.Logger.Sugar().Panicf("failed to save crawling stats for private user: %+v", err)
+	}
+}
+
+// countJobAsFailed counts a job that failed part way through as crawled
+// and records it as failed. This is called while recovering from a panic
+// so errors are logged instead
+func countJobAsFailed(cntr controller.CntrInterface, job datastructures.Job) {
+	crawlingStatus := getCrawlingStatusForSkippedJob(job)
+	crawlingStatus.FailedJobs = 1
+	success, err := cntr.SaveCrawlingStatsToDataStore(job.CurrentLevel, crawlingStatus)
+	if err != nil {
+		configuration.Logger.Sugar().Errorf("error saving crawling stats for failed job: %+v", err)
+		return
+	}
+	if !success {
+		configuration.Logger.Sugar().Errorf("failed to save crawling stats for failed job %+v", job)
+	}
+}
+
+func getCrawlingStatusForSkippedJob(job datastructures.Job) datastructures.CrawlingStatus {
+	return datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: job.OriginalTargetSteamID,
+			MaxLevel:            job.MaxLevel,
+			CrawlID:             job.CrawlID,
+			TotalUsersToCrawl:   0,
+		},
+	}
+}
+
+// runJob runs the worker for a job. A job that fails or panics before it
+// was counted in its crawl's stats is counted as failed so that its crawl
+// can still finish
+func runJob(cntr controller.CntrInterface, job datastructures.Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			configuration.Logger.Sugar().Errorf("job %+v panicked: %v", job, r)
+			countJobAsFailed(cntr, job)
+		}
+	}()
+	statsSaved, err := Worker(cntr, job)
+	if err == nil {
+		return
+	}
+	configuration.Logger.Sugar().Errorf("job %+v failed: %+v", job, err)
+	if !statsSaved {
+		countJobAsFailed(cntr, job)
 	}
-	return false, friendsList, nil
 }
 
 // ControlFunc manages workers
@@ -196,20 +368,16 @@ func ControlFunc(cntr controller.CntrInterface) {
 
 	for {
 		for d := range msgs {
-			newJob := datastructures.Job{}
-			err := json.Unmarshal(d.Body, &newJob)
-			if err != nil {
-				configuration.Logger.Sugar().Panicf(fmt.Sprintf("failed unmarshal job from queue: %v", err))
+			configuration.Logger.Sugar().Infof("control func received job: %+v", d.Job)
+			runJob(cntr, d.Job)
+			if err := d.Ack(); err != nil {
+				configuration.Logger.Sugar().Errorf("failed to ack job %+v: %v", d.Job, err)
 			}
-
-			configuration.Logger.Sugar().Infof("control func received job: %+v", newJob)
-			Worker(cntr, newJob)
-			d.Ack(false)
 		}
 	}
 }
 
-func CrawlUser(cntr controller.CntrInterface, steamID, crawlID string, level int) error {
+func CrawlUser(cntr controller.CntrInterface, steamID, crawlID string, level int, options datastructures.CrawlOptions) error {
 	newJob := datastructures.Job{
 		JobType:               "crawl",
 		OriginalTargetSteamID: steamID,
@@ -217,19 +385,24 @@ func CrawlUser(cntr controller.CntrInterface, steamID, crawlID string, level int
 		CrawlID:               crawlID,
 		MaxLevel:              level,
 		CurrentLevel:          1,
+		Budget:                options.Budget,
+		HubThreshold:          options.HubThreshold,
+		Filters:               options.Filters,
+		FreshnessWindowHours:  options.FreshnessWindowHours,
 	}
-	jsonObj, err := json.Marshal(newJob)
-	if err != nil {
-		return commonUtil.MakeErr(err, fmt.Sprintf("failed to marshal initial crawl job: %+v", newJob))
-	}
-
-	crawlingStatus := common.CrawlingStatus{
-		TimeStarted:         time.Now().Unix(),
-		OriginalCrawlTarget: newJob.OriginalTargetSteamID,
-		MaxLevel:            newJob.MaxLevel,
-		CrawlID:             newJob.CrawlID,
-		UsersCrawled:        0,
-		TotalUsersToCrawl:   1,
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted:         time.Now().Unix(),
+			OriginalCrawlTarget: newJob.OriginalTargetSteamID,
+			MaxLevel:            newJob.MaxLevel,
+			CrawlID:             newJob.CrawlID,
+			UsersCrawled:        0,
+			TotalUsersToCrawl:   1,
+		},
+		Budget:        options.Budget,
+		UsersReserved: 1,
+		HubThreshold:  options.HubThreshold,
+		Filters:       options.Filters,
 	}
 	success, err := cntr.SaveCrawlingStatsToDataStore(newJob.CurrentLevel, crawlingStatus)
 	if err != nil {
@@ -241,7 +414,7 @@ func CrawlUser(cntr controller.CntrInterface, steamID, crawlID string, level int
 	}
 	configuration.Logger.Sugar().Infof("created crawling %+v", crawlingStatus)
 
-	err = amqpchannelmanager.PublishToJobsQueue(cntr, jsonObj)
+	err = cntr.PublishToJobsQueue(newJob)
 	if err != nil {
 		configuration.Logger.Sugar().Errorf("failed to publish new crawl user job with steamID: %s level: %d to queue: %+v",
 			steamID, level, err)
@@ -251,19 +424,21 @@ func CrawlUser(cntr controller.CntrInterface, steamID, crawlID string, level int
 	return err
 }
 
-func getSummaryForMainUserFunc(cntr controller.CntrInterface, steamID string, mainUser *common.Player, durationForGetPlayerSummary *int64, waitG *sync.WaitGroup) {
+func getSummaryForMainUserFunc(cntr controller.CntrInterface, steamID string, mainUser *common.Player, durationForGetPlayerSummary *int64, funcErr *error, waitG *sync.WaitGroup) {
 	defer waitG.Done()
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 	playerSummaries, err := cntr.CallGetPlayerSummaries(steamID)
 	if err != nil {
-		configuration.Logger.Panic(fmt.Sprintf("failed to get player summary for target user %s: %v", steamID, err.Error()))
+		*funcErr = fmt.Errorf("failed to get player summary for target user %s: %v", steamID, err)
+		return
 	}
 
 	// Sometimes occurs with accounts that have complex combinatioons of data privacy settings
 	if len(playerSummaries) == 0 {
 		playerSummaries, err = cntr.CallGetPlayerSummaries(steamID)
 		if err != nil {
-			configuration.Logger.Sugar().Panicf(fmt.Sprintf("failed AGAIN to get player summary for target user: %+v", err))
+			*funcErr = fmt.Errorf("failed AGAIN to get player summary for target user %s: %+v", steamID, err)
+			return
 		}
 		if len(playerSummaries) == 0 {
 			// This is a very odd occurance and I do not know how a user can
@@ -287,21 +462,23 @@ func getSummaryForMainUserFunc(cntr controller.CntrInterface, steamID string, ma
 	*durationForGetPlayerSummary = commonUtil.GetCurrentTimeInMs() - startTime
 }
 
-func getFiftyOrFewerGamesOwnedFunc(cntr controller.CntrInterface, steamID string, gamesOwned *[]common.GameOwnedDocument, durationForGetFiftyOrFewerGamesOwned *int64, waitG *sync.WaitGroup) {
+func getFiftyOrFewerGamesOwnedFunc(cntr controller.CntrInterface, steamID string, gamesOwned *[]common.GameOwnedDocument, gamesOwnedCount *int, durationForGetFiftyOrFewerGamesOwned *int64, funcErr *error, waitG *sync.WaitGroup) {
 	defer waitG.Done()
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 	allGamesOwnedForCurrentUser, err := getGamesOwned(cntr, steamID)
 	if err != nil {
-		configuration.Logger.Sugar().Panicf("failed to get player summaries for friends: %+v", err)
+		*funcErr = fmt.Errorf("failed to get games owned for %s: %+v", steamID, err)
+		return
 	}
 	topFiftyOrFewerTopPlayedGames := getTopFiftyOrFewerGames(allGamesOwnedForCurrentUser)
 	topFiftyOrFewerGamesOwnedSlimmedDown := GetSlimmedDownOwnedGames(topFiftyOrFewerTopPlayedGames)
 
 	*gamesOwned = topFiftyOrFewerGamesOwnedSlimmedDown
+	*gamesOwnedCount = len(allGamesOwnedForCurrentUser)
 	*durationForGetFiftyOrFewerGamesOwned = commonUtil.GetCurrentTimeInMs() - startTime
 }
 
-func getSummariesForFriendsFunc(cntr controller.CntrInterface, friendIDs []string, friends *[]common.Player, durationForGetSummariesForFriends *int64, waitG *sync.WaitGroup) {
+func getSummariesForFriendsFunc(cntr controller.CntrInterface, friendIDs []string, friends *[]common.Player, durationForGetSummariesForFriends *int64, funcErr *error, waitG *sync.WaitGroup) {
 	defer waitG.Done()
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 	if len(friendIDs) == 0 {
@@ -311,32 +488,36 @@ func getSummariesForFriendsFunc(cntr controller.CntrInterface, friendIDs []strin
 
 	friendPlayerSummaries, err := getPlayerSummaries(cntr, friendIDs)
 	if err != nil {
-		configuration.Logger.Sugar().Panicf("failed to get player summaries for friends: %+v", err)
+		*funcErr = fmt.Errorf("failed to get player summaries for friends: %+v", err)
+		return
 	}
 
 	*friends = friendPlayerSummaries
 	*durationForGetSummariesForFriends = commonUtil.GetCurrentTimeInMs() - startTime
 }
 
-func publishFriendsToQueueFunc(cntr controller.CntrInterface, job datastructures.Job, friendIDs []string, publishFriendsToQueueDuration *int64, waitG *sync.WaitGroup) {
+func publishFriendsToQueueFunc(cntr controller.CntrInterface, job datastructures.Job, friendIDs []string, publishFriendsToQueueDuration *int64, funcErr *error, waitG *sync.WaitGroup) {
 	defer waitG.Done()
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 	err := putFriendsIntoQueue(cntr, job, friendIDs)
 	if err != nil {
-		configuration.Logger.Sugar().Panicf("failed publish friends from steamID: %s to queue: %+v", job.CurrentTargetSteamID, err)
+		*funcErr = fmt.Errorf("failed publish friends from steamID: %s to queue: %+v", job.CurrentTargetSteamID, err)
+		return
 	}
 	*publishFriendsToQueueDuration = commonUtil.GetCurrentTimeInMs() - startTime
 }
 
-func saveUserFunc(cntr controller.CntrInterface, saveUser dtos.SaveUserDTO, saveUserDuration *int64, waitG *sync.WaitGroup) {
+func saveUserFunc(cntr controller.CntrInterface, saveUser datastructures.SaveUserDTO, saveUserDuration *int64, funcErr *error, waitG *sync.WaitGroup) {
 	defer waitG.Done()
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 	success, err := cntr.SaveUserToDataStore(saveUser)
 	if err != nil {
-		configuration.Logger.Sugar().Panicf("error when saving user user %s to DB: %+v", saveUser.User.AccDetails.SteamID, err)
+		*funcErr = fmt.Errorf("error when saving user user %s to DB: %+v", saveUser.User.AccDetails.SteamID, err)
+		return
 	}
 	if !success {
-		configuration.Logger.Sugar().Panicf("failed to save user %s to DB: %+v", saveUser.User.AccDetails.SteamID, err)
+		*funcErr = fmt.Errorf("failed to save user %s to DB", saveUser.User.AccDetails.SteamID)
+		return
 	}
 	*saveUserDuration = commonUtil.GetCurrentTimeInMs() - startTime
 }
@@ -6,12 +6,12 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/iamcathal/neo/services/crawler/configuration"
 	"github.com/iamcathal/neo/services/crawler/controller"
 	"github.com/iamcathal/neo/services/crawler/datastructures"
 	"github.com/neosteamfriendgraphing/common"
-	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -32,7 +32,7 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 	configuration.Logger = log
-	configuration.AmqpChannels = append(configuration.AmqpChannels, amqp.Channel{})
+	configuration.WorkerConfig.FreshnessWindowHours = 168
 
 	code := m.Run()
 
@@ -56,6 +56,7 @@ func initTestData() {
 				Playtime_Forever: 1337,
 			},
 		},
+		InsertionTime: time.Now().Unix(),
 	}
 	testPlayerList = []common.Player{
 		{
@@ -107,7 +108,7 @@ func TestPutFriendsIntoJobsQueue(t *testing.T) {
 	}
 	friendIDs := []string{"12455", "29456", "05838", "54954", "45967"}
 
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(nil)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(nil)
 
 	err := putFriendsIntoQueue(mockController, currentJob, friendIDs)
 
@@ -353,29 +354,29 @@ func TestInitWorkerConfig(t *testing.T) {
 
 func TestCrawlUser(t *testing.T) {
 	mockController := controller.MockCntrInterface{}
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(nil)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(nil)
 	mockController.On("SaveCrawlingStatsToDataStore", 1, mock.Anything).Return(true, nil)
-	CrawlUser(&mockController, "testSteamID", "testcrawlID", 4)
+	CrawlUser(&mockController, "testSteamID", "testcrawlID", 4, datastructures.CrawlOptions{})
 }
 
 func TestCrawlUserWhenErrorIsReturnedPublishingJobToQueue(t *testing.T) {
 	mockController := controller.MockCntrInterface{}
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(errors.New("test error"))
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(errors.New("test error"))
 	mockController.On("SaveCrawlingStatsToDataStore", 1, mock.Anything).Return(true, nil)
 
-	CrawlUser(&mockController, "testSteamID", "testcrawlID", 4)
+	CrawlUser(&mockController, "testSteamID", "testcrawlID", 4, datastructures.CrawlOptions{})
 }
 
 func TestGetFriendsWhenFriendIsFoundFromDatastore(t *testing.T) {
 	mockController := controller.MockCntrInterface{}
 	mockController.On("GetUserFromDataStore", mock.AnythingOfType("string")).Return(testUser, nil)
 
-	didExistInDatastore, friends, err := GetFriends(&mockController, testUser.AccDetails.SteamID)
+	didExistInDatastore, user, err := GetFriends(&mockController, testUser.AccDetails.SteamID, time.Hour)
 
 	mockController.AssertNotCalled(t, "CallGetFriends")
 
 	assert.True(t, didExistInDatastore)
-	assert.Equal(t, testUser.FriendIDs, friends)
+	assert.Equal(t, testUser, user)
 	assert.Nil(t, err)
 }
 
@@ -385,13 +386,13 @@ func TestGetFriendsWhenFriendWhenAnErrorIsReturnedFromDatastoreTheSteamAPIIsUsed
 	mockController.On("GetUserFromDataStore", mock.AnythingOfType("string")).Return(noUserFound, errors.New("test error"))
 	mockController.On("CallGetFriends", mock.AnythingOfType("string")).Return(testUser.FriendIDs, nil)
 
-	didExistInDatastore, friends, err := GetFriends(&mockController, testUser.AccDetails.SteamID)
+	didExistInDatastore, user, err := GetFriends(&mockController, testUser.AccDetails.SteamID, time.Hour)
 
 	mockController.AssertNumberOfCalls(t, "GetUserFromDataStore", 1)
 	mockController.AssertNumberOfCalls(t, "CallGetFriends", 1)
 
 	assert.False(t, didExistInDatastore)
-	assert.Equal(t, testUser.FriendIDs, friends)
+	assert.Equal(t, testUser.FriendIDs, user.FriendIDs)
 	assert.Nil(t, err)
 }
 func TestGetFriendsWhenFriendIsNotFoundFromDatastoreAndSteamAPIIsCalled(t *testing.T) {
@@ -400,12 +401,12 @@ func TestGetFriendsWhenFriendIsNotFoundFromDatastoreAndSteamAPIIsCalled(t *testi
 	mockController.On("GetUserFromDataStore", mock.AnythingOfType("string")).Return(noUserFound, nil)
 	mockController.On("CallGetFriends", mock.AnythingOfType("string")).Return(noUserFound.FriendIDs, nil)
 
-	didExistInDatastore, friends, err := GetFriends(&mockController, testUser.AccDetails.SteamID)
+	didExistInDatastore, user, err := GetFriends(&mockController, testUser.AccDetails.SteamID, time.Hour)
 
 	mockController.AssertNumberOfCalls(t, "CallGetFriends", 1)
 
 	assert.False(t, didExistInDatastore)
-	assert.Equal(t, noUserFound.FriendIDs, friends)
+	assert.Equal(t, noUserFound.FriendIDs, user.FriendIDs)
 	assert.Nil(t, err)
 }
 
@@ -415,15 +416,40 @@ func TestGetFriendsWhenFriendIsNotFoundFromDatastoreAndNoUserIsFoundInSteamAPI(t
 	mockController.On("GetUserFromDataStore", mock.AnythingOfType("string")).Return(noUserFound, nil)
 	mockController.On("CallGetFriends", mock.AnythingOfType("string")).Return(noUserFound.FriendIDs, errors.New("no users found error"))
 
-	didExistInDatastore, friends, err := GetFriends(&mockController, testUser.AccDetails.SteamID)
+	didExistInDatastore, user, err := GetFriends(&mockController, testUser.AccDetails.SteamID, time.Hour)
 
 	mockController.AssertNumberOfCalls(t, "CallGetFriends", 1)
 
 	assert.False(t, didExistInDatastore)
-	assert.Equal(t, []string{}, friends)
+	assert.Equal(t, []string{}, user.FriendIDs)
 	assert.NotNil(t, err)
 }
 
+func TestGetFriendsRefetchesStoredUserOlderThanTheFreshnessWindow(t *testing.T) {
+	mockController := controller.MockCntrInterface{}
+	staleUser := testUser
+	staleUser.InsertionTime = time.Now().Add(-48 * time.Hour).Unix()
+	mockController.On("GetUserFromDataStore", mock.AnythingOfType("string")).Return(staleUser, nil)
+	mockController.On("CallGetFriends", mock.AnythingOfType("string")).Return(testUser.FriendIDs, nil)
+
+	didExistInDatastore, user, err := GetFriends(&mockController, testUser.AccDetails.SteamID, 24*time.Hour)
+
+	mockController.AssertNumberOfCalls(t, "CallGetFriends", 1)
+
+	assert.False(t, didExistInDatastore)
+	assert.Equal(t, common.UserDocument{FriendIDs: testUser.FriendIDs}, user)
+	assert.Nil(t, err)
+}
+
+func TestGetFreshnessWindowUsesTheDefaultWhenTheJobHasNone(t *testing.T) {
+	assert.Equal(t, 168*time.Hour, getFreshnessWindow(datastructures.Job{}))
+	assert.Equal(t, 2*time.Hour, getFreshnessWindow(datastructures.Job{FreshnessWindowHours: 2}))
+}
+
+func TestStoredUserIsStaleWhenItHasNoInsertionTime(t *testing.T) {
+	assert.True(t, storedUserIsStale(common.UserDocument{}, time.Hour))
+}
+
 func TestGetTopTwentyOrFewerGames(t *testing.T) {
 	expectedFirstGame := "CS Source"
 	expectedSecondGame := "CS:GO"
@@ -563,13 +589,10 @@ func TestPublishJobHasDelayForConsecutiveFailures(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
 
 	randomError := errors.New("random error")
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(randomError).Times(2)
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(nil).Times(1)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(randomError).Times(2)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(nil).Times(1)
 	mockController.On("Sleep", mock.Anything).Return()
 
-	configuration.AmqpChannels = []amqp.Channel{
-		{}, {},
-	}
 	firstJob := datastructures.Job{}
 
 	err := publishJob(mockController, firstJob)
@@ -583,12 +606,9 @@ func TestPublishJobReturnsErrorWhenAllRetriesFail(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
 
 	randomError := errors.New("random error")
-	mockController.On("PublishToJobsQueue", mock.Anything, mock.Anything).Return(randomError).Times(4)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(randomError).Times(4)
 	mockController.On("Sleep", mock.Anything).Return()
 
-	configuration.AmqpChannels = []amqp.Channel{
-		{}, {},
-	}
 	firstJob := datastructures.Job{}
 
 	err := publishJob(mockController, firstJob)
@@ -597,3 +617,418 @@ func TestPublishJobReturnsErrorWhenAllRetriesFail(t *testing.T) {
 	mockController.AssertNumberOfCalls(t, "Sleep", 3)
 	mockController.AssertNumberOfCalls(t, "PublishToJobsQueue", 4)
 }
+
+func TestGetFriendsToQueueReturnsAllFriendsWhenThereIsNoBudget(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		CrawlID:      "testcrawlID",
+		MaxLevel:     3,
+		CurrentLevel: 1,
+	}
+	friendIDs := []string{"12455", "29456", "05838"}
+
+	friendsToQueue, err := getFriendsToQueue(mockController, job, friendIDs, 4)
+
+	assert.Nil(t, err)
+	assert.Equal(t, friendIDs, friendsToQueue)
+	mockController.AssertNotCalled(t, "UpdateCrawlBudgetInDataStore", mock.Anything, mock.Anything)
+}
+
+func TestGetFriendsToQueueReturnsNoFriendsAtMaxLevel(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		CrawlID:      "testcrawlID",
+		MaxLevel:     3,
+		CurrentLevel: 3,
+		Budget: datastructures.CrawlBudget{
+			MaxAPICalls: 100,
+		},
+	}
+	expectedBudgetUsage := datastructures.CrawlBudgetUsage{
+		APICallsMade:   4,
+		UsersRequested: 0,
+	}
+	mockController.On("UpdateCrawlBudgetInDataStore", job.CrawlID, expectedBudgetUsage).Return(0, nil)
+
+	friendsToQueue, err := getFriendsToQueue(mockController, job, []string{"12455", "29456"}, 4)
+
+	assert.Nil(t, err)
+	assert.Empty(t, friendsToQueue)
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlBudgetInDataStore", 1)
+}
+
+func TestGetFriendsToQueueOnlyReturnsFriendsGrantedByTheUserBudget(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		CrawlID:      "testcrawlID",
+		MaxLevel:     5,
+		CurrentLevel: 2,
+		Budget: datastructures.CrawlBudget{
+			MaxUsers: 50,
+		},
+	}
+	friendIDs := []string{"12455", "29456", "05838", "54954", "45967"}
+	expectedBudgetUsage := datastructures.CrawlBudgetUsage{
+		APICallsMade:   4,
+		UsersRequested: len(friendIDs),
+	}
+	mockController.On("UpdateCrawlBudgetInDataStore", job.CrawlID, expectedBudgetUsage).Return(2, nil)
+
+	friendsToQueue, err := getFriendsToQueue(mockController, job, friendIDs, 4)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"12455", "29456"}, friendsToQueue)
+}
+
+func TestGetFriendsToQueueReturnsAnErrorWhenTheBudgetCannotBeUpdated(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		CrawlID:      "testcrawlID",
+		MaxLevel:     5,
+		CurrentLevel: 2,
+		Budget: datastructures.CrawlBudget{
+			MaxUsers: 50,
+		},
+	}
+	mockController.On("UpdateCrawlBudgetInDataStore", job.CrawlID, mock.Anything).Return(0, errors.New("test error"))
+
+	_, err := getFriendsToQueue(mockController, job, []string{"12455"}, 4)
+
+	assert.NotNil(t, err)
+}
+
+func TestGetAPICallsMadeForUserCountsOneCallPerBatchOfFriends(t *testing.T) {
+	friendIDs := []string{}
+	for i := 0; i < 120; i++ {
+		friendIDs = append(friendIDs, strconv.Itoa(i))
+	}
+
+	assert.Equal(t, 5, getAPICallsMadeForUser(friendIDs))
+	assert.Equal(t, 3, getAPICallsMadeForUser([]string{}))
+}
+
+func TestAPICallBudgetIsExhaustedWhenAllCallsHaveBeenMade(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		CrawlID: "testcrawlID",
+		Budget: datastructures.CrawlBudget{
+			MaxAPICalls: 100,
+		},
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", job.CrawlID).Return(datastructures.CrawlingStatus{APICallsMade: 100}, nil)
+
+	budgetExhausted, err := apiCallBudgetIsExhausted(mockController, job)
+
+	assert.Nil(t, err)
+	assert.True(t, budgetExhausted)
+}
+
+func TestAPICallBudgetIsNotExhaustedWhenCallsAreRemaining(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		CrawlID: "testcrawlID",
+		Budget: datastructures.CrawlBudget{
+			MaxAPICalls: 100,
+		},
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", job.CrawlID).Return(datastructures.CrawlingStatus{APICallsMade: 99}, nil)
+
+	budgetExhausted, err := apiCallBudgetIsExhausted(mockController, job)
+
+	assert.Nil(t, err)
+	assert.False(t, budgetExhausted)
+}
+
+func TestWorkerSkipsJobWhenAPICallBudgetIsExhausted(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  "54321",
+		CrawlID:               "testcrawlID",
+		MaxLevel:              4,
+		CurrentLevel:          2,
+		Budget: datastructures.CrawlBudget{
+			MaxAPICalls: 100,
+		},
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", job.CrawlID).Return(datastructures.CrawlingStatus{APICallsMade: 104}, nil)
+	mockController.On("UpdateCrawlBudgetInDataStore", job.CrawlID, datastructures.CrawlBudgetUsage{Truncated: true}).Return(0, nil)
+	mockController.On("SaveCrawlingStatsToDataStore", job.CurrentLevel, mock.Anything).Return(true, nil)
+
+	Worker(mockController, job)
+
+	mockController.AssertNotCalled(t, "GetUserFromDataStore", mock.Anything)
+	mockController.AssertNotCalled(t, "CallGetFriends", mock.Anything)
+	mockController.AssertNumberOfCalls(t, "SaveCrawlingStatsToDataStore", 1)
+}
+
+func TestPutFriendsIntoJobsQueuePassesOnTheCrawlBudget(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	currentJob := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  "12345",
+		CrawlID:               "2345345346546sdfdfbhfd",
+		MaxLevel:              5,
+		CurrentLevel:          1,
+		Budget: datastructures.CrawlBudget{
+			MaxUsers:    500,
+			MaxAPICalls: 2000,
+		},
+	}
+	mockController.On("PublishToJobsQueue", mock.MatchedBy(func(job datastructures.Job) bool {
+		return job.Budget == currentJob.Budget
+	})).Return(nil)
+
+	err := putFriendsIntoQueue(mockController, currentJob, []string{"12455", "29456"})
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "PublishToJobsQueue", 2)
+}
+
+func TestHubShouldBeCappedForUsersWithMoreFriendsThanTheThreshold(t *testing.T) {
+	job := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 1,
+		HubThreshold: 2,
+	}
+
+	assert.True(t, hubShouldBeCapped(job, []string{"12455", "29456", "05838"}))
+	assert.False(t, hubShouldBeCapped(job, []string{"12455", "29456"}))
+}
+
+func TestHubShouldNotBeCappedWithoutAThresholdOrAtMaxLevel(t *testing.T) {
+	friendIDs := []string{"12455", "29456", "05838"}
+	noThresholdJob := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 1,
+	}
+	maxLevelJob := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 3,
+		HubThreshold: 2,
+	}
+
+	assert.False(t, hubShouldBeCapped(noThresholdJob, friendIDs))
+	assert.False(t, hubShouldBeCapped(maxLevelJob, friendIDs))
+}
+
+func TestPutFriendsIntoJobsQueueDoesNotQueueFriendsOfHubs(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	currentJob := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  "12345",
+		CrawlID:               "2345345346546sdfdfbhfd",
+		MaxLevel:              3,
+		CurrentLevel:          1,
+		HubThreshold:          4,
+	}
+	friendIDs := []string{"12455", "29456", "05838", "54954", "45967"}
+
+	err := putFriendsIntoQueue(mockController, currentJob, friendIDs)
+
+	assert.Nil(t, err)
+	mockController.AssertNotCalled(t, "PublishToJobsQueue", mock.Anything)
+}
+
+func TestGetFriendsToExpandReturnsNoFriendsForCappedHubs(t *testing.T) {
+	job := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 1,
+		HubThreshold: 1,
+	}
+
+	friendsToExpand := getFriendsToExpand(job, []string{"12455", "29456"}, []common.Player{})
+
+	assert.Empty(t, friendsToExpand)
+}
+
+func TestGetFriendsToExpandReturnsAllFriendsWhenThereAreNoFilters(t *testing.T) {
+	job := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 1,
+	}
+	friendIDs := []string{"12455", "29456"}
+
+	friendsToExpand := getFriendsToExpand(job, friendIDs, []common.Player{})
+
+	assert.Equal(t, friendIDs, friendsToExpand)
+}
+
+func TestGetFriendsToExpandOnlyReturnsFriendsThatPassTheFilters(t *testing.T) {
+	job := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 1,
+		Filters: datastructures.CrawlFilters{
+			Countries:         []string{"IE"},
+			MinAccountAgeDays: 365,
+		},
+	}
+	threeYearsAgo := int(time.Now().AddDate(-3, 0, 0).Unix())
+	lastWeek := int(time.Now().AddDate(0, 0, -7).Unix())
+	friendSummaries := []common.Player{
+		{Steamid: "12455", Loccountrycode: "IE", Timecreated: threeYearsAgo},
+		{Steamid: "29456", Loccountrycode: "DE", Timecreated: threeYearsAgo},
+		{Steamid: "05838", Loccountrycode: "IE", Timecreated: lastWeek},
+		{Steamid: "54954", Loccountrycode: "", Timecreated: threeYearsAgo},
+		{Steamid: "45967", Loccountrycode: "ie", Timecreated: threeYearsAgo},
+	}
+
+	friendsToExpand := getFriendsToExpand(job, getSteamIDsFromPlayers(friendSummaries), friendSummaries)
+
+	assert.Equal(t, []string{"12455", "45967"}, friendsToExpand)
+}
+
+func TestUserPassesGamesFilterAlwaysPassesTheOriginalCrawlTarget(t *testing.T) {
+	job := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 1,
+		Filters: datastructures.CrawlFilters{
+			MinGames: 10,
+		},
+	}
+
+	assert.True(t, userPassesGamesFilter(job, 0, false))
+}
+
+func TestUserPassesGamesFilterForStoredUsersWithFiftyGames(t *testing.T) {
+	job := datastructures.Job{
+		MaxLevel:     3,
+		CurrentLevel: 2,
+		Filters: datastructures.CrawlFilters{
+			MinGames: 100,
+		},
+	}
+
+	assert.True(t, userPassesGamesFilter(job, 50, true))
+	assert.False(t, userPassesGamesFilter(job, 49, true))
+	assert.False(t, userPassesGamesFilter(job, 50, false))
+}
+
+func TestWorkerSkipsStoredUserWithTooFewGames(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  testUser.AccDetails.SteamID,
+		CrawlID:               "testcrawlID",
+		MaxLevel:              3,
+		CurrentLevel:          2,
+		Filters: datastructures.CrawlFilters{
+			MinGames: 5,
+		},
+	}
+	mockController.On("GetUserFromDataStore", job.CurrentTargetSteamID).Return(testUser, nil)
+	mockController.On("SaveCrawlingStatsToDataStore", job.CurrentLevel, mock.MatchedBy(func(crawlingStatus datastructures.CrawlingStatus) bool {
+		return crawlingStatus.TotalUsersToCrawl == 0
+	})).Return(true, nil)
+
+	Worker(mockController, job)
+
+	mockController.AssertNumberOfCalls(t, "SaveCrawlingStatsToDataStore", 1)
+	mockController.AssertNotCalled(t, "PublishToJobsQueue", mock.Anything)
+}
+
+func TestRunJobCountsAJobThatPanicsAsFailed(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  "54321",
+		CrawlID:               "testcrawlID",
+		MaxLevel:              3,
+		CurrentLevel:          2,
+		Budget: datastructures.CrawlBudget{
+			MaxAPICalls: 100,
+		},
+	}
+	mockController.On("GetCrawlingStatsFromDataStore", job.CrawlID).Return(datastructures.CrawlingStatus{}, errors.New("datastore is down"))
+	mockController.On("SaveCrawlingStatsToDataStore", job.CurrentLevel, mock.MatchedBy(func(crawlingStatus datastructures.CrawlingStatus) bool {
+		return crawlingStatus.FailedJobs == 1 && crawlingStatus.TotalUsersToCrawl == 0
+	})).Return(true, nil)
+
+	assert.NotPanics(t, func() {
+		runJob(mockController, job)
+	})
+
+	mockController.AssertNumberOfCalls(t, "SaveCrawlingStatsToDataStore", 1)
+}
+
+func TestRunJobCountsAJobWhoseAPICallsFailAsFailed(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	noUserFound := common.UserDocument{}
+	job := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  "54321",
+		CrawlID:               "testcrawlID",
+		MaxLevel:              3,
+		CurrentLevel:          2,
+	}
+	mockController.On("GetUserFromDataStore", job.CurrentTargetSteamID).Return(noUserFound, nil)
+	mockController.On("CallGetFriends", job.CurrentTargetSteamID).Return(testUser.FriendIDs, nil)
+	mockController.On("CallGetPlayerSummaries", mock.AnythingOfType("string")).Return([]common.Player{}, errors.New("steam is down"))
+	mockController.On("CallGetOwnedGames", job.CurrentTargetSteamID).Return(common.GamesOwnedResponse{}, nil)
+	mockController.On("SaveCrawlingStatsToDataStore", job.CurrentLevel, mock.MatchedBy(func(crawlingStatus datastructures.CrawlingStatus) bool {
+		return crawlingStatus.FailedJobs == 1 && crawlingStatus.TotalUsersToCrawl == 0
+	})).Return(true, nil)
+
+	assert.NotPanics(t, func() {
+		runJob(mockController, job)
+	})
+
+	mockController.AssertNumberOfCalls(t, "SaveCrawlingStatsToDataStore", 1)
+	mockController.AssertNotCalled(t, "SaveUserToDataStore", mock.Anything)
+}
+
+func TestRunJobDoesNotCountAStoredUserAsFailedWhenPublishingFriendsFails(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  testUser.AccDetails.SteamID,
+		CrawlID:               "testcrawlID",
+		MaxLevel:              3,
+		CurrentLevel:          2,
+	}
+	mockController.On("GetUserFromDataStore", job.CurrentTargetSteamID).Return(testUser, nil)
+	mockController.On("SaveCrawlingStatsToDataStore", job.CurrentLevel, mock.AnythingOfType("datastructures.CrawlingStatus")).Return(true, nil)
+	mockController.On("PublishToJobsQueue", mock.Anything).Return(errors.New("queue is down"))
+	mockController.On("Sleep", mock.Anything).Return()
+
+	statsSaved, err := Worker(mockController, job)
+	assert.True(t, statsSaved)
+	assert.Error(t, err)
+
+	assert.NotPanics(t, func() {
+		runJob(mockController, job)
+	})
+
+	mockController.AssertNumberOfCalls(t, "SaveCrawlingStatsToDataStore", 2)
+	for _, call := range mockController.Calls {
+		if call.Method == "SaveCrawlingStatsToDataStore" {
+			assert.Equal(t, 0, call.Arguments.Get(1).(datastructures.CrawlingStatus).FailedJobs)
+		}
+	}
+}
+
+func TestCountJobAsPrivateRecordsAPrivateProfile(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	job := datastructures.Job{
+		JobType:               "crawl",
+		OriginalTargetSteamID: "12345",
+		CurrentTargetSteamID:  "54321",
+		CrawlID:               "testcrawlID",
+		MaxLevel:              3,
+		CurrentLevel:          3,
+	}
+	mockController.On("SaveCrawlingStatsToDataStore", job.CurrentLevel, mock.MatchedBy(func(crawlingStatus datastructures.CrawlingStatus) bool {
+		return crawlingStatus.PrivateProfiles == 1 && crawlingStatus.FailedJobs == 0
+	})).Return(true, nil)
+
+	countJobAsPrivate(mockController, job)
+
+	mockController.AssertNumberOfCalls(t, "SaveCrawlingStatsToDataStore", 1)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/IamCathal/neo/services/datastore/configuration"
 	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
 	"github.com/neosteamfriendgraphing/common"
 	commonUtil "github.com/neosteamfriendgraphing/common/util"
 	"go.mongodb.org/mongo-driver/bson"
@@ -29,23 +30,38 @@ func SaveUserToDB(cntr controller.CntrInterface, userDocument common.UserDocumen
 		InsertionTime: time.Now().Unix(),
 	}
 
-	bsonObj, err := bson.Marshal(UserDocument)
+	// The previously stored friends are needed to record how
+	// the user's friend list has changed since they were last saved
+	storedUser, err := cntr.GetUser(context.TODO(), userDocument.AccDetails.SteamID)
 	if err != nil {
-		return commonUtil.MakeErr(err)
+		return err
 	}
 
-	userCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("USER_COLLECTION"))
-	_, err = cntr.InsertOne(context.TODO(), userCollection, bsonObj)
-	return err
+	// Users are refetched once their stored data is older than the
+	// crawl's freshness window so the stored document is replaced
+	if err := cntr.UpsertUser(context.TODO(), UserDocument); err != nil {
+		return err
+	}
+
+	if storedUser.AccDetails.SteamID == "" {
+		return nil
+	}
+	friendListChange := getFriendListChange(UserDocument.AccDetails.SteamID, storedUser.FriendIDs, UserDocument.FriendIDs)
+	if !friendListHasChanged(friendListChange) {
+		return nil
+	}
+	return cntr.SaveFriendListChange(context.TODO(), friendListChange)
 }
 
-func SaveCrawlingStatsToDB(cntr controller.CntrInterface, currentLevel int, crawlingStatus common.CrawlingStatus) error {
+func SaveCrawlingStatsToDB(cntr controller.CntrInterface, currentLevel int, crawlingStatus datastructures.CrawlingStatus) error {
 	crawlingStatsCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("CRAWLING_STATS_COLLECTION"))
+	crawlingStatus.Levels = getLevelProgressForJob(currentLevel, crawlingStatus)
+	crawlingStatus.JobsPerMinute = map[string]int{getMinuteKey(time.Now()): 1}
 	if (currentLevel < crawlingStatus.MaxLevel) || (currentLevel == 1 && crawlingStatus.MaxLevel == 1) {
 		// Increment the users crawled counter by one and add len(friends) to
 		// totaluserstocrawl as they need to be crawled
 		crawlingStatus.TimeStarted = time.Now().Unix()
-		docExisted, err := cntr.UpdateCrawlingStatus(context.TODO(), crawlingStatsCollection, crawlingStatus)
+		docExisted, updatedCrawlingStatus, err := cntr.UpdateCrawlingStatus(context.TODO(), crawlingStatsCollection, crawlingStatus)
 		if err != nil {
 			return err
 		}
@@ -57,6 +73,21 @@ func SaveCrawlingStatsToDB(cntr controller.CntrInterface, currentLevel int, craw
 				crawlingStatus.UsersCrawled = 1
 				crawlingStatus.TotalUsersToCrawl = 1
 			}
+			// Users that will be crawled count towards the user budget
+			crawlingStatus.UsersReserved = crawlingStatus.TotalUsersToCrawl
+			crawlingStatus.APICallsMade = 0
+			crawlingStatus.Truncated = false
+			crawlingStatus.Levels = map[string]datastructures.LevelProgress{
+				"1": {
+					Discovered: crawlingStatus.TotalUsersToCrawl,
+					Completed:  crawlingStatus.UsersCrawled,
+				},
+			}
+			crawlingStatus.JobsPerMinute = nil
+			crawlingStatus.State = datastructures.CrawlStateQueued
+			crawlingStatus.StateTimestamps = map[string]int64{
+				datastructures.CrawlStateQueued: crawlingStatus.TimeStarted,
+			}
 
 			bsonObj, err := bson.Marshal(crawlingStatus)
 			if err != nil {
@@ -67,13 +98,22 @@ func SaveCrawlingStatsToDB(cntr controller.CntrInterface, currentLevel int, craw
 			if err != nil {
 				return err
 			}
+			// Crawls with no users left to crawl after the first job, such
+			// as a target without any friends, will not be saved again so
+			// they are completed here
+			if crawlingStatus.UsersCrawled >= crawlingStatus.TotalUsersToCrawl {
+				return updateCrawlStateFromProgress(cntr, crawlingStatus)
+			}
 			return nil
 		}
+		if err := updateCrawlStateFromProgress(cntr, updatedCrawlingStatus); err != nil {
+			return err
+		}
 	} else {
 		// Increment the users crawled counter by one but
 		// do not increment users to crawl since we're at max level
 		crawlingStatus.TotalUsersToCrawl = 0
-		docExisted, err := cntr.UpdateCrawlingStatus(context.TODO(),
+		docExisted, updatedCrawlingStatus, err := cntr.UpdateCrawlingStatus(context.TODO(),
 			crawlingStatsCollection,
 			crawlingStatus)
 		if err != nil {
@@ -85,6 +125,9 @@ func SaveCrawlingStatsToDB(cntr controller.CntrInterface, currentLevel int, craw
 			configuration.Logger.Sugar().Warnf("crawlID '%s' originalcrawltarget '%s' has no crawling status entry", crawlingStatus.CrawlID, crawlingStatus.OriginalCrawlTarget)
 			return nil
 		}
+		if err := updateCrawlStateFromProgress(cntr, updatedCrawlingStatus); err != nil {
+			return err
+		}
 	}
 
 	configuration.Logger.Info("success on update crawling stats to db")
@@ -99,20 +142,52 @@ func GetUserFromDB(cntr controller.CntrInterface, steamID string) (common.UserDo
 	return user, nil
 }
 
-func GetCrawlingStatsFromDBFromCrawlID(cntr controller.CntrInterface, crawlID string) (common.CrawlingStatus, error) {
+func GetCrawlingStatsFromDBFromCrawlID(cntr controller.CntrInterface, crawlID string) (datastructures.CrawlingStatus, error) {
 	crawlingStatus, err := cntr.GetCrawlingStatusFromDBFromCrawlID(context.TODO(), crawlID)
 	if err != nil {
-		return common.CrawlingStatus{}, err
+		return datastructures.CrawlingStatus{}, err
 	}
 	return crawlingStatus, nil
 }
 
+// UpdateCrawlBudget records the budget usage for a crawl and returns how
+// many of the requested users may be placed in the queue. No users are
+// granted for a crawl that has no crawling status
+//		usersGranted, err := UpdateCrawlBudget(cntr, crawlID, usage)
+func UpdateCrawlBudget(cntr controller.CntrInterface, crawlID string, usage datastructures.CrawlBudgetUsage) (int, error) {
+	docExisted, crawlingStatusBeforeUpdate, err := cntr.UpdateCrawlBudget(context.TODO(), crawlID, usage)
+	if err != nil {
+		return 0, err
+	}
+	if !docExisted {
+		configuration.Logger.Sugar().Warnf("crawlID '%s' has no crawling status entry to update the budget of", crawlID)
+		return 0, nil
+	}
+	return getUsersGranted(crawlingStatusBeforeUpdate, usage.UsersRequested), nil
+}
+
+// getUsersGranted works out how many users can be reserved from the budget
+// given the crawling status before the reservation was made
+func getUsersGranted(crawlingStatus datastructures.CrawlingStatus, usersRequested int) int {
+	if crawlingStatus.Budget.MaxUsers <= 0 {
+		return usersRequested
+	}
+	usersRemaining := crawlingStatus.Budget.MaxUsers - crawlingStatus.UsersReserved
+	if usersRemaining <= 0 {
+		return 0
+	}
+	if usersRequested > usersRemaining {
+		return usersRemaining
+	}
+	return usersRequested
+}
+
 func IsCurrentlyBeingCrawled(cntr controller.CntrInterface, crawlID string) (bool, string, error) {
 	crawlingStatus, err := cntr.GetCrawlingStatusFromDBFromCrawlID(context.TODO(), crawlID)
 	if err != nil {
 		return false, "", err
 	}
-	if crawlingStatus.UsersCrawled < crawlingStatus.TotalUsersToCrawl {
+	if crawlingStatus.IsCrawling() {
 		return true, crawlingStatus.OriginalCrawlTarget, nil
 	}
 	return false, "", nil
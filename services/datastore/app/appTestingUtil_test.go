@@ -1,8 +1,13 @@
 package app
 
 import (
+	"testing"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
 	"github.com/neosteamfriendgraphing/common"
 	"github.com/neosteamfriendgraphing/common/dtos"
+	"github.com/stretchr/testify/mock"
 )
 
 var (
@@ -143,3 +148,39 @@ func initTestData() {
 		},
 	}
 }
+
+func makeTestUser(steamID, username, countryCode string, friendIDs []string, games ...common.GameOwnedDocument) common.UserDocument {
+	return common.UserDocument{
+		AccDetails: common.AccDetailsDocument{
+			SteamID:        steamID,
+			Personaname:    username,
+			Loccountrycode: countryCode,
+		},
+		FriendIDs:  friendIDs,
+		GamesOwned: games,
+	}
+}
+
+func mockGetUsers(mockController *controller.MockCntrInterface, users ...common.UserDocument) {
+	mockController.On("GetUsers", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		requestedIDs := args.Get(1).([]string)
+		foundUser := args.Get(2).(func(common.UserDocument) error)
+		for _, steamID := range requestedIDs {
+			for _, user := range users {
+				if user.AccDetails.SteamID == steamID {
+					foundUser(user)
+				}
+			}
+		}
+	})
+}
+
+// waitForGraphCreationToStart waits for graph creation to be started in
+// the background
+func waitForGraphCreationToStart(t *testing.T, graphCreationStarted <-chan bool) {
+	select {
+	case <-graphCreationStarted:
+	case <-time.After(time.Second):
+		t.Fatal("graph creation was not started")
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/IamCathal/neo/services/datastore/configuration"
 	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
 	"github.com/neosteamfriendgraphing/common"
 	"github.com/neosteamfriendgraphing/common/dtos"
 	"github.com/stretchr/testify/assert"
@@ -40,31 +41,54 @@ func TestMain(m *testing.M) {
 
 func TestSaveUserToDBCallsMongoDBOnce(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
-	mockController.On("InsertOne",
+	mockController.On("GetUser",
 		mock.Anything,
+		mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser",
 		mock.Anything,
-		mock.Anything).Return(nil, nil)
+		mock.Anything).Return(nil)
 	configuration.DBClient = &mongo.Client{}
 
 	err := SaveUserToDB(mockController, testSaveUserDTO.User)
 
 	assert.Nil(t, err)
-	mockController.AssertNumberOfCalls(t, "InsertOne", 1)
+	mockController.AssertNumberOfCalls(t, "UpsertUser", 1)
+}
+
+func TestSaveUserToDBSetsTheInsertionTime(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	mockController.On("GetUser",
+		mock.Anything,
+		mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser",
+		mock.Anything,
+		mock.MatchedBy(func(user common.UserDocument) bool {
+			return user.AccDetails.SteamID == testSaveUserDTO.User.AccDetails.SteamID &&
+				user.InsertionTime > 0
+		})).Return(nil)
+	configuration.DBClient = &mongo.Client{}
+
+	err := SaveUserToDB(mockController, testSaveUserDTO.User)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "UpsertUser", 1)
 }
 
 func TestSaveUserToDBCallsReturnsErrorWhenMongoDoes(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
 	expectedError := errors.New("expected error response")
-	mockController.On("InsertOne",
+	mockController.On("GetUser",
 		mock.Anything,
+		mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser",
 		mock.Anything,
-		mock.Anything).Return(nil, expectedError)
+		mock.Anything).Return(expectedError)
 	configuration.DBClient = &mongo.Client{}
 
 	err := SaveUserToDB(mockController, testSaveUserDTO.User)
 
 	assert.EqualError(t, err, expectedError.Error())
-	mockController.AssertNumberOfCalls(t, "InsertOne", 1)
+	mockController.AssertNumberOfCalls(t, "UpsertUser", 1)
 }
 
 func TestSaveCrawlingStatsToDBForExistingUserAtMaxLevelOnlyCallsUpdate(t *testing.T) {
@@ -75,14 +99,16 @@ func TestSaveCrawlingStatsToDBForExistingUserAtMaxLevelOnlyCallsUpdate(t *testin
 	mockController.On("UpdateCrawlingStatus",
 		mock.Anything,
 		mock.Anything,
-		mock.Anything).Return(true, nil)
+		mock.Anything).Return(true, datastructures.CrawlingStatus{}, nil)
 	configuration.DBClient = &mongo.Client{}
 
-	crawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: maxLevelTestSaveUserDTO.User.AccDetails.SteamID,
-		MaxLevel:            maxLevelTestSaveUserDTO.MaxLevel,
-		CrawlID:             maxLevelTestSaveUserDTO.CrawlID,
-		TotalUsersToCrawl:   len(maxLevelTestSaveUserDTO.User.FriendIDs),
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: maxLevelTestSaveUserDTO.User.AccDetails.SteamID,
+			MaxLevel:            maxLevelTestSaveUserDTO.MaxLevel,
+			CrawlID:             maxLevelTestSaveUserDTO.CrawlID,
+			TotalUsersToCrawl:   len(maxLevelTestSaveUserDTO.User.FriendIDs),
+		},
 	}
 	err := SaveCrawlingStatsToDB(mockController, maxLevelTestSaveUserDTO.MaxLevel, crawlingStatus)
 
@@ -99,7 +125,7 @@ func TestSaveCrawlingStatsToDBCallsUpdateAndThenInsertForNewUser(t *testing.T) {
 	mockController.On("UpdateCrawlingStatus",
 		mock.Anything,
 		mock.Anything,
-		mock.Anything).Return(false, nil)
+		mock.Anything).Return(false, datastructures.CrawlingStatus{}, nil)
 
 	// Return valid for insertion of new record
 	mockController.On("InsertOne",
@@ -107,11 +133,13 @@ func TestSaveCrawlingStatsToDBCallsUpdateAndThenInsertForNewUser(t *testing.T) {
 		mock.Anything,
 		mock.Anything).Return(nil, nil)
 
-	crawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: testSaveUserDTO.User.AccDetails.SteamID,
-		MaxLevel:            testSaveUserDTO.MaxLevel,
-		CrawlID:             testSaveUserDTO.CrawlID,
-		TotalUsersToCrawl:   len(testSaveUserDTO.User.FriendIDs),
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: testSaveUserDTO.User.AccDetails.SteamID,
+			MaxLevel:            testSaveUserDTO.MaxLevel,
+			CrawlID:             testSaveUserDTO.CrawlID,
+			TotalUsersToCrawl:   len(testSaveUserDTO.User.FriendIDs),
+		},
 	}
 	err := SaveCrawlingStatsToDB(mockController, 1, crawlingStatus)
 
@@ -120,6 +148,42 @@ func TestSaveCrawlingStatsToDBCallsUpdateAndThenInsertForNewUser(t *testing.T) {
 	mockController.AssertNumberOfCalls(t, "InsertOne", 1)
 }
 
+func TestSaveCrawlingStatsToDBCompletesTheCrawlOfATargetWithNoFriends(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	configuration.DBClient = &mongo.Client{}
+
+	mockController.On("UpdateCrawlingStatus",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything).Return(false, datastructures.CrawlingStatus{}, nil)
+	mockController.On("InsertOne",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything).Return(nil, nil)
+	mockController.On("UpdateCrawlState", mock.Anything, testSaveUserDTO.CrawlID, datastructures.CrawlStateCrawling, "", mock.AnythingOfType("int64")).Return(true, nil)
+	mockController.On("UpdateCrawlState", mock.Anything, testSaveUserDTO.CrawlID, datastructures.CrawlStateCrawlComplete, "", mock.AnythingOfType("int64")).Return(true, nil)
+	graphCreationStarted := make(chan bool)
+	mockController.On("StartGraphCreation", testSaveUserDTO.CrawlID).Return(nil).Run(func(args mock.Arguments) {
+		graphCreationStarted <- true
+	})
+
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: testSaveUserDTO.User.AccDetails.SteamID,
+			MaxLevel:            3,
+			CrawlID:             testSaveUserDTO.CrawlID,
+			TotalUsersToCrawl:   0,
+		},
+	}
+	err := SaveCrawlingStatsToDB(mockController, 1, crawlingStatus)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "InsertOne", 1)
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlState", 2)
+	waitForGraphCreationToStart(t, graphCreationStarted)
+	mockController.AssertNumberOfCalls(t, "StartGraphCreation", 1)
+}
+
 func TestSaveCrawlingStatsToDBReturnsNilWhenFailsToIncrementUsersCrawledForUserOnMaxLevel(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
 	configuration.DBClient = &mongo.Client{}
@@ -130,7 +194,7 @@ func TestSaveCrawlingStatsToDBReturnsNilWhenFailsToIncrementUsersCrawledForUserO
 	mockController.On("UpdateCrawlingStatus",
 		mock.Anything,
 		mock.Anything,
-		mock.Anything).Return(false, nil).Once()
+		mock.Anything).Return(false, datastructures.CrawlingStatus{}, nil).Once()
 
 	// Return an error when this max level user cannot be updated
 	mockController.On("UpdateCrawlingStatus",
@@ -138,13 +202,15 @@ func TestSaveCrawlingStatsToDBReturnsNilWhenFailsToIncrementUsersCrawledForUserO
 		mock.Anything,
 		maxLevelTestSaveUserDTO,
 		mock.AnythingOfType("int"),
-		mock.AnythingOfType("int")).Return(false, nil).Once()
-
-	crawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: testSaveUserDTO.User.AccDetails.SteamID,
-		MaxLevel:            testSaveUserDTO.MaxLevel,
-		CrawlID:             testSaveUserDTO.CrawlID,
-		TotalUsersToCrawl:   len(testSaveUserDTO.User.FriendIDs),
+		mock.AnythingOfType("int")).Return(false, datastructures.CrawlingStatus{}, nil).Once()
+
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: testSaveUserDTO.User.AccDetails.SteamID,
+			MaxLevel:            testSaveUserDTO.MaxLevel,
+			CrawlID:             testSaveUserDTO.CrawlID,
+			TotalUsersToCrawl:   len(testSaveUserDTO.User.FriendIDs),
+		},
 	}
 	err := SaveCrawlingStatsToDB(mockController, testSaveUserDTO.MaxLevel, crawlingStatus)
 
@@ -179,9 +245,11 @@ func TestGetCrawlingStatsFromDBFromCrawlID(t *testing.T) {
 	configuration.DBClient = &mongo.Client{}
 
 	crawlID := "crawlID"
-	expectedCrawlingStatus := common.CrawlingStatus{
-		TimeStarted: time.Now().Unix(),
-		CrawlID:     crawlID,
+	expectedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted: time.Now().Unix(),
+			CrawlID:     crawlID,
+		},
 	}
 	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, crawlID).Return(expectedCrawlingStatus, nil)
 
@@ -198,7 +266,7 @@ func TestGetCrawlingStatsFromDBReturnsAnErrorWhenControllerMethodDoes(t *testing
 
 	crawlID := "crawlID"
 	expectedError := errors.New("expected error")
-	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, crawlID).Return(common.CrawlingStatus{}, expectedError)
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, crawlID).Return(datastructures.CrawlingStatus{}, expectedError)
 
 	crawlingStatus, err := GetCrawlingStatsFromDBFromCrawlID(mockController, crawlID)
 
@@ -210,10 +278,12 @@ func TestGetCrawlingStatsFromDBReturnsAnErrorWhenControllerMethodDoes(t *testing
 func TestIsCurrentlyBeingCrawledReturnsTrueForAnActiveCrawl(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
 
-	activeCrawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: "steamID",
-		TotalUsersToCrawl:   140,
-		UsersCrawled:        95,
+	activeCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: "steamID",
+			TotalUsersToCrawl:   140,
+			UsersCrawled:        95,
+		},
 	}
 	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything).Return(activeCrawlingStatus, nil)
 
@@ -228,7 +298,7 @@ func TestIsCurrentlyBeingCrawledReturnsAnErrorWhenAnErrorISReturnedSearchingForC
 	mockController := &controller.MockCntrInterface{}
 
 	randomError := errors.New("random error")
-	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything).Return(common.CrawlingStatus{}, randomError)
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything).Return(datastructures.CrawlingStatus{}, randomError)
 
 	isActive, username, err := IsCurrentlyBeingCrawled(mockController, "crawlID")
 
@@ -239,10 +309,12 @@ func TestIsCurrentlyBeingCrawledReturnsAnErrorWhenAnErrorISReturnedSearchingForC
 
 func TestIsCurrentlyBeingCrawledReturnsAnEmptyUsernameAndNoErrorWhenTheCrawlIsNotFinished(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
-	activeCrawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: "steamID",
-		TotalUsersToCrawl:   140,
-		UsersCrawled:        140,
+	activeCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: "steamID",
+			TotalUsersToCrawl:   140,
+			UsersCrawled:        140,
+		},
 	}
 
 	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything).Return(activeCrawlingStatus, nil)
@@ -253,3 +325,72 @@ func TestIsCurrentlyBeingCrawledReturnsAnEmptyUsernameAndNoErrorWhenTheCrawlIsNo
 	assert.Equal(t, "", username)
 	assert.Nil(t, err)
 }
+
+func TestUpdateCrawlBudgetGrantsAllUsersWhenThereIsNoUserBudget(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	budgetUsage := datastructures.CrawlBudgetUsage{
+		APICallsMade:   4,
+		UsersRequested: 250,
+	}
+	crawlingStatusBeforeUpdate := datastructures.CrawlingStatus{
+		Budget: datastructures.CrawlBudget{
+			MaxAPICalls: 1000,
+		},
+	}
+	mockController.On("UpdateCrawlBudget", mock.Anything, "crawlID", budgetUsage).Return(true, crawlingStatusBeforeUpdate, nil)
+
+	usersGranted, err := UpdateCrawlBudget(mockController, "crawlID", budgetUsage)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 250, usersGranted)
+}
+
+func TestUpdateCrawlBudgetGrantsNoUsersForACrawlWithNoCrawlingStatus(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	budgetUsage := datastructures.CrawlBudgetUsage{
+		UsersRequested: 20,
+	}
+	mockController.On("UpdateCrawlBudget", mock.Anything, "crawlID", budgetUsage).Return(false, datastructures.CrawlingStatus{}, nil)
+
+	usersGranted, err := UpdateCrawlBudget(mockController, "crawlID", budgetUsage)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, usersGranted)
+}
+
+func TestUpdateCrawlBudgetReturnsAnErrorWhenControllerMethodDoes(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	expectedError := errors.New("expected error")
+	mockController.On("UpdateCrawlBudget", mock.Anything, "crawlID", mock.Anything).Return(false, datastructures.CrawlingStatus{}, expectedError)
+
+	usersGranted, err := UpdateCrawlBudget(mockController, "crawlID", datastructures.CrawlBudgetUsage{})
+
+	assert.Equal(t, expectedError, err)
+	assert.Equal(t, 0, usersGranted)
+}
+
+func TestGetUsersGrantedOnlyGrantsTheRemainingUserBudget(t *testing.T) {
+	crawlingStatus := datastructures.CrawlingStatus{
+		Budget: datastructures.CrawlBudget{
+			MaxUsers: 100,
+		},
+		UsersReserved: 90,
+	}
+
+	assert.Equal(t, 5, getUsersGranted(crawlingStatus, 5))
+	assert.Equal(t, 10, getUsersGranted(crawlingStatus, 40))
+}
+
+func TestGetUsersGrantedGrantsNoUsersWhenTheUserBudgetIsUsedUp(t *testing.T) {
+	crawlingStatus := datastructures.CrawlingStatus{
+		Budget: datastructures.CrawlBudget{
+			MaxUsers: 100,
+		},
+		UsersReserved: 100,
+	}
+
+	assert.Equal(t, 0, getUsersGranted(crawlingStatus, 15))
+}
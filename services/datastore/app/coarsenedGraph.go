@@ -0,0 +1,18 @@
+package app
+
+import (
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/IamCathal/neo/services/datastore/graphing"
+)
+
+// GetCoarsenedGraph gets the processed graph of a crawl with users
+// collapsed into super nodes until it has at most maxNodes nodes. False
+// is returned if the crawl has not been graphed
+func GetCoarsenedGraph(cntr controller.CntrInterface, crawlID string, maxNodes int, expanded []string) (bool, datastructures.CoarsenedGraph, error) {
+	exists, export, err := GetGraphExport(cntr, crawlID)
+	if err != nil || !exists {
+		return false, datastructures.CoarsenedGraph{}, err
+	}
+	return true, graphing.CoarsenGraph(export, maxNodes, expanded), nil
+}
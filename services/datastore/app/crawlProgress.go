@@ -0,0 +1,108 @@
+package app
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+)
+
+// throughputWindow is how far back jobs are counted when working
+// out the current throughput of a crawl
+const throughputWindow = 5 * time.Minute
+
+// getLevelProgressForJob returns the level progress to record for a job
+// at the given level. The job is completed at its own level and the
+// friends it queued are discovered at the next level
+func getLevelProgressForJob(currentLevel int, crawlingStatus datastructures.CrawlingStatus) map[string]datastructures.LevelProgress {
+	levels := map[string]datastructures.LevelProgress{
+		strconv.Itoa(currentLevel): {Completed: 1},
+	}
+	if currentLevel < crawlingStatus.MaxLevel && crawlingStatus.TotalUsersToCrawl > 0 {
+		levels[strconv.Itoa(currentLevel+1)] = datastructures.LevelProgress{
+			Discovered: crawlingStatus.TotalUsersToCrawl,
+		}
+	}
+	return levels
+}
+
+func getMinuteKey(timestamp time.Time) string {
+	return strconv.FormatInt(timestamp.Unix()/60, 10)
+}
+
+// AddCrawlProgress works out the throughput, estimated total users,
+// estimated progress and ETA of a crawl from its stored counts
+func AddCrawlProgress(crawlingStatus datastructures.CrawlingStatus, now time.Time) datastructures.CrawlingStatus {
+	crawlingStatus.EstimatedTotalUsers = estimateTotalUsers(crawlingStatus)
+	crawlingStatus.EstimatedProgress = 100
+	if crawlingStatus.EstimatedTotalUsers > 0 && crawlingStatus.UsersCrawled < crawlingStatus.EstimatedTotalUsers {
+		crawlingStatus.EstimatedProgress = crawlingStatus.UsersCrawled * 100 / crawlingStatus.EstimatedTotalUsers
+	}
+
+	crawlingStatus.UsersPerMinute = getUsersPerMinute(crawlingStatus, now)
+	crawlingStatus.ETASeconds = 0
+	usersRemaining := crawlingStatus.EstimatedTotalUsers - crawlingStatus.UsersCrawled
+	if usersRemaining > 0 && crawlingStatus.UsersPerMinute > 0 {
+		crawlingStatus.ETASeconds = int64(math.Ceil(float64(usersRemaining) / crawlingStatus.UsersPerMinute * 60))
+	}
+	return crawlingStatus
+}
+
+// estimateTotalUsers estimates how many users a crawl will have once it
+// has finished. The users discovered at a level keep growing until every
+// user at the level above has been crawled, so the amount discovered so
+// far is scaled up by how much of the level above is left to be crawled
+func estimateTotalUsers(crawlingStatus datastructures.CrawlingStatus) int {
+	// Crawls started before levels were recorded only have totals
+	if len(crawlingStatus.Levels) == 0 || crawlingStatus.UsersCrawled >= crawlingStatus.TotalUsersToCrawl {
+		return crawlingStatus.TotalUsersToCrawl
+	}
+
+	estimatedUsersAtPreviousLevel := float64(crawlingStatus.Levels["1"].Discovered)
+	estimatedTotalUsers := estimatedUsersAtPreviousLevel
+	for level := 2; level <= crawlingStatus.MaxLevel; level++ {
+		discovered := float64(crawlingStatus.Levels[strconv.Itoa(level)].Discovered)
+		previousLevelCompleted := float64(crawlingStatus.Levels[strconv.Itoa(level-1)].Completed)
+
+		estimatedUsersAtLevel := discovered
+		if previousLevelCompleted > 0 && previousLevelCompleted < estimatedUsersAtPreviousLevel {
+			estimatedUsersAtLevel = discovered / previousLevelCompleted * estimatedUsersAtPreviousLevel
+		}
+		estimatedTotalUsers += estimatedUsersAtLevel
+		estimatedUsersAtPreviousLevel = estimatedUsersAtLevel
+	}
+
+	if int(math.Round(estimatedTotalUsers)) < crawlingStatus.TotalUsersToCrawl {
+		return crawlingStatus.TotalUsersToCrawl
+	}
+	return int(math.Round(estimatedTotalUsers))
+}
+
+// getUsersPerMinute returns the average amount of jobs processed per
+// minute over the throughput window, or since the crawl started if
+// that was more recent
+func getUsersPerMinute(crawlingStatus datastructures.CrawlingStatus, now time.Time) float64 {
+	windowStart := now.Add(-throughputWindow)
+	if timeStarted := time.Unix(crawlingStatus.TimeStarted, 0); timeStarted.After(windowStart) {
+		windowStart = timeStarted
+	}
+
+	jobsInWindow := 0
+	for minuteKey, jobs := range crawlingStatus.JobsPerMinute {
+		minute, err := strconv.ParseInt(minuteKey, 10, 64)
+		if err != nil {
+			continue
+		}
+		minuteEnd := (minute + 1) * 60
+		if minuteEnd > windowStart.Unix() && minute*60 <= now.Unix() {
+			jobsInWindow += jobs
+		}
+	}
+
+	windowLength := now.Sub(windowStart)
+	if windowLength < time.Minute {
+		windowLength = time.Minute
+	}
+	return float64(jobsInWindow) / windowLength.Minutes()
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/configuration"
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSaveCrawlingStatsToDBRecordsTheJobAsCompletedAndItsFriendsAsDiscoveredAtTheNextLevel(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	configuration.DBClient = &mongo.Client{}
+
+	expectedLevels := map[string]datastructures.LevelProgress{
+		"2": {Completed: 1},
+		"3": {Discovered: 12},
+	}
+	mockController.On("UpdateCrawlingStatus",
+		mock.Anything,
+		mock.Anything,
+		mock.MatchedBy(func(crawlingStatus datastructures.CrawlingStatus) bool {
+			return assert.ObjectsAreEqual(expectedLevels, crawlingStatus.Levels) &&
+				len(crawlingStatus.JobsPerMinute) == 1
+		})).Return(true, datastructures.CrawlingStatus{}, nil)
+
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			MaxLevel:          3,
+			TotalUsersToCrawl: 12,
+		},
+	}
+	err := SaveCrawlingStatsToDB(mockController, 2, crawlingStatus)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlingStatus", 1)
+}
+
+func TestAddCrawlProgressUsesTheTotalUsersForACompletedCrawl(t *testing.T) {
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			MaxLevel:          2,
+			TotalUsersToCrawl: 13,
+			UsersCrawled:      13,
+		},
+		Levels: map[string]datastructures.LevelProgress{
+			"1": {Discovered: 1, Completed: 1},
+			"2": {Discovered: 12, Completed: 12},
+		},
+	}
+
+	progress := AddCrawlProgress(crawlingStatus, time.Now())
+
+	assert.Equal(t, 13, progress.EstimatedTotalUsers)
+	assert.Equal(t, 100, progress.EstimatedProgress)
+	assert.Equal(t, int64(0), progress.ETASeconds)
+}
+
+func TestAddCrawlProgressExtrapolatesUsersAtLevelsThatAreStillBeingDiscovered(t *testing.T) {
+	// One of the four users at level two has been crawled and they
+	// had ten friends, so forty users are expected at level three
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			MaxLevel:          3,
+			TotalUsersToCrawl: 15,
+			UsersCrawled:      2,
+		},
+		Levels: map[string]datastructures.LevelProgress{
+			"1": {Discovered: 1, Completed: 1},
+			"2": {Discovered: 4, Completed: 1},
+			"3": {Discovered: 10},
+		},
+	}
+
+	progress := AddCrawlProgress(crawlingStatus, time.Now())
+
+	assert.Equal(t, 45, progress.EstimatedTotalUsers)
+	assert.Equal(t, 4, progress.EstimatedProgress)
+}
+
+func TestAddCrawlProgressFallsBackToTheTotalUsersWhenNoLevelsAreRecorded(t *testing.T) {
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			MaxLevel:          3,
+			TotalUsersToCrawl: 200,
+			UsersCrawled:      50,
+		},
+	}
+
+	progress := AddCrawlProgress(crawlingStatus, time.Now())
+
+	assert.Equal(t, 200, progress.EstimatedTotalUsers)
+	assert.Equal(t, 25, progress.EstimatedProgress)
+}
+
+func TestAddCrawlProgressWorksOutThroughputAndETAFromRecentJobs(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	currentMinute := now.Unix() / 60
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted:       now.Add(-time.Hour).Unix(),
+			MaxLevel:          2,
+			TotalUsersToCrawl: 150,
+			UsersCrawled:      50,
+		},
+		Levels: map[string]datastructures.LevelProgress{
+			"1": {Discovered: 1, Completed: 1},
+			"2": {Discovered: 149, Completed: 49},
+		},
+		JobsPerMinute: map[string]int{
+			strconv.FormatInt(currentMinute, 10):   20,
+			strconv.FormatInt(currentMinute-2, 10): 30,
+			// Outside of the throughput window
+			strconv.FormatInt(currentMinute-30, 10): 1000,
+		},
+	}
+
+	progress := AddCrawlProgress(crawlingStatus, now)
+
+	assert.Equal(t, float64(10), progress.UsersPerMinute)
+	assert.Equal(t, int64(600), progress.ETASeconds)
+}
+
+func TestAddCrawlProgressUsesTheTimeSinceTheCrawlStartedForNewCrawls(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted:       now.Add(-2 * time.Minute).Unix(),
+			MaxLevel:          2,
+			TotalUsersToCrawl: 100,
+			UsersCrawled:      20,
+		},
+		JobsPerMinute: map[string]int{
+			strconv.FormatInt(now.Unix()/60, 10): 20,
+		},
+	}
+
+	progress := AddCrawlProgress(crawlingStatus, now)
+
+	assert.Equal(t, float64(10), progress.UsersPerMinute)
+	assert.Equal(t, int64(480), progress.ETASeconds)
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/configuration"
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+)
+
+// graphCreationGracePeriod is how long a crawl can stay complete before
+// its graph creation is assumed to have failed to start. Crawlers move
+// crawls to the graphing state as soon as they are asked to graph them
+const graphCreationGracePeriod = 2 * time.Minute
+
+// graphingLeaseTimeout is how long the graph build of a graphing crawl
+// can go without renewing its lease before it is assumed to have been
+// lost, such as when the crawler running it was restarted
+const graphingLeaseTimeout = 5 * time.Minute
+
+// TransitionCrawlState moves a crawl to the given state. False is returned
+// if the crawl is not in a state that is allowed to move to the new state.
+// Graph builds give the lease they hold on the crawl while it is graphing
+func TransitionCrawlState(cntr controller.CntrInterface, crawlID, newState, leaseID string) (bool, error) {
+	if len(datastructures.GetPreviousCrawlStates(newState)) == 0 {
+		return false, fmt.Errorf("crawls cannot be moved to state '%s'", newState)
+	}
+	return cntr.UpdateCrawlState(context.TODO(), crawlID, newState, leaseID, time.Now().Unix())
+}
+
+// updateCrawlStateFromProgress moves a crawl that has started crawling
+// users out of the queued state and marks it as complete once every user
+// has been crawled. Several jobs can see the same progress at once so a
+// transition that has already been made by another job is ignored
+func updateCrawlStateFromProgress(cntr controller.CntrInterface, crawlingStatus datastructures.CrawlingStatus) error {
+	currentState := crawlingStatus.State
+	if currentState == datastructures.CrawlStateQueued {
+		if _, err := TransitionCrawlState(cntr, crawlingStatus.CrawlID, datastructures.CrawlStateCrawling, ""); err != nil {
+			return err
+		}
+		currentState = datastructures.CrawlStateCrawling
+	}
+	if currentState == datastructures.CrawlStateCrawling &&
+		crawlingStatus.UsersCrawled >= crawlingStatus.TotalUsersToCrawl {
+		transitioned, err := TransitionCrawlState(cntr, crawlingStatus.CrawlID, datastructures.CrawlStateCrawlComplete, "")
+		if err != nil {
+			return err
+		}
+		// Only the job that completed the crawl starts its graph
+		// creation so that it is started exactly once. It is started in
+		// the background so that saving the job does not wait on the
+		// crawler, crawls it fails for are retried by the monitor
+		if transitioned {
+			configuration.Logger.Sugar().Infof("crawlID %s has finished crawling, starting graph creation", crawlingStatus.CrawlID)
+			go startGraphCreation(cntr, crawlingStatus.CrawlID)
+		}
+	}
+	return nil
+}
+
+// startGraphCreation starts the graph creation for a crawl. Failing to
+// do so is only logged as the crawl itself has been saved successfully
+// and the crawler can still be asked to create the graph later
+func startGraphCreation(cntr controller.CntrInterface, crawlID string) {
+	if err := cntr.StartGraphCreation(crawlID); err != nil {
+		configuration.Logger.Sugar().Errorf("failed to start graph creation for crawlID %s: %+v", crawlID, err)
+	}
+}
+
+// RetryStalledGraphCreations starts the graph creation again for crawls
+// that have been complete for longer than the grace period, such as when
+// the crawler could not be reached when the crawl finished, and for crawls
+// whose graph build has not renewed its lease within the lease timeout
+func RetryStalledGraphCreations(cntr controller.CntrInterface, now time.Time) error {
+	reclaimedCrawlIDs, err := cntr.ReclaimExpiredGraphingLeases(context.TODO(), now.Add(-graphingLeaseTimeout).Unix(), now.Unix())
+	if err != nil {
+		return err
+	}
+	for _, crawlID := range reclaimedCrawlIDs {
+		configuration.Logger.Sugar().Infof("the graph build for crawlID %s stopped renewing its lease, starting graph creation again", crawlID)
+		startGraphCreation(cntr, crawlID)
+	}
+
+	stalledCrawls, err := cntr.GetCrawlsInState(context.TODO(), datastructures.CrawlStateCrawlComplete, now.Add(-graphCreationGracePeriod).Unix())
+	if err != nil {
+		return err
+	}
+	for _, crawlingStatus := range stalledCrawls {
+		configuration.Logger.Sugar().Infof("crawlID %s has been complete since %d without being graphed, starting graph creation again",
+			crawlingStatus.CrawlID, crawlingStatus.StateTimestamps[datastructures.CrawlStateCrawlComplete])
+		startGraphCreation(cntr, crawlingStatus.CrawlID)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpdateCrawlStateFromProgressMovesQueuedCrawlsToCrawling(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			CrawlID:           "testcrawlID",
+			TotalUsersToCrawl: 12,
+			UsersCrawled:      1,
+		},
+		State: datastructures.CrawlStateQueued,
+	}
+	mockController.On("UpdateCrawlState", mock.Anything, crawlingStatus.CrawlID, datastructures.CrawlStateCrawling, "", mock.AnythingOfType("int64")).Return(true, nil)
+
+	err := updateCrawlStateFromProgress(mockController, crawlingStatus)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlState", 1)
+}
+
+func TestUpdateCrawlStateFromProgressCompletesCrawlsOnceEveryUserHasBeenCrawled(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			CrawlID:           "testcrawlID",
+			TotalUsersToCrawl: 12,
+			UsersCrawled:      12,
+		},
+		State: datastructures.CrawlStateCrawling,
+	}
+	// Another job may have already completed the crawl
+	mockController.On("UpdateCrawlState", mock.Anything, crawlingStatus.CrawlID, datastructures.CrawlStateCrawlComplete, "", mock.AnythingOfType("int64")).Return(false, nil)
+
+	err := updateCrawlStateFromProgress(mockController, crawlingStatus)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlState", 1)
+	mockController.AssertNotCalled(t, "StartGraphCreation", mock.Anything)
+}
+
+func TestUpdateCrawlStateFromProgressDoesNothingForCrawlsThatHaveMovedOn(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			CrawlID:           "testcrawlID",
+			TotalUsersToCrawl: 12,
+			UsersCrawled:      13,
+		},
+		State: datastructures.CrawlStateGraphing,
+	}
+
+	err := updateCrawlStateFromProgress(mockController, crawlingStatus)
+
+	assert.Nil(t, err)
+	mockController.AssertNotCalled(t, "UpdateCrawlState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransitionCrawlStateReturnsAnErrorForStatesThatCannotBeMovedTo(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	transitioned, err := TransitionCrawlState(mockController, "testcrawlID", datastructures.CrawlStateQueued, "")
+
+	assert.False(t, transitioned)
+	assert.EqualError(t, err, "crawls cannot be moved to state 'queued'")
+}
+
+func TestUpdateCrawlStateFromProgressStartsGraphCreationForTheJobThatCompletesTheCrawl(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			CrawlID:           "testcrawlID",
+			TotalUsersToCrawl: 12,
+			UsersCrawled:      12,
+		},
+		State: datastructures.CrawlStateCrawling,
+	}
+	mockController.On("UpdateCrawlState", mock.Anything, crawlingStatus.CrawlID, datastructures.CrawlStateCrawlComplete, "", mock.AnythingOfType("int64")).Return(true, nil)
+	graphCreationStarted := make(chan bool)
+	mockController.On("StartGraphCreation", crawlingStatus.CrawlID).Return(errors.New("crawler is down")).Run(func(args mock.Arguments) {
+		graphCreationStarted <- true
+	})
+
+	err := updateCrawlStateFromProgress(mockController, crawlingStatus)
+
+	assert.Nil(t, err)
+	waitForGraphCreationToStart(t, graphCreationStarted)
+	mockController.AssertNumberOfCalls(t, "StartGraphCreation", 1)
+}
+
+func TestRetryStalledGraphCreationsStartsGraphCreationForCrawlsLeftComplete(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	now := time.Unix(1650000000, 0)
+	stalledCrawls := []datastructures.CrawlingStatus{
+		{CrawlingStatus: common.CrawlingStatus{CrawlID: "firstcrawlID"}},
+		{CrawlingStatus: common.CrawlingStatus{CrawlID: "secondcrawlID"}},
+	}
+	mockController.On("ReclaimExpiredGraphingLeases", mock.Anything, mock.AnythingOfType("int64"), now.Unix()).Return([]string{}, nil)
+	mockController.On("GetCrawlsInState", mock.Anything, datastructures.CrawlStateCrawlComplete, now.Add(-graphCreationGracePeriod).Unix()).Return(stalledCrawls, nil)
+	mockController.On("StartGraphCreation", "firstcrawlID").Return(errors.New("crawler is still down"))
+	mockController.On("StartGraphCreation", "secondcrawlID").Return(nil)
+
+	err := RetryStalledGraphCreations(mockController, now)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "StartGraphCreation", 2)
+}
+
+func TestRetryStalledGraphCreationsReturnsAnErrorWhenCrawlsCannotBeFound(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	mockController.On("ReclaimExpiredGraphingLeases", mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("int64")).Return([]string{}, nil)
+	mockController.On("GetCrawlsInState", mock.Anything, mock.Anything, mock.AnythingOfType("int64")).Return([]datastructures.CrawlingStatus{}, errors.New("db is down"))
+
+	err := RetryStalledGraphCreations(mockController, time.Now())
+
+	assert.EqualError(t, err, "db is down")
+	mockController.AssertNotCalled(t, "StartGraphCreation", mock.Anything)
+}
+
+func TestRetryStalledGraphCreationsStartsGraphCreationAgainForCrawlsWhoseGraphingLeaseExpired(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	now := time.Unix(1650000000, 0)
+	mockController.On("ReclaimExpiredGraphingLeases", mock.Anything, now.Add(-graphingLeaseTimeout).Unix(), now.Unix()).Return([]string{"lostcrawlID"}, nil)
+	mockController.On("GetCrawlsInState", mock.Anything, datastructures.CrawlStateCrawlComplete, mock.AnythingOfType("int64")).Return([]datastructures.CrawlingStatus{}, nil)
+	mockController.On("StartGraphCreation", "lostcrawlID").Return(nil)
+
+	err := RetryStalledGraphCreations(mockController, now)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "StartGraphCreation", 1)
+	mockController.AssertCalled(t, "StartGraphCreation", "lostcrawlID")
+	mockController.AssertNotCalled(t, "UpdateCrawlState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRetryStalledGraphCreationsReturnsAnErrorWhenLeasesCannotBeReclaimed(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	mockController.On("ReclaimExpiredGraphingLeases", mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("int64")).Return([]string{}, errors.New("db is down"))
+
+	err := RetryStalledGraphCreations(mockController, time.Now())
+
+	assert.EqualError(t, err, "db is down")
+	mockController.AssertNotCalled(t, "GetCrawlsInState", mock.Anything, mock.Anything, mock.Anything)
+	mockController.AssertNotCalled(t, "StartGraphCreation", mock.Anything)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/IamCathal/neo/services/datastore/graphing"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+// GetEgoNetwork returns the users within radius hops of steamID. The
+// processed graph of the crawl is used if a crawlID is given, otherwise
+// every stored user is. False is returned if the crawl has not been
+// graphed or the user is not in it
+func GetEgoNetwork(cntr controller.CntrInterface, steamID, crawlID string, radius, limit int, attributes []string) (bool, datastructures.EgoNetwork, error) {
+	var export graphing.GraphExport
+	truncated := false
+	if crawlID != "" {
+		exists, crawlExport, err := GetGraphExport(cntr, crawlID)
+		if err != nil || !exists {
+			return false, datastructures.EgoNetwork{}, err
+		}
+		export = crawlExport
+	} else {
+		exists, storedExport, storedTruncated, err := getStoredUsersExport(cntr, steamID, radius, limit)
+		if err != nil || !exists {
+			return false, datastructures.EgoNetwork{}, err
+		}
+		export = storedExport
+		truncated = storedTruncated
+	}
+
+	exists, egoNetwork := graphing.GetEgoNetwork(export, steamID, radius, limit, attributes)
+	egoNetwork.Truncated = egoNetwork.Truncated || truncated
+	return exists, egoNetwork, nil
+}
+
+// getStoredUsersExport looks up stored users level by level out from
+// steamID until radius hops or limit users are reached. Users that have
+// not been crawled are left out. Levels and communities are only known
+// for crawls so they are not set. True is also returned if users were
+// left out to stay under the limit
+func getStoredUsersExport(cntr controller.CntrInterface, steamID string, radius, limit int) (bool, graphing.GraphExport, bool, error) {
+	user, err := cntr.GetUser(context.TODO(), steamID)
+	if err != nil {
+		return false, graphing.GraphExport{}, false, err
+	}
+	if user.AccDetails.SteamID == "" {
+		return false, graphing.GraphExport{}, false, nil
+	}
+
+	graphData := datastructures.UsersGraphData{}
+	graphData.UserDetails = common.UsersGraphInformation{User: user}
+	truncated := false
+	seenIDs := map[string]bool{steamID: true}
+	frontier := []common.UserDocument{user}
+	for level := 0; level < radius && len(frontier) > 0; level++ {
+		nextIDs := []string{}
+		for _, frontierUser := range frontier {
+			friendIDs := make([]string, len(frontierUser.FriendIDs))
+			copy(friendIDs, frontierUser.FriendIDs)
+			sort.Strings(friendIDs)
+			for _, friendID := range friendIDs {
+				if !seenIDs[friendID] {
+					seenIDs[friendID] = true
+					nextIDs = append(nextIDs, friendID)
+				}
+			}
+		}
+		if remaining := limit - 1 - len(graphData.FriendDetails); len(nextIDs) > remaining {
+			nextIDs = nextIDs[:remaining]
+			truncated = true
+		}
+
+		frontier, err = lookUpUsers(cntr, nextIDs)
+		if err != nil {
+			return false, graphing.GraphExport{}, false, err
+		}
+		for _, frontierUser := range frontier {
+			graphData.FriendDetails = append(graphData.FriendDetails, common.UsersGraphInformation{User: frontierUser})
+		}
+	}
+	return true, graphing.GetGraphExport("", graphData, time.Now()), truncated, nil
+}
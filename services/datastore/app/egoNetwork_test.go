@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetEgoNetworkLooksUpStoredUsersWhenNoCrawlIsGiven(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	user := makeTestUser("user", "user", "", []string{"alice", "bob"})
+	alice := makeTestUser("alice", "Alice", "", []string{"user", "carol"})
+	carol := makeTestUser("carol", "Carol", "", []string{"alice"})
+	mockController.On("GetUser", mock.Anything, "user").Return(user, nil)
+	mockGetUsers(mockController, alice, carol)
+
+	exists, egoNetwork, err := GetEgoNetwork(mockController, "user", "", 2, 100, []string{"username"})
+
+	assert.True(t, exists)
+	assert.Nil(t, err)
+	assert.False(t, egoNetwork.Truncated)
+	steamIDs := []string{}
+	for _, node := range egoNetwork.Nodes {
+		steamIDs = append(steamIDs, node.SteamID)
+	}
+	assert.Equal(t, []string{"user", "alice", "carol"}, steamIDs)
+	assert.Equal(t, 2, egoNetwork.Nodes[2].Distance)
+	assert.Len(t, egoNetwork.Edges, 2)
+}
+
+func TestGetEgoNetworkStopsLookingUpStoredUsersAtTheLimit(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	user := makeTestUser("user", "user", "", []string{"bob", "alice"})
+	alice := makeTestUser("alice", "Alice", "", []string{"user"})
+	bob := makeTestUser("bob", "Bob", "", []string{"user"})
+	mockController.On("GetUser", mock.Anything, "user").Return(user, nil)
+	mockGetUsers(mockController, alice, bob)
+
+	_, egoNetwork, err := GetEgoNetwork(mockController, "user", "", 1, 2, []string{})
+
+	assert.Nil(t, err)
+	assert.True(t, egoNetwork.Truncated)
+	assert.Len(t, egoNetwork.Nodes, 2)
+	mockController.AssertCalled(t, "GetUsers", mock.Anything, []string{"alice"}, mock.Anything)
+}
+
+func TestGetEgoNetworkReturnsFalseWhenTheUserHasNotBeenCrawled(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	mockController.On("GetUser", mock.Anything, "user").Return(common.UserDocument{}, nil)
+
+	exists, _, err := GetEgoNetwork(mockController, "user", "", 1, 100, []string{})
+
+	assert.False(t, exists)
+	assert.Nil(t, err)
+}
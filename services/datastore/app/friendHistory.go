@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+)
+
+// getFriendListChange diffs a user's stored friends against their newly
+// crawled friends. The returned change has no added or removed friends
+// if the friend list is unchanged
+func getFriendListChange(steamID string, storedFriendIDs, newFriendIDs []string) datastructures.FriendListChange {
+	storedFriends := make(map[string]bool, len(storedFriendIDs))
+	for _, friendID := range storedFriendIDs {
+		storedFriends[friendID] = true
+	}
+	newFriends := make(map[string]bool, len(newFriendIDs))
+	for _, friendID := range newFriendIDs {
+		newFriends[friendID] = true
+	}
+
+	change := datastructures.FriendListChange{
+		SteamID:   steamID,
+		Timestamp: time.Now().Unix(),
+		Added:     []string{},
+		Removed:   []string{},
+	}
+	for _, friendID := range newFriendIDs {
+		if !storedFriends[friendID] {
+			change.Added = append(change.Added, friendID)
+		}
+	}
+	for _, friendID := range storedFriendIDs {
+		if !newFriends[friendID] {
+			change.Removed = append(change.Removed, friendID)
+		}
+	}
+	return change
+}
+
+func friendListHasChanged(change datastructures.FriendListChange) bool {
+	return len(change.Added) > 0 || len(change.Removed) > 0
+}
+
+// GetFriendHistory returns the timeline of changes to a user's friend
+// list, oldest first, along with the total friends added and removed
+func GetFriendHistory(cntr controller.CntrInterface, steamID string) (datastructures.GetFriendHistoryDTO, error) {
+	changes, err := cntr.GetFriendListChanges(context.TODO(), steamID)
+	if err != nil {
+		return datastructures.GetFriendHistoryDTO{}, err
+	}
+
+	friendHistory := datastructures.GetFriendHistoryDTO{
+		Status:  "success",
+		SteamID: steamID,
+		Changes: changes,
+	}
+	for _, change := range changes {
+		friendHistory.TotalAdded += len(change.Added)
+		friendHistory.TotalRemoved += len(change.Removed)
+	}
+	return friendHistory, nil
+}
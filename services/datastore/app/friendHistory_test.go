@@ -0,0 +1,94 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/configuration"
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestGetFriendListChangeReturnsAddedAndRemovedFriends(t *testing.T) {
+	storedFriendIDs := []string{"1", "2", "3"}
+	newFriendIDs := []string{"2", "3", "4", "5"}
+
+	change := getFriendListChange("12345", storedFriendIDs, newFriendIDs)
+
+	assert.Equal(t, "12345", change.SteamID)
+	assert.Equal(t, []string{"4", "5"}, change.Added)
+	assert.Equal(t, []string{"1"}, change.Removed)
+	assert.True(t, friendListHasChanged(change))
+}
+
+func TestGetFriendListChangeForAnUnchangedFriendListHasNoChanges(t *testing.T) {
+	change := getFriendListChange("12345", []string{"1", "2"}, []string{"2", "1"})
+
+	assert.Empty(t, change.Added)
+	assert.Empty(t, change.Removed)
+	assert.False(t, friendListHasChanged(change))
+}
+
+func TestSaveUserToDBSavesTheFriendListChangeForAPreviouslyStoredUser(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	storedUser := testSaveUserDTO.User
+	storedUser.FriendIDs = []string{"76561198000000001"}
+	mockController.On("GetUser", mock.Anything, storedUser.AccDetails.SteamID).Return(storedUser, nil)
+	mockController.On("UpsertUser", mock.Anything, mock.Anything).Return(nil)
+	mockController.On("SaveFriendListChange", mock.Anything, mock.MatchedBy(func(change datastructures.FriendListChange) bool {
+		return change.SteamID == storedUser.AccDetails.SteamID &&
+			len(change.Added) == len(testSaveUserDTO.User.FriendIDs) &&
+			len(change.Removed) == 1 &&
+			change.Removed[0] == "76561198000000001"
+	})).Return(nil)
+	configuration.DBClient = &mongo.Client{}
+
+	err := SaveUserToDB(mockController, testSaveUserDTO.User)
+
+	assert.Nil(t, err)
+	mockController.AssertNumberOfCalls(t, "SaveFriendListChange", 1)
+}
+
+func TestSaveUserToDBDoesNotSaveAFriendListChangeForANewUser(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	mockController.On("GetUser", mock.Anything, mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser", mock.Anything, mock.Anything).Return(nil)
+	configuration.DBClient = &mongo.Client{}
+
+	err := SaveUserToDB(mockController, testSaveUserDTO.User)
+
+	assert.Nil(t, err)
+	mockController.AssertNotCalled(t, "SaveFriendListChange", mock.Anything, mock.Anything)
+}
+
+func TestSaveUserToDBDoesNotSaveTheUserWhenTheStoredUserCannotBeRetrieved(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	expectedError := errors.New("expected error response")
+	mockController.On("GetUser", mock.Anything, mock.Anything).Return(common.UserDocument{}, expectedError)
+	configuration.DBClient = &mongo.Client{}
+
+	err := SaveUserToDB(mockController, testSaveUserDTO.User)
+
+	assert.EqualError(t, err, expectedError.Error())
+	mockController.AssertNotCalled(t, "UpsertUser", mock.Anything, mock.Anything)
+}
+
+func TestGetFriendHistoryTotalsTheAddedAndRemovedFriends(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	changes := []datastructures.FriendListChange{
+		{SteamID: "12345", Added: []string{"1", "2"}, Removed: []string{}},
+		{SteamID: "12345", Added: []string{"3"}, Removed: []string{"1"}},
+	}
+	mockController.On("GetFriendListChanges", mock.Anything, "12345").Return(changes, nil)
+
+	friendHistory, err := GetFriendHistory(mockController, "12345")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, friendHistory.TotalAdded)
+	assert.Equal(t, 1, friendHistory.TotalRemoved)
+	assert.Equal(t, changes, friendHistory.Changes)
+}
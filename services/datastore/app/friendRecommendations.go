@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+const (
+	// maxUsersPerLookup keeps each lookup of many users under the
+	// limit of the getusers endpoint
+	maxUsersPerLookup = 1000
+	// maxRecommendationCandidates is how many of the users with the most
+	// mutual friends are looked up to be scored
+	maxRecommendationCandidates = 200
+	maxFriendRecommendations    = 20
+	// topGamesCompared is how many of each user's most played games
+	// are compared when recommending friends
+	topGamesCompared  = 10
+	mutualFriendScore = 1.0
+	sharedGameScore   = 0.5
+	sameCountryScore  = 1.0
+	// maxNamesInReason is how many friends or games are named in
+	// each reason before the rest are counted instead
+	maxNamesInReason = 3
+)
+
+// GetFriendRecommendations recommends users who are friends of the
+// user's friends, ranked by their mutual friends, shared top games and
+// whether they are from the same country. Only users that have been
+// crawled are used. False is returned if the user has not been crawled
+func GetFriendRecommendations(cntr controller.CntrInterface, steamID string) (bool, []datastructures.FriendRecommendation, error) {
+	user, err := cntr.GetUser(context.TODO(), steamID)
+	if err != nil {
+		return false, []datastructures.FriendRecommendation{}, err
+	}
+	if user.AccDetails.SteamID == "" {
+		return false, []datastructures.FriendRecommendation{}, nil
+	}
+
+	friends, err := lookUpUsers(cntr, user.FriendIDs)
+	if err != nil {
+		return false, []datastructures.FriendRecommendation{}, err
+	}
+	mutualFriends := getFriendsOfFriends(user, friends)
+	candidates, err := lookUpUsers(cntr, getMostMutualFriends(mutualFriends, maxRecommendationCandidates))
+	if err != nil {
+		return false, []datastructures.FriendRecommendation{}, err
+	}
+
+	userTopGames := getTopGames(user.GamesOwned, topGamesCompared)
+	recommendations := []datastructures.FriendRecommendation{}
+	for _, candidate := range candidates {
+		candidateID := candidate.AccDetails.SteamID
+		sharedTopGames := getSharedAppIDs(userTopGames, getTopGames(candidate.GamesOwned, topGamesCompared))
+		sameCountry := user.AccDetails.Loccountrycode != "" &&
+			user.AccDetails.Loccountrycode == candidate.AccDetails.Loccountrycode
+
+		score := mutualFriendScore*float64(len(mutualFriends[candidateID])) + sharedGameScore*float64(len(sharedTopGames))
+		if sameCountry {
+			score += sameCountryScore
+		}
+		recommendations = append(recommendations, datastructures.FriendRecommendation{
+			SteamID:        candidateID,
+			Username:       candidate.AccDetails.Personaname,
+			Avatar:         candidate.AccDetails.Avatar,
+			MutualFriends:  mutualFriends[candidateID],
+			SharedTopGames: sharedTopGames,
+			SameCountry:    sameCountry,
+			Score:          score,
+		})
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].SteamID < recommendations[j].SteamID
+	})
+	if len(recommendations) > maxFriendRecommendations {
+		recommendations = recommendations[:maxFriendRecommendations]
+	}
+
+	if err := addRecommendationReasons(cntr, user, friends, recommendations); err != nil {
+		return false, []datastructures.FriendRecommendation{}, err
+	}
+	return true, recommendations, nil
+}
+
+// lookUpUsers gets every given user that has been crawled
+func lookUpUsers(cntr controller.CntrInterface, steamIDs []string) ([]common.UserDocument, error) {
+	users := []common.UserDocument{}
+	for start := 0; start < len(steamIDs); start += maxUsersPerLookup {
+		end := start + maxUsersPerLookup
+		if end > len(steamIDs) {
+			end = len(steamIDs)
+		}
+		err := cntr.GetUsers(context.TODO(), steamIDs[start:end], func(user common.UserDocument) error {
+			users = append(users, user)
+			return nil
+		})
+		if err != nil {
+			return []common.UserDocument{}, err
+		}
+	}
+	return users, nil
+}
+
+// getFriendsOfFriends returns the mutual friends shared with each user
+// two hops away from the given user that they are not friends with
+func getFriendsOfFriends(user common.UserDocument, friends []common.UserDocument) map[string][]string {
+	alreadyFriends := make(map[string]bool)
+	for _, friendID := range user.FriendIDs {
+		alreadyFriends[friendID] = true
+	}
+
+	mutualFriends := make(map[string][]string)
+	for _, friend := range friends {
+		seenIDs := make(map[string]bool)
+		for _, friendOfFriendID := range friend.FriendIDs {
+			if friendOfFriendID == user.AccDetails.SteamID || alreadyFriends[friendOfFriendID] || seenIDs[friendOfFriendID] {
+				continue
+			}
+			seenIDs[friendOfFriendID] = true
+			mutualFriends[friendOfFriendID] = append(mutualFriends[friendOfFriendID], friend.AccDetails.SteamID)
+		}
+	}
+	return mutualFriends
+}
+
+// getMostMutualFriends returns the users with the most mutual friends
+func getMostMutualFriends(mutualFriends map[string][]string, amount int) []string {
+	steamIDs := make([]string, 0, len(mutualFriends))
+	for steamID := range mutualFriends {
+		steamIDs = append(steamIDs, steamID)
+	}
+	sort.Slice(steamIDs, func(i, j int) bool {
+		if len(mutualFriends[steamIDs[i]]) != len(mutualFriends[steamIDs[j]]) {
+			return len(mutualFriends[steamIDs[i]]) > len(mutualFriends[steamIDs[j]])
+		}
+		return steamIDs[i] < steamIDs[j]
+	})
+	if len(steamIDs) > amount {
+		steamIDs = steamIDs[:amount]
+	}
+	return steamIDs
+}
+
+// getTopGames returns the appIDs of the most played games
+func getTopGames(games []common.GameOwnedDocument, amount int) []int {
+	sortedGames := make([]common.GameOwnedDocument, len(games))
+	copy(sortedGames, games)
+	sort.SliceStable(sortedGames, func(i, j int) bool {
+		return sortedGames[i].Playtime_Forever > sortedGames[j].Playtime_Forever
+	})
+	if len(sortedGames) > amount {
+		sortedGames = sortedGames[:amount]
+	}
+
+	topGames := make([]int, len(sortedGames))
+	for i, game := range sortedGames {
+		topGames[i] = game.AppID
+	}
+	return topGames
+}
+
+// getSharedAppIDs returns the appIDs found in both lists in the order
+// they appear in the first
+func getSharedAppIDs(firstAppIDs, secondAppIDs []int) []int {
+	toIDs := func(appIDs []int) []string {
+		IDs := make([]string, len(appIDs))
+		for i, appID := range appIDs {
+			IDs[i] = strconv.Itoa(appID)
+		}
+		return IDs
+	}
+	sharedIDs, _ := getIDOverlap(toIDs(firstAppIDs), toIDs(secondAppIDs))
+
+	sharedAppIDs := make([]int, len(sharedIDs))
+	for i, ID := range sharedIDs {
+		sharedAppIDs[i], _ = strconv.Atoi(ID)
+	}
+	return sharedAppIDs
+}
+
+// addRecommendationReasons explains each recommendation using the names
+// of the mutual friends and shared games
+func addRecommendationReasons(cntr controller.CntrInterface, user common.UserDocument, friends []common.UserDocument, recommendations []datastructures.FriendRecommendation) error {
+	friendNames := make(map[string]string)
+	for _, friend := range friends {
+		friendNames[friend.AccDetails.SteamID] = friend.AccDetails.Personaname
+	}
+
+	sharedGameIDs := []int{}
+	seenGameIDs := make(map[int]bool)
+	for _, recommendation := range recommendations {
+		for _, appID := range recommendation.SharedTopGames {
+			if !seenGameIDs[appID] {
+				seenGameIDs[appID] = true
+				sharedGameIDs = append(sharedGameIDs, appID)
+			}
+		}
+	}
+	gameNames := make(map[int]string)
+	if len(sharedGameIDs) > 0 {
+		gameDetails, err := cntr.GetDetailsForGames(context.TODO(), sharedGameIDs)
+		if err != nil {
+			return err
+		}
+		for _, game := range gameDetails {
+			gameNames[game.AppID] = game.Name
+		}
+	}
+
+	for i, recommendation := range recommendations {
+		reasons := []string{}
+
+		mutualFriendNames := []string{}
+		for _, friendID := range recommendation.MutualFriends {
+			mutualFriendNames = append(mutualFriendNames, friendNames[friendID])
+		}
+		reasons = append(reasons, fmt.Sprintf("Friends with %s", listNames(mutualFriendNames, "other")))
+
+		if len(recommendation.SharedTopGames) > 0 {
+			sharedGameNames := []string{}
+			for _, appID := range recommendation.SharedTopGames {
+				if name, exists := gameNames[appID]; exists {
+					sharedGameNames = append(sharedGameNames, name)
+				} else {
+					sharedGameNames = append(sharedGameNames, fmt.Sprint(appID))
+				}
+			}
+			reasons = append(reasons, fmt.Sprintf("Also plays %s", listNames(sharedGameNames, "other game")))
+		}
+		if recommendation.SameCountry {
+			reasons = append(reasons, fmt.Sprintf("Also from %s", user.AccDetails.Loccountrycode))
+		}
+		recommendations[i].Reasons = reasons
+	}
+	return nil
+}
+
+// listNames lists the first few names and counts the rest
+func listNames(names []string, otherName string) string {
+	if len(names) <= maxNamesInReason {
+		if len(names) == 1 {
+			return names[0]
+		}
+		return fmt.Sprintf("%s and %s", strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
+	}
+	others := len(names) - maxNamesInReason
+	if others > 1 {
+		otherName += "s"
+	}
+	return fmt.Sprintf("%s and %d %s", strings.Join(names[:maxNamesInReason], ", "), others, otherName)
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetFriendRecommendationsRanksFriendsOfFriendsAndExplainsThem(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	user := makeTestUser("user", "user", "IE", []string{"alice", "bob"},
+		common.GameOwnedDocument{AppID: 730, Playtime_Forever: 100})
+	alice := makeTestUser("alice", "Alice", "FR", []string{"user", "bob", "carol", "dave"})
+	bob := makeTestUser("bob", "Bob", "FR", []string{"user", "alice", "carol"})
+	carol := makeTestUser("carol", "Carol", "DE", []string{"alice", "bob"})
+	dave := makeTestUser("dave", "Dave", "IE", []string{"alice"},
+		common.GameOwnedDocument{AppID: 730, Playtime_Forever: 5})
+
+	mockController.On("GetUser", mock.Anything, "user").Return(user, nil)
+	mockGetUsers(mockController, alice, bob, carol, dave)
+	mockController.On("GetDetailsForGames", mock.Anything, []int{730}).Return([]common.BareGameInfo{{AppID: 730, Name: "CS:GO"}}, nil)
+
+	exists, recommendations, err := GetFriendRecommendations(mockController, "user")
+
+	assert.True(t, exists)
+	assert.Nil(t, err)
+	assert.Len(t, recommendations, 2)
+
+	assert.Equal(t, "dave", recommendations[0].SteamID)
+	assert.Equal(t, []string{"alice"}, recommendations[0].MutualFriends)
+	assert.Equal(t, []int{730}, recommendations[0].SharedTopGames)
+	assert.True(t, recommendations[0].SameCountry)
+	assert.Equal(t, 2.5, recommendations[0].Score)
+	assert.Equal(t, []string{"Friends with Alice", "Also plays CS:GO", "Also from IE"}, recommendations[0].Reasons)
+
+	assert.Equal(t, "carol", recommendations[1].SteamID)
+	assert.Equal(t, []string{"alice", "bob"}, recommendations[1].MutualFriends)
+	assert.Equal(t, 2.0, recommendations[1].Score)
+	assert.Equal(t, []string{"Friends with Alice and Bob"}, recommendations[1].Reasons)
+}
+
+func TestGetFriendRecommendationsReturnsFalseWhenTheUserHasNotBeenCrawled(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	mockController.On("GetUser", mock.Anything, "user").Return(common.UserDocument{}, nil)
+
+	exists, recommendations, err := GetFriendRecommendations(mockController, "user")
+
+	assert.False(t, exists)
+	assert.Empty(t, recommendations)
+	assert.Nil(t, err)
+	mockController.AssertNotCalled(t, "GetUsers", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetTopGamesReturnsTheMostPlayedGames(t *testing.T) {
+	games := []common.GameOwnedDocument{
+		{AppID: 1, Playtime_Forever: 10},
+		{AppID: 2, Playtime_Forever: 30},
+		{AppID: 3, Playtime_Forever: 20},
+	}
+
+	assert.Equal(t, []int{2, 3}, getTopGames(games, 2))
+}
+
+func TestListNamesCountsTheNamesThatAreNotListed(t *testing.T) {
+	assert.Equal(t, "a", listNames([]string{"a"}, "other"))
+	assert.Equal(t, "a, b and c", listNames([]string{"a", "b", "c"}, "other"))
+	assert.Equal(t, "a, b, c and 1 other", listNames([]string{"a", "b", "c", "d"}, "other"))
+	assert.Equal(t, "a, b, c and 2 others", listNames([]string{"a", "b", "c", "d", "e"}, "other"))
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+const (
+	maxGameRecommendations = 20
+	// Friends count for more than the friends of friends when
+	// recommending games
+	friendCloseness         = 1.0
+	friendOfFriendCloseness = 0.5
+)
+
+// GetGameRecommendations recommends games the user does not own that are
+// played by their friends and the friends of their friends. Each player
+// counts more the closer they are to the user and the more of the user's
+// library they share, and hours played count for less the more there are
+// so that one player cannot outweigh everyone else. False is returned if
+// the user has not been crawled
+func GetGameRecommendations(cntr controller.CntrInterface, steamID string) (bool, []datastructures.GameRecommendation, error) {
+	user, err := cntr.GetUser(context.TODO(), steamID)
+	if err != nil {
+		return false, []datastructures.GameRecommendation{}, err
+	}
+	if user.AccDetails.SteamID == "" {
+		return false, []datastructures.GameRecommendation{}, nil
+	}
+
+	friends, err := lookUpUsers(cntr, user.FriendIDs)
+	if err != nil {
+		return false, []datastructures.GameRecommendation{}, err
+	}
+	friendsOfFriends, err := lookUpUsers(cntr, getMostMutualFriends(getFriendsOfFriends(user, friends), maxRecommendationCandidates))
+	if err != nil {
+		return false, []datastructures.GameRecommendation{}, err
+	}
+
+	ownedGames := make(map[int]bool)
+	for _, game := range user.GamesOwned {
+		ownedGames[game.AppID] = true
+	}
+	recommendationsByID := make(map[int]*datastructures.GameRecommendation)
+	addPlayers := func(players []common.UserDocument, closeness float64) {
+		for _, player := range players {
+			sharedGames, allGamesCount := getSharedGames(user.GamesOwned, player.GamesOwned)
+			weight := closeness * (1 + jaccardIndex(len(sharedGames), allGamesCount))
+
+			for _, game := range player.GamesOwned {
+				if ownedGames[game.AppID] || game.Playtime_Forever == 0 {
+					continue
+				}
+				recommendation, exists := recommendationsByID[game.AppID]
+				if !exists {
+					recommendation = &datastructures.GameRecommendation{AppID: game.AppID}
+					recommendationsByID[game.AppID] = recommendation
+				}
+				recommendation.Score += weight * math.Log1p(float64(game.Playtime_Forever)/60)
+				recommendation.Players++
+				recommendation.TotalPlaytime += game.Playtime_Forever
+			}
+		}
+	}
+	addPlayers(friends, friendCloseness)
+	addPlayers(friendsOfFriends, friendOfFriendCloseness)
+
+	recommendations := make([]datastructures.GameRecommendation, 0, len(recommendationsByID))
+	for _, recommendation := range recommendationsByID {
+		recommendations = append(recommendations, *recommendation)
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].AppID < recommendations[j].AppID
+	})
+	if len(recommendations) > maxGameRecommendations {
+		recommendations = recommendations[:maxGameRecommendations]
+	}
+
+	if err := addGameNames(cntr, recommendations); err != nil {
+		return false, []datastructures.GameRecommendation{}, err
+	}
+	return true, recommendations, nil
+}
+
+// addGameNames names each recommended game. Games that are not in the
+// games collection are left without a name
+func addGameNames(cntr controller.CntrInterface, recommendations []datastructures.GameRecommendation) error {
+	if len(recommendations) == 0 {
+		return nil
+	}
+	appIDs := make([]int, len(recommendations))
+	for i, recommendation := range recommendations {
+		appIDs[i] = recommendation.AppID
+	}
+	gameDetails, err := cntr.GetDetailsForGames(context.TODO(), appIDs)
+	if err != nil {
+		return err
+	}
+	gameNames := make(map[int]string)
+	for _, game := range gameDetails {
+		gameNames[game.AppID] = game.Name
+	}
+	for i := range recommendations {
+		recommendations[i].Name = gameNames[recommendations[i].AppID]
+	}
+	return nil
+}
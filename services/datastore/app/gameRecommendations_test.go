@@ -0,0 +1,79 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetGameRecommendationsOnlyRecommendsGamesTheUserDoesNotOwn(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	user := makeTestUser("user", "user", "", []string{"alice", "bob"},
+		common.GameOwnedDocument{AppID: 730, Playtime_Forever: 100})
+	alice := makeTestUser("alice", "Alice", "", []string{"user", "carol"},
+		common.GameOwnedDocument{AppID: 730, Playtime_Forever: 600},
+		common.GameOwnedDocument{AppID: 570, Playtime_Forever: 600})
+	bob := makeTestUser("bob", "Bob", "", []string{"user"},
+		common.GameOwnedDocument{AppID: 440, Playtime_Forever: 600},
+		common.GameOwnedDocument{AppID: 620, Playtime_Forever: 0})
+	carol := makeTestUser("carol", "Carol", "", []string{"alice"},
+		common.GameOwnedDocument{AppID: 570, Playtime_Forever: 60})
+
+	mockController.On("GetUser", mock.Anything, "user").Return(user, nil)
+	mockGetUsers(mockController, alice, bob, carol)
+	mockController.On("GetDetailsForGames", mock.Anything, []int{570, 440}).Return([]common.BareGameInfo{
+		{AppID: 570, Name: "Dota 2"},
+		{AppID: 440, Name: "Team Fortress 2"},
+	}, nil)
+
+	exists, recommendations, err := GetGameRecommendations(mockController, "user")
+
+	assert.True(t, exists)
+	assert.Nil(t, err)
+	assert.Len(t, recommendations, 2)
+
+	assert.Equal(t, 570, recommendations[0].AppID)
+	assert.Equal(t, "Dota 2", recommendations[0].Name)
+	assert.Equal(t, 2, recommendations[0].Players)
+	assert.Equal(t, 660, recommendations[0].TotalPlaytime)
+
+	assert.Equal(t, 440, recommendations[1].AppID)
+	assert.Equal(t, "Team Fortress 2", recommendations[1].Name)
+	assert.Equal(t, 1, recommendations[1].Players)
+}
+
+func TestGetGameRecommendationsCountsFriendsForMoreThanFriendsOfFriends(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	user := makeTestUser("user", "user", "", []string{"alice"})
+	alice := makeTestUser("alice", "Alice", "", []string{"user", "bob"},
+		common.GameOwnedDocument{AppID: 440, Playtime_Forever: 600})
+	bob := makeTestUser("bob", "Bob", "", []string{"alice"},
+		common.GameOwnedDocument{AppID: 570, Playtime_Forever: 600})
+
+	mockController.On("GetUser", mock.Anything, "user").Return(user, nil)
+	mockGetUsers(mockController, alice, bob)
+	mockController.On("GetDetailsForGames", mock.Anything, mock.Anything).Return([]common.BareGameInfo{}, nil)
+
+	_, recommendations, err := GetGameRecommendations(mockController, "user")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int{440, 570}, []int{recommendations[0].AppID, recommendations[1].AppID})
+	assert.InDelta(t, 2*recommendations[1].Score, recommendations[0].Score, 1e-9)
+}
+
+func TestGetGameRecommendationsReturnsFalseWhenTheUserHasNotBeenCrawled(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	mockController.On("GetUser", mock.Anything, "user").Return(common.UserDocument{}, nil)
+
+	exists, recommendations, err := GetGameRecommendations(mockController, "user")
+
+	assert.False(t, exists)
+	assert.Empty(t, recommendations)
+	assert.Nil(t, err)
+}
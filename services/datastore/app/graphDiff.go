@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/IamCathal/neo/services/datastore/graphing"
+)
+
+// DiffGraphs compares the processed graphs of two crawls of the same
+// user. The first bool is false if either crawl has not been graphed and
+// the second is false if the crawls are of different users
+func DiffGraphs(cntr controller.CntrInterface, fromCrawlID, toCrawlID string) (bool, bool, datastructures.GraphDiff, error) {
+	fromGraph, err := cntr.GetProcessedGraphData(fromCrawlID)
+	if err != nil {
+		return false, false, datastructures.GraphDiff{}, err
+	}
+	toGraph, err := cntr.GetProcessedGraphData(toCrawlID)
+	if err != nil {
+		return false, false, datastructures.GraphDiff{}, err
+	}
+	fromSteamID := fromGraph.UserDetails.User.AccDetails.SteamID
+	toSteamID := toGraph.UserDetails.User.AccDetails.SteamID
+	if fromSteamID == "" || toSteamID == "" {
+		return false, false, datastructures.GraphDiff{}, nil
+	}
+	if fromSteamID != toSteamID {
+		return true, false, datastructures.GraphDiff{}, nil
+	}
+
+	diff := graphing.DiffGraphs(fromCrawlID, fromGraph, toCrawlID, toGraph)
+	if len(diff.GameShifts) > 0 {
+		appIDs := make([]int, len(diff.GameShifts))
+		for i, shift := range diff.GameShifts {
+			appIDs[i] = shift.AppID
+		}
+		gameDetails, err := cntr.GetDetailsForGames(context.TODO(), appIDs)
+		if err != nil {
+			return false, false, datastructures.GraphDiff{}, err
+		}
+		gameNames := make(map[int]string)
+		for _, game := range gameDetails {
+			gameNames[game.AppID] = game.Name
+		}
+		for i := range diff.GameShifts {
+			diff.GameShifts[i].Name = gameNames[diff.GameShifts[i].AppID]
+		}
+	}
+	return true, true, diff, nil
+}
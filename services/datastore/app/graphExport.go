@@ -0,0 +1,21 @@
+package app
+
+import (
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/graphing"
+)
+
+// GetGraphExport gets the processed graph of a crawl ready to be exported.
+// False is returned if the crawl has not been graphed
+func GetGraphExport(cntr controller.CntrInterface, crawlID string) (bool, graphing.GraphExport, error) {
+	graphData, err := cntr.GetProcessedGraphData(crawlID)
+	if err != nil {
+		return false, graphing.GraphExport{}, err
+	}
+	if graphData.UserDetails.User.AccDetails.SteamID == "" {
+		return false, graphing.GraphExport{}, nil
+	}
+	return true, graphing.GetGraphExport(crawlID, graphData, time.Now()), nil
+}
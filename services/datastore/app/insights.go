@@ -0,0 +1,32 @@
+package app
+
+import (
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/IamCathal/neo/services/datastore/insights"
+)
+
+// GetInsights gets the insights saved alongside the graph of a crawl.
+// Insights for graphs saved before they were introduced are worked out
+// from the graph instead. False is returned if the crawl has not been
+// graphed
+func GetInsights(cntr controller.CntrInterface, crawlID string) (bool, datastructures.NetworkInsights, error) {
+	exists, networkInsights, err := cntr.GetInsights(crawlID)
+	if err != nil {
+		return false, datastructures.NetworkInsights{}, err
+	}
+	if exists {
+		return true, networkInsights, nil
+	}
+
+	graphData, err := cntr.GetProcessedGraphData(crawlID)
+	if err != nil {
+		return false, datastructures.NetworkInsights{}, err
+	}
+	if graphData.UserDetails.User.AccDetails.SteamID == "" {
+		return false, datastructures.NetworkInsights{}, nil
+	}
+	return true, insights.GetNetworkInsights(graphData, time.Now()), nil
+}
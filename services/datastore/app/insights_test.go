@@ -0,0 +1,58 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInsightsReturnsSavedInsights(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	savedInsights := datastructures.NetworkInsights{GamerScore: 100}
+	mockController.On("GetInsights", "crawl").Return(true, savedInsights, nil)
+
+	exists, networkInsights, err := GetInsights(mockController, "crawl")
+
+	assert.True(t, exists)
+	assert.Nil(t, err)
+	assert.Equal(t, savedInsights, networkInsights)
+	mockController.AssertNotCalled(t, "GetProcessedGraphData")
+}
+
+func TestGetInsightsWorksOutInsightsForGraphsSavedWithoutThem(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	user := makeTestUser("user", "user", "IE", []string{"alice"})
+	alice := makeTestUser("alice", "Alice", "IE", []string{"user"})
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails:   common.UsersGraphInformation{User: user},
+			FriendDetails: []common.UsersGraphInformation{{User: alice}},
+		},
+	}
+	mockController.On("GetInsights", "crawl").Return(false, datastructures.NetworkInsights{}, nil)
+	mockController.On("GetProcessedGraphData", "crawl").Return(graphData, nil)
+
+	exists, networkInsights, err := GetInsights(mockController, "crawl")
+
+	assert.True(t, exists)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, networkInsights.AlsoFromUsersCountry)
+	assert.Equal(t, 1, networkInsights.GamerScore)
+}
+
+func TestGetInsightsReturnsFalseWhenTheCrawlHasNotBeenGraphed(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	mockController.On("GetInsights", "crawl").Return(false, datastructures.NetworkInsights{}, nil)
+	mockController.On("GetProcessedGraphData", "crawl").Return(datastructures.UsersGraphData{}, nil)
+
+	exists, _, err := GetInsights(mockController, "crawl")
+
+	assert.False(t, exists)
+	assert.Nil(t, err)
+}
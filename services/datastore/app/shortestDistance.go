@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -14,6 +15,15 @@ import (
 )
 
 func CalulateShortestDistanceInfo(cntr controller.CntrInterface, firstCrawlID, secondCrawlID string) (bool, datastructures.ShortestDistanceInfo, error) {
+	for _, crawlID := range []string{firstCrawlID, secondCrawlID} {
+		hasBeenGraphed, err := crawlHasBeenGraphed(cntr, crawlID)
+		if err != nil {
+			return false, datastructures.ShortestDistanceInfo{}, err
+		}
+		if !hasBeenGraphed {
+			return false, datastructures.ShortestDistanceInfo{}, nil
+		}
+	}
 	firstUserGraphData, err := cntr.GetProcessedGraphData(firstCrawlID)
 	if err != nil {
 		return false, datastructures.ShortestDistanceInfo{}, err
@@ -28,11 +38,11 @@ func CalulateShortestDistanceInfo(cntr controller.CntrInterface, firstCrawlID, s
 		return false, datastructures.ShortestDistanceInfo{}, nil
 	}
 
-	_, userDetailsForShortestPath, err := getUserDetailsForShortestDistancePath(cntr, firstUserGraphData, secondUserGraphData)
+	_, userDetailsForShortestPath, err := getUserDetailsForShortestDistancePath(cntr, firstUserGraphData.UsersGraphData, secondUserGraphData.UsersGraphData)
 	if err != nil {
 		return false, datastructures.ShortestDistanceInfo{}, err
 	}
-	uniqueFriends := getUniqueFriends(firstUserGraphData, secondUserGraphData)
+	uniqueFriends := getUniqueFriends(firstUserGraphData.UsersGraphData, secondUserGraphData.UsersGraphData)
 	shortestDistanceInfo := datastructures.ShortestDistanceInfo{
 		CrawlIDs:         []string{firstCrawlID, secondCrawlID},
 		FirstUser:        firstUserGraphData.UserDetails.User,
@@ -45,6 +55,16 @@ func CalulateShortestDistanceInfo(cntr controller.CntrInterface, firstCrawlID, s
 	return true, shortestDistanceInfo, nil
 }
 
+// crawlHasBeenGraphed checks if a crawl is in the graphed state. Crawls
+// saved before states were recorded are checked by their graph data instead
+func crawlHasBeenGraphed(cntr controller.CntrInterface, crawlID string) (bool, error) {
+	crawlingStatus, err := cntr.GetCrawlingStatusFromDBFromCrawlID(context.TODO(), crawlID)
+	if err != nil {
+		return false, err
+	}
+	return crawlingStatus.State == "" || crawlingStatus.State == datastructures.CrawlStateGraphed, nil
+}
+
 func getUserDetailsForShortestDistancePath(cntr controller.CntrInterface, userOne, userTwo common.UsersGraphData) (bool, []common.UserDocument, error) {
 	exists, shortestPathIDs, err := graphing.GetShortestPathIDs(cntr, userOne, userTwo)
 	if err != nil {
@@ -63,27 +83,64 @@ func getUserDetailsForShortestDistancePath(cntr controller.CntrInterface, userOn
 	return true, shortestPathUserDetails, nil
 }
 
+// getUniqueFriends returns the friends of either user without duplicates,
+// leaving out the two users themselves
 func getUniqueFriends(firstUserGraphData, secondUserGraphData common.UsersGraphData) []common.UserDocument {
-	uniqueFriendsGraphInformation := []common.UserDocument{}
-	seenIDs := make(map[string]bool)
+	friendsByID := make(map[string]common.UserDocument)
+	getFriendIDs := func(graphData common.UsersGraphData) []string {
+		friendIDs := []string{}
+		for _, friend := range graphData.FriendDetails {
+			if _, exists := friendsByID[friend.User.AccDetails.SteamID]; !exists {
+				friendsByID[friend.User.AccDetails.SteamID] = friend.User
+			}
+			friendIDs = append(friendIDs, friend.User.AccDetails.SteamID)
+		}
+		return friendIDs
+	}
 
 	// Original users do not belong in the friend list
-	seenIDs[firstUserGraphData.UserDetails.User.AccDetails.SteamID] = true
-	seenIDs[secondUserGraphData.UserDetails.User.AccDetails.SteamID] = true
+	_, uniqueFriendIDs := getIDOverlap(getFriendIDs(firstUserGraphData), getFriendIDs(secondUserGraphData),
+		firstUserGraphData.UserDetails.User.AccDetails.SteamID,
+		secondUserGraphData.UserDetails.User.AccDetails.SteamID)
+
+	uniqueFriends := []common.UserDocument{}
+	for _, steamID := range uniqueFriendIDs {
+		uniqueFriends = append(uniqueFriends, friendsByID[steamID])
+	}
+	return uniqueFriends
+}
+
+// getIDOverlap returns the IDs found in both lists, in the order they
+// appear in the first, and every unique ID found in either list. Excluded
+// IDs are left out of both
+func getIDOverlap(firstIDs, secondIDs []string, excludedIDs ...string) ([]string, []string) {
+	inSecond := make(map[string]bool, len(secondIDs))
+	for _, ID := range secondIDs {
+		inSecond[ID] = true
+	}
+	seenIDs := make(map[string]bool)
+	for _, ID := range excludedIDs {
+		seenIDs[ID] = true
+	}
 
-	for _, graphData := range firstUserGraphData.FriendDetails {
-		if isTrue := ifIsTrue(graphData.User.AccDetails.SteamID, seenIDs); !isTrue {
-			seenIDs[graphData.User.AccDetails.SteamID] = true
-			uniqueFriendsGraphInformation = append(uniqueFriendsGraphInformation, graphData.User)
+	sharedIDs := []string{}
+	allIDs := []string{}
+	for _, ID := range firstIDs {
+		if !seenIDs[ID] {
+			seenIDs[ID] = true
+			allIDs = append(allIDs, ID)
+			if inSecond[ID] {
+				sharedIDs = append(sharedIDs, ID)
+			}
 		}
 	}
-	for _, graphData := range secondUserGraphData.FriendDetails {
-		if isTrue := ifIsTrue(graphData.User.AccDetails.SteamID, seenIDs); !isTrue {
-			seenIDs[graphData.User.AccDetails.SteamID] = true
-			uniqueFriendsGraphInformation = append(uniqueFriendsGraphInformation, graphData.User)
+	for _, ID := range secondIDs {
+		if !seenIDs[ID] {
+			seenIDs[ID] = true
+			allIDs = append(allIDs, ID)
 		}
 	}
-	return uniqueFriendsGraphInformation
+	return sharedIDs, allIDs
 }
 
 func toInt64(steamID string) int64 {
@@ -9,8 +9,11 @@ import (
 	"github.com/neosteamfriendgraphing/common"
 	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+var graphedCrawlingStatus = datastructures.CrawlingStatus{State: datastructures.CrawlStateGraphed}
+
 func TestGetShortestDistanceWithTwoUsersWhoAreDirectFriendsWithEachother(t *testing.T) {
 	mockController := &controller.MockCntrInterface{}
 
@@ -28,8 +31,9 @@ func TestGetShortestDistanceWithTwoUsersWhoAreDirectFriendsWithEachother(t *test
 		TotalNetworkSpan: 2,
 	}
 
-	mockController.On("GetProcessedGraphData", firstUserCrawlID).Return(userOneGraphData, nil)
-	mockController.On("GetProcessedGraphData", secondUserCrawlID).Return(userTwoGraphData, nil)
+	mockController.On("GetProcessedGraphData", firstUserCrawlID).Return(datastructures.UsersGraphData{UsersGraphData: userOneGraphData}, nil)
+	mockController.On("GetProcessedGraphData", secondUserCrawlID).Return(datastructures.UsersGraphData{UsersGraphData: userTwoGraphData}, nil)
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.AnythingOfType("string")).Return(graphedCrawlingStatus, nil)
 
 	exists, actualShortestPathInfo, err := CalulateShortestDistanceInfo(
 		mockController,
@@ -63,8 +67,9 @@ func TestGetShortestDistanceWithTwoUsersWhoShareOneCommonFriend(t *testing.T) {
 		TotalNetworkSpan: 3,
 	}
 
-	mockController.On("GetProcessedGraphData", firstUserCrawlID).Return(userOneWithOneSharedCommonFriendGraphData, nil)
-	mockController.On("GetProcessedGraphData", secondUserCrawlID).Return(userTwoWithOneSharedCommonFriendGraphData, nil)
+	mockController.On("GetProcessedGraphData", firstUserCrawlID).Return(datastructures.UsersGraphData{UsersGraphData: userOneWithOneSharedCommonFriendGraphData}, nil)
+	mockController.On("GetProcessedGraphData", secondUserCrawlID).Return(datastructures.UsersGraphData{UsersGraphData: userTwoWithOneSharedCommonFriendGraphData}, nil)
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.AnythingOfType("string")).Return(graphedCrawlingStatus, nil)
 
 	exists, actualShortestPathInfo, err := CalulateShortestDistanceInfo(
 		mockController,
@@ -147,3 +152,22 @@ func TestToInt64(t *testing.T) {
 
 	assert.Equal(t, expected, toInt64(steamID))
 }
+
+func TestGetShortestDistanceReturnsNothingWhenACrawlHasNotBeenGraphed(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	firstUserCrawlID := ksuid.New().String()
+	secondUserCrawlID := ksuid.New().String()
+
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, firstUserCrawlID).Return(graphedCrawlingStatus, nil)
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, secondUserCrawlID).Return(datastructures.CrawlingStatus{State: datastructures.CrawlStateGraphing}, nil)
+
+	exists, _, err := CalulateShortestDistanceInfo(
+		mockController,
+		firstUserCrawlID,
+		secondUserCrawlID)
+
+	assert.False(t, exists)
+	assert.Nil(t, err)
+	mockController.AssertNotCalled(t, "GetProcessedGraphData", mock.Anything)
+}
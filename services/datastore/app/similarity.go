@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"sort"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+// GetUserSimilarity compares the friends and libraries of two users that
+// have been crawled. False is returned if either user is not in the DB
+func GetUserSimilarity(cntr controller.CntrInterface, firstSteamID, secondSteamID string) (bool, datastructures.UserSimilarity, error) {
+	users := []common.UserDocument{}
+	for _, steamID := range []string{firstSteamID, secondSteamID} {
+		user, err := cntr.GetUser(context.TODO(), steamID)
+		if err != nil {
+			return false, datastructures.UserSimilarity{}, err
+		}
+		if user.AccDetails.SteamID == "" {
+			return false, datastructures.UserSimilarity{}, nil
+		}
+		users = append(users, user)
+	}
+	return true, getUserSimilarity(users[0], users[1]), nil
+}
+
+func getUserSimilarity(firstUser, secondUser common.UserDocument) datastructures.UserSimilarity {
+	mutualFriends, allFriends := getIDOverlap(firstUser.FriendIDs, secondUser.FriendIDs)
+	sharedGames, allGamesCount := getSharedGames(firstUser.GamesOwned, secondUser.GamesOwned)
+	return datastructures.UserSimilarity{
+		SteamIDs:          []string{firstUser.AccDetails.SteamID, secondUser.AccDetails.SteamID},
+		MutualFriends:     mutualFriends,
+		FriendSimilarity:  jaccardIndex(len(mutualFriends), len(allFriends)),
+		SharedGames:       sharedGames,
+		LibrarySimilarity: jaccardIndex(len(sharedGames), allGamesCount),
+	}
+}
+
+// getSharedGames returns the games owned by both users, most played
+// first, along with how many unique games they own between them
+func getSharedGames(firstGames, secondGames []common.GameOwnedDocument) ([]datastructures.SharedGame, int) {
+	firstPlaytimes := make(map[int]int, len(firstGames))
+	for _, game := range firstGames {
+		firstPlaytimes[game.AppID] = game.Playtime_Forever
+	}
+	secondPlaytimes := make(map[int]int, len(secondGames))
+	for _, game := range secondGames {
+		secondPlaytimes[game.AppID] = game.Playtime_Forever
+	}
+
+	sharedGames := []datastructures.SharedGame{}
+	for appID, firstPlaytime := range firstPlaytimes {
+		secondPlaytime, bothOwn := secondPlaytimes[appID]
+		if !bothOwn {
+			continue
+		}
+		sharedGames = append(sharedGames, datastructures.SharedGame{
+			AppID:              appID,
+			FirstUserPlaytime:  firstPlaytime,
+			SecondUserPlaytime: secondPlaytime,
+			CombinedPlaytime:   firstPlaytime + secondPlaytime,
+		})
+	}
+	sort.Slice(sharedGames, func(i, j int) bool {
+		if sharedGames[i].CombinedPlaytime != sharedGames[j].CombinedPlaytime {
+			return sharedGames[i].CombinedPlaytime > sharedGames[j].CombinedPlaytime
+		}
+		return sharedGames[i].AppID < sharedGames[j].AppID
+	})
+	return sharedGames, len(firstPlaytimes) + len(secondPlaytimes) - len(sharedGames)
+}
+
+// jaccardIndex is the size of the overlap of two sets divided by the
+// size of their union. Two empty sets have nothing in common
+func jaccardIndex(overlapCount, unionCount int) float64 {
+	if unionCount == 0 {
+		return 0
+	}
+	return float64(overlapCount) / float64(unionCount)
+}
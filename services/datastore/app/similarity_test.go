@@ -0,0 +1,86 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetUserSimilarityFindsMutualFriendsAndSharedGames(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	firstUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{SteamID: "76561197969081524"},
+		FriendIDs:  []string{"1", "2", "3"},
+		GamesOwned: []common.GameOwnedDocument{
+			{AppID: 730, Playtime_Forever: 100},
+			{AppID: 570, Playtime_Forever: 50},
+			{AppID: 440, Playtime_Forever: 10},
+		},
+	}
+	secondUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{SteamID: "76561198000000001"},
+		FriendIDs:  []string{"3", "2", "4"},
+		GamesOwned: []common.GameOwnedDocument{
+			{AppID: 570, Playtime_Forever: 200},
+			{AppID: 730, Playtime_Forever: 20},
+		},
+	}
+	mockController.On("GetUser", mock.Anything, firstUser.AccDetails.SteamID).Return(firstUser, nil)
+	mockController.On("GetUser", mock.Anything, secondUser.AccDetails.SteamID).Return(secondUser, nil)
+
+	expectedSimilarity := datastructures.UserSimilarity{
+		SteamIDs:         []string{firstUser.AccDetails.SteamID, secondUser.AccDetails.SteamID},
+		MutualFriends:    []string{"2", "3"},
+		FriendSimilarity: 0.5,
+		SharedGames: []datastructures.SharedGame{
+			{AppID: 570, FirstUserPlaytime: 50, SecondUserPlaytime: 200, CombinedPlaytime: 250},
+			{AppID: 730, FirstUserPlaytime: 100, SecondUserPlaytime: 20, CombinedPlaytime: 120},
+		},
+		LibrarySimilarity: 2.0 / 3.0,
+	}
+
+	exists, actualSimilarity, err := GetUserSimilarity(mockController, firstUser.AccDetails.SteamID, secondUser.AccDetails.SteamID)
+
+	assert.True(t, exists)
+	assert.Equal(t, expectedSimilarity, actualSimilarity)
+	assert.Nil(t, err)
+}
+
+func TestGetUserSimilarityReturnsFalseWhenAUserHasNotBeenCrawled(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+
+	firstUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{SteamID: "76561197969081524"},
+	}
+	mockController.On("GetUser", mock.Anything, firstUser.AccDetails.SteamID).Return(firstUser, nil)
+	mockController.On("GetUser", mock.Anything, "76561198000000001").Return(common.UserDocument{}, nil)
+
+	exists, similarity, err := GetUserSimilarity(mockController, firstUser.AccDetails.SteamID, "76561198000000001")
+
+	assert.False(t, exists)
+	assert.Equal(t, datastructures.UserSimilarity{}, similarity)
+	assert.Nil(t, err)
+}
+
+func TestGetIDOverlapIgnoresDuplicateIDs(t *testing.T) {
+	sharedIDs, allIDs := getIDOverlap([]string{"1", "1", "2"}, []string{"2", "2", "3"})
+
+	assert.Equal(t, []string{"2"}, sharedIDs)
+	assert.Equal(t, []string{"1", "2", "3"}, allIDs)
+}
+
+func TestGetIDOverlapLeavesOutExcludedIDs(t *testing.T) {
+	sharedIDs, allIDs := getIDOverlap([]string{"1", "2", "3"}, []string{"3", "2", "4"}, "1", "3")
+
+	assert.Equal(t, []string{"2"}, sharedIDs)
+	assert.Equal(t, []string{"2", "4"}, allIDs)
+}
+
+func TestJaccardIndexOfTwoEmptySetsIsZero(t *testing.T) {
+	assert.Equal(t, 0.0, jaccardIndex(0, 0))
+}
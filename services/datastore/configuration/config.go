@@ -42,7 +42,9 @@ func InitConfig() error {
 		"MONGODB_PASSWORD", "MONGO_INSTANCE_IP", "DB_NAME",
 		"USER_COLLECTION", "CRAWLING_STATS_COLLECTION",
 		"POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB",
-		"POSTGRES_INSTANCE_IP", "SHORTEST_DISTANCE_COLLECTION")
+		"POSTGRES_INSTANCE_IP", "SHORTEST_DISTANCE_COLLECTION",
+		"FRIEND_HISTORY_COLLECTION", "WATCHED_USERS_COLLECTION",
+		"CRAWLER_INSTANCE")
 	if err != nil {
 		return util.MakeErr(err)
 	}
@@ -19,20 +19,41 @@ type MockCntrInterface struct {
 	mock.Mock
 }
 
-// DoesProcessedGraphDataExist provides a mock function with given fields: crawlID
-func (_m *MockCntrInterface) DoesProcessedGraphDataExist(crawlID string) (bool, error) {
-	ret := _m.Called(crawlID)
+// ClaimWatchedUserCrawl provides a mock function with given fields: ctx, steamID, claim
+func (_m *MockCntrInterface) ClaimWatchedUserCrawl(ctx context.Context, steamID string, claim datastructures.WatchedUserCrawlClaim) (bool, error) {
+	ret := _m.Called(ctx, steamID, claim)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(string) bool); ok {
-		r0 = rf(crawlID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, datastructures.WatchedUserCrawlClaim) bool); ok {
+		r0 = rf(ctx, steamID, claim)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(crawlID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, datastructures.WatchedUserCrawlClaim) error); ok {
+		r1 = rf(ctx, steamID, claim)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DoesProcessedGraphDataExist provides a mock function with given fields: crawlID, includePartial
+func (_m *MockCntrInterface) DoesProcessedGraphDataExist(crawlID string, includePartial bool) (bool, error) {
+	ret := _m.Called(crawlID, includePartial)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, bool) bool); ok {
+		r0 = rf(crawlID, includePartial)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, bool) error); ok {
+		r1 = rf(crawlID, includePartial)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,14 +62,14 @@ func (_m *MockCntrInterface) DoesProcessedGraphDataExist(crawlID string) (bool,
 }
 
 // GetCrawlingStatusFromDBFromCrawlID provides a mock function with given fields: ctx, crawlID
-func (_m *MockCntrInterface) GetCrawlingStatusFromDBFromCrawlID(ctx context.Context, crawlID string) (common.CrawlingStatus, error) {
+func (_m *MockCntrInterface) GetCrawlingStatusFromDBFromCrawlID(ctx context.Context, crawlID string) (datastructures.CrawlingStatus, error) {
 	ret := _m.Called(ctx, crawlID)
 
-	var r0 common.CrawlingStatus
-	if rf, ok := ret.Get(0).(func(context.Context, string) common.CrawlingStatus); ok {
+	var r0 datastructures.CrawlingStatus
+	if rf, ok := ret.Get(0).(func(context.Context, string) datastructures.CrawlingStatus); ok {
 		r0 = rf(ctx, crawlID)
 	} else {
-		r0 = ret.Get(0).(common.CrawlingStatus)
+		r0 = ret.Get(0).(datastructures.CrawlingStatus)
 	}
 
 	var r1 error
@@ -61,6 +82,29 @@ func (_m *MockCntrInterface) GetCrawlingStatusFromDBFromCrawlID(ctx context.Cont
 	return r0, r1
 }
 
+// GetCrawlsInState provides a mock function with given fields: ctx, state, enteredBefore
+func (_m *MockCntrInterface) GetCrawlsInState(ctx context.Context, state string, enteredBefore int64) ([]datastructures.CrawlingStatus, error) {
+	ret := _m.Called(ctx, state, enteredBefore)
+
+	var r0 []datastructures.CrawlingStatus
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) []datastructures.CrawlingStatus); ok {
+		r0 = rf(ctx, state, enteredBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]datastructures.CrawlingStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, state, enteredBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetDetailsForGames provides a mock function with given fields: ctx, IDList
 func (_m *MockCntrInterface) GetDetailsForGames(ctx context.Context, IDList []int) ([]common.BareGameInfo, error) {
 	ret := _m.Called(ctx, IDList)
@@ -84,6 +128,57 @@ func (_m *MockCntrInterface) GetDetailsForGames(ctx context.Context, IDList []in
 	return r0, r1
 }
 
+// GetFriendListChanges provides a mock function with given fields: ctx, steamID
+func (_m *MockCntrInterface) GetFriendListChanges(ctx context.Context, steamID string) ([]datastructures.FriendListChange, error) {
+	ret := _m.Called(ctx, steamID)
+
+	var r0 []datastructures.FriendListChange
+	if rf, ok := ret.Get(0).(func(context.Context, string) []datastructures.FriendListChange); ok {
+		r0 = rf(ctx, steamID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]datastructures.FriendListChange)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, steamID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInsights provides a mock function with given fields: crawlID
+func (_m *MockCntrInterface) GetInsights(crawlID string) (bool, datastructures.NetworkInsights, error) {
+	ret := _m.Called(crawlID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(crawlID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 datastructures.NetworkInsights
+	if rf, ok := ret.Get(1).(func(string) datastructures.NetworkInsights); ok {
+		r1 = rf(crawlID)
+	} else {
+		r1 = ret.Get(1).(datastructures.NetworkInsights)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(crawlID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetNMostRecentFinishedCrawls provides a mock function with given fields: ctx, amount
 func (_m *MockCntrInterface) GetNMostRecentFinishedCrawls(ctx context.Context, amount int64) ([]common.CrawlingStatus, error) {
 	ret := _m.Called(ctx, amount)
@@ -131,14 +226,14 @@ func (_m *MockCntrInterface) GetNMostRecentFinishedShortestDistanceCrawls(ctx co
 }
 
 // GetProcessedGraphData provides a mock function with given fields: crawlID
-func (_m *MockCntrInterface) GetProcessedGraphData(crawlID string) (common.UsersGraphData, error) {
+func (_m *MockCntrInterface) GetProcessedGraphData(crawlID string) (datastructures.UsersGraphData, error) {
 	ret := _m.Called(crawlID)
 
-	var r0 common.UsersGraphData
-	if rf, ok := ret.Get(0).(func(string) common.UsersGraphData); ok {
+	var r0 datastructures.UsersGraphData
+	if rf, ok := ret.Get(0).(func(string) datastructures.UsersGraphData); ok {
 		r0 = rf(crawlID)
 	} else {
-		r0 = ret.Get(0).(common.UsersGraphData)
+		r0 = ret.Get(0).(datastructures.UsersGraphData)
 	}
 
 	var r1 error
@@ -244,6 +339,43 @@ func (_m *MockCntrInterface) GetUsernames(ctx context.Context, steamIDs []string
 	return r0, r1
 }
 
+// GetUsers provides a mock function with given fields: ctx, steamIDs, foundUser
+func (_m *MockCntrInterface) GetUsers(ctx context.Context, steamIDs []string, foundUser func(common.UserDocument) error) error {
+	ret := _m.Called(ctx, steamIDs, foundUser)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, func(common.UserDocument) error) error); ok {
+		r0 = rf(ctx, steamIDs, foundUser)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetWatchedUsers provides a mock function with given fields: ctx
+func (_m *MockCntrInterface) GetWatchedUsers(ctx context.Context) ([]datastructures.WatchedUser, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []datastructures.WatchedUser
+	if rf, ok := ret.Get(0).(func(context.Context) []datastructures.WatchedUser); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]datastructures.WatchedUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HasUserBeenCrawledBeforeAtLevel provides a mock function with given fields: ctx, level, steamID
 func (_m *MockCntrInterface) HasUserBeenCrawledBeforeAtLevel(ctx context.Context, level int, steamID string) (string, error) {
 	ret := _m.Called(ctx, level, steamID)
@@ -309,20 +441,78 @@ func (_m *MockCntrInterface) InsertOne(ctx context.Context, collection *mongo.Co
 	return r0, r1
 }
 
-// SaveProcessedGraphData provides a mock function with given fields: crawlID, graphData
-func (_m *MockCntrInterface) SaveProcessedGraphData(crawlID string, graphData common.UsersGraphData) (bool, error) {
-	ret := _m.Called(crawlID, graphData)
+// ReclaimExpiredGraphingLeases provides a mock function with given fields: ctx, renewedBefore, timestamp
+func (_m *MockCntrInterface) ReclaimExpiredGraphingLeases(ctx context.Context, renewedBefore int64, timestamp int64) ([]string, error) {
+	ret := _m.Called(ctx, renewedBefore, timestamp)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []string); ok {
+		r0 = rf(ctx, renewedBefore, timestamp)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, renewedBefore, timestamp)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RenewGraphingLease provides a mock function with given fields: ctx, crawlID, leaseID, timestamp
+func (_m *MockCntrInterface) RenewGraphingLease(ctx context.Context, crawlID string, leaseID string, timestamp int64) (bool, error) {
+	ret := _m.Called(ctx, crawlID, leaseID, timestamp)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(string, common.UsersGraphData) bool); ok {
-		r0 = rf(crawlID, graphData)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) bool); ok {
+		r0 = rf(ctx, crawlID, leaseID, timestamp)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, common.UsersGraphData) error); ok {
-		r1 = rf(crawlID, graphData)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(ctx, crawlID, leaseID, timestamp)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveFriendListChange provides a mock function with given fields: ctx, change
+func (_m *MockCntrInterface) SaveFriendListChange(ctx context.Context, change datastructures.FriendListChange) error {
+	ret := _m.Called(ctx, change)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, datastructures.FriendListChange) error); ok {
+		r0 = rf(ctx, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveProcessedGraphData provides a mock function with given fields: crawlID, graphData, insights
+func (_m *MockCntrInterface) SaveProcessedGraphData(crawlID string, graphData datastructures.UsersGraphData, insights datastructures.NetworkInsights) (bool, error) {
+	ret := _m.Called(crawlID, graphData, insights)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, datastructures.UsersGraphData, datastructures.NetworkInsights) bool); ok {
+		r0 = rf(crawlID, graphData, insights)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, datastructures.UsersGraphData, datastructures.NetworkInsights) error); ok {
+		r1 = rf(crawlID, graphData, insights)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -351,23 +541,121 @@ func (_m *MockCntrInterface) SaveShortestDistance(ctx context.Context, shortestD
 	return r0, r1
 }
 
+// SaveWatchedUser provides a mock function with given fields: ctx, watchedUser
+func (_m *MockCntrInterface) SaveWatchedUser(ctx context.Context, watchedUser datastructures.WatchedUser) error {
+	ret := _m.Called(ctx, watchedUser)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, datastructures.WatchedUser) error); ok {
+		r0 = rf(ctx, watchedUser)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StartGraphCreation provides a mock function with given fields: crawlID
+func (_m *MockCntrInterface) StartGraphCreation(crawlID string) error {
+	ret := _m.Called(crawlID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(crawlID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateCrawlBudget provides a mock function with given fields: ctx, crawlID, usage
+func (_m *MockCntrInterface) UpdateCrawlBudget(ctx context.Context, crawlID string, usage datastructures.CrawlBudgetUsage) (bool, datastructures.CrawlingStatus, error) {
+	ret := _m.Called(ctx, crawlID, usage)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, datastructures.CrawlBudgetUsage) bool); ok {
+		r0 = rf(ctx, crawlID, usage)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 datastructures.CrawlingStatus
+	if rf, ok := ret.Get(1).(func(context.Context, string, datastructures.CrawlBudgetUsage) datastructures.CrawlingStatus); ok {
+		r1 = rf(ctx, crawlID, usage)
+	} else {
+		r1 = ret.Get(1).(datastructures.CrawlingStatus)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, datastructures.CrawlBudgetUsage) error); ok {
+		r2 = rf(ctx, crawlID, usage)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdateCrawlState provides a mock function with given fields: ctx, crawlID, newState, leaseID, timestamp
+func (_m *MockCntrInterface) UpdateCrawlState(ctx context.Context, crawlID string, newState string, leaseID string, timestamp int64) (bool, error) {
+	ret := _m.Called(ctx, crawlID, newState, leaseID, timestamp)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64) bool); ok {
+		r0 = rf(ctx, crawlID, newState, leaseID, timestamp)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int64) error); ok {
+		r1 = rf(ctx, crawlID, newState, leaseID, timestamp)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateCrawlingStatus provides a mock function with given fields: ctx, collection, crawlingStatus
-func (_m *MockCntrInterface) UpdateCrawlingStatus(ctx context.Context, collection *mongo.Collection, crawlingStatus common.CrawlingStatus) (bool, error) {
+func (_m *MockCntrInterface) UpdateCrawlingStatus(ctx context.Context, collection *mongo.Collection, crawlingStatus datastructures.CrawlingStatus) (bool, datastructures.CrawlingStatus, error) {
 	ret := _m.Called(ctx, collection, crawlingStatus)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(context.Context, *mongo.Collection, common.CrawlingStatus) bool); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *mongo.Collection, datastructures.CrawlingStatus) bool); ok {
 		r0 = rf(ctx, collection, crawlingStatus)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *mongo.Collection, common.CrawlingStatus) error); ok {
+	var r1 datastructures.CrawlingStatus
+	if rf, ok := ret.Get(1).(func(context.Context, *mongo.Collection, datastructures.CrawlingStatus) datastructures.CrawlingStatus); ok {
 		r1 = rf(ctx, collection, crawlingStatus)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(datastructures.CrawlingStatus)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, *mongo.Collection, datastructures.CrawlingStatus) error); ok {
+		r2 = rf(ctx, collection, crawlingStatus)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpsertUser provides a mock function with given fields: ctx, user
+func (_m *MockCntrInterface) UpsertUser(ctx context.Context, user common.UserDocument) error {
+	ret := _m.Called(ctx, user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, common.UserDocument) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
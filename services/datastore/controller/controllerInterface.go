@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/IamCathal/neo/services/datastore/configuration"
 	"github.com/IamCathal/neo/services/datastore/datastructures"
-	"github.com/lib/pq"
 	"github.com/neosteamfriendgraphing/common"
 	"github.com/neosteamfriendgraphing/common/util"
 	"github.com/pkg/errors"
@@ -23,9 +25,16 @@ type Cntr struct{}
 type CntrInterface interface {
 	// MongoDB related functions
 	InsertOne(ctx context.Context, collection *mongo.Collection, bson []byte) (*mongo.InsertOneResult, error)
-	UpdateCrawlingStatus(ctx context.Context, collection *mongo.Collection, crawlingStatus common.CrawlingStatus) (bool, error)
+	UpsertUser(ctx context.Context, user common.UserDocument) error
+	UpdateCrawlingStatus(ctx context.Context, collection *mongo.Collection, crawlingStatus datastructures.CrawlingStatus) (bool, datastructures.CrawlingStatus, error)
+	UpdateCrawlBudget(ctx context.Context, crawlID string, usage datastructures.CrawlBudgetUsage) (bool, datastructures.CrawlingStatus, error)
+	UpdateCrawlState(ctx context.Context, crawlID, newState, leaseID string, timestamp int64) (bool, error)
+	RenewGraphingLease(ctx context.Context, crawlID, leaseID string, timestamp int64) (bool, error)
+	ReclaimExpiredGraphingLeases(ctx context.Context, renewedBefore, timestamp int64) ([]string, error)
+	GetCrawlsInState(ctx context.Context, state string, enteredBefore int64) ([]datastructures.CrawlingStatus, error)
 	GetUser(ctx context.Context, steamID string) (common.UserDocument, error)
-	GetCrawlingStatusFromDBFromCrawlID(ctx context.Context, crawlID string) (common.CrawlingStatus, error)
+	GetUsers(ctx context.Context, steamIDs []string, foundUser func(common.UserDocument) error) error
+	GetCrawlingStatusFromDBFromCrawlID(ctx context.Context, crawlID string) (datastructures.CrawlingStatus, error)
 	HasUserBeenCrawledBeforeAtLevel(ctx context.Context, level int, steamID string) (string, error)
 	GetUsernames(ctx context.Context, steamIDs []string) (map[string]string, error)
 	InsertGame(ctx context.Context, game common.BareGameInfo) (bool, error)
@@ -35,10 +44,18 @@ type CntrInterface interface {
 	GetNMostRecentFinishedCrawls(ctx context.Context, amount int64) ([]common.CrawlingStatus, error)
 	GetNMostRecentFinishedShortestDistanceCrawls(ctx context.Context, amount int64) ([]datastructures.ShortestDistanceInfo, error)
 	GetTotalDocumentsInCollection(ctx context.Context, collectionName string) (int64, error)
+	SaveFriendListChange(ctx context.Context, change datastructures.FriendListChange) error
+	GetFriendListChanges(ctx context.Context, steamID string) ([]datastructures.FriendListChange, error)
+	SaveWatchedUser(ctx context.Context, watchedUser datastructures.WatchedUser) error
+	GetWatchedUsers(ctx context.Context) ([]datastructures.WatchedUser, error)
+	ClaimWatchedUserCrawl(ctx context.Context, steamID string, claim datastructures.WatchedUserCrawlClaim) (bool, error)
 	// Postgresql related functions
-	SaveProcessedGraphData(crawlID string, graphData common.UsersGraphData) (bool, error)
-	GetProcessedGraphData(crawlID string) (common.UsersGraphData, error)
-	DoesProcessedGraphDataExist(crawlID string) (bool, error)
+	SaveProcessedGraphData(crawlID string, graphData datastructures.UsersGraphData, insights datastructures.NetworkInsights) (bool, error)
+	GetProcessedGraphData(crawlID string) (datastructures.UsersGraphData, error)
+	GetInsights(crawlID string) (bool, datastructures.NetworkInsights, error)
+	DoesProcessedGraphDataExist(crawlID string, includePartial bool) (bool, error)
+	// Crawler related functions
+	StartGraphCreation(crawlID string) error
 }
 
 func (control Cntr) InsertOne(ctx context.Context, collection *mongo.Collection, bson []byte) (*mongo.InsertOneResult, error) {
@@ -54,28 +71,254 @@ func (control Cntr) InsertOne(ctx context.Context, collection *mongo.Collection,
 	return insertionResult, nil
 }
 
-func (control Cntr) UpdateCrawlingStatus(ctx context.Context, collection *mongo.Collection, crawlingStatus common.CrawlingStatus) (bool, error) {
-	updatedDoc := collection.FindOneAndUpdate(context.TODO(),
-		bson.M{"crawlid": crawlingStatus.CrawlID},
-		bson.D{
-			primitive.E{
-				Key: "$inc",
-				Value: bson.D{
-					primitive.E{Key: "totaluserstocrawl", Value: crawlingStatus.TotalUsersToCrawl},
-					primitive.E{Key: "userscrawled", Value: 1},
-				},
+// UpsertUser replaces the stored document for a user with the given
+// one so that refetched users do not keep their outdated data. The
+// user is inserted if they have not been saved before
+func (control Cntr) UpsertUser(ctx context.Context, user common.UserDocument) error {
+	userCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("USER_COLLECTION"))
+	_, err := userCollection.ReplaceOne(ctx, bson.M{
+		"accdetails.steamid": user.AccDetails.SteamID,
+	}, user, options.Replace().SetUpsert(true))
+	if err != nil {
+		return util.MakeErr(err, "failed to upsert user")
+	}
+	return nil
+}
+
+// UpdateCrawlingStatus increments the counts of a crawl and returns
+// its crawling status as it is after the update
+func (control Cntr) UpdateCrawlingStatus(ctx context.Context, collection *mongo.Collection, crawlingStatus datastructures.CrawlingStatus) (bool, datastructures.CrawlingStatus, error) {
+	increments := bson.D{
+		primitive.E{Key: "totaluserstocrawl", Value: crawlingStatus.TotalUsersToCrawl},
+		primitive.E{Key: "userscrawled", Value: 1},
+	}
+	if crawlingStatus.PrivateProfiles > 0 {
+		increments = append(increments, primitive.E{Key: "privateprofiles", Value: crawlingStatus.PrivateProfiles})
+	}
+	if crawlingStatus.FailedJobs > 0 {
+		increments = append(increments, primitive.E{Key: "failedjobs", Value: crawlingStatus.FailedJobs})
+	}
+	for level, levelProgress := range crawlingStatus.Levels {
+		if levelProgress.Discovered > 0 {
+			increments = append(increments, primitive.E{Key: fmt.Sprintf("levels.%s.discovered", level), Value: levelProgress.Discovered})
+		}
+		if levelProgress.Completed > 0 {
+			increments = append(increments, primitive.E{Key: fmt.Sprintf("levels.%s.completed", level), Value: levelProgress.Completed})
+		}
+	}
+	for minute, jobs := range crawlingStatus.JobsPerMinute {
+		increments = append(increments, primitive.E{Key: fmt.Sprintf("jobsperminute.%s", minute), Value: jobs})
+	}
+	update := bson.D{
+		primitive.E{
+			Key:   "$inc",
+			Value: increments,
+		},
+	}
+	if len(crawlingStatus.CappedHubs) > 0 {
+		update = append(update, primitive.E{
+			Key: "$addToSet",
+			Value: bson.D{
+				primitive.E{Key: "cappedhubs", Value: bson.M{"$each": crawlingStatus.CappedHubs}},
 			},
 		})
+	}
+	updatedCrawlingStatus := datastructures.CrawlingStatus{}
+	err := collection.FindOneAndUpdate(context.TODO(),
+		bson.M{"crawlid": crawlingStatus.CrawlID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updatedCrawlingStatus)
 	// If the document did not exists
-	if updatedDoc.Err() == mongo.ErrNoDocuments {
-		return false, nil
+	if err == mongo.ErrNoDocuments {
+		return false, datastructures.CrawlingStatus{}, nil
 	}
 	// Document did exist but a different error was returned
-	if updatedDoc.Err() != nil {
-		return false, util.MakeErr(updatedDoc.Err())
+	if err != nil {
+		return false, datastructures.CrawlingStatus{}, util.MakeErr(err)
 	}
 	// Document did exist (best case)
-	return true, nil
+	return true, updatedCrawlingStatus, nil
+}
+
+// UpdateCrawlBudget atomically records the API calls made for a crawl and
+// reserves up to usage.UsersRequested users from its user budget. The
+// crawling status as it was before the update is returned so that the
+// amount of users granted can be worked out
+func (control Cntr) UpdateCrawlBudget(ctx context.Context, crawlID string, usage datastructures.CrawlBudgetUsage) (bool, datastructures.CrawlingStatus, error) {
+	crawlingStatsCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("CRAWLING_STATS_COLLECTION"))
+
+	usersReserved := bson.M{"$ifNull": bson.A{"$usersreserved", 0}}
+	maxUsers := bson.M{"$ifNull": bson.A{"$budget.maxusers", 0}}
+	hasUserBudget := bson.M{"$gt": bson.A{maxUsers, 0}}
+	requestedUsersReserved := bson.M{"$add": bson.A{usersReserved, usage.UsersRequested}}
+
+	// Users reserved never goes above the user budget (if there is one)
+	// and the crawl is marked as truncated once a request cannot be
+	// fully granted
+	updatePipeline := mongo.Pipeline{
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "apicallsmade", Value: bson.M{"$add": bson.A{
+					bson.M{"$ifNull": bson.A{"$apicallsmade", 0}},
+					usage.APICallsMade,
+				}}},
+				{Key: "usersreserved", Value: bson.M{"$cond": bson.A{
+					hasUserBudget,
+					bson.M{"$min": bson.A{requestedUsersReserved, bson.M{"$max": bson.A{usersReserved, maxUsers}}}},
+					requestedUsersReserved,
+				}}},
+				{Key: "truncated", Value: bson.M{"$or": bson.A{
+					bson.M{"$ifNull": bson.A{"$truncated", false}},
+					usage.Truncated,
+					bson.M{"$and": bson.A{hasUserBudget, bson.M{"$gt": bson.A{requestedUsersReserved, maxUsers}}}},
+				}}},
+			}},
+		},
+	}
+
+	crawlingStatusBeforeUpdate := datastructures.CrawlingStatus{}
+	err := crawlingStatsCollection.FindOneAndUpdate(ctx,
+		bson.M{"crawlid": crawlID},
+		updatePipeline).Decode(&crawlingStatusBeforeUpdate)
+	if err == mongo.ErrNoDocuments {
+		return false, datastructures.CrawlingStatus{}, nil
+	}
+	if err != nil {
+		return false, datastructures.CrawlingStatus{}, util.MakeErr(err)
+	}
+	return true, crawlingStatusBeforeUpdate, nil
+}
+
+// UpdateCrawlState moves a crawl to a new state and records when it did
+// so. The crawl is only updated if its current state is one that can move
+// to the new state, false is returned otherwise. A crawl moving to the
+// graphing state is leased to leaseID and only leaves it again when the
+// same leaseID is given. Crawls that never finished crawling are not
+// moved to the graphing state
+func (control Cntr) UpdateCrawlState(ctx context.Context, crawlID, newState, leaseID string, timestamp int64) (bool, error) {
+	crawlingStatsCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("CRAWLING_STATS_COLLECTION"))
+	stateUpdate := bson.M{
+		"state": newState,
+		fmt.Sprintf("statetimestamps.%s", newState): timestamp,
+	}
+	currentState := bson.M{"$in": datastructures.GetPreviousCrawlStates(newState)}
+	filter := bson.M{
+		"crawlid": crawlID,
+		"state":   currentState,
+	}
+	if newState == datastructures.CrawlStateGraphing {
+		// Only crawls that finished crawling can be graphed, failed
+		// crawls included
+		filter[fmt.Sprintf("statetimestamps.%s", datastructures.CrawlStateCrawlComplete)] = bson.M{"$exists": true}
+		stateUpdate["graphinglease"] = datastructures.GraphingLease{ID: leaseID, RenewedAt: timestamp}
+	} else if leaseID != "" {
+		currentState["$eq"] = datastructures.CrawlStateGraphing
+		filter["graphinglease.id"] = leaseID
+	} else {
+		currentState["$ne"] = datastructures.CrawlStateGraphing
+	}
+
+	updateResult, err := crawlingStatsCollection.UpdateOne(ctx, filter, bson.M{"$set": stateUpdate})
+	if err != nil {
+		return false, util.MakeErr(err, "failed to update crawl state")
+	}
+	return updateResult.ModifiedCount == 1, nil
+}
+
+// RenewGraphingLease records that the graph build holding the lease of a
+// graphing crawl is still running. False is returned if the crawl is no
+// longer graphing under the given lease
+func (control Cntr) RenewGraphingLease(ctx context.Context, crawlID, leaseID string, timestamp int64) (bool, error) {
+	crawlingStatsCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("CRAWLING_STATS_COLLECTION"))
+
+	updateResult, err := crawlingStatsCollection.UpdateOne(ctx, bson.M{
+		"crawlid":          crawlID,
+		"state":            datastructures.CrawlStateGraphing,
+		"graphinglease.id": leaseID,
+	}, bson.M{
+		"$set": bson.M{"graphinglease.renewedat": timestamp},
+	})
+	if err != nil {
+		return false, util.MakeErr(err, "failed to renew graphing lease")
+	}
+	return updateResult.MatchedCount == 1, nil
+}
+
+// ReclaimExpiredGraphingLeases moves graphing crawls whose lease was last
+// renewed before the given unix time back to crawl-complete and returns
+// their crawlIDs. Crawls that started graphing before leases were recorded
+// are reclaimed once they have been graphing for as long
+func (control Cntr) ReclaimExpiredGraphingLeases(ctx context.Context, renewedBefore, timestamp int64) ([]string, error) {
+	crawlingStatsCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("CRAWLING_STATS_COLLECTION"))
+	hasExpiredLease := bson.A{
+		bson.M{"graphinglease.renewedat": bson.M{"$lt": renewedBefore}},
+		bson.M{
+			"graphinglease.renewedat": bson.M{"$exists": false},
+			fmt.Sprintf("statetimestamps.%s", datastructures.CrawlStateGraphing): bson.M{"$lt": renewedBefore},
+		},
+	}
+
+	cursor, err := crawlingStatsCollection.Find(ctx, bson.M{
+		"state": datastructures.CrawlStateGraphing,
+		"$or":   hasExpiredLease,
+	})
+	if err != nil {
+		return []string{}, util.MakeErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	reclaimedCrawlIDs := []string{}
+	for cursor.Next(ctx) {
+		crawlingStatus := datastructures.CrawlingStatus{}
+		if err := cursor.Decode(&crawlingStatus); err != nil {
+			return []string{}, util.MakeErr(err)
+		}
+		// The lease is checked again as it may have been renewed
+		// since the crawl was found
+		updateResult, err := crawlingStatsCollection.UpdateOne(ctx, bson.M{
+			"crawlid": crawlingStatus.CrawlID,
+			"state":   datastructures.CrawlStateGraphing,
+			"$or":     hasExpiredLease,
+		}, bson.M{
+			"$set": bson.M{
+				"state": datastructures.CrawlStateCrawlComplete,
+				fmt.Sprintf("statetimestamps.%s", datastructures.CrawlStateCrawlComplete): timestamp,
+			},
+			"$unset": bson.M{"graphinglease": ""},
+		})
+		if err != nil {
+			return []string{}, util.MakeErr(err, "failed to reclaim graphing lease")
+		}
+		if updateResult.ModifiedCount == 1 {
+			reclaimedCrawlIDs = append(reclaimedCrawlIDs, crawlingStatus.CrawlID)
+		}
+	}
+	return reclaimedCrawlIDs, nil
+}
+
+// GetCrawlsInState returns the crawls that are in the given state and
+// entered it before the given unix time
+func (control Cntr) GetCrawlsInState(ctx context.Context, state string, enteredBefore int64) ([]datastructures.CrawlingStatus, error) {
+	crawlingStatsCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("CRAWLING_STATS_COLLECTION"))
+
+	cursor, err := crawlingStatsCollection.Find(ctx, bson.M{
+		"state":                                  state,
+		fmt.Sprintf("statetimestamps.%s", state): bson.M{"$lt": enteredBefore},
+	})
+	if err != nil {
+		return []datastructures.CrawlingStatus{}, util.MakeErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	crawlingStatuses := []datastructures.CrawlingStatus{}
+	for cursor.Next(ctx) {
+		crawlingStatus := datastructures.CrawlingStatus{}
+		if err := cursor.Decode(&crawlingStatus); err != nil {
+			return []datastructures.CrawlingStatus{}, util.MakeErr(err)
+		}
+		crawlingStatuses = append(crawlingStatuses, crawlingStatus)
+	}
+	return crawlingStatuses, nil
 }
 
 func (control Cntr) GetUser(ctx context.Context, steamID string) (common.UserDocument, error) {
@@ -93,17 +336,45 @@ func (control Cntr) GetUser(ctx context.Context, steamID string) (common.UserDoc
 	return userDoc, nil
 }
 
-func (control Cntr) GetCrawlingStatusFromDBFromCrawlID(ctx context.Context, crawlID string) (common.CrawlingStatus, error) {
+// GetUsers calls foundUser for each of the given users as they are read
+// from the DB. Users that are not in the DB are skipped
+func (control Cntr) GetUsers(ctx context.Context, steamIDs []string, foundUser func(common.UserDocument) error) error {
+	userCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("USER_COLLECTION"))
+
+	cursor, err := userCollection.Find(ctx, bson.M{
+		"accdetails.steamid": bson.M{"$in": steamIDs},
+	})
+	if err != nil {
+		return util.MakeErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		userDoc := common.UserDocument{}
+		if err := cursor.Decode(&userDoc); err != nil {
+			return util.MakeErr(err)
+		}
+		if err := foundUser(userDoc); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return util.MakeErr(err)
+	}
+	return nil
+}
+
+func (control Cntr) GetCrawlingStatusFromDBFromCrawlID(ctx context.Context, crawlID string) (datastructures.CrawlingStatus, error) {
 	crawlingStatsCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("CRAWLING_STATS_COLLECTION"))
-	crawlingStatus := common.CrawlingStatus{}
+	crawlingStatus := datastructures.CrawlingStatus{}
 
 	if err := crawlingStatsCollection.FindOne(ctx, bson.M{
 		"crawlid": crawlID,
 	}).Decode(&crawlingStatus); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return common.CrawlingStatus{}, nil
+			return datastructures.CrawlingStatus{}, nil
 		}
-		return common.CrawlingStatus{}, util.MakeErr(err)
+		return datastructures.CrawlingStatus{}, util.MakeErr(err)
 	}
 	return crawlingStatus, nil
 }
@@ -249,8 +520,20 @@ func (control Cntr) GetNMostRecentFinishedCrawls(ctx context.Context, amount int
 	options.SetSort(bson.D{{Key: "timestarted", Value: -1}})
 	options.SetLimit(amount)
 
-	cursor, err := crawlingStatsCollection.Find(ctx,
-		bson.D{{Key: "$where", Value: "this.totaluserstocrawl == this.userscrawled"}}, options)
+	// Crawls saved before states were recorded are checked using their counts
+	cursor, err := crawlingStatsCollection.Find(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"state": bson.M{"$in": bson.A{
+				datastructures.CrawlStateCrawlComplete,
+				datastructures.CrawlStateGraphing,
+				datastructures.CrawlStateGraphed,
+			}}},
+			bson.M{
+				"state": bson.M{"$exists": false},
+				"$expr": bson.M{"$eq": bson.A{"$totaluserstocrawl", "$userscrawled"}},
+			},
+		},
+	}, options)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return []common.CrawlingStatus{}, nil
@@ -307,56 +590,89 @@ func (control Cntr) GetTotalDocumentsInCollection(ctx context.Context, collectio
 	return configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(collectionName).CountDocuments(ctx, bson.D{})
 }
 
-func (control Cntr) SaveProcessedGraphData(crawlID string, graphData common.UsersGraphData) (bool, error) {
+func (control Cntr) SaveProcessedGraphData(crawlID string, graphData datastructures.UsersGraphData, insights datastructures.NetworkInsights) (bool, error) {
 	jsonBody, err := json.Marshal(graphData)
 	if err != nil {
 		return false, util.MakeErr(err, "failed to unmarshal graphdata json")
 	}
+	insightsJSON, err := json.Marshal(insights)
+	if err != nil {
+		return false, util.MakeErr(err, "failed to marshal insights json")
+	}
 
-	queryString := `INSERT INTO graphdata (crawlid, graphdata) VALUES ($1, $2)`
-	_, err = configuration.SQLClient.Exec(queryString, crawlID, string(jsonBody))
+	// A partial graph is replaced by any newer graph but a full graph is
+	// never replaced
+	queryString := `INSERT INTO graphdata (crawlid, graphdata, partial, insights) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (crawlid) DO UPDATE SET graphdata = EXCLUDED.graphdata, partial = EXCLUDED.partial, insights = EXCLUDED.insights
+		WHERE graphdata.partial`
+	res, err := configuration.SQLClient.Exec(queryString, crawlID, string(jsonBody), graphData.Partial, string(insightsJSON))
 	if err != nil {
-		if err, ok := err.(*pq.Error); ok {
-			// Attempts to duplicate an insert are not an issue
-			if err.Code.Name() != "unique_violation" {
-				return false, util.MakeErr(err, "failed to exec insert into graphdata")
-			} else {
-				configuration.Logger.Sugar().Infof("duplicate insert processed data for crawlid %s was attempted", crawlID)
-			}
-		}
+		return false, util.MakeErr(err, "failed to exec insert into graphdata")
+	}
+	if rowsAffected, err := res.RowsAffected(); err == nil && rowsAffected == 0 {
+		configuration.Logger.Sugar().Infof("processed data for crawlid %s was not saved as its full graph already exists", crawlID)
 	}
 	return true, nil
 }
 
-func (control Cntr) GetProcessedGraphData(crawlID string) (common.UsersGraphData, error) {
-	graphData := common.UsersGraphData{}
+func (control Cntr) GetProcessedGraphData(crawlID string) (datastructures.UsersGraphData, error) {
+	graphData := datastructures.UsersGraphData{}
 
-	queryString := `SELECT * FROM graphdata WHERE crawlid = $1`
+	queryString := `SELECT crawlid, graphdata FROM graphdata WHERE crawlid = $1`
 	res, err := configuration.SQLClient.Query(queryString, crawlID)
 	if err != nil {
-		return common.UsersGraphData{}, util.MakeErr(err)
+		return datastructures.UsersGraphData{}, util.MakeErr(err)
 	}
 	defer res.Close()
 	graphDataJSON := ""
 	for res.Next() {
 		crawlID := ""
 		if err := res.Scan(&crawlID, &graphDataJSON); err != nil {
-			return common.UsersGraphData{}, util.MakeErr(err, "failed to scan returned row")
+			return datastructures.UsersGraphData{}, util.MakeErr(err, "failed to scan returned row")
 		}
 	}
 	if len(graphDataJSON) == 0 {
-		return common.UsersGraphData{}, nil
+		return datastructures.UsersGraphData{}, nil
 	}
 	err = json.Unmarshal([]byte(graphDataJSON), &graphData)
 	if err != nil {
-		return common.UsersGraphData{}, fmt.Errorf("failed to unmarshal returned data for crawlid %s: %+v", crawlID, err)
+		return datastructures.UsersGraphData{}, fmt.Errorf("failed to unmarshal returned data for crawlid %s: %+v", crawlID, err)
 	}
 	return graphData, nil
 }
 
-func (control Cntr) DoesProcessedGraphDataExist(crawlID string) (bool, error) {
-	queryString := `SELECT crawlid FROM graphdata WHERE crawlid = $1`
+// GetInsights returns the insights saved alongside the graph of a crawl.
+// Graphs saved before insights were introduced have none
+func (control Cntr) GetInsights(crawlID string) (bool, datastructures.NetworkInsights, error) {
+	insights := datastructures.NetworkInsights{}
+
+	queryString := `SELECT insights FROM graphdata WHERE crawlid = $1 AND insights IS NOT NULL`
 	res, err := configuration.SQLClient.Query(queryString, crawlID)
+	if err != nil {
+		return false, datastructures.NetworkInsights{}, util.MakeErr(err)
+	}
+	defer res.Close()
+	insightsJSON := ""
+	for res.Next() {
+		if err := res.Scan(&insightsJSON); err != nil {
+			return false, datastructures.NetworkInsights{}, util.MakeErr(err, "failed to scan returned row")
+		}
+	}
+	if len(insightsJSON) == 0 {
+		return false, datastructures.NetworkInsights{}, nil
+	}
+	err = json.Unmarshal([]byte(insightsJSON), &insights)
+	if err != nil {
+		return false, datastructures.NetworkInsights{}, fmt.Errorf("failed to unmarshal insights for crawlid %s: %+v", crawlID, err)
+	}
+	return true, insights, nil
+}
+
+// DoesProcessedGraphDataExist checks if the full graph of a crawl has
+// been saved. Partial graphs are also counted if includePartial is given
+func (control Cntr) DoesProcessedGraphDataExist(crawlID string, includePartial bool) (bool, error) {
+	queryString := `SELECT crawlid FROM graphdata WHERE crawlid = $1 AND (NOT partial OR $2)`
+	res, err := configuration.SQLClient.Query(queryString, crawlID, includePartial)
 	if err != nil {
 		return false, util.MakeErr(err)
 	}
@@ -372,3 +688,139 @@ func (control Cntr) DoesProcessedGraphDataExist(crawlID string) (bool, error) {
 	}
 	return true, nil
 }
+
+func (control Cntr) SaveFriendListChange(ctx context.Context, change datastructures.FriendListChange) error {
+	friendHistoryCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("FRIEND_HISTORY_COLLECTION"))
+	_, err := friendHistoryCollection.InsertOne(ctx, change)
+	if err != nil {
+		return util.MakeErr(err, "failed to save friend list change")
+	}
+	return nil
+}
+
+// GetFriendListChanges returns every recorded change to a
+// user's friend list sorted from oldest to newest
+func (control Cntr) GetFriendListChanges(ctx context.Context, steamID string) ([]datastructures.FriendListChange, error) {
+	friendHistoryCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("FRIEND_HISTORY_COLLECTION"))
+
+	options := options.Find()
+	options.SetSort(bson.D{{Key: "timestamp", Value: 1}})
+
+	cursor, err := friendHistoryCollection.Find(ctx, bson.M{
+		"steamid": steamID,
+	}, options)
+	if err != nil {
+		return []datastructures.FriendListChange{}, util.MakeErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	changes := []datastructures.FriendListChange{}
+	for cursor.Next(ctx) {
+		change := datastructures.FriendListChange{}
+		if err := cursor.Decode(&change); err != nil {
+			return []datastructures.FriendListChange{}, util.MakeErr(err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// SaveWatchedUser adds a user to be crawled on a schedule. If the user
+// is already watched their level and schedule are updated while their
+// crawl history is kept
+func (control Cntr) SaveWatchedUser(ctx context.Context, watchedUser datastructures.WatchedUser) error {
+	watchedUsersCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("WATCHED_USERS_COLLECTION"))
+	update := bson.M{
+		"$set": bson.M{
+			"level":           watchedUser.Level,
+			"cronexpression":  watchedUser.CronExpression,
+			"intervalminutes": watchedUser.IntervalMinutes,
+		},
+		"$setOnInsert": bson.M{
+			"timeadded":     watchedUser.TimeAdded,
+			"lastcrawltime": int64(0),
+			"crawlids":      []string{},
+		},
+	}
+	_, err := watchedUsersCollection.UpdateOne(ctx, bson.M{
+		"steamid": watchedUser.SteamID,
+	}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return util.MakeErr(err, "failed to save watched user")
+	}
+	return nil
+}
+
+func (control Cntr) GetWatchedUsers(ctx context.Context) ([]datastructures.WatchedUser, error) {
+	watchedUsersCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("WATCHED_USERS_COLLECTION"))
+
+	cursor, err := watchedUsersCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return []datastructures.WatchedUser{}, util.MakeErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	watchedUsers := []datastructures.WatchedUser{}
+	for cursor.Next(ctx) {
+		watchedUser := datastructures.WatchedUser{}
+		if err := cursor.Decode(&watchedUser); err != nil {
+			return []datastructures.WatchedUser{}, util.MakeErr(err)
+		}
+		watchedUsers = append(watchedUsers, watchedUser)
+	}
+	return watchedUsers, nil
+}
+
+// ClaimWatchedUserCrawl records a new crawl for a watched user if no
+// other crawl has been recorded since the claim's previous crawl time
+func (control Cntr) ClaimWatchedUserCrawl(ctx context.Context, steamID string, claim datastructures.WatchedUserCrawlClaim) (bool, error) {
+	watchedUsersCollection := configuration.DBClient.Database(os.Getenv("DB_NAME")).Collection(os.Getenv("WATCHED_USERS_COLLECTION"))
+	update := bson.M{
+		"$set": bson.M{
+			"lastcrawltime": claim.CrawlTime,
+		},
+		"$push": bson.M{
+			"crawlids": claim.CrawlID,
+		},
+	}
+	updateResult, err := watchedUsersCollection.UpdateOne(ctx, bson.M{
+		"steamid":       steamID,
+		"lastcrawltime": claim.PreviousCrawlTime,
+	}, update)
+	if err != nil {
+		return false, util.MakeErr(err, "failed to claim watched user crawl")
+	}
+	return updateResult.ModifiedCount == 1, nil
+}
+
+// StartGraphCreation asks a crawler to create the graph for a crawl
+// that has finished crawling. The crawler only queues the graph creation
+// so each request is given a short timeout
+func (control Cntr) StartGraphCreation(crawlID string) error {
+	targetURL := fmt.Sprintf("http://%s/creategraph/%s", os.Getenv("CRAWLER_INSTANCE"), crawlID)
+	client := &http.Client{Timeout: 10 * time.Second}
+	maxRetryCount := 3
+
+	for i := 0; i <= maxRetryCount; i++ {
+		if i > 0 {
+			exponentialBackOffSleepTime := math.Pow(2, float64(i-1)) * 16
+			configuration.Logger.Sugar().Infof("failed to call %s %d times. Sleeping for %v ms", targetURL, i, exponentialBackOffSleepTime)
+			time.Sleep(time.Duration(exponentialBackOffSleepTime) * time.Millisecond)
+		}
+		req, err := http.NewRequest("POST", targetURL, nil)
+		if err != nil {
+			return util.MakeErr(err)
+		}
+		req.Close = true
+
+		res, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			return nil
+		}
+	}
+	return util.MakeErr(fmt.Errorf("failed all retries to %s for crawlID: %s", targetURL, crawlID))
+}
@@ -0,0 +1,50 @@
+package datastructures
+
+// CoarsenedGraph is a processed graph with groups of users collapsed into
+// super nodes so that it has at most MaxNodes nodes. Coarsened is false
+// if the graph was already small enough. Partial is true if the graph was
+// made while the crawl was still going and Progress is how much of it
+// was done
+type CoarsenedGraph struct {
+	CrawlID   string          `json:"crawlid"`
+	Partial   bool            `json:"partial"`
+	Progress  int             `json:"progress"`
+	MaxNodes  int             `json:"maxnodes"`
+	UserCount int             `json:"usercount"`
+	Coarsened bool            `json:"coarsened"`
+	Expanded  []string        `json:"expanded"`
+	Nodes     []CoarsenedNode `json:"nodes"`
+	Edges     []CoarsenedEdge `json:"edges"`
+}
+
+// CoarsenedNode is either a single user or a super node of several users.
+// The ID of a user is their steamID while super nodes are named after the
+// community or user they were collapsed into. Attributes of super nodes
+// are aggregated over their users. Playtime is in minutes and Community
+// is -1 if the users are not all in the same community
+type CoarsenedNode struct {
+	ID                    string  `json:"id"`
+	Kind                  string  `json:"kind"`
+	Label                 string  `json:"label"`
+	Size                  int     `json:"size"`
+	Country               string  `json:"country"`
+	Community             int     `json:"community"`
+	TotalPlaytime         int     `json:"totalplaytime"`
+	AverageFriendCount    float64 `json:"averagefriendcount"`
+	AverageAccountAgeDays float64 `json:"averageaccountagedays"`
+	// InternalEdges is how many friendships are between users in the node
+	InternalEdges int `json:"internaledges"`
+}
+
+// CoarsenedEdge links two nodes of a coarsened graph. Weight is how many
+// friendships there are between their users
+type CoarsenedEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+type GetCoarsenedGraphDTO struct {
+	Status         string         `json:"status"`
+	CoarsenedGraph CoarsenedGraph `json:"coarsenedgraph"`
+}
@@ -0,0 +1,77 @@
+package datastructures
+
+// The states a crawl moves through. A crawl starts queued and moves
+// forward one state at a time until it is graphed. It can be failed or
+// cancelled at any point before that. Crawls whose graph build lost its
+// lease move back to crawl-complete and crawls that failed after they
+// finished crawling can be graphed again
+const (
+	CrawlStateQueued        = "queued"
+	CrawlStateCrawling      = "crawling"
+	CrawlStateCrawlComplete = "crawl-complete"
+	CrawlStateGraphing      = "graphing"
+	CrawlStateGraphed       = "graphed"
+	CrawlStateFailed        = "failed"
+	CrawlStateCancelled     = "cancelled"
+)
+
+// crawlStateTransitions maps each state to the states a crawl
+// may be in immediately before moving to it
+var crawlStateTransitions = map[string][]string{
+	CrawlStateCrawling:      {CrawlStateQueued},
+	CrawlStateCrawlComplete: {CrawlStateCrawling, CrawlStateGraphing},
+	CrawlStateGraphing:      {CrawlStateCrawlComplete, CrawlStateFailed},
+	CrawlStateGraphed:       {CrawlStateGraphing},
+	CrawlStateFailed:        {CrawlStateQueued, CrawlStateCrawling, CrawlStateCrawlComplete, CrawlStateGraphing},
+	CrawlStateCancelled:     {CrawlStateQueued, CrawlStateCrawling, CrawlStateCrawlComplete, CrawlStateGraphing},
+}
+
+// UpdateCrawlStateInputDTO moves a crawl to a new state. LeaseID is given
+// by the graph build that moves a crawl to the graphing state and is
+// needed to move the crawl out of it again
+type UpdateCrawlStateInputDTO struct {
+	State   string `json:"state"`
+	LeaseID string `json:"leaseid"`
+}
+
+// UpdateCrawlStateDTO is returned after a crawl state update. Updated is
+// false when the crawl was not in a state that can move to the new state
+type UpdateCrawlStateDTO struct {
+	Status  string `json:"status"`
+	Updated bool   `json:"updated"`
+}
+
+// GraphingLease is held by the graph build of a graphing crawl. The build
+// renews it while it runs and the crawl is only taken from the build once
+// the lease has not been renewed for a while
+type GraphingLease struct {
+	ID        string `json:"id" bson:"id"`
+	RenewedAt int64  `json:"renewedat" bson:"renewedat"`
+}
+
+type RenewGraphingLeaseInputDTO struct {
+	LeaseID string `json:"leaseid"`
+}
+
+// RenewGraphingLeaseDTO is returned after a lease renewal. Renewed is
+// false when the crawl is no longer graphing under the given lease
+type RenewGraphingLeaseDTO struct {
+	Status  string `json:"status"`
+	Renewed bool   `json:"renewed"`
+}
+
+// GetPreviousCrawlStates returns the states from which a crawl can move
+// to the given state. No states are returned for unknown states or for
+// the initial queued state
+func GetPreviousCrawlStates(state string) []string {
+	return crawlStateTransitions[state]
+}
+
+// IsCrawling checks if users are still being crawled. Crawls saved
+// before states were recorded are checked using their counts
+func (crawlingStatus CrawlingStatus) IsCrawling() bool {
+	if crawlingStatus.State == "" {
+		return crawlingStatus.UsersCrawled < crawlingStatus.TotalUsersToCrawl
+	}
+	return crawlingStatus.State == CrawlStateQueued || crawlingStatus.State == CrawlStateCrawling
+}
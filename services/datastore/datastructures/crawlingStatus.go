@@ -0,0 +1,100 @@
+package datastructures
+
+import (
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/neosteamfriendgraphing/common/dtos"
+)
+
+// CrawlingStatus extends common.CrawlingStatus with the crawl budget
+// and how much of it has been consumed so far
+type CrawlingStatus struct {
+	common.CrawlingStatus `bson:",inline"`
+
+	Budget        CrawlBudget `json:"budget" bson:"budget"`
+	APICallsMade  int         `json:"apicallsmade" bson:"apicallsmade"`
+	UsersReserved int         `json:"usersreserved" bson:"usersreserved"`
+	// Truncated is set once the crawl stops expanding users
+	// because its budget has been used up
+	Truncated bool `json:"truncated" bson:"truncated"`
+	// HubThreshold is the friend count above which users are saved
+	// but not expanded, their steamIDs are recorded in CappedHubs
+	HubThreshold int          `json:"hubthreshold" bson:"hubthreshold"`
+	CappedHubs   []string     `json:"cappedhubs" bson:"cappedhubs,omitempty"`
+	Filters      CrawlFilters `json:"filters" bson:"filters"`
+
+	// State is where the crawl is in its lifecycle and StateTimestamps
+	// holds the unix time at which each state was entered
+	State           string           `json:"state" bson:"state,omitempty"`
+	StateTimestamps map[string]int64 `json:"statetimestamps" bson:"statetimestamps,omitempty"`
+	// GraphingLease is set whenever the crawl moves to the graphing state
+	GraphingLease GraphingLease `json:"graphinglease" bson:"graphinglease"`
+
+	// Levels holds how many users have been discovered and completed
+	// at each level of the crawl, keyed by level
+	Levels          map[string]LevelProgress `json:"levels" bson:"levels,omitempty"`
+	PrivateProfiles int                      `json:"privateprofiles" bson:"privateprofiles"`
+	FailedJobs      int                      `json:"failedjobs" bson:"failedjobs"`
+	// JobsPerMinute counts the jobs processed in each minute of the crawl,
+	// keyed by unix minute, so that the recent job rate can be worked out
+	JobsPerMinute map[string]int `json:"jobsperminute" bson:"jobsperminute,omitempty"`
+
+	// The following are worked out from the above whenever the
+	// crawling status is served and so are not stored
+	UsersPerMinute      float64 `json:"userspermin" bson:"-"`
+	EstimatedTotalUsers int     `json:"estimatedtotalusers" bson:"-"`
+	EstimatedProgress   int     `json:"estimatedprogress" bson:"-"`
+	ETASeconds          int64   `json:"etaseconds" bson:"-"`
+}
+
+// LevelProgress is the amount of users discovered at a level of a crawl
+// and how many of them have been crawled, skipped or have failed
+type LevelProgress struct {
+	Discovered int `json:"discovered" bson:"discovered"`
+	Completed  int `json:"completed" bson:"completed"`
+}
+
+// CrawlBudget bounds how much work a crawl may do. A value of
+// zero means that there is no limit
+type CrawlBudget struct {
+	MaxUsers    int `json:"maxusers" bson:"maxusers"`
+	MaxAPICalls int `json:"maxapicalls" bson:"maxapicalls"`
+}
+
+// CrawlFilters are the filters that friends had to pass to be expanded
+type CrawlFilters struct {
+	Countries         []string `json:"countries" bson:"countries"`
+	MinAccountAgeDays int      `json:"minaccountagedays" bson:"minaccountagedays"`
+	MinGames          int      `json:"mingames" bson:"mingames"`
+}
+
+// CrawlBudgetUsage is sent by the crawler to record steam API calls made
+// and to reserve users from the budget before they are placed in the queue
+type CrawlBudgetUsage struct {
+	APICallsMade   int  `json:"apicallsmade"`
+	UsersRequested int  `json:"usersrequested"`
+	Truncated      bool `json:"truncated"`
+}
+
+type UpdateCrawlBudgetDTO struct {
+	Status       string `json:"status"`
+	UsersGranted int    `json:"usersgranted"`
+}
+
+type SaveCrawlingStatsDTO struct {
+	CurrentLevel   int            `json:"currentlevel"`
+	CrawlingStatus CrawlingStatus `json:"crawlingstatus"`
+}
+
+type GetCrawlingStatusDTO struct {
+	Status         string         `json:"status"`
+	CrawlingStatus CrawlingStatus `json:"crawlingstatus"`
+}
+
+// SaveUserDTO extends dtos.SaveUserDTO with the amount of the
+// user's friends that the crawler placed in the jobs queue
+type SaveUserDTO struct {
+	dtos.SaveUserDTO
+
+	FriendsQueued int  `json:"friendsqueued"`
+	HubCapped     bool `json:"hubcapped"`
+}
@@ -0,0 +1,35 @@
+package datastructures
+
+// EgoNetwork is the part of a graph within a number of hops of a user.
+// Truncated is true if users within that radius were left out to stay
+// under the node limit. Partial is true if the graph was made while the
+// crawl was still going and Progress is how much of it was done
+type EgoNetwork struct {
+	SteamID   string           `json:"steamid"`
+	CrawlID   string           `json:"crawlid"`
+	Partial   bool             `json:"partial"`
+	Progress  int              `json:"progress"`
+	Radius    int              `json:"radius"`
+	Truncated bool             `json:"truncated"`
+	Nodes     []EgoNetworkNode `json:"nodes"`
+	Edges     []GraphEdge      `json:"edges"`
+}
+
+// EgoNetworkNode is a user in an ego network along with how many hops
+// they are from the user at its centre
+type EgoNetworkNode struct {
+	SteamID    string                 `json:"steamid"`
+	Distance   int                    `json:"distance"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// GraphEdge is a friendship between two users. Edges are undirected
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+type GetEgoNetworkDTO struct {
+	Status     string     `json:"status"`
+	EgoNetwork EgoNetwork `json:"egonetwork"`
+}
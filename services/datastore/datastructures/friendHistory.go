@@ -0,0 +1,18 @@
+package datastructures
+
+// FriendListChange records the friends that were added to and removed
+// from a user's friend list between two crawls of that user
+type FriendListChange struct {
+	SteamID   string   `json:"steamid" bson:"steamid"`
+	Timestamp int64    `json:"timestamp" bson:"timestamp"`
+	Added     []string `json:"added" bson:"added"`
+	Removed   []string `json:"removed" bson:"removed"`
+}
+
+type GetFriendHistoryDTO struct {
+	Status       string             `json:"status"`
+	SteamID      string             `json:"steamid"`
+	TotalAdded   int                `json:"totaladded"`
+	TotalRemoved int                `json:"totalremoved"`
+	Changes      []FriendListChange `json:"changes"`
+}
@@ -0,0 +1,20 @@
+package datastructures
+
+// FriendRecommendation is a user within two hops of another that they
+// are not friends with yet. Reasons explain why they were recommended
+type FriendRecommendation struct {
+	SteamID        string   `json:"steamid"`
+	Username       string   `json:"username"`
+	Avatar         string   `json:"avatar"`
+	MutualFriends  []string `json:"mutualfriends"`
+	SharedTopGames []int    `json:"sharedtopgames"`
+	SameCountry    bool     `json:"samecountry"`
+	Score          float64  `json:"score"`
+	Reasons        []string `json:"reasons"`
+}
+
+type GetFriendRecommendationsDTO struct {
+	Status          string                 `json:"status"`
+	SteamID         string                 `json:"steamid"`
+	Recommendations []FriendRecommendation `json:"recommendations"`
+}
@@ -0,0 +1,18 @@
+package datastructures
+
+// GameRecommendation is a game a user does not own that is played by
+// the users around them. Players is how many of those users play it and
+// TotalPlaytime is their combined playtime in minutes
+type GameRecommendation struct {
+	AppID         int     `json:"appid"`
+	Name          string  `json:"name"`
+	Score         float64 `json:"score"`
+	Players       int     `json:"players"`
+	TotalPlaytime int     `json:"totalplaytime"`
+}
+
+type GetGameRecommendationsDTO struct {
+	Status          string               `json:"status"`
+	SteamID         string               `json:"steamid"`
+	Recommendations []GameRecommendation `json:"recommendations"`
+}
@@ -0,0 +1,57 @@
+package datastructures
+
+// GraphDiff is how the network of a user changed between two crawls of
+// them. Playtimes are in minutes and only cover the most played games
+// kept in processed graphs. Partial graphs only have the users crawled
+// so far, so users missing from them may not have been removed
+type GraphDiff struct {
+	SteamID        string               `json:"steamid"`
+	FromCrawlID    string               `json:"fromcrawlid"`
+	FromPartial    bool                 `json:"frompartial"`
+	FromProgress   int                  `json:"fromprogress"`
+	ToCrawlID      string               `json:"tocrawlid"`
+	ToPartial      bool                 `json:"topartial"`
+	ToProgress     int                  `json:"toprogress"`
+	AddedUsers     []string             `json:"addedusers"`
+	RemovedUsers   []string             `json:"removedusers"`
+	AddedEdges     []GraphEdge          `json:"addededges"`
+	RemovedEdges   []GraphEdge          `json:"removededges"`
+	CountryChanges []CountryChange      `json:"countrychanges"`
+	GameShifts     []GameShift          `json:"gameshifts"`
+	PlaytimeDeltas []UserPlaytimeChange `json:"playtimedeltas"`
+}
+
+// CountryChange is how many users in the network were from a country
+// in each crawl
+type CountryChange struct {
+	CountryCode string `json:"countrycode"`
+	FromCount   int    `json:"fromcount"`
+	ToCount     int    `json:"tocount"`
+	Change      int    `json:"change"`
+}
+
+// GameShift is how many users played a game and for how long in each
+// crawl
+type GameShift struct {
+	AppID          int    `json:"appid"`
+	Name           string `json:"name"`
+	FromPlayers    int    `json:"fromplayers"`
+	ToPlayers      int    `json:"toplayers"`
+	FromPlaytime   int    `json:"fromplaytime"`
+	ToPlaytime     int    `json:"toplaytime"`
+	PlaytimeChange int    `json:"playtimechange"`
+}
+
+// UserPlaytimeChange is how much a user in both crawls played between them
+type UserPlaytimeChange struct {
+	SteamID      string `json:"steamid"`
+	Username     string `json:"username"`
+	FromPlaytime int    `json:"fromplaytime"`
+	ToPlaytime   int    `json:"toplaytime"`
+	Change       int    `json:"change"`
+}
+
+type GetGraphDiffDTO struct {
+	Status string    `json:"status"`
+	Diff   GraphDiff `json:"diff"`
+}
@@ -0,0 +1,56 @@
+package datastructures
+
+// NetworkInsights are the statistics shown on the graph page of a crawl,
+// worked out from every user found by the crawl other than its target.
+// Percentages are whole numbers rounded down and months are numbered
+// from 0 for January. Ages are as of ComputedAt
+type NetworkInsights struct {
+	ComputedAt int64 `json:"computedat"`
+
+	// CountryFrequencies is keyed by lowercase country code
+	CountryFrequencies    map[string]int `json:"countryfrequencies"`
+	TopCountries          []string       `json:"topcountries"`
+	UniqueCountries       int            `json:"uniquecountries"`
+	GlobalCoverage        int            `json:"globalcoverage"`
+	DictatorshipCountries int            `json:"dictatorshipcountries"`
+	AlsoFromUsersCountry  int            `json:"alsofromuserscountry"`
+	ContinentsCovered     []string       `json:"continentscovered"`
+	ContinentCoverage     int            `json:"continentcoverage"`
+
+	// AccountAgeHistogram is how many users have accounts of each age
+	// in whole years. AccountAgeVsFriendCount is the friend count and
+	// account age in months of each user
+	AccountAgeHistogram       []int       `json:"accountagehistogram"`
+	AccountAgeVsFriendCount   [][2]int    `json:"accountagevsfriendcount"`
+	CreationYearFrequencies   map[int]int `json:"creationyearfrequencies"`
+	CreationMonthFrequencies  [12]int     `json:"creationmonthfrequencies"`
+	MostPopularCreationMonth  int         `json:"mostpopularcreationmonth"`
+	LeastPopularCreationMonth int         `json:"leastpopularcreationmonth"`
+	OldestUser                string      `json:"oldestuser"`
+	NewestUser                string      `json:"newestuser"`
+	HighestFriendCountUser    string      `json:"highestfriendcountuser"`
+
+	// HoursPlayedLeaderboard is the users with the most hours played,
+	// including the crawl target. UserRank is where the crawl target
+	// placed out of everyone
+	HoursPlayedLeaderboard      []HoursPlayedEntry `json:"hoursplayedleaderboard"`
+	UserHoursPlayed             int                `json:"userhoursplayed"`
+	UserRank                    int                `json:"userrank"`
+	FriendsWithFewerHoursPlayed int                `json:"friendswithfewerhoursplayed"`
+	GamerScore                  int                `json:"gamerscore"`
+}
+
+// HoursPlayedEntry is a user on the hours played leaderboard. Hours are
+// counted in whole hours per game
+type HoursPlayedEntry struct {
+	SteamID    string `json:"steamid"`
+	Username   string `json:"username"`
+	Avatar     string `json:"avatar"`
+	ProfileURL string `json:"profileurl"`
+	Hours      int    `json:"hours"`
+}
+
+type GetInsightsDTO struct {
+	Status   string          `json:"status"`
+	Insights NetworkInsights `json:"insights"`
+}
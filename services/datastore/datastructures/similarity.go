@@ -0,0 +1,25 @@
+package datastructures
+
+// UserSimilarity compares the friends and games of two users.
+// Similarities are the Jaccard index of their friends and of their
+// libraries, between 0 and 1
+type UserSimilarity struct {
+	SteamIDs          []string     `json:"steamids"`
+	MutualFriends     []string     `json:"mutualfriends"`
+	FriendSimilarity  float64      `json:"friendsimilarity"`
+	SharedGames       []SharedGame `json:"sharedgames"`
+	LibrarySimilarity float64      `json:"librarysimilarity"`
+}
+
+// SharedGame is a game owned by both users. Playtimes are in minutes
+type SharedGame struct {
+	AppID              int `json:"appid"`
+	FirstUserPlaytime  int `json:"firstuserplaytime"`
+	SecondUserPlaytime int `json:"seconduserplaytime"`
+	CombinedPlaytime   int `json:"combinedplaytime"`
+}
+
+type GetUserSimilarityDTO struct {
+	Status     string         `json:"status"`
+	Similarity UserSimilarity `json:"similarity"`
+}
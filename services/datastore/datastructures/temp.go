@@ -3,8 +3,78 @@ package datastructures
 import "github.com/neosteamfriendgraphing/common"
 
 type GetProcessedGraphDataDTO struct {
-	Status        string                `json:"status"`
-	UserGraphData common.UsersGraphData `json:"usergraphdata"`
+	Status        string         `json:"status"`
+	UserGraphData UsersGraphData `json:"usergraphdata"`
+}
+
+// UsersGraphData extends common.UsersGraphData with the steamIDs
+// of hubs whose friends were not crawled
+type UsersGraphData struct {
+	common.UsersGraphData
+
+	CappedHubs []string `json:"cappedhubs"`
+	// OldestDataTimestamp is the unix time at which the oldest
+	// user in the graph was fetched from steam
+	OldestDataTimestamp int64 `json:"oldestdatatimestamp"`
+	// Partial graphs are made from the users crawled so far while the
+	// crawl is still going. Progress is how much of the crawl was done
+	Partial  bool `json:"partial"`
+	Progress int  `json:"progress"`
+	// UserCommunities is the community of each user by steamID
+	UserCommunities map[string]int     `json:"usercommunities"`
+	Communities     []CommunitySummary `json:"communities"`
+	// UserCentrality is the centrality of each user by steamID.
+	// MostConnectedUsers have the most friends in the crawl and
+	// BridgeUsers are on the most shortest paths between others
+	UserCentrality     map[string]Centrality `json:"usercentrality"`
+	MostConnectedUsers []string              `json:"mostconnectedusers"`
+	BridgeUsers        []string              `json:"bridgeusers"`
+	NetworkStats       NetworkStats          `json:"networkstats"`
+	Layout             *GraphLayout          `json:"layout,omitempty"`
+}
+
+// CommunitySummary describes a group of users that are more connected
+// to each other than to the rest of the crawl
+type CommunitySummary struct {
+	ID              int    `json:"id"`
+	Size            int    `json:"size"`
+	DominantCountry string `json:"dominantcountry"`
+	// TopGames are the appIDs of the games with the most playtime
+	TopGames []int `json:"topgames"`
+}
+
+// Centrality measures how important a user is to the shape of a graph.
+// Betweenness and Closeness are between 0 and 1
+type Centrality struct {
+	Degree      int     `json:"degree"`
+	Betweenness float64 `json:"betweenness"`
+	Closeness   float64 `json:"closeness"`
+	PageRank    float64 `json:"pagerank"`
+}
+
+// NetworkStats describes the overall shape of a graph. DegreeDistribution
+// is how many users have each amount of friends in the crawl. The diameter
+// and average path length only count users that can reach each other
+type NetworkStats struct {
+	NodeCount             int     `json:"nodecount"`
+	EdgeCount             int     `json:"edgecount"`
+	Density               float64 `json:"density"`
+	AverageDegree         float64 `json:"averagedegree"`
+	DegreeDistribution    []int   `json:"degreedistribution"`
+	ClusteringCoefficient float64 `json:"clusteringcoefficient"`
+	ConnectedComponents   int     `json:"connectedcomponents"`
+	LargestComponentSize  int     `json:"largestcomponentsize"`
+	EstimatedDiameter     int     `json:"estimateddiameter"`
+	AveragePathLength     float64 `json:"averagepathlength"`
+}
+
+// GraphLayout is the position of every user by steamID when the graph
+// is laid out in two and three dimensions. The same seed always gives
+// the same layout. Graphs created before layouts were added have none
+type GraphLayout struct {
+	Seed        int64                 `json:"seed"`
+	Positions2D map[string][2]float64 `json:"positions2d,omitempty"`
+	Positions3D map[string][3]float64 `json:"positions3d,omitempty"`
 }
 
 type AddUserEvent struct {
@@ -0,0 +1,15 @@
+package datastructures
+
+import "github.com/neosteamfriendgraphing/common"
+
+type GetUsersInputDTO struct {
+	SteamIDs []string `json:"steamids"`
+}
+
+// GetUsersDTO is the response of the getusers endpoint. Users are
+// streamed as they are read from the DB so a response that was cut
+// short is not valid JSON
+type GetUsersDTO struct {
+	Status string                `json:"status"`
+	Users  []common.UserDocument `json:"users"`
+}
@@ -0,0 +1,35 @@
+package datastructures
+
+// WatchedUser is a user that is crawled on a schedule. Either a five
+// field cron expression or an interval in minutes is given
+type WatchedUser struct {
+	SteamID         string `json:"steamid" bson:"steamid"`
+	Level           int    `json:"level" bson:"level"`
+	CronExpression  string `json:"cronexpression" bson:"cronexpression"`
+	IntervalMinutes int    `json:"intervalminutes" bson:"intervalminutes"`
+	TimeAdded       int64  `json:"timeadded" bson:"timeadded"`
+	// LastCrawlTime is zero if the user has not been crawled yet
+	LastCrawlTime int64 `json:"lastcrawltime" bson:"lastcrawltime"`
+	// CrawlIDs are the crawls started for this user, oldest first
+	CrawlIDs []string `json:"crawlids" bson:"crawlids"`
+}
+
+// WatchedUserCrawlClaim is sent by a scheduler before it starts a
+// crawl for a watched user. The claim only succeeds if the user's
+// last crawl time is still PreviousCrawlTime so that only one
+// crawler starts each scheduled crawl
+type WatchedUserCrawlClaim struct {
+	PreviousCrawlTime int64  `json:"previouscrawltime"`
+	CrawlTime         int64  `json:"crawltime"`
+	CrawlID           string `json:"crawlid"`
+}
+
+type ClaimWatchedUserCrawlDTO struct {
+	Status  string `json:"status"`
+	Claimed bool   `json:"claimed"`
+}
+
+type GetWatchedUsersDTO struct {
+	Status       string        `json:"status"`
+	WatchedUsers []WatchedUser `json:"watchedusers"`
+}
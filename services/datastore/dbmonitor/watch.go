@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/IamCathal/neo/services/datastore/app"
 	"github.com/IamCathal/neo/services/datastore/configuration"
 	"github.com/IamCathal/neo/services/datastore/controller"
 	"github.com/IamCathal/neo/services/datastore/datastructures"
@@ -53,6 +54,7 @@ func Monitor(cntr controller.CntrInterface) {
 	go watchRecentFinishedShortestDistances(cntr)
 	go watchTotalUsersInDB(cntr)
 	go watchTotalCrawlsCompleted(cntr)
+	go watchStalledGraphCreations(cntr)
 }
 
 func watchNewUsers() {
@@ -151,7 +153,7 @@ func watchCrawlingStatusUpdates() {
 	configuration.Logger.Info("watching crawling stats collection")
 
 	for crawlingStatsCollectionStream.Next(context.TODO()) {
-		var crawlingStat common.CrawlingStatus
+		var crawlingStat datastructures.CrawlingStatus
 		var event bson.M
 
 		if err := crawlingStatsCollectionStream.Decode(&event); err != nil {
@@ -166,11 +168,11 @@ func watchCrawlingStatusUpdates() {
 			configuration.Logger.Sugar().Panicf("failed to unmarshal event from users collection stream: %+v", util.MakeErr(err))
 		}
 
-		writeCrawlingStatsUpdateToAllWebsockets(crawlingStat)
+		writeCrawlingStatsUpdateToAllWebsockets(app.AddCrawlProgress(crawlingStat, time.Now()))
 	}
 }
 
-func writeCrawlingStatsUpdateToAllWebsockets(crawlingStat common.CrawlingStatus) error {
+func writeCrawlingStatsUpdateToAllWebsockets(crawlingStat datastructures.CrawlingStatus) error {
 	websockets := GetCrawlingStatsStreamWebsocketConnections()
 
 	jsonObj, err := json.Marshal(crawlingStat)
@@ -266,3 +268,14 @@ func watchTotalCrawlsCompleted(cntr controller.CntrInterface) {
 		time.Sleep(30 * time.Second)
 	}
 }
+
+// watchStalledGraphCreations periodically retries the graph creation of
+// crawls whose graph creation could not be started when they finished
+func watchStalledGraphCreations(cntr controller.CntrInterface) {
+	for {
+		if err := app.RetryStalledGraphCreations(cntr, time.Now()); err != nil {
+			configuration.Logger.Sugar().Errorf("failed to retry stalled graph creations: %+v", err)
+		}
+		time.Sleep(time.Minute)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/IamCathal/neo/services/datastore/app"
@@ -16,6 +17,8 @@ import (
 	"github.com/IamCathal/neo/services/datastore/controller"
 	"github.com/IamCathal/neo/services/datastore/datastructures"
 	"github.com/IamCathal/neo/services/datastore/dbmonitor"
+	"github.com/IamCathal/neo/services/datastore/graphing"
+	"github.com/IamCathal/neo/services/datastore/insights"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	influxdb2 "github.com/influxdata/influxdb-client-go"
@@ -27,6 +30,20 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// maxUsersPerGetUsersRequest is the most users that can be requested
+	// from the getusers endpoint at once
+	maxUsersPerGetUsersRequest = 1000
+	// Ego networks are one hop and 500 users unless asked otherwise
+	defaultEgoNetworkRadius = 1
+	maxEgoNetworkRadius     = 3
+	defaultEgoNetworkLimit  = 500
+	maxEgoNetworkLimit      = 5000
+	// Coarsened graphs have at most 500 nodes unless asked otherwise
+	defaultCoarsenedGraphMaxNodes = 500
+	maxCoarsenedGraphMaxNodes     = 5000
+)
+
 var (
 	upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
@@ -44,11 +61,18 @@ func init() {
 	authRequiredEndpoints["saveuser"] = true
 	authRequiredEndpoints["insertgame"] = true
 	authRequiredEndpoints["getuser"] = true
+	authRequiredEndpoints["getusers"] = true
 	authRequiredEndpoints["getdetailsforgames"] = true
 	authRequiredEndpoints["savecrawlingstats"] = true
 	authRequiredEndpoints["getgraphabledata"] = true
 	authRequiredEndpoints["getusernamesfromsteamids"] = true
 	authRequiredEndpoints["saveprocessedgraphdata"] = true
+	authRequiredEndpoints["updatecrawlbudget"] = true
+	authRequiredEndpoints["updatecrawlstate"] = true
+	authRequiredEndpoints["renewgraphinglease"] = true
+	authRequiredEndpoints["savewatcheduser"] = true
+	authRequiredEndpoints["getwatchedusers"] = true
+	authRequiredEndpoints["claimwatchedusercrawl"] = true
 }
 func (endpoints *Endpoints) SetupRouter() *mux.Router {
 	r := mux.NewRouter()
@@ -58,16 +82,32 @@ func (endpoints *Endpoints) SetupRouter() *mux.Router {
 	apiRouter.HandleFunc("/saveuser", endpoints.SaveUser).Methods("POST")
 	apiRouter.HandleFunc("/insertgame", endpoints.InsertGame).Methods("POST")
 	apiRouter.HandleFunc("/getuser/{steamid}", endpoints.GetUser).Methods("GET")
+	apiRouter.HandleFunc("/getusers", endpoints.GetUsers).Methods("POST")
 	apiRouter.HandleFunc("/getdetailsforgames", endpoints.GetDetailsForGames).Methods("POST")
 	apiRouter.HandleFunc("/savecrawlingstats", endpoints.SaveCrawlingStatsToDB).Methods("POST")
 	apiRouter.HandleFunc("/getcrawlinguser/{crawlid}", endpoints.GetCrawlingUser).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/hasbeencrawledbefore", endpoints.HasBeenCrawledBefore).Methods("POST", "OPTIONS")
 	apiRouter.HandleFunc("/getcrawlingstatus/{crawlid}", endpoints.GetCrawlingStatus).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/updatecrawlbudget/{crawlid}", endpoints.UpdateCrawlBudget).Methods("POST")
+	apiRouter.HandleFunc("/updatecrawlstate/{crawlid}", endpoints.UpdateCrawlState).Methods("POST")
+	apiRouter.HandleFunc("/renewgraphinglease/{crawlid}", endpoints.RenewGraphingLease).Methods("POST")
 	apiRouter.HandleFunc("/getgraphabledata/{steamid}", endpoints.GetGraphableData).Methods("GET")
+	apiRouter.HandleFunc("/getfriendhistory/{steamid}", endpoints.GetFriendHistory).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/getsimilarity/{firststeamid}/{secondsteamid}", endpoints.GetSimilarity).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/recommendfriends/{steamid}", endpoints.RecommendFriends).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/recommendgames/{steamid}", endpoints.RecommendGames).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/savewatcheduser", endpoints.SaveWatchedUser).Methods("POST")
+	apiRouter.HandleFunc("/getwatchedusers", endpoints.GetWatchedUsers).Methods("GET")
+	apiRouter.HandleFunc("/claimwatchedusercrawl/{steamid}", endpoints.ClaimWatchedUserCrawl).Methods("POST")
 	apiRouter.HandleFunc("/getusernamesfromsteamids", endpoints.GetUsernamesFromSteamIDs).Methods("POST")
 	apiRouter.HandleFunc("/saveprocessedgraphdata/{crawlid}", endpoints.SaveProcessedGraphData).Methods("POST")
 	apiRouter.HandleFunc("/getprocessedgraphdata/{crawlid}", endpoints.GetProcessedGraphData).Methods("POST", "OPTIONS")
 	apiRouter.HandleFunc("/doesprocessedgraphdataexist/{crawlid}", endpoints.DoesProcessedGraphDataExist).Methods("POST", "OPTIONS")
+	apiRouter.HandleFunc("/exportgraph/{crawlid}", endpoints.ExportGraph).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/egonetwork/{steamid}", endpoints.GetEgoNetwork).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/diffgraphs", endpoints.DiffGraphs).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/getinsights/{crawlid}", endpoints.GetInsights).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/coarsengraph/{crawlid}", endpoints.GetCoarsenedGraph).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/calculateshortestdistanceinfo", endpoints.CalculateShortestDistanceInfo).Methods("POST", "OPTIONS")
 	apiRouter.HandleFunc("/getshortestdistanceinfo", endpoints.GetShortestDistanceInfo).Methods("POST", "OPTIONS")
 	apiRouter.HandleFunc("/getfinishedcrawlsaftertimestamp", endpoints.GetFinishedCrawlsAfterTimestamp).Methods("GET", "OPTIONS")
@@ -175,7 +215,7 @@ func (endpoints *Endpoints) LoggingMiddleware(next http.Handler) http.Handler {
 
 func (endpoints *Endpoints) SaveUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	saveUserDTO := dtos.SaveUserDTO{}
+	saveUserDTO := datastructures.SaveUserDTO{}
 
 	err := json.NewDecoder(r.Body).Decode(&saveUserDTO)
 	if err != nil {
@@ -183,11 +223,18 @@ func (endpoints *Endpoints) SaveUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	crawlingStats := common.CrawlingStatus{
-		CrawlID:             saveUserDTO.CrawlID,
-		OriginalCrawlTarget: saveUserDTO.OriginalCrawlTarget,
-		MaxLevel:            saveUserDTO.MaxLevel,
-		TotalUsersToCrawl:   len(saveUserDTO.User.FriendIDs),
+	// Only the friends that were placed in the queue are left to be
+	// crawled, budgets and filters can prevent the rest from being crawled
+	crawlingStats := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			CrawlID:             saveUserDTO.CrawlID,
+			OriginalCrawlTarget: saveUserDTO.OriginalCrawlTarget,
+			MaxLevel:            saveUserDTO.MaxLevel,
+			TotalUsersToCrawl:   saveUserDTO.FriendsQueued,
+		},
+	}
+	if saveUserDTO.HubCapped {
+		crawlingStats.CappedHubs = []string{saveUserDTO.User.AccDetails.SteamID}
 	}
 
 	err = app.SaveCrawlingStatsToDB(endpoints.Cntr, saveUserDTO.CurrentLevel, crawlingStats)
@@ -250,7 +297,7 @@ func (endpoints *Endpoints) InsertGame(w http.ResponseWriter, r *http.Request) {
 
 func (endpoints *Endpoints) SaveCrawlingStatsToDB(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	crawlingStatusInput := dtos.SaveCrawlingStatsDTO{}
+	crawlingStatusInput := datastructures.SaveCrawlingStatsDTO{}
 
 	err := json.NewDecoder(r.Body).Decode(&crawlingStatusInput)
 	if err != nil {
@@ -393,6 +440,50 @@ func (endpoints *Endpoints) GetUser(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// GetUsers streams the given users back as they are read from the DB.
+// Users that do not exist are left out of the response
+func (endpoints *Endpoints) GetUsers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	usersInput := datastructures.GetUsersInputDTO{}
+	err := json.NewDecoder(r.Body).Decode(&usersInput)
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	if len(usersInput.SteamIDs) == 0 || len(usersInput.SteamIDs) > maxUsersPerGetUsersRequest {
+		util.SendBasicInvalidResponse(w, r, fmt.Sprintf("Can only request 1-%d users in a request", maxUsersPerGetUsersRequest), vars, http.StatusBadRequest)
+		return
+	}
+	for _, steamID := range usersInput.SteamIDs {
+		if isValid := util.IsValidFormatSteamID(steamID); !isValid {
+			util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, `{"status":"success","users":[`)
+
+	encoder := json.NewEncoder(w)
+	usersWritten := 0
+	err = endpoints.Cntr.GetUsers(context.TODO(), usersInput.SteamIDs, func(user common.UserDocument) error {
+		if usersWritten > 0 {
+			fmt.Fprint(w, ",")
+		}
+		usersWritten++
+		return encoder.Encode(user)
+	})
+	if err != nil {
+		// The response is left unfinished so that it cannot be mistaken
+		// for a complete list of users
+		configuration.Logger.Sugar().Errorf("failed to stream users after writing %d of them: %+v", usersWritten, err)
+		return
+	}
+	fmt.Fprint(w, "]}")
+}
+
 func (endpoints *Endpoints) GetDetailsForGames(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -442,9 +533,306 @@ func (endpoints *Endpoints) GetCrawlingStatus(w http.ResponseWriter, r *http.Req
 		configuration.Logger.Error(logMsg)
 		panic(logMsg)
 	}
-	response := dtos.GetCrawlingStatusDTO{
+	response := datastructures.GetCrawlingStatusDTO{
 		Status:         "success",
-		CrawlingStatus: crawlingStatus,
+		CrawlingStatus: app.AddCrawlProgress(crawlingStatus, time.Now()),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (endpoints *Endpoints) GetFriendHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if isValid := util.IsValidFormatSteamID(vars["steamid"]); !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	friendHistory, err := app.GetFriendHistory(endpoints.Cntr, vars["steamid"])
+	if err != nil {
+		logMsg := fmt.Sprintf("couldn't get friend history: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(friendHistory)
+}
+
+// GetSimilarity compares the mutual friends and shared games of two
+// users that have been crawled
+func (endpoints *Endpoints) GetSimilarity(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	for _, steamID := range []string{vars["firststeamid"], vars["secondsteamid"]} {
+		if isValid := util.IsValidFormatSteamID(steamID); !isValid {
+			util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+			return
+		}
+	}
+	exists, similarity, err := app.GetUserSimilarity(endpoints.Cntr, vars["firststeamid"], vars["secondsteamid"])
+	if err != nil {
+		logMsg := fmt.Sprintf("couldn't get similarity: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+	if !exists {
+		util.SendBasicInvalidResponse(w, r, "user does not exist", vars, http.StatusNotFound)
+		return
+	}
+
+	response := datastructures.GetUserSimilarityDTO{
+		Status:     "success",
+		Similarity: similarity,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RecommendFriends recommends users that the given user may know from
+// the friends of their friends
+func (endpoints *Endpoints) RecommendFriends(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if isValid := util.IsValidFormatSteamID(vars["steamid"]); !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	exists, recommendations, err := app.GetFriendRecommendations(endpoints.Cntr, vars["steamid"])
+	if err != nil {
+		logMsg := fmt.Sprintf("couldn't get friend recommendations: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+	if !exists {
+		util.SendBasicInvalidResponse(w, r, "user does not exist", vars, http.StatusNotFound)
+		return
+	}
+
+	response := datastructures.GetFriendRecommendationsDTO{
+		Status:          "success",
+		SteamID:         vars["steamid"],
+		Recommendations: recommendations,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RecommendGames recommends games that are played by the friends of
+// the given user and the users around them
+func (endpoints *Endpoints) RecommendGames(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if isValid := util.IsValidFormatSteamID(vars["steamid"]); !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	exists, recommendations, err := app.GetGameRecommendations(endpoints.Cntr, vars["steamid"])
+	if err != nil {
+		logMsg := fmt.Sprintf("couldn't get game recommendations: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+	if !exists {
+		util.SendBasicInvalidResponse(w, r, "user does not exist", vars, http.StatusNotFound)
+		return
+	}
+
+	response := datastructures.GetGameRecommendationsDTO{
+		Status:          "success",
+		SteamID:         vars["steamid"],
+		Recommendations: recommendations,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (endpoints *Endpoints) SaveWatchedUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	watchedUser := datastructures.WatchedUser{}
+	err := json.NewDecoder(r.Body).Decode(&watchedUser)
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	if isValid := util.IsValidFormatSteamID(watchedUser.SteamID); !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	if !watchedUserScheduleIsValid(watchedUser) {
+		util.SendBasicInvalidResponse(w, r, "Invalid schedule given", vars, http.StatusBadRequest)
+		return
+	}
+	watchedUser.TimeAdded = time.Now().Unix()
+
+	err = endpoints.Cntr.SaveWatchedUser(context.TODO(), watchedUser)
+	if err != nil {
+		logMsg := fmt.Sprintf("failed to save watched user: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+
+	response := struct {
+		Status string `json:"status"`
+	}{
+		"success",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (endpoints *Endpoints) GetWatchedUsers(w http.ResponseWriter, r *http.Request) {
+	watchedUsers, err := endpoints.Cntr.GetWatchedUsers(context.TODO())
+	if err != nil {
+		logMsg := fmt.Sprintf("couldn't get watched users: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+
+	response := datastructures.GetWatchedUsersDTO{
+		Status:       "success",
+		WatchedUsers: watchedUsers,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (endpoints *Endpoints) ClaimWatchedUserCrawl(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if isValid := util.IsValidFormatSteamID(vars["steamid"]); !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	claim := datastructures.WatchedUserCrawlClaim{}
+	err := json.NewDecoder(r.Body).Decode(&claim)
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	if _, err := ksuid.Parse(claim.CrawlID); err != nil {
+		util.SendBasicInvalidResponse(w, r, "invalid crawlid", vars, http.StatusBadRequest)
+		return
+	}
+
+	claimed, err := endpoints.Cntr.ClaimWatchedUserCrawl(context.TODO(), vars["steamid"], claim)
+	if err != nil {
+		logMsg := fmt.Sprintf("failed to claim watched user crawl: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+
+	response := datastructures.ClaimWatchedUserCrawlDTO{
+		Status:  "success",
+		Claimed: claimed,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (endpoints *Endpoints) UpdateCrawlState(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := ksuid.Parse(vars["crawlid"])
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "invalid crawlid", vars, http.StatusBadRequest)
+		return
+	}
+	stateInput := datastructures.UpdateCrawlStateInputDTO{}
+	err = json.NewDecoder(r.Body).Decode(&stateInput)
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	if len(datastructures.GetPreviousCrawlStates(stateInput.State)) == 0 {
+		util.SendBasicInvalidResponse(w, r, "Invalid crawl state", vars, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := app.TransitionCrawlState(endpoints.Cntr, vars["crawlid"], stateInput.State, stateInput.LeaseID)
+	if err != nil {
+		logMsg := fmt.Sprintf("failed to update crawl state: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+
+	response := datastructures.UpdateCrawlStateDTO{
+		Status:  "success",
+		Updated: updated,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (endpoints *Endpoints) RenewGraphingLease(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := ksuid.Parse(vars["crawlid"])
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "invalid crawlid", vars, http.StatusBadRequest)
+		return
+	}
+	leaseInput := datastructures.RenewGraphingLeaseInputDTO{}
+	err = json.NewDecoder(r.Body).Decode(&leaseInput)
+	if err != nil || leaseInput.LeaseID == "" {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+
+	renewed, err := endpoints.Cntr.RenewGraphingLease(context.TODO(), vars["crawlid"], leaseInput.LeaseID, time.Now().Unix())
+	if err != nil {
+		logMsg := fmt.Sprintf("failed to renew graphing lease: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+
+	response := datastructures.RenewGraphingLeaseDTO{
+		Status:  "success",
+		Renewed: renewed,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (endpoints *Endpoints) UpdateCrawlBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := ksuid.Parse(vars["crawlid"])
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "invalid crawlid", vars, http.StatusBadRequest)
+		return
+	}
+	budgetUsage := datastructures.CrawlBudgetUsage{}
+	err = json.NewDecoder(r.Body).Decode(&budgetUsage)
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	if budgetUsage.APICallsMade < 0 || budgetUsage.UsersRequested < 0 {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+
+	usersGranted, err := app.UpdateCrawlBudget(endpoints.Cntr, vars["crawlid"], budgetUsage)
+	if err != nil {
+		logMsg := fmt.Sprintf("couldn't update crawl budget: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+
+	response := datastructures.UpdateCrawlBudgetDTO{
+		Status:       "success",
+		UsersGranted: usersGranted,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -524,7 +912,7 @@ func (endpoints *Endpoints) SaveProcessedGraphData(w http.ResponseWriter, r *htt
 		return
 	}
 
-	graphData := common.UsersGraphData{}
+	graphData := datastructures.UsersGraphData{}
 	reqBodyBytes, err := gunzip(r.Body)
 	if err != nil {
 		util.SendBasicInvalidResponse(w, r, "invalid input", vars, http.StatusBadRequest)
@@ -536,7 +924,8 @@ func (endpoints *Endpoints) SaveProcessedGraphData(w http.ResponseWriter, r *htt
 		return
 	}
 
-	success, err := endpoints.Cntr.SaveProcessedGraphData(vars["crawlid"], graphData)
+	networkInsights := insights.GetNetworkInsights(graphData, time.Now())
+	success, err := endpoints.Cntr.SaveProcessedGraphData(vars["crawlid"], graphData, networkInsights)
 	if err != nil || !success {
 		configuration.Logger.Sugar().Errorf("could not save graph data: %+v", err)
 		util.SendBasicInvalidResponse(w, r, "could not save graph data", vars, http.StatusBadRequest)
@@ -600,6 +989,224 @@ func (endpoints *Endpoints) GetProcessedGraphData(w http.ResponseWriter, r *http
 	}
 }
 
+// ExportGraph serves the processed graph of a crawl in a graph
+// interchange format. CSV exports are the edges unless ?table=nodes
+// is given
+func (endpoints *Endpoints) ExportGraph(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := ksuid.Parse(vars["crawlid"])
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	exportFormat, isValidFormat := graphing.ExportFormats[format]
+	if !isValidFormat {
+		util.SendBasicInvalidResponse(w, r, "invalid format", vars, http.StatusBadRequest)
+		return
+	}
+	table := r.URL.Query().Get("table")
+	if table != "" && table != graphing.ExportTableNodes && table != graphing.ExportTableEdges {
+		util.SendBasicInvalidResponse(w, r, "invalid table", vars, http.StatusBadRequest)
+		return
+	}
+
+	exists, graphExport, err := app.GetGraphExport(endpoints.Cntr, vars["crawlid"])
+	if err != nil {
+		configuration.Logger.Sugar().Errorf("failed to get processed graph data to export: %+v", err)
+		util.SendBasicInvalidResponse(w, r, "failed to get processed graph data", vars, http.StatusBadRequest)
+		return
+	}
+	if !exists {
+		util.SendBasicInvalidResponse(w, r, "graph does not exist", vars, http.StatusNotFound)
+		return
+	}
+
+	fileName := vars["crawlid"]
+	if graphExport.Partial {
+		fileName += "-partial"
+	}
+	if format == graphing.ExportFormatCSV && table == graphing.ExportTableNodes {
+		fileName += "-nodes"
+	}
+	fileName = fmt.Sprintf("%s.%s", fileName, exportFormat.Extension)
+	w.Header().Set("Content-Type", exportFormat.ContentType)
+	w.Header().Set("X-Graph-Partial", strconv.FormatBool(graphExport.Partial))
+	w.Header().Set("X-Graph-Progress", strconv.Itoa(graphExport.Progress))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.WriteHeader(http.StatusOK)
+	if err := graphing.WriteGraphExport(w, graphExport, format, table); err != nil {
+		configuration.Logger.Sugar().Errorf("failed to write %s export of crawlid %s: %+v", format, vars["crawlid"], err)
+	}
+}
+
+// GetEgoNetwork returns the users within a number of hops of a user in
+// a crawl's processed graph, or in every stored user if no crawlid is
+// given
+func (endpoints *Endpoints) GetEgoNetwork(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if isValid := util.IsValidFormatSteamID(vars["steamid"]); !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	crawlID := r.URL.Query().Get("crawlid")
+	if crawlID != "" {
+		if _, err := ksuid.Parse(crawlID); err != nil {
+			util.SendBasicInvalidResponse(w, r, "Invalid input", vars, http.StatusBadRequest)
+			return
+		}
+	}
+	radius, isValid := getIntQueryParam(r, "radius", defaultEgoNetworkRadius, maxEgoNetworkRadius)
+	if !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid radius", vars, http.StatusBadRequest)
+		return
+	}
+	limit, isValid := getIntQueryParam(r, "limit", defaultEgoNetworkLimit, maxEgoNetworkLimit)
+	if !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid limit", vars, http.StatusBadRequest)
+		return
+	}
+	attributes := []string{}
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		attributes = strings.Split(fields, ",")
+	}
+	for _, attribute := range attributes {
+		if !graphing.IsExportNodeAttribute(attribute) {
+			util.SendBasicInvalidResponse(w, r, "Invalid fields", vars, http.StatusBadRequest)
+			return
+		}
+	}
+
+	exists, egoNetwork, err := app.GetEgoNetwork(endpoints.Cntr, vars["steamid"], crawlID, radius, limit, attributes)
+	if err != nil {
+		logMsg := fmt.Sprintf("couldn't get ego network: %+v", err)
+		configuration.Logger.Error(logMsg)
+		panic(logMsg)
+	}
+	if !exists {
+		util.SendBasicInvalidResponse(w, r, "user does not exist", vars, http.StatusNotFound)
+		return
+	}
+
+	response := datastructures.GetEgoNetworkDTO{
+		Status:     "success",
+		EgoNetwork: egoNetwork,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DiffGraphs compares the processed graphs of two crawls of the same user
+func (endpoints *Endpoints) DiffGraphs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	fromCrawlID := r.URL.Query().Get("from")
+	toCrawlID := r.URL.Query().Get("to")
+	for _, crawlID := range []string{fromCrawlID, toCrawlID} {
+		if _, err := ksuid.Parse(crawlID); err != nil {
+			util.SendBasicInvalidResponse(w, r, "invalid input", vars, http.StatusBadRequest)
+			return
+		}
+	}
+
+	graphed, sameUser, diff, err := app.DiffGraphs(endpoints.Cntr, fromCrawlID, toCrawlID)
+	if err != nil {
+		configuration.Logger.Sugar().Errorf("failed to diff processed graph data: %+v", err)
+		util.SendBasicInvalidResponse(w, r, "failed to get processed graph data", vars, http.StatusBadRequest)
+		return
+	}
+	if !graphed {
+		util.SendBasicInvalidResponse(w, r, "graph does not exist", vars, http.StatusNotFound)
+		return
+	}
+	if !sameUser {
+		util.SendBasicInvalidResponse(w, r, "crawls are of different users", vars, http.StatusBadRequest)
+		return
+	}
+
+	response := datastructures.GetGraphDiffDTO{
+		Status: "success",
+		Diff:   diff,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCoarsenedGraph serves the processed graph of a crawl with users
+// collapsed into super nodes so that it has at most ?maxnodes= nodes.
+// Super nodes given in ?expand= are shown as their users instead
+func (endpoints *Endpoints) GetCoarsenedGraph(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := ksuid.Parse(vars["crawlid"])
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "invalid input", vars, http.StatusBadRequest)
+		return
+	}
+	maxNodes, isValid := getIntQueryParam(r, "maxnodes", defaultCoarsenedGraphMaxNodes, maxCoarsenedGraphMaxNodes)
+	if !isValid {
+		util.SendBasicInvalidResponse(w, r, "Invalid maxnodes", vars, http.StatusBadRequest)
+		return
+	}
+	expanded := []string{}
+	if expand := r.URL.Query().Get("expand"); expand != "" {
+		expanded = strings.Split(expand, ",")
+	}
+
+	exists, coarsenedGraph, err := app.GetCoarsenedGraph(endpoints.Cntr, vars["crawlid"], maxNodes, expanded)
+	if err != nil {
+		configuration.Logger.Sugar().Errorf("failed to get processed graph data: %+v", err)
+		util.SendBasicInvalidResponse(w, r, "failed to get processed graph data", vars, http.StatusBadRequest)
+		return
+	}
+	if !exists {
+		util.SendBasicInvalidResponse(w, r, "graph does not exist", vars, http.StatusNotFound)
+		return
+	}
+
+	response := datastructures.GetCoarsenedGraphDTO{
+		Status:         "success",
+		CoarsenedGraph: coarsenedGraph,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetInsights serves the statistics shown on the graph page of a crawl
+func (endpoints *Endpoints) GetInsights(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, err := ksuid.Parse(vars["crawlid"])
+	if err != nil {
+		util.SendBasicInvalidResponse(w, r, "invalid input", vars, http.StatusBadRequest)
+		return
+	}
+
+	exists, networkInsights, err := app.GetInsights(endpoints.Cntr, vars["crawlid"])
+	if err != nil {
+		configuration.Logger.Sugar().Errorf("failed to get insights: %+v", err)
+		util.SendBasicInvalidResponse(w, r, "failed to get insights", vars, http.StatusBadRequest)
+		return
+	}
+	if !exists {
+		util.SendBasicInvalidResponse(w, r, "graph does not exist", vars, http.StatusNotFound)
+		return
+	}
+
+	response := datastructures.GetInsightsDTO{
+		Status:   "success",
+		Insights: networkInsights,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 func (endpoints *Endpoints) DoesProcessedGraphDataExist(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -609,7 +1216,10 @@ func (endpoints *Endpoints) DoesProcessedGraphDataExist(w http.ResponseWriter, r
 		return
 	}
 
-	exists, err := endpoints.Cntr.DoesProcessedGraphDataExist(vars["crawlid"])
+	// Partial graphs are only counted when asked for so that pages waiting
+	// for the full graph are not sent to a partial one
+	includePartial := r.URL.Query().Get("partial") == "true"
+	exists, err := endpoints.Cntr.DoesProcessedGraphDataExist(vars["crawlid"], includePartial)
 	if err != nil {
 		util.SendBasicInvalidResponse(w, r, "failed to get processed graph data", vars, http.StatusBadRequest)
 		configuration.Logger.Sugar().Errorf("failed to get processed graph data: %+v", err)
@@ -671,12 +1281,16 @@ func (endpoints *Endpoints) CalculateShortestDistanceInfo(w http.ResponseWriter,
 		return
 	}
 
-	_, shortestDistanceInfo, err := app.CalulateShortestDistanceInfo(endpoints.Cntr, crawlIDsInput.CrawlIDs[0], crawlIDsInput.CrawlIDs[1])
+	bothCrawlsAreGraphed, shortestDistanceInfo, err := app.CalulateShortestDistanceInfo(endpoints.Cntr, crawlIDsInput.CrawlIDs[0], crawlIDsInput.CrawlIDs[1])
 	if err != nil {
 		util.SendBasicInvalidResponse(w, r, "could not find shortest distance", vars, http.StatusBadRequest)
 		configuration.Logger.Sugar().Errorf("failed to get shortest distance: %s", err.Error())
 		return
 	}
+	if !bothCrawlsAreGraphed {
+		util.SendBasicInvalidResponse(w, r, "crawls have not been graphed yet", vars, http.StatusBadRequest)
+		return
+	}
 
 	success, err := endpoints.Cntr.SaveShortestDistance(context.TODO(), shortestDistanceInfo)
 	if err != nil || !success {
@@ -142,13 +142,14 @@ func TestSaveUserWithExistingUser(t *testing.T) {
 	mockController.On("UpdateCrawlingStatus",
 		mock.Anything,
 		mock.Anything,
-		mock.Anything).Return(true, nil)
+		mock.Anything).Return(true, datastructures.CrawlingStatus{}, nil)
 
-	insertResult := mongo.InsertOneResult{}
-	mockController.On("InsertOne",
+	mockController.On("GetUser",
 		mock.Anything,
+		mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser",
 		mock.Anything,
-		mock.Anything).Return(&insertResult, nil)
+		mock.Anything).Return(nil)
 
 	expectedResponse := struct {
 		Status  string `json:"status"`
@@ -186,13 +187,57 @@ func TestSaveUserWithExistingUser(t *testing.T) {
 	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
 }
 
+func TestSaveUserRecordsCappedHubsInTheCrawlingStatus(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	mockController.On("UpdateCrawlingStatus",
+		mock.Anything,
+		mock.Anything,
+		mock.MatchedBy(func(crawlingStatus datastructures.CrawlingStatus) bool {
+			return len(crawlingStatus.CappedHubs) == 1 &&
+				crawlingStatus.CappedHubs[0] == testSaveUserDTO.User.AccDetails.SteamID &&
+				crawlingStatus.TotalUsersToCrawl == 0
+		})).Return(true, datastructures.CrawlingStatus{}, nil)
+
+	mockController.On("GetUser",
+		mock.Anything,
+		mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser",
+		mock.Anything,
+		mock.Anything).Return(nil)
+
+	saveUserInput := datastructures.SaveUserDTO{
+		SaveUserDTO: testSaveUserDTO,
+		HubCapped:   true,
+	}
+	requestBodyJSON, err := json.Marshal(saveUserInput)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/saveuser", serverPort), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	mockController.AssertNumberOfCalls(t, "UpdateCrawlingStatus", 1)
+}
+
 func TestSaveUserReturnsInvalidResponseWhenSaveCrawlingStatsReturnsAnError(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
 	mockController.On("UpdateCrawlingStatus",
 		mock.Anything,
 		mock.Anything,
-		mock.Anything).Return(false, errors.New("random error from UpdateCrawlingStatus"))
+		mock.Anything).Return(false, datastructures.CrawlingStatus{}, errors.New("random error from UpdateCrawlingStatus"))
 
 	requestBodyJSON, err := json.Marshal(testSaveUserDTO)
 	if err != nil {
@@ -226,13 +271,14 @@ func TestSaveUserReturnsInvalidResponseWhenSaveUserToDBReturnsAnError(t *testing
 	mockController.On("UpdateCrawlingStatus",
 		mock.Anything,
 		mock.Anything,
-		mock.Anything).Return(true, nil)
+		mock.Anything).Return(true, datastructures.CrawlingStatus{}, nil)
 
-	insertResult := mongo.InsertOneResult{}
-	mockController.On("InsertOne",
+	mockController.On("GetUser",
 		mock.Anything,
+		mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser",
 		mock.Anything,
-		mock.Anything).Return(&insertResult, errors.New("random error from SaveUserToDB"))
+		mock.Anything).Return(errors.New("random error from SaveUserToDB"))
 
 	requestBodyJSON, err := json.Marshal(testSaveUserDTO)
 	if err != nil {
@@ -268,13 +314,14 @@ func TestSaveUserOnlyCallsUpdateCrawlingStatusIfUserIsAtMaxLevel(t *testing.T) {
 	mockController.On("UpdateCrawlingStatus",
 		mock.Anything,
 		mock.Anything,
-		mock.Anything).Return(true, nil)
+		mock.Anything).Return(true, datastructures.CrawlingStatus{}, nil)
 
-	singleResult := mongo.InsertOneResult{}
-	mockController.On("InsertOne",
+	mockController.On("GetUser",
 		mock.Anything,
+		mock.Anything).Return(common.UserDocument{}, nil)
+	mockController.On("UpsertUser",
 		mock.Anything,
-		mock.Anything).Return(&singleResult, nil)
+		mock.Anything).Return(nil)
 
 	expectedResponse := struct {
 		Status  string `json:"status"`
@@ -309,7 +356,7 @@ func TestSaveUserOnlyCallsUpdateCrawlingStatusIfUserIsAtMaxLevel(t *testing.T) {
 	}
 
 	mockController.AssertNumberOfCalls(t, "UpdateCrawlingStatus", 1)
-	mockController.AssertNumberOfCalls(t, "InsertOne", 1)
+	mockController.AssertNumberOfCalls(t, "UpsertUser", 1)
 
 	assert.Equal(t, 200, res.StatusCode)
 	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
@@ -336,148 +383,1015 @@ func TestGetUser(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
+func TestGetUsersStreamsBackEveryUserFound(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	secondUser := testUser
+	secondUser.AccDetails.SteamID = "76561198054243122"
+	requestedSteamIDs := []string{testUser.AccDetails.SteamID, secondUser.AccDetails.SteamID, "76561198054243133"}
+	mockController.On("GetUsers", mock.Anything, requestedSteamIDs, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		foundUser := args.Get(2).(func(common.UserDocument) error)
+		foundUser(testUser)
+		foundUser(secondUser)
+	})
+
+	requestBody, err := json.Marshal(datastructures.GetUsersInputDTO{SteamIDs: requestedSteamIDs})
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusers", serverPort), bytes.NewBuffer(requestBody))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	usersResponse := datastructures.GetUsersDTO{}
+	err = json.NewDecoder(res.Body).Decode(&usersResponse)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "success", usersResponse.Status)
+	assert.Equal(t, []common.UserDocument{testUser, secondUser}, usersResponse.Users)
+}
+
+func TestGetUsersLeavesTheResponseUnfinishedWhenReadingUsersFails(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	mockController.On("GetUsers", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("cursor failed")).Run(func(args mock.Arguments) {
+		foundUser := args.Get(2).(func(common.UserDocument) error)
+		foundUser(testUser)
+	})
+
+	requestBody, err := json.Marshal(datastructures.GetUsersInputDTO{SteamIDs: []string{testUser.AccDetails.SteamID}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusers", serverPort), bytes.NewBuffer(requestBody))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	usersResponse := datastructures.GetUsersDTO{}
+	err = json.NewDecoder(res.Body).Decode(&usersResponse)
+
+	assert.NotNil(t, err)
+}
+
+func TestGetUsersReturnsInvalidResponseForTooManyUsers(t *testing.T) {
+	_, serverPort := initServerAndDependencies()
+
+	requestedSteamIDs := []string{}
+	for i := 0; i <= maxUsersPerGetUsersRequest; i++ {
+		requestedSteamIDs = append(requestedSteamIDs, testUser.AccDetails.SteamID)
+	}
+	requestBody, err := json.Marshal(datastructures.GetUsersInputDTO{SteamIDs: requestedSteamIDs})
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusers", serverPort), bytes.NewBuffer(requestBody))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGetUserReturnsInvalidResponseWhenGetUseFromDBReturnsAnError(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	getUserError := errors.New("couldn't get user")
+	mockController.On("GetUser", mock.Anything, mock.AnythingOfType("string")).Return(common.UserDocument{}, getUserError)
+
+	authHeader := http.Header{}
+	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
+	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getuser/%s", serverPort, testUser.AccDetails.SteamID), []http.Header{authHeader})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Contains(t, string(res), defaultPanicErrorMessageStarter)
+	fmt.Print(string(res))
+}
+
+func TestGetUserReturnsInvalidResponseWhenGivenAnInvalidSteamID(t *testing.T) {
+	mockController := &controller.MockCntrInterface{}
+	endpoints := Endpoints{
+		mockController,
+	}
+	assert.HTTPStatusCode(t, endpoints.GetUser, "GET", "/getuser/invalidsteamid", nil, 400)
+}
+
+func TestGetCrawlingStatsReturnsInvalidCrawlIDWhenGivenAnInvalidID(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	randomErr := errors.New("random error")
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.AnythingOfType("string")).Return(common.UserDocument{}, randomErr)
+
+	expectedResponse := struct {
+		Error string `json:"error"`
+	}{
+		"invalid crawlid",
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getcrawlingstatus/gobbeldygook", serverPort), []http.Header{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+}
+
+func TestGetCrawlingStatsReturnsCorrectCrawlingStatusWhenGivenValidCrawlID(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	expectedCrawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			TimeStarted:         time.Now().Unix(),
+			CrawlID:             ksuid.New().String(),
+			OriginalCrawlTarget: "someuser",
+			MaxLevel:            3,
+			TotalUsersToCrawl:   1337,
+			UsersCrawled:        625,
+		},
+	}
+
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(expectedCrawlingStatus, nil)
+
+	expectedResponse := datastructures.GetCrawlingStatusDTO{
+		Status:         "success",
+		CrawlingStatus: expectedCrawlingStatus,
+	}
+	expectedResponse.CrawlingStatus.EstimatedTotalUsers = 1337
+	expectedResponse.CrawlingStatus.EstimatedProgress = 46
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getcrawlingstatus/%s", serverPort, expectedCrawlingStatus.CrawlID), []http.Header{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+}
+
+func TestGetCrawlingStatsReturnsCouldntGetCrawlingStatusWhenDBReturnsAnError(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	randomError := errors.New("hello world")
+	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(datastructures.CrawlingStatus{}, randomError)
+
+	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getcrawlingstatus/%s", serverPort, ksuid.New().String()), []http.Header{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Contains(t, string(res), defaultPanicErrorMessageStarter)
+}
+
+func TestGetUsernamesFromSteamIDsReturnsUsernamesForSteamID(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+	expectedUsername := "Cathal"
+
+	expectedSteamIDsToUsernames := make(map[string]string)
+	expectedSteamIDsToUsernames[validFormatSteamID] = expectedUsername
+
+	mockController.On("GetUsernames", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(expectedSteamIDsToUsernames, nil)
+
+	expectedResponse := dtos.GetUsernamesFromSteamIDsDTO{
+		SteamIDAndUsername: []dtos.SteamIDAndUsername{
+			{
+				SteamID:  validFormatSteamID,
+				Username: expectedUsername,
+			},
+		},
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+	requestBody := dtos.GetUsernamesFromSteamIDsInputDTO{
+		SteamIDs: []string{
+			validFormatSteamID,
+		},
+	}
+	requestBodyJSON, err := json.Marshal(requestBody)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusernamesfromsteamids", serverPort), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
+func TestGetUsernamesReturnsInvalidInputForAnyInvalidFormatSteamIDsGiven(t *testing.T) {
+	_, serverPort := initServerAndDependencies()
+
+	requestBody := dtos.GetUsernamesFromSteamIDsInputDTO{
+		SteamIDs: []string{
+			invalidFormatSteamID,
+		},
+	}
+	requestBodyJSON, err := json.Marshal(requestBody)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusernamesfromsteamids", serverPort), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGetUsernamesFromSteamIDsReturnsInvalidRequestWhenCallToDataStoreFails(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	randomError := errors.New("hello world")
+	mockController.On("GetUsernames", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(make(map[string]string), randomError)
+
+	expectedResponse := struct {
+		Error string `json:"error"`
+	}{
+		"couldn't get usernames",
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	requestBody := dtos.GetUsernamesFromSteamIDsInputDTO{
+		SteamIDs: []string{
+			validFormatSteamID,
+		},
+	}
+	requestBodyJSON, err := json.Marshal(requestBody)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusernamesfromsteamids", serverPort), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	defer res.Body.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
+func TestGetGraphableDataReturnsGraphableDataForAValidUser(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	foundUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{
+			Personaname: "Cathal",
+			SteamID:     validFormatSteamID,
+		},
+		FriendIDs: []string{
+			"1",
+			"2",
+			"3",
+		},
+	}
+	expectedResponse := dtos.GetGraphableDataForUserDTO{
+		Username:  foundUser.AccDetails.Personaname,
+		SteamID:   foundUser.AccDetails.SteamID,
+		FriendIDs: foundUser.FriendIDs,
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.On("GetUser", mock.Anything, foundUser.AccDetails.SteamID).Return(foundUser, nil)
+
+	authHeader := http.Header{}
+	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
+	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getgraphabledata/%s", serverPort, foundUser.AccDetails.SteamID), []http.Header{authHeader})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+}
+
+func TestGetGraphableDataReturnsCouldntGetUserWhenNoUserIsFound(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	expectedResponse := struct {
+		Error string `json:"error"`
+	}{
+		"couldn't get user",
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+	randomError := errors.New("hello world")
+	mockController.On("GetUser", mock.Anything, validFormatSteamID).Return(common.UserDocument{}, randomError)
+
+	authHeader := http.Header{}
+	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
+	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getgraphabledata/%s", serverPort, validFormatSteamID), []http.Header{authHeader})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+}
+
+func TestGetGraphableDataReturnsInvalidInputForInvalidFormatSteamIDs(t *testing.T) {
+	_, serverPort := initServerAndDependencies()
+
+	expectedResponse := struct {
+		Error string `json:"error"`
+	}{
+		"Invalid input",
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authHeader := http.Header{}
+	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
+	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getgraphabledata/%s", serverPort, invalidFormatSteamID), []http.Header{authHeader})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+}
+
+func TestSaveCrawlingStatsToDB(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlingStatsInput := datastructures.SaveCrawlingStatsDTO{
+		CurrentLevel: 2,
+		CrawlingStatus: datastructures.CrawlingStatus{
+			CrawlingStatus: common.CrawlingStatus{
+				TimeStarted:       time.Now().Unix(),
+				MaxLevel:          3,
+				UsersCrawled:      5,
+				TotalUsersToCrawl: 20,
+			},
+		},
+	}
+	requestBodyJSON, err := json.Marshal(crawlingStatsInput)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mockController.On("UpdateCrawlingStatus", mock.Anything, mock.Anything, mock.Anything).Return(true, datastructures.CrawlingStatus{}, nil)
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/savecrawlingstats", serverPort), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestUpdateCrawlBudgetReturnsTheAmountOfUsersGranted(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlID := ksuid.New().String()
+	budgetUsage := datastructures.CrawlBudgetUsage{
+		APICallsMade:   5,
+		UsersRequested: 30,
+	}
+	crawlingStatusBeforeUpdate := datastructures.CrawlingStatus{
+		Budget: datastructures.CrawlBudget{
+			MaxUsers: 100,
+		},
+		UsersReserved: 80,
+	}
+	requestBodyJSON, err := json.Marshal(budgetUsage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mockController.On("UpdateCrawlBudget", mock.Anything, crawlID, budgetUsage).Return(true, crawlingStatusBeforeUpdate, nil)
+
+	expectedResponse := datastructures.UpdateCrawlBudgetDTO{
+		Status:       "success",
+		UsersGranted: 20,
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/updatecrawlbudget/%s", serverPort, crawlID), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
+func TestUpdateCrawlBudgetReturnsInvalidInputForNegativeUsage(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	budgetUsage := datastructures.CrawlBudgetUsage{
+		APICallsMade: -5,
+	}
+	requestBodyJSON, err := json.Marshal(budgetUsage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/updatecrawlbudget/%s", serverPort, ksuid.New().String()), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "UpdateCrawlBudget", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGetFriendHistoryReturnsTheChangesAndTotals(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	steamID := "76561197969081524"
+	changes := []datastructures.FriendListChange{
+		{
+			SteamID:   steamID,
+			Timestamp: 1645000000,
+			Added:     []string{"76561198000000001", "76561198000000002"},
+			Removed:   []string{},
+		},
+		{
+			SteamID:   steamID,
+			Timestamp: 1646000000,
+			Added:     []string{},
+			Removed:   []string{"76561198000000001"},
+		},
+	}
+	mockController.On("GetFriendListChanges", mock.Anything, steamID).Return(changes, nil)
+
+	expectedResponse := datastructures.GetFriendHistoryDTO{
+		Status:       "success",
+		SteamID:      steamID,
+		TotalAdded:   2,
+		TotalRemoved: 1,
+		Changes:      changes,
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getfriendhistory/%s", serverPort, steamID))
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
+func TestGetFriendHistoryReturnsInvalidInputForAnInvalidSteamID(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getfriendhistory/%s", serverPort, "invalidsteamid"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "GetFriendListChanges", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGetSimilarityReturnsTheSimilarityOfTwoUsers(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	firstUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{SteamID: "76561197969081524"},
+		FriendIDs:  []string{"76561198000000002"},
+		GamesOwned: []common.GameOwnedDocument{{AppID: 730, Playtime_Forever: 100}},
+	}
+	secondUser := common.UserDocument{
+		AccDetails: common.AccDetailsDocument{SteamID: "76561198000000001"},
+		FriendIDs:  []string{"76561198000000002", "76561198000000003"},
+		GamesOwned: []common.GameOwnedDocument{{AppID: 730, Playtime_Forever: 20}},
+	}
+	mockController.On("GetUser", mock.Anything, firstUser.AccDetails.SteamID).Return(firstUser, nil)
+	mockController.On("GetUser", mock.Anything, secondUser.AccDetails.SteamID).Return(secondUser, nil)
+
+	expectedResponse := datastructures.GetUserSimilarityDTO{
+		Status: "success",
+		Similarity: datastructures.UserSimilarity{
+			SteamIDs:         []string{firstUser.AccDetails.SteamID, secondUser.AccDetails.SteamID},
+			MutualFriends:    []string{"76561198000000002"},
+			FriendSimilarity: 0.5,
+			SharedGames: []datastructures.SharedGame{
+				{AppID: 730, FirstUserPlaytime: 100, SecondUserPlaytime: 20, CombinedPlaytime: 120},
+			},
+			LibrarySimilarity: 1,
+		},
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getsimilarity/%s/%s", serverPort, firstUser.AccDetails.SteamID, secondUser.AccDetails.SteamID))
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
+func TestGetSimilarityReturnsNotFoundWhenAUserHasNotBeenCrawled(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	mockController.On("GetUser", mock.Anything, "76561197969081524").Return(common.UserDocument{}, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getsimilarity/%s/%s", serverPort, "76561197969081524", "76561198000000001"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestGetSimilarityReturnsInvalidInputForAnInvalidSteamID(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getsimilarity/%s/%s", serverPort, "76561197969081524", "invalidsteamid"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestRecommendFriendsReturnsNotFoundWhenTheUserHasNotBeenCrawled(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	mockController.On("GetUser", mock.Anything, "76561197969081524").Return(common.UserDocument{}, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/recommendfriends/%s", serverPort, "76561197969081524"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestRecommendFriendsReturnsInvalidInputForAnInvalidSteamID(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/recommendfriends/%s", serverPort, "invalidsteamid"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestRecommendGamesReturnsNotFoundWhenTheUserHasNotBeenCrawled(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	mockController.On("GetUser", mock.Anything, "76561197969081524").Return(common.UserDocument{}, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/recommendgames/%s", serverPort, "76561197969081524"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestExportGraphServesTheGraphAsAnAttachment(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlID := ksuid.New().String()
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: common.UsersGraphInformation{User: testUser},
+		},
+	}
+	mockController.On("GetProcessedGraphData", crawlID).Return(graphData, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/exportgraph/%s?format=csv&table=nodes", serverPort, crawlID))
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+	assert.Equal(t, fmt.Sprintf("attachment; filename=\"%s-nodes.csv\"", crawlID), res.Header.Get("Content-Disposition"))
+	assert.Contains(t, string(body), testUser.AccDetails.SteamID)
+}
+
+func TestExportGraphSaysWhenTheGraphIsPartial(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlID := ksuid.New().String()
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: common.UsersGraphInformation{User: testUser},
+		},
+		Partial:  true,
+		Progress: 60,
+	}
+	mockController.On("GetProcessedGraphData", crawlID).Return(graphData, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/exportgraph/%s?format=dot", serverPort, crawlID))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "true", res.Header.Get("X-Graph-Partial"))
+	assert.Equal(t, "60", res.Header.Get("X-Graph-Progress"))
+	assert.Equal(t, fmt.Sprintf("attachment; filename=\"%s-partial.dot\"", crawlID), res.Header.Get("Content-Disposition"))
+}
+
+func TestExportGraphReturnsNotFoundForACrawlThatHasNotBeenGraphed(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlID := ksuid.New().String()
+	mockController.On("GetProcessedGraphData", crawlID).Return(datastructures.UsersGraphData{}, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/exportgraph/%s?format=graphml", serverPort, crawlID))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestExportGraphReturnsInvalidInputForAnUnknownFormat(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/exportgraph/%s?format=png", serverPort, ksuid.New().String()))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mockController.AssertNotCalled(t, "GetProcessedGraphData", mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGetEgoNetworkReturnsTheNeighbourhoodOfAUserInACrawl(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlID := ksuid.New().String()
+	friend := testUser
+	friend.AccDetails.SteamID = "76561198054243122"
+	friend.FriendIDs = []string{testUser.AccDetails.SteamID}
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails:   common.UsersGraphInformation{User: testUser},
+			FriendDetails: []common.UsersGraphInformation{{User: friend}},
+		},
+	}
+	mockController.On("GetProcessedGraphData", crawlID).Return(graphData, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/egonetwork/%s?crawlid=%s&fields=country", serverPort, friend.AccDetails.SteamID, crawlID))
+	if err != nil {
+		log.Fatal(err)
+	}
+	response := datastructures.GetEgoNetworkDTO{}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, response.EgoNetwork.Radius)
+	assert.Len(t, response.EgoNetwork.Nodes, 2)
+	assert.Equal(t, map[string]interface{}{"country": testUser.AccDetails.Loccountrycode}, response.EgoNetwork.Nodes[1].Attributes)
+	assert.Len(t, response.EgoNetwork.Edges, 1)
+}
+
+func TestGetEgoNetworkReturnsInvalidInputForBadQueryParams(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	for _, query := range []string{"radius=4", "radius=0", "limit=abc", "fields=password", "crawlid=invalid"} {
+		res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/egonetwork/%s?%s", serverPort, "76561197969081524", query))
+		if err != nil {
+			log.Fatal(err)
+		}
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+	mockController.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+	mockController.AssertNotCalled(t, "GetProcessedGraphData", mock.Anything)
+}
+
+func TestDiffGraphsReturnsTheDiffOfTwoCrawlsOfTheSameUser(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	fromCrawlID := ksuid.New().String()
+	toCrawlID := ksuid.New().String()
+	friend := testUser
+	friend.AccDetails.SteamID = "76561198054243122"
+	fromGraph := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: common.UsersGraphInformation{User: testUser},
+		},
+	}
+	toGraph := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails:   common.UsersGraphInformation{User: testUser},
+			FriendDetails: []common.UsersGraphInformation{{User: friend}},
+		},
+	}
+	mockController.On("GetProcessedGraphData", fromCrawlID).Return(fromGraph, nil)
+	mockController.On("GetProcessedGraphData", toCrawlID).Return(toGraph, nil)
+	mockController.On("GetDetailsForGames", mock.Anything, mock.Anything).Return([]common.BareGameInfo{}, nil)
 
-	res, err := client.Do(req)
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/diffgraphs?from=%s&to=%s", serverPort, fromCrawlID, toCrawlID))
 	if err != nil {
 		log.Fatal(err)
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
+	response := datastructures.GetGraphDiffDTO{}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 		log.Fatal(err)
 	}
 
 	assert.Equal(t, http.StatusOK, res.StatusCode)
-	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+	assert.Equal(t, []string{friend.AccDetails.SteamID}, response.Diff.AddedUsers)
+	assert.Empty(t, response.Diff.RemovedUsers)
 }
 
-func TestGetUserReturnsInvalidResponseWhenGetUseFromDBReturnsAnError(t *testing.T) {
+func TestDiffGraphsReturnsInvalidInputForCrawlsOfDifferentUsers(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	getUserError := errors.New("couldn't get user")
-	mockController.On("GetUser", mock.Anything, mock.AnythingOfType("string")).Return(common.UserDocument{}, getUserError)
+	fromCrawlID := ksuid.New().String()
+	toCrawlID := ksuid.New().String()
+	otherUser := testUser
+	otherUser.AccDetails.SteamID = "76561198054243122"
+	mockController.On("GetProcessedGraphData", fromCrawlID).Return(datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{UserDetails: common.UsersGraphInformation{User: testUser}},
+	}, nil)
+	mockController.On("GetProcessedGraphData", toCrawlID).Return(datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{UserDetails: common.UsersGraphInformation{User: otherUser}},
+	}, nil)
 
-	authHeader := http.Header{}
-	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
-	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getuser/%s", serverPort, testUser.AccDetails.SteamID), []http.Header{authHeader})
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/diffgraphs?from=%s&to=%s", serverPort, fromCrawlID, toCrawlID))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	assert.Contains(t, string(res), defaultPanicErrorMessageStarter)
-	fmt.Print(string(res))
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 }
 
-func TestGetUserReturnsInvalidResponseWhenGivenAnInvalidSteamID(t *testing.T) {
-	mockController := &controller.MockCntrInterface{}
-	endpoints := Endpoints{
-		mockController,
+func TestDiffGraphsReturnsNotFoundWhenACrawlHasNotBeenGraphed(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	fromCrawlID := ksuid.New().String()
+	toCrawlID := ksuid.New().String()
+	mockController.On("GetProcessedGraphData", fromCrawlID).Return(datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{UserDetails: common.UsersGraphInformation{User: testUser}},
+	}, nil)
+	mockController.On("GetProcessedGraphData", toCrawlID).Return(datastructures.UsersGraphData{}, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/diffgraphs?from=%s&to=%s", serverPort, fromCrawlID, toCrawlID))
+	if err != nil {
+		log.Fatal(err)
 	}
-	assert.HTTPStatusCode(t, endpoints.GetUser, "GET", "/getuser/invalidsteamid", nil, 400)
+
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
 }
 
-func TestGetCrawlingStatsReturnsInvalidCrawlIDWhenGivenAnInvalidID(t *testing.T) {
+func TestGetInsightsReturnsTheSavedInsightsOfACrawl(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	randomErr := errors.New("random error")
-	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.AnythingOfType("string")).Return(common.UserDocument{}, randomErr)
+	crawlID := ksuid.New().String()
+	savedInsights := datastructures.NetworkInsights{GamerScore: 100, TopCountries: []string{"IE"}}
+	mockController.On("GetInsights", crawlID).Return(true, savedInsights, nil)
 
-	expectedResponse := struct {
-		Error string `json:"error"`
-	}{
-		"invalid crawlid",
-	}
-	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getinsights/%s", serverPort, crawlID))
 	if err != nil {
 		log.Fatal(err)
 	}
+	response := datastructures.GetInsightsDTO{}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.Fatal(err)
+	}
 
-	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getcrawlingstatus/gobbeldygook", serverPort), []http.Header{})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, 100, response.Insights.GamerScore)
+	assert.Equal(t, []string{"IE"}, response.Insights.TopCountries)
+}
+
+func TestGetInsightsReturnsNotFoundForACrawlThatHasNotBeenGraphed(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlID := ksuid.New().String()
+	mockController.On("GetInsights", crawlID).Return(false, datastructures.NetworkInsights{}, nil)
+	mockController.On("GetProcessedGraphData", crawlID).Return(datastructures.UsersGraphData{}, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getinsights/%s", serverPort, crawlID))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
 }
 
-func TestGetCrawlingStatsReturnsCorrectCrawlingStatusWhenGivenValidCrawlID(t *testing.T) {
+func TestGetInsightsReturnsInvalidInputForInvalidFormatCrawlID(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	expectedCrawlingStatus := common.CrawlingStatus{
-		TimeStarted:         time.Now().Unix(),
-		CrawlID:             ksuid.New().String(),
-		OriginalCrawlTarget: "someuser",
-		MaxLevel:            3,
-		TotalUsersToCrawl:   1337,
-		UsersCrawled:        625,
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/getinsights/notacrawlid", serverPort))
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(expectedCrawlingStatus, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	mockController.AssertNotCalled(t, "GetInsights")
+}
 
-	expectedResponse := dtos.GetCrawlingStatusDTO{
-		Status:         "success",
-		CrawlingStatus: expectedCrawlingStatus,
+func TestGetCoarsenedGraphServesTheGraphOfACrawl(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	crawlID := ksuid.New().String()
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: common.UsersGraphInformation{User: testUser},
+		},
 	}
-	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	mockController.On("GetProcessedGraphData", crawlID).Return(graphData, nil)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/coarsengraph/%s?maxnodes=10&expand=community-0", serverPort, crawlID))
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getcrawlingstatus/%s", serverPort, expectedCrawlingStatus.CrawlID), []http.Header{})
-	if err != nil {
+	response := datastructures.GetCoarsenedGraphDTO{}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 		log.Fatal(err)
 	}
 
-	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 10, response.CoarsenedGraph.MaxNodes)
+	assert.Equal(t, []string{"community-0"}, response.CoarsenedGraph.Expanded)
+	assert.Len(t, response.CoarsenedGraph.Nodes, 1)
+	assert.Equal(t, testUser.AccDetails.SteamID, response.CoarsenedGraph.Nodes[0].ID)
 }
 
-func TestGetCrawlingStatsReturnsCouldntGetCrawlingStatusWhenDBReturnsAnError(t *testing.T) {
+func TestGetCoarsenedGraphReturnsNotFoundForACrawlThatHasNotBeenGraphed(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	randomError := errors.New("hello world")
-	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(common.CrawlingStatus{}, randomError)
+	crawlID := ksuid.New().String()
+	mockController.On("GetProcessedGraphData", crawlID).Return(datastructures.UsersGraphData{}, nil)
 
-	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getcrawlingstatus/%s", serverPort, ksuid.New().String()), []http.Header{})
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/coarsengraph/%s", serverPort, crawlID))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	assert.Contains(t, string(res), defaultPanicErrorMessageStarter)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
 }
 
-func TestGetUsernamesFromSteamIDsReturnsUsernamesForSteamID(t *testing.T) {
+func TestGetCoarsenedGraphReturnsInvalidInputForAnInvalidMaxNodes(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
-	expectedUsername := "Cathal"
-
-	expectedSteamIDsToUsernames := make(map[string]string)
-	expectedSteamIDsToUsernames[validFormatSteamID] = expectedUsername
-
-	mockController.On("GetUsernames", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(expectedSteamIDsToUsernames, nil)
 
-	expectedResponse := dtos.GetUsernamesFromSteamIDsDTO{
-		SteamIDAndUsername: []dtos.SteamIDAndUsername{
-			{
-				SteamID:  validFormatSteamID,
-				Username: expectedUsername,
-			},
-		},
-	}
-	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/api/coarsengraph/%s?maxnodes=0", serverPort, ksuid.New().String()))
 	if err != nil {
 		log.Fatal(err)
 	}
-	requestBody := dtos.GetUsernamesFromSteamIDsInputDTO{
-		SteamIDs: []string{
-			validFormatSteamID,
-		},
+
+	mockController.AssertNotCalled(t, "GetProcessedGraphData", mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestSaveWatchedUserSavesAUserWithAnInterval(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+
+	watchedUser := datastructures.WatchedUser{
+		SteamID:         "76561197969081524",
+		Level:           2,
+		IntervalMinutes: 10080,
 	}
-	requestBodyJSON, err := json.Marshal(requestBody)
+	requestBodyJSON, err := json.Marshal(watchedUser)
 	if err != nil {
 		log.Fatal(err)
 	}
+	mockController.On("SaveWatchedUser", mock.Anything, mock.MatchedBy(func(savedUser datastructures.WatchedUser) bool {
+		return savedUser.SteamID == watchedUser.SteamID &&
+			savedUser.IntervalMinutes == watchedUser.IntervalMinutes &&
+			savedUser.TimeAdded > 0
+	})).Return(nil)
 
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusernamesfromsteamids", serverPort), bytes.NewBuffer(requestBodyJSON))
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/savewatcheduser", serverPort), bytes.NewBuffer(requestBodyJSON))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -487,29 +1401,27 @@ func TestGetUsernamesFromSteamIDsReturnsUsernamesForSteamID(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
 
-	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	mockController.AssertNumberOfCalls(t, "SaveWatchedUser", 1)
 }
 
-func TestGetUsernamesReturnsInvalidInputForAnyInvalidFormatSteamIDsGiven(t *testing.T) {
-	_, serverPort := initServerAndDependencies()
+func TestSaveWatchedUserReturnsInvalidInputWhenBothACronExpressionAndIntervalAreGiven(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
 
-	requestBody := dtos.GetUsernamesFromSteamIDsInputDTO{
-		SteamIDs: []string{
-			invalidFormatSteamID,
-		},
+	watchedUser := datastructures.WatchedUser{
+		SteamID:         "76561197969081524",
+		Level:           2,
+		CronExpression:  "0 6 * * 1",
+		IntervalMinutes: 10080,
 	}
-	requestBodyJSON, err := json.Marshal(requestBody)
+	requestBodyJSON, err := json.Marshal(watchedUser)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusernamesfromsteamids", serverPort), bytes.NewBuffer(requestBodyJSON))
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/savewatcheduser", serverPort), bytes.NewBuffer(requestBodyJSON))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -520,37 +1432,36 @@ func TestGetUsernamesReturnsInvalidInputForAnyInvalidFormatSteamIDsGiven(t *test
 		log.Fatal(err)
 	}
 
+	mockController.AssertNotCalled(t, "SaveWatchedUser", mock.Anything, mock.Anything)
 	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 }
 
-func TestGetUsernamesFromSteamIDsReturnsInvalidRequestWhenCallToDataStoreFails(t *testing.T) {
+func TestClaimWatchedUserCrawlReturnsWhetherTheCrawlWasClaimed(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	randomError := errors.New("hello world")
-	mockController.On("GetUsernames", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return(make(map[string]string), randomError)
-
-	expectedResponse := struct {
-		Error string `json:"error"`
-	}{
-		"couldn't get usernames",
+	steamID := "76561197969081524"
+	claim := datastructures.WatchedUserCrawlClaim{
+		PreviousCrawlTime: 1645000000,
+		CrawlTime:         1645604800,
+		CrawlID:           ksuid.New().String(),
 	}
-	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	requestBodyJSON, err := json.Marshal(claim)
 	if err != nil {
 		log.Fatal(err)
 	}
+	mockController.On("ClaimWatchedUserCrawl", mock.Anything, steamID, claim).Return(true, nil)
 
-	requestBody := dtos.GetUsernamesFromSteamIDsInputDTO{
-		SteamIDs: []string{
-			validFormatSteamID,
-		},
+	expectedResponse := datastructures.ClaimWatchedUserCrawlDTO{
+		Status:  "success",
+		Claimed: true,
 	}
-	requestBodyJSON, err := json.Marshal(requestBody)
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/getusernamesfromsteamids", serverPort), bytes.NewBuffer(requestBodyJSON))
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/claimwatchedusercrawl/%s", serverPort, steamID), bytes.NewBuffer(requestBodyJSON))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -561,119 +1472,119 @@ func TestGetUsernamesFromSteamIDsReturnsInvalidRequestWhenCallToDataStoreFails(t
 		log.Fatal(err)
 	}
 	body, err := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
 	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
 }
 
-func TestGetGraphableDataReturnsGraphableDataForAValidUser(t *testing.T) {
+func TestUpdateCrawlStateReturnsWhetherTheStateWasUpdated(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	foundUser := common.UserDocument{
-		AccDetails: common.AccDetailsDocument{
-			Personaname: "Cathal",
-			SteamID:     validFormatSteamID,
-		},
-		FriendIDs: []string{
-			"1",
-			"2",
-			"3",
-		},
+	crawlID := ksuid.New().String()
+	requestBodyJSON, err := json.Marshal(datastructures.UpdateCrawlStateInputDTO{
+		State:   datastructures.CrawlStateGraphing,
+		LeaseID: "leaseID",
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
-	expectedResponse := dtos.GetGraphableDataForUserDTO{
-		Username:  foundUser.AccDetails.Personaname,
-		SteamID:   foundUser.AccDetails.SteamID,
-		FriendIDs: foundUser.FriendIDs,
+	mockController.On("UpdateCrawlState", mock.Anything, crawlID, datastructures.CrawlStateGraphing, "leaseID", mock.AnythingOfType("int64")).Return(false, nil)
+
+	expectedResponse := datastructures.UpdateCrawlStateDTO{
+		Status:  "success",
+		Updated: false,
 	}
 	expectedJSONResponse, err := json.Marshal(expectedResponse)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	mockController.On("GetUser", mock.Anything, foundUser.AccDetails.SteamID).Return(foundUser, nil)
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/updatecrawlstate/%s", serverPort, crawlID), bytes.NewBuffer(requestBodyJSON))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
 
-	authHeader := http.Header{}
-	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
-	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getgraphabledata/%s", serverPort, foundUser.AccDetails.SteamID), []http.Header{authHeader})
+	res, err := client.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
 }
 
-func TestGetGraphableDataReturnsCouldntGetUserWhenNoUserIsFound(t *testing.T) {
+func TestUpdateCrawlStateRejectsStatesThatCannotBeMovedTo(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
+	requestBodyJSON, err := json.Marshal(datastructures.UpdateCrawlStateInputDTO{
+		State: datastructures.CrawlStateQueued,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 	expectedResponse := struct {
 		Error string `json:"error"`
 	}{
-		"couldn't get user",
+		"Invalid crawl state",
 	}
 	expectedJSONResponse, err := json.Marshal(expectedResponse)
 	if err != nil {
 		log.Fatal(err)
 	}
-	randomError := errors.New("hello world")
-	mockController.On("GetUser", mock.Anything, validFormatSteamID).Return(common.UserDocument{}, randomError)
 
-	authHeader := http.Header{}
-	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
-	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getgraphabledata/%s", serverPort, validFormatSteamID), []http.Header{authHeader})
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/updatecrawlstate/%s", serverPort, ksuid.New().String()), bytes.NewBuffer(requestBodyJSON))
 	if err != nil {
 		log.Fatal(err)
 	}
+	req.Header.Set("Authentication", os.Getenv("AUTH_KEY"))
 
-	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
-}
-
-func TestGetGraphableDataReturnsInvalidInputForInvalidFormatSteamIDs(t *testing.T) {
-	_, serverPort := initServerAndDependencies()
-
-	expectedResponse := struct {
-		Error string `json:"error"`
-	}{
-		"Invalid input",
-	}
-	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	res, err := client.Do(req)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	authHeader := http.Header{}
-	authHeader.Set("Authentication", os.Getenv("AUTH_KEY"))
-	res, err := util.GetAndRead(fmt.Sprintf("http://localhost:%d/api/getgraphabledata/%s", serverPort, invalidFormatSteamID), []http.Header{authHeader})
+	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	assert.Equal(t, string(expectedJSONResponse)+"\n", string(res))
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+	mockController.AssertNotCalled(t, "UpdateCrawlState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
-func TestSaveCrawlingStatsToDB(t *testing.T) {
+func TestRenewGraphingLeaseReturnsWhetherTheLeaseWasRenewed(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 
-	crawlingStatsInput := dtos.SaveCrawlingStatsDTO{
-		CurrentLevel: 2,
-		CrawlingStatus: common.CrawlingStatus{
-			TimeStarted:       time.Now().Unix(),
-			MaxLevel:          3,
-			UsersCrawled:      5,
-			TotalUsersToCrawl: 20,
-		},
+	crawlID := ksuid.New().String()
+	requestBodyJSON, err := json.Marshal(datastructures.RenewGraphingLeaseInputDTO{
+		LeaseID: "leaseID",
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
-	requestBodyJSON, err := json.Marshal(crawlingStatsInput)
+	mockController.On("RenewGraphingLease", mock.Anything, crawlID, "leaseID", mock.AnythingOfType("int64")).Return(true, nil)
+
+	expectedResponse := datastructures.RenewGraphingLeaseDTO{
+		Status:  "success",
+		Renewed: true,
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
 	if err != nil {
 		log.Fatal(err)
 	}
-	mockController.On("UpdateCrawlingStatus", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
 
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/savecrawlingstats", serverPort), bytes.NewBuffer(requestBodyJSON))
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://localhost:%d/api/renewgraphinglease/%s", serverPort, crawlID), bytes.NewBuffer(requestBodyJSON))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -683,8 +1594,13 @@ func TestSaveCrawlingStatsToDB(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
 }
 
 func TestInsertGame(t *testing.T) {
@@ -956,7 +1872,7 @@ func TestSaveProcessedGraphData(t *testing.T) {
 		"success",
 	}
 	expectedResponseJSON, _ := json.Marshal(expectedResponse)
-	mockController.On("SaveProcessedGraphData", mock.Anything, input).Return(true, nil)
+	mockController.On("SaveProcessedGraphData", mock.Anything, datastructures.UsersGraphData{UsersGraphData: input}, mock.Anything).Return(true, nil)
 
 	requestBodyJSON, err := json.Marshal(input)
 	if err != nil {
@@ -997,7 +1913,7 @@ func TestSaveProcessedGraphDataReturnsInvalidInputForInvalidFormatCrawlID(t *tes
 		"invalid input",
 	}
 	expectedResponseJSON, _ := json.Marshal(expectedResponse)
-	mockController.On("SaveProcessedGraphData", mock.Anything, mock.Anything).Return(true, nil)
+	mockController.On("SaveProcessedGraphData", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
 
 	requestBodyJSON, err := json.Marshal(common.UsersGraphData{})
 	if err != nil {
@@ -1030,7 +1946,7 @@ func TestSaveProcessedGraphDataReturnsAnErrorWhenGraphDataCannotBeRetrieved(t *t
 
 	crawlID := ksuid.New().String()
 	err := errors.New("random error")
-	mockController.On("SaveProcessedGraphData", mock.Anything, mock.Anything).Return(false, err)
+	mockController.On("SaveProcessedGraphData", mock.Anything, mock.Anything, mock.Anything).Return(false, err)
 
 	expectedResponse := struct {
 		Error string `json:"error"`
@@ -1101,11 +2017,14 @@ func TestGetProcessedGraphData(t *testing.T) {
 		},
 	}
 	expectedResponse := datastructures.GetProcessedGraphDataDTO{
-		Status:        "success",
-		UserGraphData: input,
+		Status: "success",
+		UserGraphData: datastructures.UsersGraphData{
+			UsersGraphData: input,
+			CappedHubs:     []string{"76561197969081524"},
+		},
 	}
 	expectedResponseJSON, _ := json.Marshal(expectedResponse)
-	mockController.On("GetProcessedGraphData", mock.Anything, mock.Anything).Return(input, nil)
+	mockController.On("GetProcessedGraphData", mock.Anything, mock.Anything).Return(expectedResponse.UserGraphData, nil)
 
 	requestBodyJSON, err := json.Marshal(common.UsersGraphData{})
 	if err != nil {
@@ -1161,7 +2080,7 @@ func TestSaveProcessedGraphDataReturnsAnErrorWhenRetrievingGraphDataReturnsAnErr
 
 	crawlID := ksuid.New().String()
 	err := errors.New("random error")
-	mockController.On("GetProcessedGraphData", mock.Anything, mock.Anything).Return(common.UsersGraphData{}, err)
+	mockController.On("GetProcessedGraphData", mock.Anything, mock.Anything).Return(datastructures.UsersGraphData{}, err)
 
 	expectedResponse := struct {
 		Error string `json:"error"`
@@ -1315,7 +2234,7 @@ func TestDoesProcessedGraphDataExist(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	mockController.On("DoesProcessedGraphDataExist", crawlID).Return(false, nil)
+	mockController.On("DoesProcessedGraphDataExist", crawlID, false).Return(false, nil)
 
 	res, err := http.Post(fmt.Sprintf("http://localhost:%d/api/doesprocessedgraphdataexist/%s", serverPort, crawlID), "application/json", nil)
 	if err != nil {
@@ -1331,6 +2250,34 @@ func TestDoesProcessedGraphDataExist(t *testing.T) {
 	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
 }
 
+func TestDoesProcessedGraphDataExistCountsPartialGraphsWhenAskedTo(t *testing.T) {
+	mockController, serverPort := initServerAndDependencies()
+	crawlID := ksuid.New().String()
+
+	expectedResponse := dtos.DoesProcessedGraphDataExistDTO{
+		Status: "success",
+		Exists: "yes",
+	}
+	expectedJSONResponse, err := json.Marshal(expectedResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mockController.On("DoesProcessedGraphDataExist", crawlID, true).Return(true, nil)
+
+	res, err := http.Post(fmt.Sprintf("http://localhost:%d/api/doesprocessedgraphdataexist/%s?partial=true", serverPort, crawlID), "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	defer res.Body.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, string(expectedJSONResponse)+"\n", string(body))
+}
+
 func TestDoesProcessedGraphDataExistReturnsInvalidWhenGivenAnInvalidCrawlID(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 	crawlID := "invalid crawlID"
@@ -1365,7 +2312,7 @@ func TestDoesProcessedGraphDataExistReturnsInvalidWhenItCannotRetrieveGraphData(
 	crawlID := ksuid.New().String()
 
 	randomError := errors.New("random error")
-	mockController.On("DoesProcessedGraphDataExist", crawlID).Return(false, randomError)
+	mockController.On("DoesProcessedGraphDataExist", crawlID, false).Return(false, randomError)
 
 	expectedResponse := struct {
 		Error string `json:"error"`
@@ -1396,10 +2343,12 @@ func TestGetCrawlingUser(t *testing.T) {
 	mockController, serverPort := initServerAndDependencies()
 	crawlID := ksuid.New().String()
 
-	crawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: testUser.AccDetails.SteamID,
-		TotalUsersToCrawl:   140,
-		UsersCrawled:        95,
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: testUser.AccDetails.SteamID,
+			TotalUsersToCrawl:   140,
+			UsersCrawled:        95,
+		},
 	}
 
 	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, crawlID).Return(crawlingStatus, nil)
@@ -1429,10 +2378,12 @@ func TestGetCrawlingUserReturnsNotBeingCrawledWhenUserIsNotBeingCrawled(t *testi
 	mockController, serverPort := initServerAndDependencies()
 	crawlID := ksuid.New().String()
 
-	crawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: testUser.AccDetails.SteamID,
-		TotalUsersToCrawl:   140,
-		UsersCrawled:        140,
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: testUser.AccDetails.SteamID,
+			TotalUsersToCrawl:   140,
+			UsersCrawled:        140,
+		},
 	}
 	randomError := errors.New("random error")
 	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, crawlID).Return(crawlingStatus, nil)
@@ -1460,10 +2411,12 @@ func TestGetCrawlingUserReturnsUserDoesNotExistWhenUserIsNotFound(t *testing.T)
 	mockController, serverPort := initServerAndDependencies()
 	crawlID := ksuid.New().String()
 
-	crawlingStatus := common.CrawlingStatus{
-		OriginalCrawlTarget: testUser.AccDetails.SteamID,
-		TotalUsersToCrawl:   140,
-		UsersCrawled:        90,
+	crawlingStatus := datastructures.CrawlingStatus{
+		CrawlingStatus: common.CrawlingStatus{
+			OriginalCrawlTarget: testUser.AccDetails.SteamID,
+			TotalUsersToCrawl:   140,
+			UsersCrawled:        90,
+		},
 	}
 
 	mockController.On("GetCrawlingStatusFromDBFromCrawlID", mock.Anything, crawlID).Return(crawlingStatus, nil)
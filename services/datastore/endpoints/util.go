@@ -6,9 +6,12 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/IamCathal/neo/services/datastore/configuration"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
 	"github.com/IamCathal/neo/services/datastore/dbmonitor"
 	"github.com/neosteamfriendgraphing/common/util"
 )
@@ -101,3 +104,35 @@ func wsReader(ws dbmonitor.WebsocketConn, streamType string) {
 		}
 	}
 }
+
+// watchedUserScheduleIsValid checks that a watched user has a level and
+// exactly one of a cron expression or an interval. Cron expressions are
+// fully parsed by the crawler's scheduler
+func watchedUserScheduleIsValid(watchedUser datastructures.WatchedUser) bool {
+	if watchedUser.Level < 1 || watchedUser.IntervalMinutes < 0 {
+		return false
+	}
+	hasCronExpression := watchedUser.CronExpression != ""
+	hasInterval := watchedUser.IntervalMinutes > 0
+	if hasCronExpression == hasInterval {
+		return false
+	}
+	if hasCronExpression && len(strings.Fields(watchedUser.CronExpression)) != 5 {
+		return false
+	}
+	return true
+}
+
+// getIntQueryParam reads a whole number between 1 and max from the query
+// string. The default is used if it was not given
+func getIntQueryParam(r *http.Request, name string, defaultValue, max int) (int, bool) {
+	rawValue := r.URL.Query().Get(name)
+	if rawValue == "" {
+		return defaultValue, true
+	}
+	value, err := strconv.Atoi(rawValue)
+	if err != nil || value < 1 || value > max {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,270 @@
+package graphing
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+)
+
+const (
+	CoarsenedNodeUser      = "user"
+	CoarsenedNodeLeaves    = "leaves"
+	CoarsenedNodeCommunity = "community"
+)
+
+// coarseningGroup is a group of users that can be collapsed into a
+// super node
+type coarseningGroup struct {
+	id      string
+	kind    string
+	label   string
+	members []string
+}
+
+// CoarsenGraph collapses users into super nodes until the graph has at
+// most maxNodes nodes. Users whose only friend in the crawl is the same
+// user are collapsed first, starting with the user with the most of them,
+// and then whole communities from largest to smallest. The crawl target
+// is never collapsed so graphs can still have more than maxNodes nodes
+// once everything else has been. Users in the super nodes given in
+// expanded are never collapsed so that clients can drill down into them
+func CoarsenGraph(export GraphExport, maxNodes int, expanded []string) datastructures.CoarsenedGraph {
+	coarsenedGraph := datastructures.CoarsenedGraph{
+		CrawlID:   export.CrawlID,
+		Partial:   export.Partial,
+		Progress:  export.Progress,
+		MaxNodes:  maxNodes,
+		UserCount: len(export.Nodes),
+		Expanded:  append([]string{}, expanded...),
+		Nodes:     []datastructures.CoarsenedNode{},
+		Edges:     []datastructures.CoarsenedEdge{},
+	}
+	if len(export.Nodes) == 0 {
+		return coarsenedGraph
+	}
+	targetID := export.Nodes[0].SteamID
+	neighbours := make(map[string][]string)
+	for _, edge := range export.Edges {
+		neighbours[edge[0]] = append(neighbours[edge[0]], edge[1])
+		neighbours[edge[1]] = append(neighbours[edge[1]], edge[0])
+	}
+
+	groups := append(getLeafGroups(export, neighbours, targetID), getCommunityGroups(export, targetID)...)
+	isExpanded := make(map[string]bool)
+	for _, groupID := range expanded {
+		isExpanded[groupID] = true
+	}
+	pinned := make(map[string]bool)
+	for _, group := range groups {
+		if isExpanded[group.id] {
+			for _, member := range group.members {
+				pinned[member] = true
+			}
+		}
+	}
+
+	nodeOf := make(map[string]string)
+	nodeSizes := make(map[string]int)
+	for _, node := range export.Nodes {
+		nodeOf[node.SteamID] = node.SteamID
+		nodeSizes[node.SteamID] = 1
+	}
+	nodeCount := len(export.Nodes)
+	collapsedGroups := make(map[string]coarseningGroup)
+	for _, group := range groups {
+		if nodeCount <= maxNodes {
+			break
+		}
+		if isExpanded[group.id] {
+			continue
+		}
+		members := []string{}
+		for _, member := range group.members {
+			if !pinned[member] {
+				members = append(members, member)
+			}
+		}
+		if len(members) < 2 {
+			continue
+		}
+		for _, member := range members {
+			nodeSizes[nodeOf[member]]--
+			if nodeSizes[nodeOf[member]] == 0 {
+				nodeCount--
+			}
+			nodeOf[member] = group.id
+			nodeSizes[group.id]++
+			if nodeSizes[group.id] == 1 {
+				nodeCount++
+			}
+		}
+		collapsedGroups[group.id] = group
+	}
+
+	coarsenedGraph.Nodes = getCoarsenedNodes(export, nodeOf, collapsedGroups)
+	coarsenedGraph.Edges = getCoarsenedEdges(export, nodeOf, coarsenedGraph.Nodes)
+	coarsenedGraph.Coarsened = len(coarsenedGraph.Nodes) < len(export.Nodes)
+	return coarsenedGraph
+}
+
+// getLeafGroups groups users whose only friend in the crawl is the same
+// user. Groups with the most users come first
+func getLeafGroups(export GraphExport, neighbours map[string][]string, targetID string) []coarseningGroup {
+	usernames := make(map[string]string)
+	for _, node := range export.Nodes {
+		usernames[node.SteamID] = node.Username
+	}
+	groupsByFriend := make(map[string]*coarseningGroup)
+	friendOrder := []string{}
+	for _, node := range export.Nodes {
+		if node.SteamID == targetID || len(neighbours[node.SteamID]) != 1 {
+			continue
+		}
+		friendID := neighbours[node.SteamID][0]
+		if _, exists := groupsByFriend[friendID]; !exists {
+			groupsByFriend[friendID] = &coarseningGroup{
+				id:    CoarsenedNodeLeaves + "-" + friendID,
+				kind:  CoarsenedNodeLeaves,
+				label: fmt.Sprintf("friends only of %s", usernames[friendID]),
+			}
+			friendOrder = append(friendOrder, friendID)
+		}
+		groupsByFriend[friendID].members = append(groupsByFriend[friendID].members, node.SteamID)
+	}
+
+	groups := []coarseningGroup{}
+	for _, friendID := range friendOrder {
+		groups = append(groups, *groupsByFriend[friendID])
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].members) > len(groups[j].members)
+	})
+	return groups
+}
+
+// getCommunityGroups groups users by community, largest first. The crawl
+// target is left out of its community
+func getCommunityGroups(export GraphExport, targetID string) []coarseningGroup {
+	membersByCommunity := make(map[int][]string)
+	for _, node := range export.Nodes {
+		if node.SteamID == targetID || node.Community < 0 {
+			continue
+		}
+		membersByCommunity[node.Community] = append(membersByCommunity[node.Community], node.SteamID)
+	}
+
+	groups := []coarseningGroup{}
+	for community, members := range membersByCommunity {
+		groups = append(groups, coarseningGroup{
+			id:      fmt.Sprintf("%s-%d", CoarsenedNodeCommunity, community),
+			kind:    CoarsenedNodeCommunity,
+			label:   fmt.Sprintf("community %d", community),
+			members: members,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].members) != len(groups[j].members) {
+			return len(groups[i].members) > len(groups[j].members)
+		}
+		return groups[i].id < groups[j].id
+	})
+	return groups
+}
+
+// getCoarsenedNodes aggregates the users in each node in the order the
+// nodes are first found in the graph
+func getCoarsenedNodes(export GraphExport, nodeOf map[string]string, collapsedGroups map[string]coarseningGroup) []datastructures.CoarsenedNode {
+	nodeIndexes := make(map[string]int)
+	nodes := []datastructures.CoarsenedNode{}
+	countryCounts := []map[string]int{}
+	for _, user := range export.Nodes {
+		nodeID := nodeOf[user.SteamID]
+		index, exists := nodeIndexes[nodeID]
+		if !exists {
+			index = len(nodes)
+			nodeIndexes[nodeID] = index
+			node := datastructures.CoarsenedNode{
+				ID:        nodeID,
+				Kind:      CoarsenedNodeUser,
+				Label:     user.Username,
+				Community: user.Community,
+			}
+			if group, isGroup := collapsedGroups[nodeID]; isGroup {
+				node.Kind = group.kind
+				node.Label = group.label
+			}
+			nodes = append(nodes, node)
+			countryCounts = append(countryCounts, make(map[string]int))
+		}
+
+		node := &nodes[index]
+		node.Size++
+		node.TotalPlaytime += user.Playtime
+		node.AverageFriendCount += float64(user.FriendCount)
+		node.AverageAccountAgeDays += float64(user.AccountAgeDays)
+		if node.Community != user.Community {
+			node.Community = -1
+		}
+		if user.Country != "" {
+			countryCounts[index][user.Country]++
+		}
+	}
+
+	for i := range nodes {
+		nodes[i].AverageFriendCount /= float64(nodes[i].Size)
+		nodes[i].AverageAccountAgeDays /= float64(nodes[i].Size)
+		nodes[i].Country = getMostCommonCountry(countryCounts[i])
+	}
+	return nodes
+}
+
+// getMostCommonCountry returns the country with the most users, the
+// first alphabetically if there is a tie
+func getMostCommonCountry(countryCounts map[string]int) string {
+	mostCommonCountry := ""
+	for country, count := range countryCounts {
+		if mostCommonCountry == "" || count > countryCounts[mostCommonCountry] ||
+			(count == countryCounts[mostCommonCountry] && country < mostCommonCountry) {
+			mostCommonCountry = country
+		}
+	}
+	return mostCommonCountry
+}
+
+// getCoarsenedEdges merges the friendships between the users of each pair
+// of nodes into one edge. Friendships inside a node are counted on it
+func getCoarsenedEdges(export GraphExport, nodeOf map[string]string, nodes []datastructures.CoarsenedNode) []datastructures.CoarsenedEdge {
+	nodeIndexes := make(map[string]int)
+	for i, node := range nodes {
+		nodeIndexes[node.ID] = i
+	}
+	weights := make(map[[2]string]int)
+	for _, edge := range export.Edges {
+		source, target := nodeOf[edge[0]], nodeOf[edge[1]]
+		if source == target {
+			nodes[nodeIndexes[source]].InternalEdges++
+			continue
+		}
+		if target < source {
+			source, target = target, source
+		}
+		weights[[2]string{source, target}]++
+	}
+
+	edges := []datastructures.CoarsenedEdge{}
+	for pair, weight := range weights {
+		edges = append(edges, datastructures.CoarsenedEdge{
+			Source: pair[0],
+			Target: pair[1],
+			Weight: weight,
+		})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Target < edges[j].Target
+	})
+	return edges
+}
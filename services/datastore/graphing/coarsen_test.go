@@ -0,0 +1,104 @@
+package graphing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// getTestCoarseningExport is a crawl of user 1 where users 5, 6 and 7
+// are only friends with user 2 and user 8 is only friends with user 3
+func getTestCoarseningExport() GraphExport {
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUser("1", "1", 1, "2", "3", "4"),
+			FriendDetails: []common.UsersGraphInformation{
+				makeTestUser("2", "1", 2, "5", "6", "7"),
+				makeTestUser("3", "1", 2, "4", "8"),
+				makeTestUser("4", "1", 2),
+				makeTestUser("5", "2", 3),
+				makeTestUser("6", "2", 3),
+				makeTestUser("7", "2", 3),
+				makeTestUser("8", "3", 3),
+			},
+		},
+		UserCommunities: map[string]int{"1": 0, "2": 0, "5": 0, "6": 0, "7": 0, "3": 1, "4": 1, "8": 1},
+	}
+	return GetGraphExport("crawl", graphData, time.Unix(1000000000, 0))
+}
+
+func getCoarsenedNodeIDs(coarsenedGraph datastructures.CoarsenedGraph) []string {
+	nodeIDs := []string{}
+	for _, node := range coarsenedGraph.Nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+	return nodeIDs
+}
+
+func TestCoarsenGraphLeavesSmallGraphsAsTheyAre(t *testing.T) {
+	coarsenedGraph := CoarsenGraph(getTestCoarseningExport(), 8, []string{})
+
+	assert.False(t, coarsenedGraph.Coarsened)
+	assert.Equal(t, 8, coarsenedGraph.UserCount)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8"}, getCoarsenedNodeIDs(coarsenedGraph))
+	assert.Len(t, coarsenedGraph.Edges, 8)
+	assert.Equal(t, CoarsenedNodeUser, coarsenedGraph.Nodes[1].Kind)
+	assert.Equal(t, "user 2", coarsenedGraph.Nodes[1].Label)
+}
+
+func TestCoarsenGraphCollapsesUsersWithOnlyOneFriendFirst(t *testing.T) {
+	coarsenedGraph := CoarsenGraph(getTestCoarseningExport(), 6, []string{})
+
+	assert.True(t, coarsenedGraph.Coarsened)
+	assert.Equal(t, []string{"1", "2", "3", "4", "leaves-2", "8"}, getCoarsenedNodeIDs(coarsenedGraph))
+	assert.Equal(t, datastructures.CoarsenedNode{
+		ID:                    "leaves-2",
+		Kind:                  CoarsenedNodeLeaves,
+		Label:                 "friends only of user 2",
+		Size:                  3,
+		Country:               "IE",
+		Community:             0,
+		TotalPlaytime:         180,
+		AverageFriendCount:    0,
+		AverageAccountAgeDays: 0,
+	}, coarsenedGraph.Nodes[4])
+	assert.Contains(t, coarsenedGraph.Edges, datastructures.CoarsenedEdge{Source: "2", Target: "leaves-2", Weight: 3})
+	assert.Len(t, coarsenedGraph.Edges, 6)
+}
+
+func TestCoarsenGraphCollapsesCommunitiesButNotTheCrawlTarget(t *testing.T) {
+	coarsenedGraph := CoarsenGraph(getTestCoarseningExport(), 3, []string{})
+
+	assert.Equal(t, []string{"1", "community-0", "community-1"}, getCoarsenedNodeIDs(coarsenedGraph))
+	assert.Equal(t, 4, coarsenedGraph.Nodes[1].Size)
+	assert.Equal(t, 3, coarsenedGraph.Nodes[1].InternalEdges)
+	assert.Equal(t, 2, coarsenedGraph.Nodes[2].InternalEdges)
+	assert.Equal(t, []datastructures.CoarsenedEdge{
+		{Source: "1", Target: "community-0", Weight: 1},
+		{Source: "1", Target: "community-1", Weight: 2},
+	}, coarsenedGraph.Edges)
+}
+
+func TestCoarsenGraphDoesNotCollapseExpandedSuperNodes(t *testing.T) {
+	coarsenedGraph := CoarsenGraph(getTestCoarseningExport(), 3, []string{"community-0"})
+
+	assert.Equal(t, []string{"1", "2", "community-1", "5", "6", "7"}, getCoarsenedNodeIDs(coarsenedGraph))
+	assert.Equal(t, []string{"community-0"}, coarsenedGraph.Expanded)
+}
+
+func TestCoarsenGraphAggregatesMixedCommunitiesAndCountries(t *testing.T) {
+	nodes := []ExportNode{
+		{SteamID: "1", Country: "IE", Community: 0, FriendCount: 2},
+		{SteamID: "2", Country: "", Community: 1, FriendCount: 4},
+		{SteamID: "3", Country: "DE", Community: 0, FriendCount: 6},
+	}
+	coarsenedNodes := getCoarsenedNodes(GraphExport{Nodes: nodes}, map[string]string{"1": "group", "2": "group", "3": "group"}, map[string]coarseningGroup{})
+
+	assert.Len(t, coarsenedNodes, 1)
+	assert.Equal(t, -1, coarsenedNodes[0].Community)
+	assert.Equal(t, "DE", coarsenedNodes[0].Country)
+	assert.InDelta(t, 4, coarsenedNodes[0].AverageFriendCount, 1e-9)
+}
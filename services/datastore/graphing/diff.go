@@ -0,0 +1,218 @@
+package graphing
+
+import (
+	"sort"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+// maxGameShifts is how many of the games whose playtime changed the
+// most are reported
+const maxGameShifts = 20
+
+// DiffGraphs compares two processed graphs of the same user
+func DiffGraphs(fromCrawlID string, fromGraph datastructures.UsersGraphData, toCrawlID string, toGraph datastructures.UsersGraphData) datastructures.GraphDiff {
+	fromExport := GetGraphExport(fromCrawlID, fromGraph, time.Now())
+	toExport := GetGraphExport(toCrawlID, toGraph, time.Now())
+
+	diff := datastructures.GraphDiff{
+		SteamID:      toGraph.UserDetails.User.AccDetails.SteamID,
+		FromCrawlID:  fromCrawlID,
+		FromPartial:  fromGraph.Partial,
+		FromProgress: getGraphProgress(fromGraph),
+		ToCrawlID:    toCrawlID,
+		ToPartial:    toGraph.Partial,
+		ToProgress:   getGraphProgress(toGraph),
+	}
+	diff.AddedUsers, diff.RemovedUsers = diffUsers(fromExport.Nodes, toExport.Nodes)
+	diff.AddedEdges, diff.RemovedEdges = diffEdges(fromExport.Edges, toExport.Edges)
+	diff.CountryChanges = diffCountries(fromExport.Nodes, toExport.Nodes)
+
+	fromUsers := getUniqueUsers(fromGraph)
+	toUsers := getUniqueUsers(toGraph)
+	diff.GameShifts = diffGames(fromUsers, toUsers)
+	diff.PlaytimeDeltas = diffPlaytimes(fromExport.Nodes, toExport.Nodes)
+	return diff
+}
+
+func getUniqueUsers(graphData datastructures.UsersGraphData) []common.UserDocument {
+	users := []common.UserDocument{}
+	seenIDs := make(map[string]bool)
+	for _, user := range append([]common.UsersGraphInformation{graphData.UserDetails}, graphData.FriendDetails...) {
+		if !seenIDs[user.User.AccDetails.SteamID] {
+			seenIDs[user.User.AccDetails.SteamID] = true
+			users = append(users, user.User)
+		}
+	}
+	return users
+}
+
+func diffUsers(fromNodes, toNodes []ExportNode) ([]string, []string) {
+	inFrom := make(map[string]bool)
+	for _, node := range fromNodes {
+		inFrom[node.SteamID] = true
+	}
+	inTo := make(map[string]bool)
+	for _, node := range toNodes {
+		inTo[node.SteamID] = true
+	}
+
+	added := []string{}
+	for _, node := range toNodes {
+		if !inFrom[node.SteamID] {
+			added = append(added, node.SteamID)
+		}
+	}
+	removed := []string{}
+	for _, node := range fromNodes {
+		if !inTo[node.SteamID] {
+			removed = append(removed, node.SteamID)
+		}
+	}
+	return added, removed
+}
+
+func diffEdges(fromEdges, toEdges [][2]string) ([]datastructures.GraphEdge, []datastructures.GraphEdge) {
+	inFrom := make(map[[2]string]bool)
+	for _, edge := range fromEdges {
+		inFrom[edge] = true
+	}
+	inTo := make(map[[2]string]bool)
+	for _, edge := range toEdges {
+		inTo[edge] = true
+	}
+
+	added := []datastructures.GraphEdge{}
+	for _, edge := range toEdges {
+		if !inFrom[edge] {
+			added = append(added, datastructures.GraphEdge{Source: edge[0], Target: edge[1]})
+		}
+	}
+	removed := []datastructures.GraphEdge{}
+	for _, edge := range fromEdges {
+		if !inTo[edge] {
+			removed = append(removed, datastructures.GraphEdge{Source: edge[0], Target: edge[1]})
+		}
+	}
+	return added, removed
+}
+
+// diffCountries counts the users from each country in both graphs,
+// biggest changes first. Users without a country are left out
+func diffCountries(fromNodes, toNodes []ExportNode) []datastructures.CountryChange {
+	changesByCountry := make(map[string]*datastructures.CountryChange)
+	getChange := func(countryCode string) *datastructures.CountryChange {
+		if _, exists := changesByCountry[countryCode]; !exists {
+			changesByCountry[countryCode] = &datastructures.CountryChange{CountryCode: countryCode}
+		}
+		return changesByCountry[countryCode]
+	}
+	for _, node := range fromNodes {
+		if node.Country != "" {
+			getChange(node.Country).FromCount++
+		}
+	}
+	for _, node := range toNodes {
+		if node.Country != "" {
+			getChange(node.Country).ToCount++
+		}
+	}
+
+	countryChanges := []datastructures.CountryChange{}
+	for _, change := range changesByCountry {
+		change.Change = change.ToCount - change.FromCount
+		countryChanges = append(countryChanges, *change)
+	}
+	sort.Slice(countryChanges, func(i, j int) bool {
+		if abs(countryChanges[i].Change) != abs(countryChanges[j].Change) {
+			return abs(countryChanges[i].Change) > abs(countryChanges[j].Change)
+		}
+		return countryChanges[i].CountryCode < countryChanges[j].CountryCode
+	})
+	return countryChanges
+}
+
+// diffGames returns the games whose total playtime across the network
+// changed the most
+func diffGames(fromUsers, toUsers []common.UserDocument) []datastructures.GameShift {
+	shiftsByID := make(map[int]*datastructures.GameShift)
+	getShift := func(appID int) *datastructures.GameShift {
+		if _, exists := shiftsByID[appID]; !exists {
+			shiftsByID[appID] = &datastructures.GameShift{AppID: appID}
+		}
+		return shiftsByID[appID]
+	}
+	for _, user := range fromUsers {
+		for _, game := range user.GamesOwned {
+			shift := getShift(game.AppID)
+			shift.FromPlayers++
+			shift.FromPlaytime += game.Playtime_Forever
+		}
+	}
+	for _, user := range toUsers {
+		for _, game := range user.GamesOwned {
+			shift := getShift(game.AppID)
+			shift.ToPlayers++
+			shift.ToPlaytime += game.Playtime_Forever
+		}
+	}
+
+	gameShifts := []datastructures.GameShift{}
+	for _, shift := range shiftsByID {
+		shift.PlaytimeChange = shift.ToPlaytime - shift.FromPlaytime
+		if shift.PlaytimeChange != 0 || shift.FromPlayers != shift.ToPlayers {
+			gameShifts = append(gameShifts, *shift)
+		}
+	}
+	sort.Slice(gameShifts, func(i, j int) bool {
+		if abs(gameShifts[i].PlaytimeChange) != abs(gameShifts[j].PlaytimeChange) {
+			return abs(gameShifts[i].PlaytimeChange) > abs(gameShifts[j].PlaytimeChange)
+		}
+		return gameShifts[i].AppID < gameShifts[j].AppID
+	})
+	if len(gameShifts) > maxGameShifts {
+		gameShifts = gameShifts[:maxGameShifts]
+	}
+	return gameShifts
+}
+
+// diffPlaytimes returns how much each user in both graphs played
+// between them, biggest changes first. Users whose playtime did not
+// change are left out
+func diffPlaytimes(fromNodes, toNodes []ExportNode) []datastructures.UserPlaytimeChange {
+	fromPlaytimes := make(map[string]int)
+	for _, node := range fromNodes {
+		fromPlaytimes[node.SteamID] = node.Playtime
+	}
+
+	playtimeChanges := []datastructures.UserPlaytimeChange{}
+	for _, node := range toNodes {
+		fromPlaytime, inBoth := fromPlaytimes[node.SteamID]
+		if !inBoth || fromPlaytime == node.Playtime {
+			continue
+		}
+		playtimeChanges = append(playtimeChanges, datastructures.UserPlaytimeChange{
+			SteamID:      node.SteamID,
+			Username:     node.Username,
+			FromPlaytime: fromPlaytime,
+			ToPlaytime:   node.Playtime,
+			Change:       node.Playtime - fromPlaytime,
+		})
+	}
+	sort.Slice(playtimeChanges, func(i, j int) bool {
+		if abs(playtimeChanges[i].Change) != abs(playtimeChanges[j].Change) {
+			return abs(playtimeChanges[i].Change) > abs(playtimeChanges[j].Change)
+		}
+		return playtimeChanges[i].SteamID < playtimeChanges[j].SteamID
+	})
+	return playtimeChanges
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package graphing
+
+import (
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffGraphsReportsWhatChangedBetweenTwoCrawls(t *testing.T) {
+	fromGraph := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUserWithGames("1", "IE", []string{"2", "3"}, common.GameOwnedDocument{AppID: 730, Playtime_Forever: 100}),
+			FriendDetails: []common.UsersGraphInformation{
+				makeTestUserWithGames("2", "IE", []string{"1"}, common.GameOwnedDocument{AppID: 570, Playtime_Forever: 50}),
+				makeTestUserWithGames("3", "FR", []string{"1"}),
+			},
+		},
+	}
+	toGraph := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUserWithGames("1", "IE", []string{"2", "4"}, common.GameOwnedDocument{AppID: 730, Playtime_Forever: 160}),
+			FriendDetails: []common.UsersGraphInformation{
+				makeTestUserWithGames("2", "IE", []string{"1", "4"}, common.GameOwnedDocument{AppID: 570, Playtime_Forever: 50}),
+				makeTestUserWithGames("4", "DE", []string{"1"}, common.GameOwnedDocument{AppID: 440, Playtime_Forever: 20}),
+			},
+		},
+	}
+
+	diff := DiffGraphs("from", fromGraph, "to", toGraph)
+
+	assert.Equal(t, "1", diff.SteamID)
+	assert.Equal(t, []string{"4"}, diff.AddedUsers)
+	assert.Equal(t, []string{"3"}, diff.RemovedUsers)
+	assert.Equal(t, []datastructures.GraphEdge{{Source: "1", Target: "4"}, {Source: "2", Target: "4"}}, diff.AddedEdges)
+	assert.Equal(t, []datastructures.GraphEdge{{Source: "1", Target: "3"}}, diff.RemovedEdges)
+	assert.Equal(t, []datastructures.CountryChange{
+		{CountryCode: "DE", FromCount: 0, ToCount: 1, Change: 1},
+		{CountryCode: "FR", FromCount: 1, ToCount: 0, Change: -1},
+		{CountryCode: "IE", FromCount: 2, ToCount: 2, Change: 0},
+	}, diff.CountryChanges)
+	assert.Equal(t, []datastructures.GameShift{
+		{AppID: 730, FromPlayers: 1, ToPlayers: 1, FromPlaytime: 100, ToPlaytime: 160, PlaytimeChange: 60},
+		{AppID: 440, FromPlayers: 0, ToPlayers: 1, FromPlaytime: 0, ToPlaytime: 20, PlaytimeChange: 20},
+	}, diff.GameShifts)
+	assert.Equal(t, []datastructures.UserPlaytimeChange{
+		{SteamID: "1", Username: "user 1", FromPlaytime: 100, ToPlaytime: 160, Change: 60},
+	}, diff.PlaytimeDeltas)
+}
+
+func TestDiffGraphsSaysWhichGraphsArePartial(t *testing.T) {
+	fromGraph := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUserWithGames("1", "IE", []string{}),
+		},
+	}
+	toGraph := fromGraph
+	toGraph.Partial = true
+	toGraph.Progress = 25
+
+	diff := DiffGraphs("from", fromGraph, "to", toGraph)
+
+	assert.False(t, diff.FromPartial)
+	assert.Equal(t, 100, diff.FromProgress)
+	assert.True(t, diff.ToPartial)
+	assert.Equal(t, 25, diff.ToProgress)
+}
@@ -0,0 +1,98 @@
+package graphing
+
+import (
+	"sort"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+)
+
+// IsExportNodeAttribute checks if a node attribute can be exported
+func IsExportNodeAttribute(name string) bool {
+	for _, attribute := range exportNodeAttributes {
+		if attribute.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetEgoNetwork returns the users within radius hops of steamID and the
+// friendships between them. Closer users are kept first when there are
+// more than limit users. Only the given attributes are kept, or every
+// attribute if none are given. False is returned if steamID is not in
+// the graph
+func GetEgoNetwork(export GraphExport, steamID string, radius, limit int, attributes []string) (bool, datastructures.EgoNetwork) {
+	nodesByID := make(map[string]ExportNode)
+	for _, node := range export.Nodes {
+		nodesByID[node.SteamID] = node
+	}
+	if _, exists := nodesByID[steamID]; !exists {
+		return false, datastructures.EgoNetwork{}
+	}
+	neighbours := make(map[string][]string)
+	for _, edge := range export.Edges {
+		neighbours[edge[0]] = append(neighbours[edge[0]], edge[1])
+		neighbours[edge[1]] = append(neighbours[edge[1]], edge[0])
+	}
+
+	egoNetwork := datastructures.EgoNetwork{
+		SteamID:  steamID,
+		CrawlID:  export.CrawlID,
+		Partial:  export.Partial,
+		Progress: export.Progress,
+		Radius:   radius,
+		Nodes:    []datastructures.EgoNetworkNode{},
+		Edges:    []datastructures.GraphEdge{},
+	}
+	distances := map[string]int{steamID: 0}
+	visitOrder := []string{steamID}
+	for next := 0; next < len(visitOrder); next++ {
+		current := visitOrder[next]
+		if distances[current] == radius {
+			continue
+		}
+		currentNeighbours := neighbours[current]
+		sort.Strings(currentNeighbours)
+		for _, neighbour := range currentNeighbours {
+			if _, seen := distances[neighbour]; seen {
+				continue
+			}
+			if len(visitOrder) == limit {
+				egoNetwork.Truncated = true
+				break
+			}
+			distances[neighbour] = distances[current] + 1
+			visitOrder = append(visitOrder, neighbour)
+		}
+	}
+
+	for _, nodeID := range visitOrder {
+		egoNetwork.Nodes = append(egoNetwork.Nodes, datastructures.EgoNetworkNode{
+			SteamID:    nodeID,
+			Distance:   distances[nodeID],
+			Attributes: projectAttributes(nodesByID[nodeID], attributes),
+		})
+	}
+	for _, edge := range export.Edges {
+		_, hasSource := distances[edge[0]]
+		_, hasTarget := distances[edge[1]]
+		if hasSource && hasTarget {
+			egoNetwork.Edges = append(egoNetwork.Edges, datastructures.GraphEdge{Source: edge[0], Target: edge[1]})
+		}
+	}
+	return true, egoNetwork
+}
+
+func projectAttributes(node ExportNode, attributes []string) map[string]interface{} {
+	wanted := make(map[string]bool)
+	for _, attribute := range attributes {
+		wanted[attribute] = true
+	}
+	projected := make(map[string]interface{})
+	for i, value := range node.attributeValues() {
+		if name := exportNodeAttributes[i].name; len(wanted) == 0 || wanted[name] {
+			projected[name] = value
+		}
+	}
+	return projected
+}
@@ -0,0 +1,52 @@
+package graphing
+
+import (
+	"testing"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/stretchr/testify/assert"
+)
+
+func getPathGraphExport() GraphExport {
+	return GraphExport{
+		CrawlID: "crawl",
+		Nodes: []ExportNode{
+			{SteamID: "1", Username: "one"},
+			{SteamID: "2", Username: "two"},
+			{SteamID: "3", Username: "three"},
+			{SteamID: "4", Username: "four"},
+		},
+		Edges: [][2]string{{"1", "2"}, {"2", "3"}, {"3", "4"}},
+	}
+}
+
+func TestGetEgoNetworkOnlyKeepsUsersWithinTheRadius(t *testing.T) {
+	exists, egoNetwork := GetEgoNetwork(getPathGraphExport(), "2", 1, 100, []string{"username"})
+
+	assert.True(t, exists)
+	assert.False(t, egoNetwork.Truncated)
+	assert.Equal(t, []datastructures.EgoNetworkNode{
+		{SteamID: "2", Distance: 0, Attributes: map[string]interface{}{"username": "two"}},
+		{SteamID: "1", Distance: 1, Attributes: map[string]interface{}{"username": "one"}},
+		{SteamID: "3", Distance: 1, Attributes: map[string]interface{}{"username": "three"}},
+	}, egoNetwork.Nodes)
+	assert.Equal(t, []datastructures.GraphEdge{
+		{Source: "1", Target: "2"},
+		{Source: "2", Target: "3"},
+	}, egoNetwork.Edges)
+}
+
+func TestGetEgoNetworkKeepsTheClosestUsersWhenOverTheLimit(t *testing.T) {
+	_, egoNetwork := GetEgoNetwork(getPathGraphExport(), "1", 3, 2, []string{})
+
+	assert.True(t, egoNetwork.Truncated)
+	assert.Len(t, egoNetwork.Nodes, 2)
+	assert.Equal(t, "2", egoNetwork.Nodes[1].SteamID)
+	assert.Len(t, egoNetwork.Nodes[1].Attributes, len(exportNodeAttributes))
+}
+
+func TestGetEgoNetworkReturnsFalseForAUserNotInTheGraph(t *testing.T) {
+	exists, _ := GetEgoNetwork(getPathGraphExport(), "5", 1, 100, []string{})
+
+	assert.False(t, exists)
+}
@@ -0,0 +1,415 @@
+package graphing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+const (
+	ExportFormatGraphML   = "graphml"
+	ExportFormatGEXF      = "gexf"
+	ExportFormatDOT       = "dot"
+	ExportFormatCSV       = "csv"
+	ExportFormatJSONGraph = "jsongraph"
+
+	// CSV exports are either the list of nodes or the list of edges
+	ExportTableNodes = "nodes"
+	ExportTableEdges = "edges"
+)
+
+// ExportFormats maps each export format to the content type and file
+// extension it is served with
+var ExportFormats = map[string]struct {
+	ContentType string
+	Extension   string
+}{
+	ExportFormatGraphML:   {"application/graphml+xml", "graphml"},
+	ExportFormatGEXF:      {"application/gexf+xml", "gexf"},
+	ExportFormatDOT:       {"text/vnd.graphviz", "dot"},
+	ExportFormatCSV:       {"text/csv", "csv"},
+	ExportFormatJSONGraph: {"application/json", "json"},
+}
+
+// exportNodeAttributes are the attributes given to every node in the
+// order they are written
+var exportNodeAttributes = []struct {
+	name    string
+	xmlType string
+}{
+	{"username", "string"},
+	{"country", "string"},
+	{"timecreated", "long"},
+	{"accountagedays", "int"},
+	{"friendcount", "int"},
+	{"gamecount", "int"},
+	{"playtime", "long"},
+	{"level", "int"},
+	{"community", "int"},
+}
+
+// GraphExport is a crawl's users and the friendships between them.
+// Partial graphs only have the users crawled so far and Progress is the
+// percentage of the crawl that was done
+type GraphExport struct {
+	CrawlID  string
+	Partial  bool
+	Progress int
+	Nodes    []ExportNode
+	Edges    [][2]string
+}
+
+// ExportNode is a user in an exported graph. Games are the user's most
+// played games that were kept in the processed graph and playtime is
+// in minutes. Community is -1 for graphs without communities
+type ExportNode struct {
+	SteamID        string
+	Username       string
+	Country        string
+	TimeCreated    int
+	AccountAgeDays int
+	FriendCount    int
+	GameCount      int
+	Playtime       int
+	Level          int
+	Community      int
+}
+
+func (node ExportNode) attributeValues() []interface{} {
+	return []interface{}{
+		node.Username,
+		node.Country,
+		node.TimeCreated,
+		node.AccountAgeDays,
+		node.FriendCount,
+		node.GameCount,
+		node.Playtime,
+		node.Level,
+		node.Community,
+	}
+}
+
+func (node ExportNode) attributeStrings() []string {
+	values := []string{}
+	for _, value := range node.attributeValues() {
+		values = append(values, fmt.Sprint(value))
+	}
+	return values
+}
+
+// GetGraphExport lists the users in a processed graph and the friendships
+// between them. Friend lists can be out of date with each other so two
+// users are linked if either lists the other or one was found through
+// the other
+func GetGraphExport(crawlID string, graphData datastructures.UsersGraphData, now time.Time) GraphExport {
+	export := GraphExport{
+		CrawlID:  crawlID,
+		Partial:  graphData.Partial,
+		Progress: getGraphProgress(graphData),
+		Nodes:    []ExportNode{},
+		Edges:    [][2]string{},
+	}
+	users := append([]common.UsersGraphInformation{graphData.UserDetails}, graphData.FriendDetails...)
+
+	inGraph := make(map[string]bool)
+	for _, user := range users {
+		steamID := user.User.AccDetails.SteamID
+		if inGraph[steamID] {
+			continue
+		}
+		inGraph[steamID] = true
+
+		community := -1
+		if userCommunity, exists := graphData.UserCommunities[steamID]; exists {
+			community = userCommunity
+		}
+		playtime := 0
+		for _, game := range user.User.GamesOwned {
+			playtime += game.Playtime_Forever
+		}
+		export.Nodes = append(export.Nodes, ExportNode{
+			SteamID:        steamID,
+			Username:       user.User.AccDetails.Personaname,
+			Country:        user.User.AccDetails.Loccountrycode,
+			TimeCreated:    user.User.AccDetails.Timecreated,
+			AccountAgeDays: int(now.Sub(time.Unix(int64(user.User.AccDetails.Timecreated), 0)).Hours() / 24),
+			FriendCount:    len(user.User.FriendIDs),
+			GameCount:      len(user.User.GamesOwned),
+			Playtime:       playtime,
+			Level:          user.CurrentLevel,
+			Community:      community,
+		})
+	}
+
+	seenEdges := make(map[[2]string]bool)
+	addEdge := func(first, second string) {
+		if first == second || !inGraph[first] || !inGraph[second] {
+			return
+		}
+		if second < first {
+			first, second = second, first
+		}
+		edge := [2]string{first, second}
+		if !seenEdges[edge] {
+			seenEdges[edge] = true
+			export.Edges = append(export.Edges, edge)
+		}
+	}
+	for _, user := range users {
+		steamID := user.User.AccDetails.SteamID
+		addEdge(user.FromID, steamID)
+		for _, friendID := range user.User.FriendIDs {
+			addEdge(steamID, friendID)
+		}
+	}
+	return export
+}
+
+// getGraphProgress returns the percentage of the crawl a graph was made
+// from. Full graphs are made once the crawl has finished
+func getGraphProgress(graphData datastructures.UsersGraphData) int {
+	if !graphData.Partial {
+		return 100
+	}
+	return graphData.Progress
+}
+
+// WriteGraphExport writes the graph in the given format. The table is
+// only used by CSV exports
+func WriteGraphExport(w io.Writer, export GraphExport, format, table string) error {
+	switch format {
+	case ExportFormatGraphML:
+		return writeGraphML(w, export)
+	case ExportFormatGEXF:
+		return writeGEXF(w, export)
+	case ExportFormatDOT:
+		return writeDOT(w, export)
+	case ExportFormatCSV:
+		return writeCSV(w, export, table)
+	case ExportFormatJSONGraph:
+		return writeJSONGraph(w, export)
+	}
+	return fmt.Errorf("unknown export format %s", format)
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+func writeGraphML(w io.Writer, export GraphExport) error {
+	document := graphMLDocument{XMLNS: "http://graphml.graphdrawing.org/xmlns"}
+	document.Graph.ID = export.CrawlID
+	document.Graph.EdgeDefault = "undirected"
+	for _, attribute := range exportNodeAttributes {
+		document.Keys = append(document.Keys, graphMLKey{attribute.name, "node", attribute.name, attribute.xmlType})
+	}
+	for _, node := range export.Nodes {
+		data := []graphMLData{}
+		for i, value := range node.attributeStrings() {
+			data = append(data, graphMLData{exportNodeAttributes[i].name, value})
+		}
+		document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{node.SteamID, data})
+	}
+	for _, edge := range export.Edges {
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{edge[0], edge[1]})
+	}
+	return writeXML(w, document)
+}
+
+type gexfAttribute struct {
+	ID    int    `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributeValue struct {
+	For   int    `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID              string               `xml:"id,attr"`
+	Label           string               `xml:"label,attr"`
+	AttributeValues []gexfAttributeValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     int    `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type gexfDocument struct {
+	XMLName xml.Name `xml:"gexf"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Graph   struct {
+		DefaultEdgeType string `xml:"defaultedgetype,attr"`
+		Attributes      struct {
+			Class      string          `xml:"class,attr"`
+			Attributes []gexfAttribute `xml:"attribute"`
+		} `xml:"attributes"`
+		Nodes []gexfNode `xml:"nodes>node"`
+		Edges []gexfEdge `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+func writeGEXF(w io.Writer, export GraphExport) error {
+	document := gexfDocument{XMLNS: "http://gexf.net/1.3", Version: "1.3"}
+	document.Graph.DefaultEdgeType = "undirected"
+	document.Graph.Attributes.Class = "node"
+	for i, attribute := range exportNodeAttributes {
+		document.Graph.Attributes.Attributes = append(document.Graph.Attributes.Attributes, gexfAttribute{i, attribute.name, attribute.xmlType})
+	}
+	for _, node := range export.Nodes {
+		attributeValues := []gexfAttributeValue{}
+		for i, value := range node.attributeStrings() {
+			attributeValues = append(attributeValues, gexfAttributeValue{i, value})
+		}
+		document.Graph.Nodes = append(document.Graph.Nodes, gexfNode{node.SteamID, node.Username, attributeValues})
+	}
+	for i, edge := range export.Edges {
+		document.Graph.Edges = append(document.Graph.Edges, gexfEdge{i, edge[0], edge[1]})
+	}
+	return writeXML(w, document)
+}
+
+func writeXML(w io.Writer, document interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeDOT(w io.Writer, export GraphExport) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "graph %s {\n", quoteDOT(export.CrawlID))
+	for _, node := range export.Nodes {
+		attributes := []string{fmt.Sprintf("label=%s", quoteDOT(node.Username))}
+		for i, value := range node.attributeStrings() {
+			attributes = append(attributes, fmt.Sprintf("%s=%s", exportNodeAttributes[i].name, quoteDOT(value)))
+		}
+		fmt.Fprintf(&builder, "  %s [%s];\n", quoteDOT(node.SteamID), strings.Join(attributes, ", "))
+	}
+	for _, edge := range export.Edges {
+		fmt.Fprintf(&builder, "  %s -- %s;\n", quoteDOT(edge[0]), quoteDOT(edge[1]))
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// quoteDOT quotes a DOT ID. Only quotes and backslashes need escaping
+func quoteDOT(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", " ")
+	return `"` + value + `"`
+}
+
+func writeCSV(w io.Writer, export GraphExport, table string) error {
+	csvWriter := csv.NewWriter(w)
+	switch table {
+	case ExportTableNodes:
+		header := []string{"id"}
+		for _, attribute := range exportNodeAttributes {
+			header = append(header, attribute.name)
+		}
+		csvWriter.Write(header)
+		for _, node := range export.Nodes {
+			csvWriter.Write(append([]string{node.SteamID}, node.attributeStrings()...))
+		}
+	default:
+		csvWriter.Write([]string{"source", "target"})
+		for _, edge := range export.Edges {
+			csvWriter.Write([]string{edge[0], edge[1]})
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// writeJSONGraph writes the graph in the JSON Graph Format
+func writeJSONGraph(w io.Writer, export GraphExport) error {
+	type jsonGraphNode struct {
+		Label    string                 `json:"label"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	type jsonGraphEdge struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+	type jsonGraph struct {
+		ID       string                   `json:"id"`
+		Directed bool                     `json:"directed"`
+		Metadata map[string]interface{}   `json:"metadata"`
+		Nodes    map[string]jsonGraphNode `json:"nodes"`
+		Edges    []jsonGraphEdge          `json:"edges"`
+	}
+
+	graph := jsonGraph{
+		ID: export.CrawlID,
+		Metadata: map[string]interface{}{
+			"partial":  export.Partial,
+			"progress": export.Progress,
+		},
+		Nodes: make(map[string]jsonGraphNode),
+		Edges: []jsonGraphEdge{},
+	}
+	for _, node := range export.Nodes {
+		metadata := make(map[string]interface{})
+		for i, value := range node.attributeValues() {
+			metadata[exportNodeAttributes[i].name] = value
+		}
+		graph.Nodes[node.SteamID] = jsonGraphNode{node.Username, metadata}
+	}
+	for _, edge := range export.Edges {
+		graph.Edges = append(graph.Edges, jsonGraphEdge{edge[0], edge[1]})
+	}
+	return json.NewEncoder(w).Encode(struct {
+		Graph jsonGraph `json:"graph"`
+	}{graph})
+}
@@ -0,0 +1,164 @@
+package graphing
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestGraphExport() GraphExport {
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUser("1", "1", 1, "2", "3"),
+			FriendDetails: []common.UsersGraphInformation{
+				makeTestUser("2", "1", 2, "1", "3", "99"),
+				makeTestUser("3", "1", 2),
+				makeTestUser("2", "1", 2, "1"),
+			},
+		},
+		UserCommunities: map[string]int{"1": 0, "2": 0, "3": 1},
+	}
+	return GetGraphExport("crawl", graphData, time.Unix(1000000000+10*24*60*60, 0))
+}
+
+func TestGetGraphExportLinksFriendsOnceAndLeavesOutUsersThatWereNotCrawled(t *testing.T) {
+	export := getTestGraphExport()
+
+	assert.Len(t, export.Nodes, 3)
+	assert.Equal(t, [][2]string{{"1", "2"}, {"1", "3"}, {"2", "3"}}, export.Edges)
+	assert.Equal(t, ExportNode{
+		SteamID:        "2",
+		Username:       "user 2",
+		Country:        "IE",
+		TimeCreated:    1000000000,
+		AccountAgeDays: 10,
+		FriendCount:    3,
+		GameCount:      1,
+		Playtime:       60,
+		Level:          2,
+		Community:      0,
+	}, export.Nodes[1])
+}
+
+func TestGetGraphExportGivesUsersWithoutACommunityMinusOne(t *testing.T) {
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUser("1", "1", 1),
+		},
+	}
+
+	export := GetGraphExport("crawl", graphData, time.Now())
+
+	assert.Equal(t, -1, export.Nodes[0].Community)
+}
+
+func TestWriteGraphExportWritesValidGraphMLAndGEXF(t *testing.T) {
+	export := getTestGraphExport()
+
+	for _, format := range []string{ExportFormatGraphML, ExportFormatGEXF} {
+		var output bytes.Buffer
+		err := WriteGraphExport(&output, export, format, "")
+		assert.Nil(t, err)
+
+		var document struct {
+			XMLName xml.Name
+		}
+		assert.Nil(t, xml.Unmarshal(output.Bytes(), &document))
+		assert.Equal(t, format, document.XMLName.Local)
+		assert.Contains(t, output.String(), `source="2" target="3"`)
+	}
+}
+
+func TestWriteGraphExportWritesDOT(t *testing.T) {
+	export := GraphExport{
+		CrawlID: "crawl",
+		Nodes:   []ExportNode{{SteamID: "1", Username: `say "hi"`}, {SteamID: "2"}},
+		Edges:   [][2]string{{"1", "2"}},
+	}
+	var output bytes.Buffer
+
+	err := WriteGraphExport(&output, export, ExportFormatDOT, "")
+
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), `graph "crawl" {`)
+	assert.Contains(t, output.String(), `"1" [label="say \"hi\"", username="say \"hi\"", country=""`)
+	assert.Contains(t, output.String(), `"1" -- "2";`)
+}
+
+func TestWriteGraphExportWritesCSVNodesAndEdges(t *testing.T) {
+	export := getTestGraphExport()
+
+	var edges bytes.Buffer
+	assert.Nil(t, WriteGraphExport(&edges, export, ExportFormatCSV, ""))
+	assert.Equal(t, "source,target\n1,2\n1,3\n2,3\n", edges.String())
+
+	var nodes bytes.Buffer
+	assert.Nil(t, WriteGraphExport(&nodes, export, ExportFormatCSV, ExportTableNodes))
+	assert.Equal(t, "id,username,country,timecreated,accountagedays,friendcount,gamecount,playtime,level,community\n"+
+		"1,user 1,IE,1000000000,10,2,1,60,1,0\n"+
+		"2,user 2,IE,1000000000,10,3,1,60,2,0\n"+
+		"3,user 3,IE,1000000000,10,0,1,60,2,1\n", nodes.String())
+}
+
+func TestWriteGraphExportWritesJSONGraphFormat(t *testing.T) {
+	var output bytes.Buffer
+
+	err := WriteGraphExport(&output, getTestGraphExport(), ExportFormatJSONGraph, "")
+
+	assert.Nil(t, err)
+	document := struct {
+		Graph struct {
+			Directed bool                   `json:"directed"`
+			Metadata map[string]interface{} `json:"metadata"`
+			Nodes    map[string]struct {
+				Label    string                 `json:"label"`
+				Metadata map[string]interface{} `json:"metadata"`
+			} `json:"nodes"`
+			Edges []map[string]string `json:"edges"`
+		} `json:"graph"`
+	}{}
+	assert.Nil(t, json.Unmarshal(output.Bytes(), &document))
+	assert.False(t, document.Graph.Directed)
+	assert.Equal(t, map[string]interface{}{"partial": false, "progress": 100.0}, document.Graph.Metadata)
+	assert.Equal(t, "user 3", document.Graph.Nodes["3"].Label)
+	assert.Equal(t, 1.0, document.Graph.Nodes["3"].Metadata["community"])
+	assert.Len(t, document.Graph.Edges, 3)
+}
+
+func TestWriteGraphExportReturnsAnErrorForAnUnknownFormat(t *testing.T) {
+	var output bytes.Buffer
+
+	err := WriteGraphExport(&output, getTestGraphExport(), "png", "")
+
+	assert.NotNil(t, err)
+}
+
+func TestGetGraphExportKeepsHowMuchOfTheCrawlAGraphCovers(t *testing.T) {
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUser("1", "1", 1),
+		},
+	}
+	fullExport := GetGraphExport("crawl", graphData, time.Now())
+	graphData.Partial = true
+	graphData.Progress = 40
+	partialExport := GetGraphExport("crawl", graphData, time.Now())
+
+	assert.False(t, fullExport.Partial)
+	assert.Equal(t, 100, fullExport.Progress)
+	assert.True(t, partialExport.Partial)
+	assert.Equal(t, 40, partialExport.Progress)
+
+	_, egoNetwork := GetEgoNetwork(partialExport, "1", 1, 10, []string{})
+	assert.True(t, egoNetwork.Partial)
+	assert.Equal(t, 40, egoNetwork.Progress)
+	coarsenedGraph := CoarsenGraph(partialExport, 10, []string{})
+	assert.True(t, coarsenedGraph.Partial)
+	assert.Equal(t, 40, coarsenedGraph.Progress)
+}
@@ -0,0 +1,29 @@
+package graphing
+
+import (
+	"github.com/neosteamfriendgraphing/common"
+)
+
+func makeTestUser(steamID, fromID string, level int, friendIDs ...string) common.UsersGraphInformation {
+	return common.UsersGraphInformation{
+		User: common.UserDocument{
+			AccDetails: common.AccDetailsDocument{
+				SteamID:        steamID,
+				Personaname:    "user " + steamID,
+				Loccountrycode: "IE",
+				Timecreated:    1000000000,
+			},
+			FriendIDs:  friendIDs,
+			GamesOwned: []common.GameOwnedDocument{{AppID: 730, Playtime_Forever: 60}},
+		},
+		FromID:       fromID,
+		CurrentLevel: level,
+	}
+}
+
+func makeTestUserWithGames(steamID, countryCode string, friendIDs []string, games ...common.GameOwnedDocument) common.UsersGraphInformation {
+	user := makeTestUser(steamID, "1", 2, friendIDs...)
+	user.User.AccDetails.Loccountrycode = countryCode
+	user.User.GamesOwned = games
+	return user
+}
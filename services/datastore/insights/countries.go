@@ -0,0 +1,74 @@
+package insights
+
+// The country lists below are the same as the ones used by the frontend
+// so that insights match what was previously shown on the graph page
+
+var continentOrder = []string{"asia", "africa", "europe", "northamerica", "southamerica", "australia"}
+
+var continents = map[string]map[string]bool{
+	"asia": {
+		"CN": true, "IN": true, "ID": true, "PK": true, "BD": true, "JP": true,
+		"PH": true, "VN": true, "TR": true, "IR": true, "TH": true, "MM": true,
+		"KR": true, "IQ": true, "AF": true, "SA": true, "UZ": true, "MY": true,
+		"YE": true, "NP": true, "TW": true, "LK": true, "KZ": true, "SY": true,
+		"KH": true, "JO": true, "AZ": true, "AE": true, "TJ": true, "IL": true,
+		"HK": true, "LA": true, "LB": true, "KG": true, "TM": true, "SG": true,
+		"OM": true, "PS": true, "KW": true, "GE": true, "MN": true, "AM": true,
+		"QA": true, "BH": true, "TL": true, "CY": true, "BT": true, "MO": true,
+		"MV": true, "BN": true,
+	},
+	"africa": {
+		"NG": true, "ET": true, "EG": true, "CD": true, "CG": true, "TZ": true,
+		"SA": true, "KE": true, "UG": true, "DZ": true, "SD": true, "MA": true,
+		"AO": true, "MZ": true, "GH": true, "MG": true, "CM": true, "CI": true,
+		"NE": true, "BF": true, "ML": true, "MW": true, "ZM": true, "SN": true,
+		"TD": true, "SO": true, "ZW": true, "GN": true, "RW": true, "BJ": true,
+		"BI": true, "TN": true, "TG": true, "SL": true, "LY": true, "LR": true,
+		"CF": true, "MR": true, "ER": true, "NA": true, "GM": true, "BW": true,
+		"GA": true, "LS": true, "GW": true, "GQ": true, "MU": true, "DJ": true,
+		"RE": true, "KM": true, "EH": true, "YT": true, "ST": true, "SC": true,
+		"SH": true,
+	},
+	"europe": {
+		"RU": true, "DE": true, "GB": true, "FR": true, "IT": true, "ES": true,
+		"UA": true, "PL": true, "RO": true, "NL": true, "BE": true, "CZ": true,
+		"GR": true, "PT": true, "SE": true, "HU": true, "BY": true, "AT": true,
+		"RS": true, "CH": true, "BG": true, "DK": true, "FI": true, "SK": true,
+		"NO": true, "HR": true, "IE": true, "MD": true, "BA": true, "AL": true,
+		"LT": true, "MK": true, "SI": true, "LV": true, "EE": true, "ME": true,
+		"LU": true, "MT": true, "IS": true, "AD": true, "FO": true, "MC": true,
+		"LI": true, "SM": true, "GI": true, "VA": true,
+	},
+	"northamerica": {
+		"US": true, "MX": true, "CA": true, "GT": true, "HT": true, "CU": true,
+		"DO": true, "HN": true, "NI": true, "SV": true, "CR": true, "PA": true,
+		"JM": true, "PR": true, "TT": true, "GP": true, "BZ": true, "BS": true,
+		"MQ": true, "BB": true, "LC": true, "GD": true, "VC": true, "AW": true,
+		"VI": true, "AG": true, "DM": true, "KY": true, "BM": true, "GL": true,
+		"KN": true, "MF": true, "VG": true, "AN": true, "AI": true, "BL": true,
+		"PM": true, "MS": true,
+	},
+	"southamerica": {
+		"BR": true, "CO": true, "AR": true, "PE": true, "VE": true, "CL": true,
+		"EC": true, "BO": true, "PY": true, "UY": true, "SR": true, "GF": true,
+		"FK": true,
+	},
+	"australia": {
+		"AU": true, "PG": true, "NZ": true, "FJ": true, "SB": true, "FM": true,
+		"VU": true, "NC": true, "PF": true, "WS": true, "GU": true, "KI": true,
+		"TO": true, "MH": true, "MP": true, "AS": true, "PW": true, "CK": true,
+		"TB": true, "WF": true, "NR": true, "NU": true, "TK": true,
+	},
+}
+
+var dictatorRuledCountries = map[string]bool{
+	"AF": true, "AL": true, "AO": true, "AZ": true, "BH": true, "BD": true,
+	"BY": true, "BN": true, "BI": true, "KH": true, "CM": true, "CF": true,
+	"TD": true, "CN": true, "CU": true, "DJ": true, "CD": true, "EG": true,
+	"GQ": true, "ER": true, "SZ": true, "ET": true, "GA": true, "IR": true,
+	"IQ": true, "KZ": true, "LA": true, "LY": true, "MM": true, "NI": true,
+	"KP": true, "OM": true, "QA": true, "RU": true, "RW": true, "SA": true,
+	"SO": true, "SD": true, "SY": true, "SS": true, "TJ": true, "TR": true,
+	"TM": true, "UG": true, "AE": true, "UZ": true, "VE": true, "VN": true,
+	"EH": true, "YE": true,
+}
@@ -0,0 +1,202 @@
+package insights
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+)
+
+const (
+	recognisedCountries = 195
+	totalContinents     = 7
+	maxTopCountries     = 10
+	leaderboardLength   = 8
+	maxGamerScore       = 5000
+)
+
+// GetNetworkInsights works out the statistics shown on the graph page
+// of a crawl so that every client gets the same numbers
+func GetNetworkInsights(graphData datastructures.UsersGraphData, now time.Time) datastructures.NetworkInsights {
+	now = now.UTC()
+	target := graphData.UserDetails.User
+	friends := []common.UserDocument{}
+	for _, friend := range graphData.FriendDetails {
+		friends = append(friends, friend.User)
+	}
+
+	insights := datastructures.NetworkInsights{ComputedAt: now.Unix()}
+	addCountryInsights(&insights, target, friends)
+	addAccountAgeInsights(&insights, friends, now)
+	addHoursPlayedInsights(&insights, target, friends)
+	return insights
+}
+
+func addCountryInsights(insights *datastructures.NetworkInsights, target common.UserDocument, friends []common.UserDocument) {
+	insights.CountryFrequencies = make(map[string]int)
+	for _, friend := range friends {
+		if countryCode := friend.AccDetails.Loccountrycode; countryCode != "" {
+			insights.CountryFrequencies[strings.ToLower(countryCode)]++
+		}
+	}
+
+	countryCodes := []string{}
+	for countryCode := range insights.CountryFrequencies {
+		countryCodes = append(countryCodes, countryCode)
+	}
+	sort.Slice(countryCodes, func(i, j int) bool {
+		if insights.CountryFrequencies[countryCodes[i]] != insights.CountryFrequencies[countryCodes[j]] {
+			return insights.CountryFrequencies[countryCodes[i]] > insights.CountryFrequencies[countryCodes[j]]
+		}
+		return countryCodes[i] < countryCodes[j]
+	})
+
+	insights.TopCountries = []string{}
+	for i, countryCode := range countryCodes {
+		if i == maxTopCountries {
+			break
+		}
+		insights.TopCountries = append(insights.TopCountries, strings.ToUpper(countryCode))
+	}
+	insights.UniqueCountries = len(countryCodes)
+	insights.GlobalCoverage = insights.UniqueCountries * 100 / recognisedCountries
+	for _, countryCode := range countryCodes {
+		if dictatorRuledCountries[strings.ToUpper(countryCode)] {
+			insights.DictatorshipCountries++
+		}
+	}
+	insights.AlsoFromUsersCountry = insights.CountryFrequencies[strings.ToLower(target.AccDetails.Loccountrycode)]
+
+	insights.ContinentsCovered = []string{}
+	for _, continent := range continentOrder {
+		for _, countryCode := range countryCodes {
+			if continents[continent][strings.ToUpper(countryCode)] {
+				insights.ContinentsCovered = append(insights.ContinentsCovered, continent)
+				break
+			}
+		}
+	}
+	insights.ContinentCoverage = len(insights.ContinentsCovered) * 100 / totalContinents
+}
+
+func addAccountAgeInsights(insights *datastructures.NetworkInsights, friends []common.UserDocument, now time.Time) {
+	insights.AccountAgeHistogram = []int{}
+	insights.AccountAgeVsFriendCount = [][2]int{}
+	insights.CreationYearFrequencies = make(map[int]int)
+	if len(friends) == 0 {
+		return
+	}
+
+	oldestYear := now.Year()
+	oldestTimeCreated, newestTimeCreated, highestFriendCount := 0, 0, 0
+	for i, friend := range friends {
+		created := time.Unix(int64(friend.AccDetails.Timecreated), 0).UTC()
+		accountAgeMonths := monthsSince(created, now)
+		for len(insights.AccountAgeHistogram) <= accountAgeMonths/12 {
+			insights.AccountAgeHistogram = append(insights.AccountAgeHistogram, 0)
+		}
+		insights.AccountAgeHistogram[accountAgeMonths/12]++
+		insights.AccountAgeVsFriendCount = append(insights.AccountAgeVsFriendCount, [2]int{len(friend.FriendIDs), accountAgeMonths})
+
+		insights.CreationYearFrequencies[created.Year()]++
+		if created.Year() < oldestYear {
+			oldestYear = created.Year()
+		}
+		insights.CreationMonthFrequencies[created.Month()-1]++
+
+		if i == 0 || friend.AccDetails.Timecreated < oldestTimeCreated {
+			insights.OldestUser = friend.AccDetails.SteamID
+			oldestTimeCreated = friend.AccDetails.Timecreated
+		}
+		if i == 0 || friend.AccDetails.Timecreated > newestTimeCreated {
+			insights.NewestUser = friend.AccDetails.SteamID
+			newestTimeCreated = friend.AccDetails.Timecreated
+		}
+		if i == 0 || len(friend.FriendIDs) > highestFriendCount {
+			insights.HighestFriendCountUser = friend.AccDetails.SteamID
+			highestFriendCount = len(friend.FriendIDs)
+		}
+	}
+	// Years without any new accounts are shown as well
+	for year := oldestYear; year < now.Year(); year++ {
+		if _, exists := insights.CreationYearFrequencies[year]; !exists {
+			insights.CreationYearFrequencies[year] = 0
+		}
+	}
+
+	insights.LeastPopularCreationMonth = -1
+	for month, count := range insights.CreationMonthFrequencies {
+		if count > insights.CreationMonthFrequencies[insights.MostPopularCreationMonth] {
+			insights.MostPopularCreationMonth = month
+		}
+		if count > 0 && (insights.LeastPopularCreationMonth == -1 || count < insights.CreationMonthFrequencies[insights.LeastPopularCreationMonth]) {
+			insights.LeastPopularCreationMonth = month
+		}
+	}
+}
+
+// monthsSince counts calendar months between two times
+func monthsSince(then, now time.Time) int {
+	months := (now.Year()-then.Year())*12 + int(now.Month()) - int(then.Month())
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+func addHoursPlayedInsights(insights *datastructures.NetworkInsights, target common.UserDocument, friends []common.UserDocument) {
+	entries := []datastructures.HoursPlayedEntry{getHoursPlayedEntry(target)}
+	for _, friend := range friends {
+		entries = append(entries, getHoursPlayedEntry(friend))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Hours > entries[j].Hours
+	})
+
+	for i, entry := range entries {
+		if entry.SteamID == target.AccDetails.SteamID {
+			insights.UserRank = i + 1
+			break
+		}
+	}
+	if len(entries) > leaderboardLength {
+		entries = entries[:leaderboardLength]
+	}
+	insights.HoursPlayedLeaderboard = entries
+
+	insights.UserHoursPlayed = getHoursPlayed(target)
+	if len(friends) > 0 {
+		friendsWithFewerHoursPlayed := 0
+		for _, friend := range friends {
+			if getHoursPlayed(friend) < insights.UserHoursPlayed {
+				friendsWithFewerHoursPlayed++
+			}
+		}
+		insights.FriendsWithFewerHoursPlayed = friendsWithFewerHoursPlayed * 100 / len(friends)
+	}
+	insights.GamerScore = insights.UserHoursPlayed*2 + len(target.FriendIDs)
+	if insights.GamerScore > maxGamerScore {
+		insights.GamerScore = maxGamerScore
+	}
+}
+
+func getHoursPlayedEntry(user common.UserDocument) datastructures.HoursPlayedEntry {
+	return datastructures.HoursPlayedEntry{
+		SteamID:    user.AccDetails.SteamID,
+		Username:   user.AccDetails.Personaname,
+		Avatar:     user.AccDetails.Avatar,
+		ProfileURL: user.AccDetails.Profileurl,
+		Hours:      getHoursPlayed(user),
+	}
+}
+
+// getHoursPlayed counts whole hours played of each game
+func getHoursPlayed(user common.UserDocument) int {
+	hours := 0
+	for _, game := range user.GamesOwned {
+		hours += game.Playtime_Forever / 60
+	}
+	return hours
+}
@@ -0,0 +1,25 @@
+package insights
+
+import (
+	"time"
+
+	"github.com/neosteamfriendgraphing/common"
+)
+
+func makeTestUser(steamID, country string, created time.Time, friendCount int, minutesPlayed ...int) common.UsersGraphInformation {
+	user := common.UsersGraphInformation{
+		User: common.UserDocument{
+			AccDetails: common.AccDetailsDocument{
+				SteamID:        steamID,
+				Personaname:    "user " + steamID,
+				Loccountrycode: country,
+				Timecreated:    int(created.Unix()),
+			},
+			FriendIDs: make([]string, friendCount),
+		},
+	}
+	for i, minutes := range minutesPlayed {
+		user.User.GamesOwned = append(user.User.GamesOwned, common.GameOwnedDocument{AppID: i, Playtime_Forever: minutes})
+	}
+	return user
+}
@@ -0,0 +1,85 @@
+package insights
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IamCathal/neo/services/datastore/datastructures"
+	"github.com/neosteamfriendgraphing/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestInsights() datastructures.NetworkInsights {
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUser("target", "IE", time.Date(2010, time.May, 1, 0, 0, 0, 0, time.UTC), 3, 600),
+			FriendDetails: []common.UsersGraphInformation{
+				makeTestUser("1", "IE", time.Date(2008, time.March, 1, 0, 0, 0, 0, time.UTC), 5, 90, 90),
+				makeTestUser("2", "cn", time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC), 10, 6000),
+				makeTestUser("3", "BR", time.Date(2021, time.December, 1, 0, 0, 0, 0, time.UTC), 1),
+				makeTestUser("4", "", time.Date(2020, time.July, 1, 0, 0, 0, 0, time.UTC), 10, 59),
+			},
+		},
+	}
+	return GetNetworkInsights(graphData, time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC))
+}
+
+func TestGetNetworkInsightsCountsCountriesOfFriends(t *testing.T) {
+	insights := getTestInsights()
+
+	assert.Equal(t, map[string]int{"ie": 1, "cn": 1, "br": 1}, insights.CountryFrequencies)
+	assert.Equal(t, []string{"BR", "CN", "IE"}, insights.TopCountries)
+	assert.Equal(t, 3, insights.UniqueCountries)
+	assert.Equal(t, 1, insights.GlobalCoverage)
+	assert.Equal(t, 1, insights.DictatorshipCountries)
+	assert.Equal(t, 1, insights.AlsoFromUsersCountry)
+	assert.Equal(t, []string{"asia", "europe", "southamerica"}, insights.ContinentsCovered)
+	assert.Equal(t, 42, insights.ContinentCoverage)
+}
+
+func TestGetNetworkInsightsCountsAccountAges(t *testing.T) {
+	insights := getTestInsights()
+
+	assert.Equal(t, []int{1, 1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, insights.AccountAgeHistogram)
+	assert.Equal(t, [][2]int{{5, 168}, {10, 36}, {1, 3}, {10, 20}}, insights.AccountAgeVsFriendCount)
+	assert.Len(t, insights.CreationYearFrequencies, 2021-2008+1)
+	assert.Equal(t, 0, insights.CreationYearFrequencies[2012])
+	assert.Equal(t, 1, insights.CreationYearFrequencies[2021])
+	assert.Equal(t, [12]int{0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 0, 1}, insights.CreationMonthFrequencies)
+	assert.Equal(t, 2, insights.MostPopularCreationMonth)
+	assert.Equal(t, 6, insights.LeastPopularCreationMonth)
+	assert.Equal(t, "1", insights.OldestUser)
+	assert.Equal(t, "3", insights.NewestUser)
+	assert.Equal(t, "2", insights.HighestFriendCountUser)
+}
+
+func TestGetNetworkInsightsRanksUsersByWholeHoursPlayed(t *testing.T) {
+	insights := getTestInsights()
+
+	hours := []int{}
+	steamIDs := []string{}
+	for _, entry := range insights.HoursPlayedLeaderboard {
+		hours = append(hours, entry.Hours)
+		steamIDs = append(steamIDs, entry.SteamID)
+	}
+	assert.Equal(t, []int{100, 10, 2, 0, 0}, hours)
+	assert.Equal(t, []string{"2", "target", "1", "3", "4"}, steamIDs)
+	assert.Equal(t, 10, insights.UserHoursPlayed)
+	assert.Equal(t, 75, insights.FriendsWithFewerHoursPlayed)
+	assert.Equal(t, 2, insights.UserRank)
+	assert.Equal(t, 23, insights.GamerScore)
+}
+
+func TestGetNetworkInsightsCapsTheGamerScore(t *testing.T) {
+	graphData := datastructures.UsersGraphData{
+		UsersGraphData: common.UsersGraphData{
+			UserDetails: makeTestUser("target", "IE", time.Now(), 10, 60*10000),
+		},
+	}
+
+	insights := GetNetworkInsights(graphData, time.Now())
+
+	assert.Equal(t, maxGamerScore, insights.GamerScore)
+	assert.Empty(t, insights.AccountAgeHistogram)
+	assert.Equal(t, 1, insights.UserRank)
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/IamCathal/neo/services/datastore/configuration"
 	"github.com/IamCathal/neo/services/datastore/controller"
+	"github.com/IamCathal/neo/services/datastore/datastructures"
 	"github.com/IamCathal/neo/services/datastore/endpoints"
 	"github.com/joho/godotenv"
 	"github.com/neosteamfriendgraphing/common"
@@ -209,15 +210,19 @@ func TestHasUserBeenCrawledBeforeForUserThatDoesHasNotBeenCrawled(t *testing.T)
 func TestGetCrawlingStatus(t *testing.T) {
 	targetCrawlID := "253v0czhdyyYWfce4LhfN1x1Nhv"
 
-	expectedResponse := dtos.GetCrawlingStatusDTO{
+	expectedResponse := datastructures.GetCrawlingStatusDTO{
 		Status: "success",
-		CrawlingStatus: common.CrawlingStatus{
-			TimeStarted:         1644768362,
-			CrawlID:             targetCrawlID,
-			OriginalCrawlTarget: "76561198079437417",
-			MaxLevel:            2,
-			TotalUsersToCrawl:   13,
-			UsersCrawled:        13,
+		CrawlingStatus: datastructures.CrawlingStatus{
+			CrawlingStatus: common.CrawlingStatus{
+				TimeStarted:         1644768362,
+				CrawlID:             targetCrawlID,
+				OriginalCrawlTarget: "76561198079437417",
+				MaxLevel:            2,
+				TotalUsersToCrawl:   13,
+				UsersCrawled:        13,
+			},
+			EstimatedTotalUsers: 13,
+			EstimatedProgress:   100,
 		},
 	}
 	expectedResponseJSON, err := json.Marshal(expectedResponse)
@@ -96,6 +96,7 @@ if (crawlIDs.length == 1) {
     utilRequest.doesProcessedGraphDataExist(crawlIDs[0]).then(doesExist => {
         if (!doesExist) {
             renderCrawlStatusBoxes(1)
+            initPreviewGraphLink(crawlIDs[0], "firstCrawl")
             // subscribe to crawling status updates
             initAndMonitorCrawlingStatusWebsocket(crawlIDs[0], "firstCrawl").then(res => {
                 document.getElementById(`firstCrawlCrawlStatus`).textContent = 'Processing graph';
@@ -129,6 +130,30 @@ if (crawlIDs.length == 1) {
     })
 } 
 
+// The preview graph is made from the users crawled so far and is
+// replaced by the full graph once the crawl has finished
+function initPreviewGraphLink(crawlID, idPrefix) {
+    const previewLink = document.getElementById(`${idPrefix}PreviewGraph`)
+    previewLink.addEventListener("click", (event) => {
+        event.preventDefault()
+        previewLink.textContent = "Creating preview"
+        utilRequest.startCreatePartialGraph(crawlID).then(res => {
+            if (res.status != "success") {
+                previewLink.textContent = "Preview the graph so far"
+                return
+            }
+            utilRequest.waitUntilGraphDataExists(crawlID, true).then(() => {
+                previewLink.textContent = "Preview the graph so far"
+                window.open(`/graph/${crawlID}`, "_blank")
+            }, err => {
+                console.error(`error waiting for preview graph: ${err}`)
+            })
+        }, err => {
+            console.error(`err from createPartialGraph ${err}`)
+        })
+    })
+}
+
 function renderCrawlStatusBoxes(numberOfBoxes) {
     if (numberOfBoxes == 1) {
         // Add spacing to left
@@ -184,6 +209,12 @@ function renderCrawlStatusBoxes(numberOfBoxes) {
                 <!-- crawling progress bar -->
                 <div class="row text-center mt-1">
                     <div class="col" style="font-size: 0.9rem;" id="firstCrawlPercentageDone"> 0% </div>
+                    <div class="col" style="font-size: 0.9rem;" id="firstCrawlCrawlETA"> </div>
+                </div>
+                <div class="row text-center mt-1">
+                    <div class="col" style="font-size: 0.9rem;">
+                        <a href="#" id="firstCrawlPreviewGraph">Preview the graph so far</a>
+                    </div>
                 </div>
                     
 
@@ -321,6 +352,7 @@ function renderCrawlStatusBoxes(numberOfBoxes) {
             <!-- crawling progress bar -->
             <div class="row text-center mt-1">
                 <div class="col" style="font-size: 0.9rem;" id="firstCrawlPercentageDone"> 0% </div>
+                <div class="col" style="font-size: 0.9rem;" id="firstCrawlCrawlETA"> </div>
             </div>
                 
 
@@ -452,6 +484,7 @@ function renderCrawlStatusBoxes(numberOfBoxes) {
             <!-- crawling progress bar -->
             <div class="row text-center mt-1">
                 <div class="col" style="font-size: 0.9rem;" id="secondCrawlPercentageDone"> 0% </div>
+                <div class="col" style="font-size: 0.9rem;" id="secondCrawlCrawlETA"> </div>
             </div>
                 
 
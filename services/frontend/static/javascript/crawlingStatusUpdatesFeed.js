@@ -1,5 +1,9 @@
 import * as utilRequest from '/static/javascript/utilRequests.js';
 
+// The estimated progress of a crawl can drop when many more users are
+// discovered than expected so the highest progress shown is kept
+let highestProgressShown = {}
+
 export function initAndMonitorCrawlingStatusWebsocket(crawlID, idPrefix, isAlreadyDone) {
   return new Promise((resolve, reject) => {
     if (isAlreadyDone) {
@@ -8,9 +12,8 @@ export function initAndMonitorCrawlingStatusWebsocket(crawlID, idPrefix, isAlrea
         document.getElementById(`${idPrefix}CrawlStatus`).textContent = 'Completed'
         document.getElementById(`${idPrefix}UsersCrawled`).textContent = crawlingStatus.userscrawled;
         document.getElementById(`${idPrefix}TotalUsersToCrawl`).textContent = crawlingStatus.totaluserstocrawl;
-        document.getElementById(`${idPrefix}PercentageDone`).textContent = `${Math.floor((crawlingStatus.userscrawled/crawlingStatus.totaluserstocrawl)*100)}%`;
         document.getElementById(`${idPrefix}CrawlTime`).textContent = timeSince(new Date(crawlingStatus.timestarted*1000));
-        document.getElementById(`${idPrefix}ProgressBarID`).style.width = `${Math.floor((crawlingStatus.userscrawled/crawlingStatus.totaluserstocrawl)*100)}%`;
+        setCrawlProgress(idPrefix, 100, 0)
         
         resolve()
       }, err => {
@@ -25,7 +28,13 @@ export function initAndMonitorCrawlingStatusWebsocket(crawlID, idPrefix, isAlrea
     
     wsConn.addEventListener("message", (evt) => {
         const crawlingStatUpdate = JSON.parse(evt.data);
-        if (crawlingStatUpdate.userscrawled == crawlingStatUpdate.totaluserstocrawl) {
+        if (crawlingStatUpdate.state == 'failed' || crawlingStatUpdate.state == 'cancelled') {
+            document.getElementById(`${idPrefix}CrawlStatus`).textContent = crawlingStatUpdate.state == 'failed' ? 'Failed' : 'Cancelled'
+            wsConn.close()
+            reject(`crawl ${crawlID} is ${crawlingStatUpdate.state}`)
+            return
+        }
+        if (utilRequest.hasFinishedCrawling(crawlingStatUpdate)) {
             setCrawlingStatusToProcessing(idPrefix).then((res) => {
               wsConn.close()
               resolve()
@@ -35,15 +44,33 @@ export function initAndMonitorCrawlingStatusWebsocket(crawlID, idPrefix, isAlrea
         document.getElementById(`${idPrefix}CrawlStatus`).textContent = 'Crawling'
         document.getElementById(`${idPrefix}UsersCrawled`).textContent = crawlingStatUpdate.userscrawled;
         document.getElementById(`${idPrefix}TotalUsersToCrawl`).textContent = crawlingStatUpdate.totaluserstocrawl;
-        document.getElementById(`${idPrefix}PercentageDone`).textContent = `${Math.floor((crawlingStatUpdate.userscrawled/crawlingStatUpdate.totaluserstocrawl)*100)}%`;
         document.getElementById(`${idPrefix}CrawlTime`).textContent = timeSince(new Date(crawlingStatUpdate.timestarted*1000));
-        document.getElementById(`${idPrefix}ProgressBarID`).style.width = `${Math.floor((crawlingStatUpdate.userscrawled/crawlingStatUpdate.totaluserstocrawl)*100)}%`;
+        setCrawlProgress(idPrefix, crawlingStatUpdate.estimatedprogress, crawlingStatUpdate.etaseconds)
     
         document.title = `${crawlingStatUpdate.userscrawled}/${crawlingStatUpdate.totaluserstocrawl} - Crawling`;
       })
   })
 }
 
+function setCrawlProgress(idPrefix, estimatedProgress, etaSeconds) {
+    const progress = Math.max(highestProgressShown[idPrefix] || 0, estimatedProgress)
+    highestProgressShown[idPrefix] = progress
+
+    document.getElementById(`${idPrefix}PercentageDone`).textContent = `${progress}%`;
+    document.getElementById(`${idPrefix}ProgressBarID`).style.width = `${progress}%`;
+    document.getElementById(`${idPrefix}CrawlETA`).textContent = etaSeconds > 0 ? `about ${timeLeft(etaSeconds)} left` : '';
+}
+
+function timeLeft(seconds) {
+    if (seconds >= 3600) {
+      return Math.floor(seconds / 3600) + "h " + Math.floor((seconds % 3600) / 60) + "m";
+    }
+    if (seconds >= 60) {
+      return Math.ceil(seconds / 60) + "m";
+    }
+    return Math.ceil(seconds) + "s";
+}
+
 function timeSince(targetDate) {
     let seconds = Math.floor((new Date()-targetDate)/1000)
     let interval = seconds / 31536000 // years
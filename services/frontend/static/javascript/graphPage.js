@@ -7,52 +7,56 @@ import * as utilRequest from '/static/javascript/utilRequests.js';
 const URLarr = window.location.href.split("/");
 const crawlID = URLarr[URLarr.length-1];
 let crawlData = {}
+// Crawls with more users than this are drawn with groups of users
+// collapsed into super nodes that are expanded by clicking on them
+const maxBrowserGraphNodes = 500
 
-utilRequest.doesProcessedGraphDataExist(crawlID).then(doesExist => {
+utilRequest.doesProcessedGraphDataExist(crawlID, true).then(doesExist => {
     if (doesExist === false) {
         window.location.href = "/"
     }
-    utilRequest.getProcessedGraphData(crawlID).then(crawlDataObj => {
+    // Statistics are worked out by the datastore so that every client
+    // shows the same numbers
+    const processedGraphData = utilRequest.getProcessedGraphData(crawlID)
+    const networkInsights = utilRequest.getInsights(crawlID)
+    Promise.all([processedGraphData, networkInsights]).then(([crawlDataObj, insights]) => {
         crawlData = crawlDataObj
         setUserCardDetails(crawlData.usergraphdata.userdetails.User);
-        let countryFrequencies = {}
-        var countryFrequenciesArr = []
-
-        crawlDataObj.usergraphdata.frienddetails.forEach(friend => {
-            const countryCode = friend.User.accdetails.loccountrycode;
-            if (countryCode != "") {
-                countryFrequencies[countryCode.toLowerCase()] = countryFrequencies[countryCode.toLowerCase()] ? countryFrequencies[countryCode.toLowerCase()] + 1 : 1;
-            }
-        });
-        countryFrequenciesArr = Object.entries(countryFrequencies)
+        fillInOldestDataAge(crawlData.usergraphdata);
 
         // Geographic Stats
-        initWorldMap(countryFrequenciesArr)
+        initWorldMap(Object.entries(insights.countryfrequencies))
         fillInFlagsDiv(crawlDataObj.usergraphdata.frienddetails)
-        fillInTopStatBoxes(crawlData, countryFrequencies)
-        fillInTop10Countries(countryFrequencies)
-        fillInFromYourCountryStatBox(crawlDataObj, countryFrequencies)
-        fillInContinentCoverage(countryFrequencies)
+        fillInTopStatBoxes(crawlData, insights)
+        fillInTop10Countries(insights.topcountries)
+        fillInFromYourCountryStatBox(insights)
+        fillInContinentCoverage(insights)
 
         // Games stats
         initAndRenderGamesBarChart(getDataForGamesBarChart(crawlDataObj.usergraphdata))
-        fillInGamesStatBoxes(crawlDataObj.usergraphdata)
+        fillInGamesStatBoxes(insights)
         fillInUserAndNetworkFavoriteGameStatBoxes(crawlDataObj.usergraphdata)
-        let usersLeaderboard = getMostHoursPlayedStats(crawlDataObj.usergraphdata)
+        let usersLeaderboard = getMostHoursPlayedStats(crawlDataObj.usergraphdata, insights)
         fillInHoursPlayedLeaderboard(usersLeaderboard)
+        fillInCentralityLeaderboards(crawlDataObj.usergraphdata)
+        fillInNetworkStatBoxes(crawlDataObj.usergraphdata)
         initNetWorkMostHoursPlayedBarChart(usersLeaderboard)
 
         // Friend network stats
-        userCreatedGraph(crawlDataObj.usergraphdata)
-        userCreatedMonthChart(crawlDataObj.usergraphdata)
-        fillInOldestAndNewestUserCards(crawlDataObj.usergraphdata)
-        initAndRenderAccountAgeVsFriendCountChart(crawlDataObj.usergraphdata)
+        userCreatedGraph(insights)
+        userCreatedMonthChart(insights)
+        fillInOldestAndNewestUserCards(crawlDataObj.usergraphdata, insights)
+        initAndRenderAccountAgeVsFriendCountChart(crawlDataObj.usergraphdata, insights)
 
-        initGamerScore(crawlDataObj.usergraphdata)
+        initGamerScore(insights)
         initLinkForInteractiveGraphPage()
 
         var myChart = echarts.init(document.getElementById('graphContainer'));
-        const graph = getDataInGraphFormat(crawlDataObj.usergraphdata, countryFrequencies)
+        const graph = getDataInGraphFormat(crawlDataObj.usergraphdata, insights.topcountries)
+        if (graph.nodes.length > maxBrowserGraphNodes) {
+            renderCoarsenedGraph(myChart, [])
+            return
+        }
         var option;
         myChart.showLoading();
         myChart.hideLoading()
@@ -102,7 +106,7 @@ utilRequest.doesProcessedGraphDataExist(crawlID).then(doesExist => {
             {
                 name: 'Friend Network',
                 type: 'graph',
-                layout: 'force',
+                layout: graph.precomputedlayout ? 'none' : 'force',
                 data: graph.nodes,
                 links: graph.links,
                 categories: graph.categories,
@@ -123,14 +127,86 @@ utilRequest.doesProcessedGraphDataExist(crawlID).then(doesExist => {
 
 
     }, err => {
-        console.error(`error retrieving processed graph data: ${err}`)
+        console.error(`error retrieving processed graph data or insights: ${err}`)
     })
 }, err => {
     console.error(`error calling does processed graphdata exist: ${err}`)
 })
 
-function getDataInGraphFormat(gData, countryFrequencies) {
-    const topTenCountryNames = getTopTenCountries(countryFrequencies);
+function renderCoarsenedGraph(chart, expanded) {
+    utilRequest.getCoarsenedGraph(crawlID, maxBrowserGraphNodes, expanded).then(coarsenedGraph => {
+        const nodes = coarsenedGraph.nodes.map(node => {
+            const isUser = node.kind == "user"
+            return {
+                "id": node.id,
+                "name": isUser ? node.label : `${node.label} (${node.size} users)`,
+                "kind": node.kind,
+                "category": isUser ? 0 : 1,
+                "symbolSize": isUser ? 10 : Math.min(10 + Math.sqrt(node.size) * 3, 60)
+            }
+        })
+        const links = coarsenedGraph.edges.map(edge => {
+            return { "source": edge.source, "target": edge.target, "value": edge.weight }
+        })
+        const categories = [{ "name": "User" }, { "name": "Group of users" }]
+
+        chart.setOption({
+            title: {
+                text: 'Your friend network',
+                subtext: `${coarsenedGraph.usercount} users, click a group to expand it`,
+                top: 'bottom',
+                left: 'right',
+                textStyle: {
+                    color: '#ffffff'
+                }
+            },
+            tooltip: {
+                show: true
+            },
+            legend: [
+            {
+                data: categories.map(category => category.name),
+                show: true,
+                left: 'left',
+                textStyle: {
+                    color: '#ffffff'
+                }
+            }
+            ],
+            series: [
+            {
+                name: 'Friend Network',
+                type: 'graph',
+                layout: 'force',
+                data: nodes,
+                links: links,
+                categories: categories,
+                roam: true,
+                label: {
+                    position: 'right'
+                },
+                force: {
+                    gravity: 0.5,
+                    repulsion: 370,
+                    friction: 0.2,
+                }
+            }
+            ]
+        }, true)
+
+        chart.off('click')
+        chart.on('click', params => {
+            if (params.dataType == 'node' && params.data.kind != 'user') {
+                renderCoarsenedGraph(chart, expanded.concat(params.data.id))
+            }
+        })
+    }, err => {
+        console.error(`error retrieving coarsened graph: ${err}`)
+    })
+}
+
+function getDataInGraphFormat(gData, topCountries) {
+    const topTenCountryNames = topCountries.map(countryCode => util.countryCodeToName(countryCode));
     // TODO change to top 10 frequency countries instead
     let countryCategories = []
     topTenCountryNames.forEach(countryName => {
@@ -173,10 +249,22 @@ function getDataInGraphFormat(gData, countryFrequencies) {
         })
     }
     
+    // Graphs laid out when they were created only need to be drawn. Older
+    // graphs and ones missing a position for any user are laid out here
+    const positions = gData.layout ? gData.layout.positions2d : undefined
+    const precomputedLayout = Boolean(positions) && nodes.every(node => positions[node.id])
+    if (precomputedLayout) {
+        nodes.forEach(node => {
+            node.x = positions[node.id][0]
+            node.y = positions[node.id][1]
+        })
+    }
+
     const echartsData = {
         "nodes": nodes,
         "links": links,
-        "categories": countryCategories
+        "categories": countryCategories,
+        "precomputedlayout": precomputedLayout
     }
     return echartsData
 }
@@ -274,34 +362,14 @@ function getDataForGamesBarChart(gData) {
     return barChartData;
 }
 
-function fillInGamesStatBoxes(graphData) {
+function fillInGamesStatBoxes(insights) {
     const minWage = 10.20
-    let totalHoursPlayedForUser = 0
-    graphData.userdetails.User.gamesowned.forEach(game => {
-        totalHoursPlayedForUser += game.playtime_forever
-    })
-    totalHoursPlayedForUser = Math.floor(totalHoursPlayedForUser/60)
-
-    const entireDaysOfPlaytime = Math.floor(totalHoursPlayedForUser / 24)
-    const minWageEarnedForGaming = Math.floor(totalHoursPlayedForUser * minWage)
-    let percentageOfFriendsWithLessHoursPlayed = 0
-    let friendsWithLessHoursPlayed = 0
-    if (totalHoursPlayedForUser != 0) {
-        graphData.frienddetails.forEach(user => {
-            let hoursPlayedForCurrUser = 0
-            user.User.gamesowned.forEach(game => {
-                hoursPlayedForCurrUser += Math.floor(game.playtime_forever / 60)
-            });
-            if (hoursPlayedForCurrUser < totalHoursPlayedForUser) {
-                friendsWithLessHoursPlayed++
-            }
-        })
-        percentageOfFriendsWithLessHoursPlayed = Math.floor((friendsWithLessHoursPlayed / graphData.frienddetails.length) * 100)
-    }
+    const entireDaysOfPlaytime = Math.floor(insights.userhoursplayed / 24)
+    const minWageEarnedForGaming = Math.floor(insights.userhoursplayed * minWage)
 
-    countUpElement("statBoxHoursAcrossLibrary", totalHoursPlayedForUser)
+    countUpElement("statBoxHoursAcrossLibrary", insights.userhoursplayed)
     countUpElement("statBoxEntireDaysOfPlaytime", entireDaysOfPlaytime)
-    countUpElement("statBoxFriendsWithLessHoursPlayed", percentageOfFriendsWithLessHoursPlayed, {suffix: "%"})
+    countUpElement("statBoxFriendsWithLessHoursPlayed", insights.friendswithfewerhoursplayed, {suffix: "%"})
     countUpElement("statBoxMinWageEarned", minWageEarnedForGaming, {prefix: "€"})
 
     util.removeSkeletonClasses(["statBoxHoursAcrossLibrary", "statBoxEntireDaysOfPlaytime",
@@ -384,28 +452,16 @@ function fillInUserAndNetworkFavoriteGameStatBoxes(graphData) {
 }
 
 
-function initAndRenderAccountAgeVsFriendCountChart(graphData) {
-    let scatterPlotData = []
+function initAndRenderAccountAgeVsFriendCountChart(graphData, insights) {
+    const scatterPlotData = insights.accountagevsfriendcount
     let maxAccountAge = 0
-
-    let highestFriendCountUser;
-    let maxFriends = 0;
-    graphData.frienddetails.forEach(user => {
-        const friends = user.User.friendids.length;
-        if (friends > maxFriends) {
-            highestFriendCountUser = user;
-            maxFriends = friends
-        }
-        const accAge = user.User.accdetails.timecreated;
-        let monthsSinceCreation = util.monthsSince(accAge)
+    scatterPlotData.forEach(([friends, monthsSinceCreation]) => {
         if (monthsSinceCreation > maxAccountAge) {
             maxAccountAge = monthsSinceCreation
         }
-        scatterPlotData.push([
-            friends, monthsSinceCreation
-        ])
     })
-    highestFriendCountUser = highestFriendCountUser.User;
+
+    const highestFriendCountUser = getFriendBySteamID(graphData, insights.highestfriendcountuser);
     document.getElementById("highestFriendCountUserUsername").textContent = highestFriendCountUser.accdetails.personaname;
     document.getElementById("highestFriendCountUserCountry").textContent = util.countryCodeToName(highestFriendCountUser.accdetails.loccountrycode) === "" ? 'unknown' : util.countryCodeToName(highestFriendCountUser.accdetails.loccountrycode);
     document.getElementById("highestFriendCountUserFriendCount").textContent = highestFriendCountUser.friendids.length;
@@ -823,65 +879,34 @@ function getUsernamesFromCountry(friends, countryCode) {
     return usersFromCountry;
 }
 
-function generateOverallGamerScore(graphData) {
-    const totalHoursPlayed  = getHoursPlayedForUser(graphData.userdetails.User);
-    const totalFriends = graphData.userdetails.User.friendids.length;
-
-    const gamerScore = (totalHoursPlayed * 2) + totalFriends
-    return gamerScore >= 5000 ? 5000 : gamerScore 
-}
-
-function getMostHoursPlayedStats(graphData) {
-    let allUsers = []
-    
-    const mainUserObj = {
-        "username": graphData.userdetails.User.accdetails.personaname,
-        "profiler": graphData.userdetails.User.accdetails.avatar,
-        "profileURL": graphData.userdetails.User.accdetails.profileurl,
-        "hours": getHoursPlayedForUser(graphData.userdetails.User)
-    }
-    allUsers.push(mainUserObj)
-
-    graphData.frienddetails.forEach(user => {
-        const friend = user.User;
-        allUsers.push({
-            "username": friend.accdetails.personaname,
-            "profiler": friend.accdetails.avatar,
-            "profileURL": friend.accdetails.profileurl,
-            "hours": getHoursPlayedForUser(friend)
+function getMostHoursPlayedStats(graphData, insights) {
+    let topEightUsers = []
+    insights.hoursplayedleaderboard.forEach(user => {
+        topEightUsers.push({
+            "username": user.username,
+            "profiler": user.avatar,
+            "profileURL": user.profileurl,
+            "hours": user.hours
         })
-    }) 
-    allUsers.sort(function(userOne, userTwo) {
-        return userOne.hours < userTwo.hours ? 1 : userTwo.hours < userOne.hours ? -1 : 0
     })
-
-    for (let i = 0; i < allUsers.length; i++) {
-        const currUser = allUsers[i]
-        if (currUser.avatar === graphData.userdetails.User.accdetails.avatar &&
-            currUser.username === graphData.userdetails.User.accdetails.personaname) {
-
-                let topEightUsers = allUsers.length >= 8 ? allUsers.slice(0, 8) : allUsers;
-                const returnObj = {
-                        "users": topEightUsers
-                }
-                return returnObj
-        }
+    const returnObj = {
+        "users": topEightUsers
     }
     // The main user is not in the top 8. Include them to be displayed seperately
-    let topEightUsers = allUsers.length >= 8 ? allUsers.slice(0, 8) : allUsers;
-    const returnObj = {
-        "users": topEightUsers,
-        "mainUser": mainUserObj
+    if (insights.userrank > topEightUsers.length) {
+        returnObj.mainUser = {
+            "username": graphData.userdetails.User.accdetails.personaname,
+            "profiler": graphData.userdetails.User.accdetails.avatar,
+            "profileURL": graphData.userdetails.User.accdetails.profileurl,
+            "hours": insights.userhoursplayed
+        }
     }
     return returnObj
 }
 
-function getHoursPlayedForUser(user) {
-    let totalHours = 0
-    user.gamesowned.forEach(game => {
-        totalHours += Math.floor(game.playtime_forever/60)
-    })
-    return totalHours
+function getFriendBySteamID(graphData, steamID) {
+    const friend = graphData.frienddetails.find(friend => friend.User.accdetails.steamid === steamID)
+    return friend === undefined ? undefined : friend.User
 }
 
 function fillInHoursPlayedLeaderboard(leaderboardData) {
@@ -937,6 +962,80 @@ function fillInHoursPlayedLeaderboard(leaderboardData) {
     document.getElementById("hoursPlayedLeaderboard").innerHTML = htmlContent;
 }
 
+function fillInNetworkStatBoxes(graphData) {
+    const stats = graphData.networkstats
+    if (stats === undefined || stats === null) {
+        return
+    }
+    countUpElement('statBoxNetworkUsers', stats.nodecount)
+    countUpElement('statBoxNetworkFriendships', stats.edgecount)
+    countUpElement('statBoxNetworkClustering', Math.round(stats.clusteringcoefficient*100), {suffix: "%"})
+    countUpElement('statBoxNetworkSeparation', stats.averagepathlength, {decimalPlaces: 1})
+
+    util.removeSkeletonClasses(["statBoxNetworkUsers", "statBoxNetworkFriendships",
+            "statBoxNetworkClustering", "statBoxNetworkSeparation"])
+}
+
+// Lists the friends with the most friends in the crawl and the friends
+// that are on the most shortest paths between others
+function fillInCentralityLeaderboards(graphData) {
+    if (graphData.usercentrality === undefined || graphData.usercentrality === null) {
+        return
+    }
+    let steamIDToUser = new Map()
+    graphData.frienddetails.forEach(friend => {
+        steamIDToUser.set(friend.User.accdetails.steamid, friend.User)
+    })
+
+    const mostConnectedUsers = (graphData.mostconnectedusers || []).map(steamID => {
+        return {
+            "user": steamIDToUser.get(steamID),
+            "value": graphData.usercentrality[steamID].degree
+        }
+    })
+    const bridgeUsers = (graphData.bridgeusers || []).map(steamID => {
+        return {
+            "user": steamIDToUser.get(steamID),
+            "value": `${(graphData.usercentrality[steamID].betweenness*100).toFixed(1)}%`
+        }
+    })
+    fillInCentralityLeaderboard("mostConnectedLeaderboard", mostConnectedUsers)
+    fillInCentralityLeaderboard("bridgeUsersLeaderboard", bridgeUsers)
+}
+
+function fillInCentralityLeaderboard(elementID, leaderboardData) {
+    let htmlContent = ``
+    const backgroundColors = ['#292929', '#414141']
+    let i = 0;
+    leaderboardData.forEach(entry => {
+        if (entry.user === undefined) {
+            return
+        }
+        htmlContent += `
+        <div class="row justify-content-start mt-1 pb-1" style="font-size: 1.07rem; border-radius: 6px; background-color:${backgroundColors[i%backgroundColors.length]}; border-color: white;">
+                    <div class="col-1 truncate pt-1 text-center">
+                        ${i+1}.
+                    </div>
+                    <div class="col-1 text-center">
+                        <a href="${entry.user.accdetails.profileurl}">
+                            <img
+                                src="${entry.user.accdetails.avatar}"
+                                style="height: 100%; width: auto"
+                            >
+                        </a>
+                    </div>
+                    <div class="col-7 truncate pt-1">
+                        ${entry.user.accdetails.personaname}
+                    </div>
+                    <div class="col-3 truncate pt-1">
+                        ${entry.value}
+                    </div>
+                </div>`
+        i++
+    })
+    document.getElementById(elementID).innerHTML = htmlContent;
+}
+
 function initNetWorkMostHoursPlayedBarChart(chartData) {
 
     let chartDom = document.getElementById('networkMostHoursPlayedBarChart');
@@ -1017,39 +1116,36 @@ function initNetWorkMostHoursPlayedBarChart(chartData) {
     option && myChart.setOption(option);
 }
 
-function fillInTopStatBoxes(graphData, countryFreqs) {
-    const UNCountries = 195;
-    let uniqueCountryCodes = extractUniqueCountryCodesFromFriends(graphData.usergraphdata.frienddetails)
+function fillInOldestDataAge(graphData) {
+    if (graphData.oldestdatatimestamp === undefined || graphData.oldestdatatimestamp == 0) {
+        document.getElementById("oldestDataAge").textContent = "Unknown";
+    } else {
+        const oldestDataDate = new Date(graphData.oldestdatatimestamp*1000);
+        document.getElementById("oldestDataAge").textContent = `${util.timeSinceLong(oldestDataDate)} ago at the oldest`;
+    }
+    if (graphData.partial) {
+        document.getElementById("oldestDataAge").textContent += `, preview from ${graphData.progress}% of the crawl`;
+    }
+    util.removeSkeletonClasses(["oldestDataAge"])
+}
 
+function fillInTopStatBoxes(graphData, insights) {
     countUpElement('statBoxFriendCount', graphData.usergraphdata.userdetails.User.friendids.length)
-    countUpElement('statBoxUniqueCountries', uniqueCountryCodes.length)
-    countUpElement('statBoxGlobalCoverage', Math.floor((uniqueCountryCodes.length/UNCountries)*100), {suffix: "%"})
-    countUpElement('statBoxDictatorships', ruledByDictatorCountries(uniqueCountryCodes))
+    countUpElement('statBoxUniqueCountries', insights.uniquecountries)
+    countUpElement('statBoxGlobalCoverage', insights.globalcoverage, {suffix: "%"})
+    countUpElement('statBoxDictatorships', insights.dictatorshipcountries)
 
     util.removeSkeletonClasses(["statBoxFriendCount", "statBoxUniqueCountries", 
             "statBoxGlobalCoverage", "statBoxDictatorships"])
 }
 
-function fillInFromYourCountryStatBox(graphDataObj, countryFreq) {
-    let alsoFromCountry = 0;
-    const usersCountry = graphDataObj.usergraphdata.userdetails.User.accdetails.loccountrycode;
-    if (usersCountry === undefined) {
-        document.getElementById("statBoxAlsoFromYourCountry").textContent = alsoFromCountry;
-        util.removeSkeletonClasses(["statBoxAlsoFromYourCountry"])
-        return
-    }
-    const otherFriendsInUsersCountry = countryFreq[usersCountry.toLowerCase()];
-    if (!isNaN(otherFriendsInUsersCountry)) {
-        document.getElementById("statBoxAlsoFromYourCountry").textContent = otherFriendsInUsersCountry;
-        util.removeSkeletonClasses(["statBoxAlsoFromYourCountry"])
-        return
-    }
-    document.getElementById("statBoxAlsoFromYourCountry").textContent = alsoFromCountry;
+function fillInFromYourCountryStatBox(insights) {
+    document.getElementById("statBoxAlsoFromYourCountry").textContent = insights.alsofromuserscountry;
     util.removeSkeletonClasses(["statBoxAlsoFromYourCountry"])
 }
 
-function fillInTop10Countries(countriesFreq) {
-    const topTenCountryNames = getTopTenCountries(countriesFreq);
+function fillInTop10Countries(topCountries) {
+    const topTenCountryNames = topCountries.map(countryCode => util.countryCodeToName(countryCode));
     let i = 1;
     topTenCountryNames.forEach(countryName => {
         document.getElementById("topTenCountriesList").innerHTML += `
@@ -1066,31 +1162,8 @@ function fillInTop10Countries(countriesFreq) {
     });
 }
 
-function userCreatedGraph(graphData) {
-    let friendCreationDatesByMonth = {}
-    let creationDates = []
-    let creationTimestamps = []
-    let creationYearFrequencies = {}
-    
-    graphData.frienddetails.forEach(friend => {
-        creationTimestamps.push(friend.User.accdetails.timecreated)
-    })
-    creationTimestamps.sort(function(j, k) {
-        return j < k;
-    })
-
-    creationTimestamps.forEach(date => {
-        creationDates.push(new Date(date * 1000))
-    })
-    
-    creationDates.forEach(date => {
-        creationYearFrequencies[date.getFullYear()] = creationYearFrequencies[date.getFullYear()] ? creationYearFrequencies[date.getFullYear()] + 1 : 1;
-    })
-
-    const oldestYear = Object.entries(creationYearFrequencies)[0][0]
-    for (let i = oldestYear; i < new Date().getFullYear(); i++) {
-        creationYearFrequencies[i] = creationYearFrequencies[i] ? creationYearFrequencies[i] : 0
-    }
+function userCreatedGraph(insights) {
+    const creationYearFrequencies = insights.creationyearfrequencies
 
     let chartDom = document.getElementById('creationYearBarChartContainer');
     let myChart = echarts.init(chartDom);
@@ -1124,33 +1197,19 @@ function userCreatedGraph(graphData) {
     option && myChart.setOption(option);
 }
 
-function userCreatedMonthChart(graphData) {
-    let creationDates = []
-    let creationTimestamps = []
-    
-    graphData.frienddetails.forEach(friend => {
-        creationTimestamps.push(friend.User.accdetails.timecreated)
-    })
-    creationTimestamps.sort(function(j, k) {
-        return j < k;
-    })
-
-    creationTimestamps.forEach(date => {
-        creationDates.push(new Date(date * 1000))
-    })
-
+function userCreatedMonthChart(insights) {
     let chartDom = document.getElementById('creationMonthHeatMapContainer');
     let myChart = echarts.init(chartDom);
     let option;
 
-    let heatmapData = getHeatmapData(creationDates)
-    fillInMonthStatBoxes(creationDates)
+    let heatmapData = getHeatmapData(insights.creationmonthfrequencies)
+    fillInMonthStatBoxes(insights)
 
     option = {
         visualMap: {
             show: false,
             min: 0,
-            max: getMaxMonthFrequency(creationDates),
+            max: getMaxMonthFrequency(insights.creationmonthfrequencies),
             inRange: {
                 color: ['#d6a1ff', '#2b054a']
               },
@@ -1185,16 +1244,13 @@ function userCreatedMonthChart(graphData) {
     option && myChart.setOption(option);
 }
 
-function fillInContinentCoverage(countryFreqs) {
-    const allCountryCodes = Object.keys(countryFreqs)
-    const continentCoverage = getContinentsCovered(allCountryCodes)
-
-    document.getElementById("statBoxContinentCoverage").textContent = Math.floor(continentCoverage*100)+"%";
+function fillInContinentCoverage(insights) {
+    document.getElementById("statBoxContinentCoverage").textContent = insights.continentcoverage+"%";
     util.removeSkeletonClasses(["statBoxContinentCoverage"])
     return
 }
 
-function initGamerScore(mainUser) {
+function initGamerScore(insights) {
     let chartDom = document.getElementById('gamerScore');
     let myChart = echarts.init(chartDom);
     let option;
@@ -1279,7 +1335,7 @@ function initGamerScore(mainUser) {
         },
         data: [
             {
-            value: generateOverallGamerScore(mainUser)/5000,
+            value: insights.gamerscore/5000,
             }
         ]
         }
@@ -1300,97 +1356,16 @@ function extractUniqueCountryCodesFromFriends(friends) {
     return allCountryCodes;
 }
 
-function getContinentsCovered(countryCodes) {
-    const TOTAL_CONTINENTS = 7
-    let continentCoverage = 0
-    let asiaMatch = false;
-    let africaMatch = false;
-    let australiaMatch = false;
-    let europeMatch = false;
-    let northAmericaMatch = false;
-    let southAmericaMatch = false
-
-    countryCodes.forEach(countryCode => {
-        if (!asiaMatch) {
-            if (util.continents["asia"].includes(countryCode.toUpperCase())) {
-                continentCoverage++
-                asiaMatch = true
-            }
-        }
-        if (!africaMatch) {
-            if (util.continents["africa"].includes(countryCode.toUpperCase())) {
-                continentCoverage++
-                africaMatch = true
-            }
-        }
-        if (!australiaMatch) {
-            if (util.continents["australia"].includes(countryCode.toUpperCase())) {
-                continentCoverage++
-                australiaMatch = true
-            }
-        }
-        if (!europeMatch) {
-            if (util.continents["europe"].includes(countryCode.toUpperCase())) {
-                continentCoverage++
-                europeMatch = true
-            }
-        }
-        if (!northAmericaMatch) {
-            if (util.continents["north america"].includes(countryCode.toUpperCase())) {
-                continentCoverage++
-                northAmericaMatch = true
-            }
-        }
-        if (!southAmericaMatch) {
-            if (util.continents["south america"].includes(countryCode.toUpperCase())) {
-                continentCoverage++
-                southAmericaMatch = true
-            }
-        }
-    })
-    return continentCoverage / TOTAL_CONTINENTS
-}
-
-function fillInMonthStatBoxes(creationDates) {
-    let userCreationMonthFrequencies = {}
-    creationDates.forEach(date => {
-        userCreationMonthFrequencies[date.getMonth()] = userCreationMonthFrequencies[date.getMonth()] ? userCreationMonthFrequencies[date.getMonth()] + 1 : 1;
-    })
-    const sortedMonthFrequencies = Object.entries(userCreationMonthFrequencies).sort((a, b) => { return a[1] < b[1]})
-
-    document.getElementById("statBoxMostPopularMonth").textContent = util.intToMonth(Object.values(sortedMonthFrequencies)[0][0])
-    document.getElementById("statBoxLeastPopularMonth").textContent = util.intToMonth(Object.values(sortedMonthFrequencies)[sortedMonthFrequencies.length-1][0])
+function fillInMonthStatBoxes(insights) {
+    document.getElementById("statBoxMostPopularMonth").textContent = util.intToMonth(insights.mostpopularcreationmonth)
+    document.getElementById("statBoxLeastPopularMonth").textContent = util.intToMonth(insights.leastpopularcreationmonth)
 
     util.removeSkeletonClasses(["statBoxMostPopularMonth", "statBoxLeastPopularMonth"])
 }
 
-// https://worldpopulationreview.com/country-rankings/dictatorship-countries  
-function ruledByDictatorCountries(countries) {
-    let dictatorRuledCountryCount = 0
-    const dictatorRuledCountries = [
-        "AF", "AL", "AO", "AZ", "BH", "BD", "BY", "BN", "BI", "KH",
-        "CM", "CF", "TD", "CN", "CU", "DJ", "CD", "EG", "GQ", "ER", 
-        "SZ", "ET", "GA", "IR", "IQ", "KZ", "LA", "LY", "MM", "NI",
-        "KP", "OM", "QA", "CD", "RU", "RW", "SA", "SO", "SD", "SY",
-        "SS", "TJ", "TR", "TM", "UG", "AE", "UZ", "VE", "VN", "EH",
-        "YE"
-    ]
-    countries.forEach(countryCode => {
-        if (dictatorRuledCountries.includes(countryCode.toUpperCase())) {
-            dictatorRuledCountryCount++;
-        }
-    })
-    return dictatorRuledCountryCount;
-}
-
-function fillInOldestAndNewestUserCards(graphData) {
-    let allFriends = graphData.frienddetails;
-    allFriends.sort((f1,f2) => { 
-        return new Date(f1.User.accdetails.timecreated * 1000) - new Date(f2.User.accdetails.timecreated * 1000) 
-    })
-
-    const oldestUser = allFriends[0].User
-    const newestUser = allFriends[allFriends.length-1].User
+function fillInOldestAndNewestUserCards(graphData, insights) {
+    const oldestUser = getFriendBySteamID(graphData, insights.oldestuser)
+    const newestUser = getFriendBySteamID(graphData, insights.newestuser)
 
     document.getElementById("oldestUserUsername").textContent = oldestUser.accdetails.personaname;
     document.getElementById("oldestUserCountry").textContent = util.countryCodeToName(oldestUser.accdetails.loccountrycode) === "" ? 'unknown' : util.countryCodeToName(oldestUser.accdetails.loccountrycode);
@@ -1417,15 +1392,3 @@ function fillInOldestAndNewestUserCards(graphData) {
     "newestUserUsername", "newestUserCountry", "newestUserFriendCount", "newestUserCreationDate",
     "newestUserProfile", "newestUserAvatar"])
 }
-
-function getTopTenCountries(countriesFreq) {
-    let countryNames = []
-    const sortedCountriesFreq = Object.entries(countriesFreq).sort((a,b) => b[1]-a[1])
-    for (let i = 0; i < sortedCountriesFreq.length; i++) {
-        countryNames.push(util.countryCodeToName(sortedCountriesFreq[i][0].toUpperCase()))
-    }
-    if (countryNames.length >= 10) {
-        return countryNames.slice(0, 10)
-    }
-    return countryNames;
-}
\ No newline at end of file
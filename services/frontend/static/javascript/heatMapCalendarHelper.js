@@ -1,10 +1,8 @@
-export function getHeatmapData(userCreationDates) {
-    let userCreationMonthFrequencies = {}
+// creationMonthFrequencies is how many users were created in each month
+// starting from January
+export function getHeatmapData(creationMonthFrequencies) {
     let heatMapData = []
 
-    userCreationDates.forEach(date => {
-        userCreationMonthFrequencies[date.getMonth()] = userCreationMonthFrequencies[date.getMonth()] ? userCreationMonthFrequencies[date.getMonth()] + 1 : 1;
-    })
     const monthLengthsInDays = [31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31]
     let i = 0;
     monthLengthsInDays.forEach(monthLength => {
@@ -12,7 +10,7 @@ export function getHeatmapData(userCreationDates) {
             let currTime = echarts.number.parseDate(`2022-${i+1}-${k}`)
             heatMapData.push([
                 echarts.format.formatTime('yyyy-MM-dd', currTime),
-                Object.values(userCreationMonthFrequencies)[i]
+                creationMonthFrequencies[i]
             ]);
         }
         i++;
@@ -20,10 +18,6 @@ export function getHeatmapData(userCreationDates) {
     return heatMapData;
 }
 
-export function getMaxMonthFrequency(userCreationDates) {
-    let userCreationMonthFrequencies = {}
-    userCreationDates.forEach(date => {
-        userCreationMonthFrequencies[date.getMonth()] = userCreationMonthFrequencies[date.getMonth()] ? userCreationMonthFrequencies[date.getMonth()] + 1 : 1;
-    })
-    return Object.values(userCreationMonthFrequencies).sort((a,b) => { return b-a})[0]
+export function getMaxMonthFrequency(creationMonthFrequencies) {
+    return Math.max(...creationMonthFrequencies)
 }
\ No newline at end of file
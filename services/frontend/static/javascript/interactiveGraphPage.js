@@ -53,10 +53,12 @@ function initThreeJSGraph(crawlData) {
     let nodes = []
     let links = []
 
+    const userCommunities = crawlData.usercommunities || {}
     nodes.push({
         "id": crawlData.userdetails.User.accdetails.steamid, 
         "username": crawlData.userdetails.User.accdetails.personaname,
-        "avatar":crawlData.userdetails.User.accdetails.avatar
+        "avatar":crawlData.userdetails.User.accdetails.avatar,
+        "community": userCommunities[crawlData.userdetails.User.accdetails.steamid]
     })
     seenNodes.set(crawlData.userdetails.User.accdetails.steamid, true)
 
@@ -64,7 +66,8 @@ function initThreeJSGraph(crawlData) {
         nodes.push({
             "id":friend.User.accdetails.steamid, 
             "username": friend.User.accdetails.personaname,
-            "avatar":friend.User.accdetails.avatar
+            "avatar":friend.User.accdetails.avatar,
+            "community": userCommunities[friend.User.accdetails.steamid]
         })
         seenNodes.set(friend.User.accdetails.steamid, true)
     })
@@ -108,6 +111,18 @@ function initThreeJSGraph(crawlData) {
         dst.neighbourLinks.push(link)
     });
 
+    // Graphs laid out when they were created are fixed in place so the
+    // browser does not have to lay them out. Older graphs and ones missing
+    // a position for any user are laid out here
+    const positions = crawlData.layout ? crawlData.layout.positions3d : undefined
+    const precomputedLayout = Boolean(positions) && nodes.every(node => positions[node.id])
+    if (precomputedLayout) {
+        nodes.forEach(node => {
+            [node.x, node.y, node.z] = positions[node.id];
+            [node.fx, node.fy, node.fz] = positions[node.id]
+        })
+    }
+
     const threeJSGraphData = {
         nodes: nodes,
         links: links
@@ -118,6 +133,7 @@ function initThreeJSGraph(crawlData) {
     let highlightedNodes = new Set()
     let highlightedLinks = new Set()
     const g = ForceGraph3D()(threeJSGraphDiv)
+        .cooldownTicks(precomputedLayout ? 0 : Infinity)
         .graphData(threeJSGraphData)
         .nodeAutoColorBy('user')
         .nodeThreeObject(({ avatar }) => {
@@ -142,7 +158,7 @@ function initThreeJSGraph(crawlData) {
             }, 3300)
         })
         .linkWidth(link => highlightedLinks.has(link) ? 4 : 1)
-        .linkColor(link => highlightedLinks.has(link) ? 'green' : 'white')
+        .linkColor(link => highlightedLinks.has(link) ? 'green' : getCommunityLinkColour(link))
         .linkDirectionalParticles(link => highlightedLinks.has(link) ? 8 : 0)
         .linkDirectionalParticleWidth(3)
         .linkDirectionalParticleColor(() => 'green')
@@ -180,6 +196,18 @@ function initThreeJSGraph(crawlData) {
     });
 }
 
+const communityColours = ["#e6194b", "#3cb44b", "#ffe119", "#4363d8", "#f58231", "#911eb4", "#46f0f0", "#f032e6"]
+
+// Links between friends in the same community are coloured by that community.
+// Communities are numbered from largest to smallest so only the largest get a colour
+function getCommunityLinkColour(link) {
+    const community = link.source.community
+    if (community === undefined || community !== link.target.community || community >= communityColours.length) {
+        return 'white'
+    }
+    return communityColours[community]
+}
+
 function initThreeJSGraphForTwoUsersCombined(crawlData) {
     console.log(crawlData)
     const shortestDistanceIDs = crawlData.shortestdistance.map(n => n.accdetails.steamid)
@@ -1,8 +1,11 @@
 import { setCrawlPageUserCardDetails } from '/static/javascript/userCard.js';
 
-export function doesProcessedGraphDataExist(crawlID) {
+// Partial graphs of crawls that are still going are only counted
+// when includePartial is given
+export function doesProcessedGraphDataExist(crawlID, includePartial = false) {
+    const partialQuery = includePartial ? "?partial=true" : ""
     return new Promise((resolve, reject) => {
-        fetch(`http://localhost:2590/api/doesprocessedgraphdataexist/${crawlID}`, {
+        fetch(`http://localhost:2590/api/doesprocessedgraphdataexist/${crawlID}${partialQuery}`, {
             method: 'POST',
             headers: {
                 "Content-Type": "application/json"
@@ -35,6 +38,40 @@ export function getProcessedGraphData(crawlID) {
     });
 }
 
+// Insights are the statistics shown on the graph page, worked out by
+// the datastore when the graph is saved
+export function getInsights(crawlID) {
+    return new Promise((resolve, reject) => {
+        fetch(`http://localhost:2590/api/getinsights/${crawlID}`, {
+            headers: {
+                "Content-Type": "application/json"
+            },
+        }).then(res => res.json())
+        .then(data => {
+            resolve(data.insights)
+        }).catch(err => {
+            reject(err)
+        })
+    });
+}
+
+// Super nodes given in expanded are shown as the users in them
+export function getCoarsenedGraph(crawlID, maxNodes, expanded = []) {
+    const expandQuery = encodeURIComponent(expanded.join(","))
+    return new Promise((resolve, reject) => {
+        fetch(`http://localhost:2590/api/coarsengraph/${crawlID}?maxnodes=${maxNodes}&expand=${expandQuery}`, {
+            headers: {
+                "Content-Type": "application/json"
+            },
+        }).then(res => res.json())
+        .then(data => {
+            resolve(data.coarsenedgraph)
+        }).catch(err => {
+            reject(err)
+        })
+    });
+}
+
 export function getAnyNewFinishedCrawlStatuses() {
     return new Promise((resolve, reject) => {
         fetch(`http://localhost:2590/api/getfinishedcrawlsaftertimestamp?timestamp=${5}`, {
@@ -103,6 +140,17 @@ export function getShortestDistanceInfo(crawlIDs) {
     })
 }
 
+const finishedCrawlStates = ['crawl-complete', 'graphing', 'graphed']
+
+// Crawls started before states were recorded have no state so their
+// counts are used instead
+export function hasFinishedCrawling(crawlingStatus) {
+    if (!crawlingStatus.state) {
+        return crawlingStatus.totaluserstocrawl == crawlingStatus.userscrawled
+    }
+    return finishedCrawlStates.includes(crawlingStatus.state)
+}
+
 export function isCrawlingFinished(crawlID) {
     return new Promise((resolve, reject) => {
         fetch(`http://localhost:2590/api/getcrawlingstatus/${crawlID}`, {
@@ -111,10 +159,7 @@ export function isCrawlingFinished(crawlID) {
             }
         }).then((res => res.json()))
         .then(data => {
-            if (data.crawlingstatus.totaluserstocrawl == data.crawlingstatus.userscrawled) {
-                resolve(true)
-            }
-            resolve(false)
+            resolve(hasFinishedCrawling(data.crawlingstatus))
         }).catch(err => {
             reject(err)
         })
@@ -153,10 +198,26 @@ export function startCreateGraph(crawlID) {
     })
 }
 
-export function waitUntilGraphDataExists(crawlID) {
+export function startCreatePartialGraph(crawlID) {
+    return new Promise((resolve, reject) => {
+        fetch(`http://localhost:2570/creategraph/${crawlID}?partial=true`, {
+            method: 'POST',
+            headers: {
+                "Content-Type": "application/json"
+            },
+        }).then((res => res.json()))
+        .then(data => {
+            resolve(data)
+        }).catch(err => {
+            reject(err)
+        })
+    })
+}
+
+export function waitUntilGraphDataExists(crawlID, includePartial = false) {
     return new Promise((resolve, reject) => {
         let interval = setInterval(function() {
-            doesProcessedGraphDataExist(crawlID).then(doesExist => {
+            doesProcessedGraphDataExist(crawlID, includePartial).then(doesExist => {
                 if (doesExist === true) {
                     clearInterval(interval);
                     resolve(true)